@@ -3,38 +3,50 @@ package app
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/fiffu/arisa3/app/cogs"
 	"github.com/fiffu/arisa3/app/database"
 	"github.com/fiffu/arisa3/app/engine"
+	"github.com/fiffu/arisa3/app/engine/scheduler"
 	"github.com/fiffu/arisa3/app/instrumentation"
 	"github.com/fiffu/arisa3/app/log"
-	"github.com/fiffu/arisa3/app/types"
 	"github.com/fiffu/arisa3/app/utils"
+	"github.com/fiffu/arisa3/lib"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 // IDependencyInjector is an interface for initializing injected dependencies.
 type IDependencyInjector interface {
-	NewDatabase(ctx context.Context, dsn string) (database.IDatabase, error)
+	NewDatabase(ctx context.Context, dsn string, lockWait time.Duration) (database.IDatabase, error)
 	NewInstrumentationClient(ctx context.Context) (instrumentation.Client, error)
+	NewCacheStore(url string) (lib.ICacheStore, error)
 	Bot(token string, debugMode bool) (*discordgo.Session, error)
 }
 
 // DefaultInjector provides default methods satisfying IDependencyInjector.
 type DefaultInjector struct{}
 
-func (d DefaultInjector) NewDatabase(ctx context.Context, dsn string) (database.IDatabase, error) {
-	return database.NewDBClient(ctx, dsn)
+func (d DefaultInjector) NewDatabase(ctx context.Context, dsn string, lockWait time.Duration) (database.IDatabase, error) {
+	return database.NewDBClient(ctx, dsn, lockWait)
 }
 
 func (d DefaultInjector) NewInstrumentationClient(ctx context.Context) (instrumentation.Client, error) {
 	return instrumentation.NewInstrumentationClient(ctx)
 }
 
+// NewCacheStore connects to the store at the URL, or returns nil if the URL is empty.
+func (d DefaultInjector) NewCacheStore(url string) (lib.ICacheStore, error) {
+	if url == "" {
+		return nil, nil
+	}
+	return lib.NewRedisStore(url)
+}
+
 func (d DefaultInjector) Bot(token string, debugMode bool) (*discordgo.Session, error) {
 	sess, err := discordgo.New("Bot " + token)
 	if err != nil {
@@ -52,13 +64,21 @@ type app struct {
 	db          database.IDatabase
 	inst        instrumentation.Client
 	sess        *discordgo.Session
+	sched       scheduler.IScheduler
+	cacheStore  lib.ICacheStore
+
+	migrationDriftOverrides []string
+	migrationLockWait       time.Duration
 }
 
 func (a *app) Configs() map[string]interface{} { return a.cogsConfigs }
 func (a *app) Database() database.IDatabase    { return a.db }
 func (a *app) BotSession() *discordgo.Session  { return a.sess }
+func (a *app) Scheduler() scheduler.IScheduler { return a.sched }
+func (a *app) CacheStore() lib.ICacheStore     { return a.cacheStore }
 func (a *app) Shutdown(ctx context.Context) {
 	defer a.inst.Shutdown()
+	a.sched.Stop()
 	if err := a.sess.Close(); err != nil {
 		log.Errorf(ctx, err, "Error while closing session")
 		log.Stack(ctx, err)
@@ -67,6 +87,11 @@ func (a *app) Shutdown(ctx context.Context) {
 		log.Errorf(ctx, err, "Error while closing DB connection")
 		log.Stack(ctx, err)
 	}
+	if closer, ok := a.cacheStore.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Errorf(ctx, err, "Error while closing cache store")
+		}
+	}
 }
 
 func Main(deps IDependencyInjector, configPath string) error {
@@ -77,6 +102,11 @@ func Main(deps IDependencyInjector, configPath string) error {
 		return err
 	}
 
+	repos := repositories(app)
+	if err := engine.MigrateCogs(ctx, app.Database(), app.migrationLockWait, app.migrationDriftOverrides, repos...); err != nil {
+		return err
+	}
+
 	log.Infof(ctx, "Initializing cogs")
 	if err = cogs.SetupCogs(ctx, app); err != nil {
 		return err
@@ -91,13 +121,53 @@ func Main(deps IDependencyInjector, configPath string) error {
 	log.Infof(ctx, "Gateway session established")
 	defer app.Shutdown(ctx)
 
+	if err := app.Scheduler().Start(ctx); err != nil {
+		return err
+	}
+
 	log.Infof(ctx, "Press Ctrl+C to exit")
 	waitUntilInterrupt(ctx)
 
 	return nil
 }
 
-func newApp(ctx context.Context, deps IDependencyInjector, configPath string) (types.IApp, error) {
+// MigrationStatus prints the state of every cog's migrations, without starting the bot.
+func MigrationStatus(deps IDependencyInjector, configPath string) error {
+	ctx := engine.StartupContext()
+
+	app, err := newApp(ctx, deps, configPath)
+	if err != nil {
+		return err
+	}
+	defer app.Shutdown(ctx)
+
+	report, err := engine.ReportMigrations(ctx, app.Database(), repositories(app)...)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+	return nil
+}
+
+// RollbackMigrations reverts every cog's migrations that are newer than the target version,
+// without starting the bot.
+func RollbackMigrations(deps IDependencyInjector, configPath, target string) error {
+	ctx := engine.StartupContext()
+
+	app, err := newApp(ctx, deps, configPath)
+	if err != nil {
+		return err
+	}
+	defer app.Shutdown(ctx)
+
+	rolledBack, err := engine.RollbackMigrations(ctx, app.Database(), app.migrationLockWait, target, repositories(app)...)
+	for _, version := range rolledBack {
+		fmt.Printf("Rolled back %s\n", version)
+	}
+	return err
+}
+
+func newApp(ctx context.Context, deps IDependencyInjector, configPath string) (*app, error) {
 	log.SetupLogger()
 
 	cfg, err := Configure(configPath)
@@ -106,11 +176,16 @@ func newApp(ctx context.Context, deps IDependencyInjector, configPath string) (t
 	}
 	cogsCfg := getCogsConfigs(cfg)
 
-	db, err := deps.NewDatabase(ctx, cfg.DatabaseDSN)
+	db, err := deps.NewDatabase(ctx, cfg.DatabaseDSN, cfg.MigrationLockWait())
 	if err != nil {
 		return nil, err
 	}
 
+	cacheStore, err := deps.NewCacheStore(cfg.CacheStoreURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache store: %w", err)
+	}
+
 	inst, err := instrumentation.NewInstrumentationClient(ctx)
 	if err != nil {
 		return nil, err
@@ -126,9 +201,19 @@ func newApp(ctx context.Context, deps IDependencyInjector, configPath string) (t
 		db:          db,
 		inst:        inst,
 		sess:        sess,
+		sched:       scheduler.New(db),
+		cacheStore:  cacheStore,
+
+		migrationDriftOverrides: cfg.MigrationDriftOverrides(),
+		migrationLockWait:       cfg.MigrationLockWait(),
 	}, nil
 }
 
+// repositories lists the cogs with migrations, and the scheduler, whose table is migrated with them.
+func repositories(app *app) []engine.IRepository {
+	return append(cogs.Repositories(app), scheduler.Migrations{})
+}
+
 func getCogsConfigs(cfg *Config) map[string]interface{} {
 	out := make(map[string]interface{})
 	for k, v := range cfg.Cogs {
@@ -3,10 +3,12 @@ package app
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/fiffu/arisa3/app/database"
 	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/lib"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,7 +17,7 @@ type testDependencyInjector struct {
 	ctrl *gomock.Controller
 }
 
-func (d testDependencyInjector) NewDatabase(ctx context.Context, dsn string) (database.IDatabase, error) {
+func (d testDependencyInjector) NewDatabase(ctx context.Context, dsn string, lockWait time.Duration) (database.IDatabase, error) {
 	return database.NewMockIDatabase(d.ctrl), nil
 }
 
@@ -23,6 +25,10 @@ func (d testDependencyInjector) NewInstrumentationClient(ctx context.Context) (i
 	return instrumentation.NewInstrumentationClient(ctx)
 }
 
+func (d testDependencyInjector) NewCacheStore(url string) (lib.ICacheStore, error) {
+	return nil, nil
+}
+
 func (d testDependencyInjector) Bot(token string, debugMode bool) (*discordgo.Session, error) {
 	return nil, nil
 }
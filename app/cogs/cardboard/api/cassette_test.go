@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/fiffu/arisa3/testfixtures/cassette"
+	"github.com/stretchr/testify/assert"
+)
+
+// Cassettes are replayed from testdata/cassettes. To re-record them against the live API, run:
+//
+//	RECORD_CASSETTES=1 go test ./app/cogs/cardboard/api/ -run Test_Cassette
+func newCassetteClient(t *testing.T, name string) *client {
+	tape := cassette.Load(t, filepath.Join("testdata", "cassettes", name))
+	client := newClient("", "", 10)
+	client.fetch = tape.Fetcher()
+	return client
+}
+
+func Test_Cassette_GetPosts(t *testing.T) {
+	client := newCassetteClient(t, "posts.json")
+
+	posts, err := client.GetPosts(context.Background(), []string{"capybara", "original"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, posts)
+	for _, post := range posts {
+		assert.Contains(t, post.TagsList(), "capybara")
+		assert.NotEmpty(t, post.GetFileURL())
+	}
+}
+
+func Test_Cassette_GetTags(t *testing.T) {
+	client := newCassetteClient(t, "tags.json")
+
+	tags, err := client.GetTags(context.Background(), []string{"capybara"})
+	assert.NoError(t, err)
+	assert.Contains(t, tags, "capybara")
+	assert.Positive(t, tags["capybara"].PostCount)
+
+	matching, err := client.GetTagsMatching(context.Background(), "capy*")
+	assert.NoError(t, err)
+	for _, tag := range matching {
+		assert.Regexp(t, "^capy", tag.Name)
+	}
+}
+
+func Test_Cassette_AutocompleteTag(t *testing.T) {
+	client := newCassetteClient(t, "autocomplete.json")
+
+	suggestions, err := client.AutocompleteTag(context.Background(), "ting")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, suggestions)
+	for _, suggestion := range suggestions {
+		assert.NotEmpty(t, suggestion.Name)
+		assert.Regexp(t, "^"+apiHostHTTPS+"/posts", suggestion.Link)
+	}
+}
+
+// The maintenance cassette can only be recorded during an outage, so keep it when re-recording.
+func Test_Cassette_maintenance(t *testing.T) {
+	t.Setenv(cassette.EnvRecord, "")
+	client := newCassetteClient(t, "maintenance.json")
+
+	_, err := client.GetPosts(context.Background(), []string{"capybara"})
+	assert.ErrorIs(t, err, ErrUnderMaintenance)
+
+	_, err = client.AutocompleteTag(context.Background(), "ting")
+	assert.ErrorIs(t, err, ErrUnderMaintenance)
+}
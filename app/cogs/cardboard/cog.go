@@ -2,7 +2,8 @@ package cardboard
 
 import (
 	"context"
-	"path/filepath"
+	"embed"
+	"io/fs"
 
 	"github.com/fiffu/arisa3/app/commandfilters"
 	"github.com/fiffu/arisa3/app/database"
@@ -14,7 +15,8 @@ import (
 )
 
 var (
-	migrationsDir = filepath.Join(lib.MustGetCallerDir(), "dbmigrations")
+	//go:embed dbmigrations
+	embeddedMigrations embed.FS
 
 	respRequiresGuild = types.NewResponse().Content("This command can only be used from a server.")
 	respRequiresAdmin = types.NewResponse().Content("This command can only be used from a server by a server admin.")
@@ -25,6 +27,7 @@ var (
 type Cog struct {
 	commands *engine.CommandsRegistry
 	db       database.IDatabase
+	store    lib.ICacheStore
 
 	cfg    *Config
 	domain IDomain
@@ -40,6 +43,7 @@ func NewCog(a types.IApp) types.ICog {
 	return &Cog{
 		commands: engine.NewCommandRegistry(),
 		db:       a.Database(),
+		store:    a.CacheStore(),
 	}
 }
 
@@ -54,6 +58,7 @@ func (c *Cog) Configure(ctx context.Context, cfg types.CogConfig) error {
 
 	c.domain = NewDomain(
 		c.db,
+		c.store,
 		c.cfg,
 	)
 	return nil
@@ -63,8 +68,10 @@ func (c *Cog) OnStartup(ctx context.Context, app types.IApp, rawConfig types.Cog
 	return engine.Bootstrap(ctx, app, rawConfig, c)
 }
 
-func (c *Cog) MigrationsDir() string {
-	return migrationsDir
+func (c *Cog) MigrationsFS() fs.FS {
+	// Sub only fails on invalid paths, and this one is fixed by the embed directive above
+	migrations, _ := fs.Sub(embeddedMigrations, "dbmigrations")
+	return migrations
 }
 
 func (c *Cog) ReadyCallback(ctx context.Context, s *dgo.Session, r *dgo.Ready) error {
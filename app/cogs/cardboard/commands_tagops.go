@@ -73,7 +73,7 @@ func (c *Cog) promote(ctx context.Context, req types.ICommandEvent) error {
 		return req.Respond(ctx, respRequiresAdmin)
 	}
 
-	if err := c.domain.SetPromote(ctx, tagName, guildID); err != nil {
+	if err := c.domain.SetPromote(ctx, guildID, tagName); err != nil {
 		return err
 	}
 	resp := types.NewResponse().Content(fmt.Sprintf("Marked `%s` to be promoted.", tagName))
@@ -88,7 +88,7 @@ func (c *Cog) demote(ctx context.Context, req types.ICommandEvent) error {
 		return req.Respond(ctx, respRequiresAdmin)
 	}
 
-	if err := c.domain.SetDemote(ctx, tagName, guildID); err != nil {
+	if err := c.domain.SetDemote(ctx, guildID, tagName); err != nil {
 		return err
 	}
 	resp := types.NewResponse().Content(fmt.Sprintf("Marked `%s` to be demoted.", tagName))
@@ -103,7 +103,7 @@ func (c *Cog) omit(ctx context.Context, req types.ICommandEvent) error {
 		return req.Respond(ctx, respRequiresAdmin)
 	}
 
-	if err := c.domain.SetOmit(ctx, tagName, guildID); err != nil {
+	if err := c.domain.SetOmit(ctx, guildID, tagName); err != nil {
 		return err
 	}
 	resp := types.NewResponse().Content(fmt.Sprintf("Marked `%s` to be omitted.", tagName))
@@ -6,6 +6,7 @@ import (
 	"github.com/fiffu/arisa3/app/cogs/cardboard/api"
 	"github.com/fiffu/arisa3/app/database"
 	"github.com/fiffu/arisa3/app/types"
+	"github.com/fiffu/arisa3/lib"
 )
 
 type domain struct {
@@ -13,10 +14,10 @@ type domain struct {
 	client api.IClient
 }
 
-func NewDomain(db database.IDatabase, cfg *Config) *domain {
+func NewDomain(db database.IDatabase, store lib.ICacheStore, cfg *Config) *domain {
 	apiClient := api.NewClient(cfg.User, cfg.APIKey, cfg.APITimeoutSecs)
 	return &domain{
-		NewRepository(db),
+		NewRepository(db, store),
 		apiClient,
 	}
 }
@@ -43,21 +44,21 @@ func (d *domain) PostsResult(ctx context.Context, query IQueryPosts, posts []*ap
 // Remaining methods proxy to the repo
 
 func (d *domain) SetPromote(ctx context.Context, gid, tagName string) error {
-	return d.repo.SetPromote(gid, tagName)
+	return d.repo.SetPromote(ctx, gid, tagName)
 }
 
 func (d *domain) SetDemote(ctx context.Context, gid, tagName string) error {
-	return d.repo.SetDemote(gid, tagName)
+	return d.repo.SetDemote(ctx, gid, tagName)
 }
 
 func (d *domain) SetOmit(ctx context.Context, gid, tagName string) error {
-	return d.repo.SetOmit(gid, tagName)
+	return d.repo.SetOmit(ctx, gid, tagName)
 }
 
 func (d *domain) SetAlias(ctx context.Context, gid string, al Alias, ac Actual) error {
-	return d.repo.SetAlias(gid, al, ac)
+	return d.repo.SetAlias(ctx, gid, al, ac)
 }
 
 func (d *domain) GetAliases(ctx context.Context, gid string) (map[Alias]Actual, error) {
-	return d.repo.GetAliases(gid)
+	return d.repo.GetAliases(ctx, gid)
 }
@@ -27,7 +27,8 @@ func newTestPost() *api.Post {
 func Test_formatZeroResults(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	db := database.NewMockIDatabase(ctrl)
-	d := NewDomain(db, &Config{})
+	db.EXPECT().Listen(gomock.Any(), invalidationChannel, gomock.Any())
+	d := NewDomain(db, nil, &Config{})
 
 	term := "testing"
 	query := NewMockIQueryPosts(ctrl)
@@ -40,6 +41,7 @@ func Test_formatZeroResults(t *testing.T) {
 func Test_formatResult(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	db := database.NewMockIDatabase(ctrl)
+	db.EXPECT().Listen(gomock.Any(), invalidationChannel, gomock.Any())
 	client := api.NewMockIClient(ctrl)
 	client.EXPECT().
 		GetTags(gomock.Any(), gomock.Any()).
@@ -53,7 +55,7 @@ func Test_formatResult(t *testing.T) {
 			}, nil
 		})
 
-	d := NewDomain(db, &Config{})
+	d := NewDomain(db, nil, &Config{})
 	d.client = client
 
 	testTerm := "testing"
@@ -76,6 +78,7 @@ func Test_formatResult(t *testing.T) {
 func Test_formatResult_shouldOnlyGetTagsForTermArtistCopyright(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	db := database.NewMockIDatabase(ctrl)
+	db.EXPECT().Listen(gomock.Any(), invalidationChannel, gomock.Any())
 	client := api.NewMockIClient(ctrl)
 	client.EXPECT().
 		GetTags(gomock.Any(), []string{"testing", "artist", "copyright"}).
@@ -89,7 +92,7 @@ func Test_formatResult_shouldOnlyGetTagsForTermArtistCopyright(t *testing.T) {
 			}, nil
 		})
 
-	d := NewDomain(db, &Config{})
+	d := NewDomain(db, nil, &Config{})
 	d.client = client
 
 	testTerm := "testing"
@@ -109,6 +112,7 @@ func Test_formatResult_shouldOnlyGetTagsForTermArtistCopyright(t *testing.T) {
 func Test_formatResult_shouldNotFailIfGetTagsErrors(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	db := database.NewMockIDatabase(ctrl)
+	db.EXPECT().Listen(gomock.Any(), invalidationChannel, gomock.Any())
 	client := api.NewMockIClient(ctrl)
 	client.EXPECT().
 		GetTags(gomock.Any(), []string{"testing", "artist", "copyright"}).
@@ -117,7 +121,7 @@ func Test_formatResult_shouldNotFailIfGetTagsErrors(t *testing.T) {
 			return nil, assert.AnError
 		})
 
-	d := NewDomain(db, &Config{})
+	d := NewDomain(db, nil, &Config{})
 	d.client = client
 
 	testTerm := "testing"
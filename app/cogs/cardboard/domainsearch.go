@@ -61,7 +61,7 @@ func (d *domain) magicSearch(ctx context.Context, q IQueryPosts, trySuggestion b
 func (d *domain) resolveAndNormalize(ctx context.Context, q IQueryPosts) {
 	// Resolve any alias matches on the term.
 	// Log any errors, but don't break the flow.
-	if newTerm, err := d.findAlias(q); err != nil {
+	if newTerm, err := d.findAlias(ctx, q); err != nil {
 		log.Errorf(ctx, err, "Errored while fetching aliases")
 	} else if newTerm != q.Term() {
 		log.Infof(ctx, "Resolved alias %s -> %s", q.Term(), newTerm)
@@ -100,7 +100,7 @@ func (d *domain) filter(ctx context.Context, q IQueryPosts, posts []*api.Post) (
 
 	guildID := q.GuildID()
 	if guildID != "" {
-		guildOpsMapping, err := d.repo.GetTagOperations(guildID)
+		guildOpsMapping, err := d.repo.GetTagOperations(ctx, guildID)
 		if err != nil {
 			return nil, err
 		}
@@ -131,13 +131,13 @@ func (d *domain) filter(ctx context.Context, q IQueryPosts, posts []*api.Post) (
 	return posts, nil
 }
 
-func (d *domain) findAlias(q IQueryPosts) (string, error) {
+func (d *domain) findAlias(ctx context.Context, q IQueryPosts) (string, error) {
 	term := q.Term()
 	aliases := make(map[Alias]Actual)
 
 	guildID := q.GuildID()
 	if guildID != "" {
-		guildAliases, err := d.repo.GetAliases(guildID)
+		guildAliases, err := d.repo.GetAliases(ctx, guildID)
 		if err != nil {
 			return term, err
 		}
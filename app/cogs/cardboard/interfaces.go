@@ -21,6 +21,10 @@ const (
 	Noop    TagOperation = ""
 )
 
+// tagOperations lists the operations that can be set on tags. A tag may have several of them, in
+// which case GetTagOperations reports the last.
+var tagOperations = []TagOperation{Promote, Demote, Omit}
+
 type IDomain interface {
 	TagsSearch(ctx context.Context, query string) ([]*api.TagSuggestion, error)
 	PostsSearch(context.Context, IQueryPosts) ([]*api.Post, error)
@@ -49,16 +53,18 @@ type IQueryPosts interface {
 	GuildID() string
 }
 
+// IRepository stores the aliases and tag operations of each guild. Methods take the context of
+// the command being handled, so that queries are traced under its span and cancelled with it.
 type IRepository interface {
-	GetAliases(guildID string) (map[Alias]Actual, error)
-	SetAlias(guildID string, ali Alias, act Actual) error
+	GetAliases(ctx context.Context, guildID string) (map[Alias]Actual, error)
+	SetAlias(ctx context.Context, guildID string, ali Alias, act Actual) error
 
-	GetTagOperations(guildID string) (map[string]TagOperation, error)
-	GetPromotes(guildID string) ([]string, error)
-	GetDemotes(guildID string) ([]string, error)
-	GetOmits(guildID string) ([]string, error)
+	GetTagOperations(ctx context.Context, guildID string) (map[string]TagOperation, error)
+	GetPromotes(ctx context.Context, guildID string) ([]string, error)
+	GetDemotes(ctx context.Context, guildID string) ([]string, error)
+	GetOmits(ctx context.Context, guildID string) ([]string, error)
 
-	SetPromote(guildID string, tag string) error
-	SetDemote(guildID string, tag string) error
-	SetOmit(guildID string, tag string) error
+	SetPromote(ctx context.Context, guildID string, tag string) error
+	SetDemote(ctx context.Context, guildID string, tag string) error
+	SetOmit(ctx context.Context, guildID string, tag string) error
 }
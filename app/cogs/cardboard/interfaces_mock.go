@@ -281,132 +281,132 @@ func (m *MockIRepository) EXPECT() *MockIRepositoryMockRecorder {
 }
 
 // GetAliases mocks base method.
-func (m *MockIRepository) GetAliases(guildID string) (map[Alias]Actual, error) {
+func (m *MockIRepository) GetAliases(ctx context.Context, guildID string) (map[Alias]Actual, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAliases", guildID)
+	ret := m.ctrl.Call(m, "GetAliases", ctx, guildID)
 	ret0, _ := ret[0].(map[Alias]Actual)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAliases indicates an expected call of GetAliases.
-func (mr *MockIRepositoryMockRecorder) GetAliases(guildID interface{}) *gomock.Call {
+func (mr *MockIRepositoryMockRecorder) GetAliases(ctx, guildID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAliases", reflect.TypeOf((*MockIRepository)(nil).GetAliases), guildID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAliases", reflect.TypeOf((*MockIRepository)(nil).GetAliases), ctx, guildID)
 }
 
 // GetDemotes mocks base method.
-func (m *MockIRepository) GetDemotes(guildID string) ([]string, error) {
+func (m *MockIRepository) GetDemotes(ctx context.Context, guildID string) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetDemotes", guildID)
+	ret := m.ctrl.Call(m, "GetDemotes", ctx, guildID)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetDemotes indicates an expected call of GetDemotes.
-func (mr *MockIRepositoryMockRecorder) GetDemotes(guildID interface{}) *gomock.Call {
+func (mr *MockIRepositoryMockRecorder) GetDemotes(ctx, guildID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDemotes", reflect.TypeOf((*MockIRepository)(nil).GetDemotes), guildID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDemotes", reflect.TypeOf((*MockIRepository)(nil).GetDemotes), ctx, guildID)
 }
 
 // GetOmits mocks base method.
-func (m *MockIRepository) GetOmits(guildID string) ([]string, error) {
+func (m *MockIRepository) GetOmits(ctx context.Context, guildID string) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOmits", guildID)
+	ret := m.ctrl.Call(m, "GetOmits", ctx, guildID)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOmits indicates an expected call of GetOmits.
-func (mr *MockIRepositoryMockRecorder) GetOmits(guildID interface{}) *gomock.Call {
+func (mr *MockIRepositoryMockRecorder) GetOmits(ctx, guildID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOmits", reflect.TypeOf((*MockIRepository)(nil).GetOmits), guildID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOmits", reflect.TypeOf((*MockIRepository)(nil).GetOmits), ctx, guildID)
 }
 
 // GetPromotes mocks base method.
-func (m *MockIRepository) GetPromotes(guildID string) ([]string, error) {
+func (m *MockIRepository) GetPromotes(ctx context.Context, guildID string) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPromotes", guildID)
+	ret := m.ctrl.Call(m, "GetPromotes", ctx, guildID)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetPromotes indicates an expected call of GetPromotes.
-func (mr *MockIRepositoryMockRecorder) GetPromotes(guildID interface{}) *gomock.Call {
+func (mr *MockIRepositoryMockRecorder) GetPromotes(ctx, guildID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPromotes", reflect.TypeOf((*MockIRepository)(nil).GetPromotes), guildID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPromotes", reflect.TypeOf((*MockIRepository)(nil).GetPromotes), ctx, guildID)
 }
 
 // GetTagOperations mocks base method.
-func (m *MockIRepository) GetTagOperations(guildID string) (map[string]TagOperation, error) {
+func (m *MockIRepository) GetTagOperations(ctx context.Context, guildID string) (map[string]TagOperation, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTagOperations", guildID)
+	ret := m.ctrl.Call(m, "GetTagOperations", ctx, guildID)
 	ret0, _ := ret[0].(map[string]TagOperation)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTagOperations indicates an expected call of GetTagOperations.
-func (mr *MockIRepositoryMockRecorder) GetTagOperations(guildID interface{}) *gomock.Call {
+func (mr *MockIRepositoryMockRecorder) GetTagOperations(ctx, guildID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagOperations", reflect.TypeOf((*MockIRepository)(nil).GetTagOperations), guildID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagOperations", reflect.TypeOf((*MockIRepository)(nil).GetTagOperations), ctx, guildID)
 }
 
 // SetAlias mocks base method.
-func (m *MockIRepository) SetAlias(guildID string, ali Alias, act Actual) error {
+func (m *MockIRepository) SetAlias(ctx context.Context, guildID string, ali Alias, act Actual) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetAlias", guildID, ali, act)
+	ret := m.ctrl.Call(m, "SetAlias", ctx, guildID, ali, act)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetAlias indicates an expected call of SetAlias.
-func (mr *MockIRepositoryMockRecorder) SetAlias(guildID, ali, act interface{}) *gomock.Call {
+func (mr *MockIRepositoryMockRecorder) SetAlias(ctx, guildID, ali, act interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAlias", reflect.TypeOf((*MockIRepository)(nil).SetAlias), guildID, ali, act)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAlias", reflect.TypeOf((*MockIRepository)(nil).SetAlias), ctx, guildID, ali, act)
 }
 
 // SetDemote mocks base method.
-func (m *MockIRepository) SetDemote(guildID, tag string) error {
+func (m *MockIRepository) SetDemote(ctx context.Context, guildID, tag string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetDemote", guildID, tag)
+	ret := m.ctrl.Call(m, "SetDemote", ctx, guildID, tag)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetDemote indicates an expected call of SetDemote.
-func (mr *MockIRepositoryMockRecorder) SetDemote(guildID, tag interface{}) *gomock.Call {
+func (mr *MockIRepositoryMockRecorder) SetDemote(ctx, guildID, tag interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDemote", reflect.TypeOf((*MockIRepository)(nil).SetDemote), guildID, tag)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDemote", reflect.TypeOf((*MockIRepository)(nil).SetDemote), ctx, guildID, tag)
 }
 
 // SetOmit mocks base method.
-func (m *MockIRepository) SetOmit(guildID, tag string) error {
+func (m *MockIRepository) SetOmit(ctx context.Context, guildID, tag string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetOmit", guildID, tag)
+	ret := m.ctrl.Call(m, "SetOmit", ctx, guildID, tag)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetOmit indicates an expected call of SetOmit.
-func (mr *MockIRepositoryMockRecorder) SetOmit(guildID, tag interface{}) *gomock.Call {
+func (mr *MockIRepositoryMockRecorder) SetOmit(ctx, guildID, tag interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOmit", reflect.TypeOf((*MockIRepository)(nil).SetOmit), guildID, tag)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOmit", reflect.TypeOf((*MockIRepository)(nil).SetOmit), ctx, guildID, tag)
 }
 
 // SetPromote mocks base method.
-func (m *MockIRepository) SetPromote(guildID, tag string) error {
+func (m *MockIRepository) SetPromote(ctx context.Context, guildID, tag string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetPromote", guildID, tag)
+	ret := m.ctrl.Call(m, "SetPromote", ctx, guildID, tag)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetPromote indicates an expected call of SetPromote.
-func (mr *MockIRepositoryMockRecorder) SetPromote(guildID, tag interface{}) *gomock.Call {
+func (mr *MockIRepositoryMockRecorder) SetPromote(ctx, guildID, tag interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPromote", reflect.TypeOf((*MockIRepository)(nil).SetPromote), guildID, tag)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPromote", reflect.TypeOf((*MockIRepository)(nil).SetPromote), ctx, guildID, tag)
 }
@@ -8,32 +8,41 @@ import (
 	"github.com/fiffu/arisa3/lib"
 )
 
-type perGuildCache struct {
-	aliases    lib.ICache[AliasesMap, guildKey]
-	operations lib.ICache[OperationsMap, guildKey]
-	ops2tags   lib.ICache[TagsPerOperation, TagOperation]
+type caches struct {
+	aliases    lib.ICache[*cachedAliases, string]
+	operations lib.ICache[*cachedOperations, string]
+	ops2tags   lib.ICache[*cachedTags, tagsKey]
 }
 
 type repo struct {
-	db     database.IDatabase
-	caches map[string]perGuildCache // one cache instance per guildID
+	db            database.IDatabase
+	caches        caches
+	invalidations lib.IInvalidationChannel // evicts caches on every replica after writes
 }
 
-func NewRepository(db database.IDatabase) IRepository {
-	return &repo{
-		db,
-		make(map[string]perGuildCache),
+// NewRepository caches in the store if there is one, or else in memory.
+func NewRepository(db database.IDatabase, store lib.ICacheStore) IRepository {
+	r := &repo{
+		db:            db,
+		caches:        newCaches(store),
+		invalidations: database.NewInvalidationChannel(db, invalidationChannel),
 	}
+	r.observeCaches()
+	r.subscribeInvalidations(context.Background())
+	return r
 }
 
-func (r *repo) GetAliases(guildID string) (map[Alias]Actual, error) {
+func (r *repo) GetAliases(ctx context.Context, guildID string) (map[Alias]Actual, error) {
 	if cached, ok := r.peekAliasesMap(guildID); ok {
 		return cached, nil
 	}
 
-	aliases := make(map[Alias]Actual)
-	rows, err := r.db.Query(
-		context.Background(),
+	type aliasRow struct {
+		Alias  Alias  `db:"alias"`
+		Actual Actual `db:"actual"`
+	}
+	rows, err := database.QueryAll[aliasRow](
+		ctx, r.db,
 		"SELECT alias, actual FROM aliases WHERE guildid=$1",
 		guildID,
 	)
@@ -41,38 +50,34 @@ func (r *repo) GetAliases(guildID string) (map[Alias]Actual, error) {
 		return nil, err
 	}
 
-	for rows.Next() {
-		var ali, act string
-		if err := rows.Scan(&ali, &act); err != nil {
-			return nil, err
-		}
-		aliases[Alias(ali)] = Actual(act)
+	aliases := make(map[Alias]Actual)
+	for _, row := range rows {
+		aliases[row.Alias] = row.Actual
 	}
 
 	r.putAliasesMap(guildID, AliasesMap(aliases))
 	return aliases, nil
 }
 
-func (r *repo) SetAlias(guildID string, ali Alias, act Actual) error {
+func (r *repo) SetAlias(ctx context.Context, guildID string, ali Alias, act Actual) error {
 	if _, err := r.db.Exec(
-		context.Background(),
+		ctx,
 		"INSERT INTO aliases(alias, actual, guildid) VALUES ($1, $2, $3)",
 		string(ali), string(act), guildID,
 	); err != nil {
 		return err
 	}
-	r.clearAliasesMap(guildID)
+	r.invalidate(ctx, guildID, aliasesKind)
 	return nil
 }
 
-func (r *repo) getTagsByOperation(guildID string, oper TagOperation) ([]string, error) {
+func (r *repo) getTagsByOperation(ctx context.Context, guildID string, oper TagOperation) ([]string, error) {
 	if cached, ok := r.peekTagOperation(guildID, oper); ok {
 		return cached, nil
 	}
 
-	tags := make([]string, 0)
-	rows, err := r.db.Query(
-		context.Background(),
+	tags, err := database.QueryAll[string](
+		ctx, r.db,
 		fmt.Sprintf("SELECT tag FROM tag_%s WHERE guildid=$1", oper),
 		guildID,
 	)
@@ -80,14 +85,6 @@ func (r *repo) getTagsByOperation(guildID string, oper TagOperation) ([]string,
 		return nil, err
 	}
 
-	for rows.Next() {
-		var tag string
-		if err := rows.Scan(&tag); err != nil {
-			return nil, err
-		}
-		tags = append(tags, tag)
-	}
-
 	r.putTagOperations(
 		guildID,
 		TagsPerOperation{oper, tags},
@@ -96,35 +93,52 @@ func (r *repo) getTagsByOperation(guildID string, oper TagOperation) ([]string,
 	return tags, nil
 }
 
-func (r *repo) setTagOperation(guildID string, tag string, oper TagOperation) error {
-	if _, err := r.db.Exec(
-		context.Background(),
-		fmt.Sprintf(`INSERT INTO tag_%s(tag, guildid) VALUES ($1, $2) ON CONFLICT DO NOTHING`, string(oper)),
-		tag, guildID,
-	); err != nil {
+// setTagOperation adds the tag to the operation in the guild. Caches are only cleared once the
+// change is committed.
+func (r *repo) setTagOperation(ctx context.Context, guildID string, tag string, oper TagOperation) error {
+	err := database.WithTransaction(ctx, r.db, func(ctx context.Context, tx database.ITransaction) error {
+		_, err := tx.Exec(
+			ctx,
+			fmt.Sprintf(`INSERT INTO tag_%s(tag, guildid) VALUES ($1, $2) ON CONFLICT DO NOTHING`, string(oper)),
+			tag, guildID,
+		)
+		return err
+	})
+	if err != nil {
 		return err
 	}
-	r.clearTagOperation(guildID, oper)
-	r.clearOperationsMap(guildID)
+	r.invalidate(ctx, guildID, tagsKind)
 	return nil
 }
 
-func (r *repo) GetPromotes(gid string) ([]string, error) { return r.getTagsByOperation(gid, Promote) }
-func (r *repo) GetDemotes(gid string) ([]string, error)  { return r.getTagsByOperation(gid, Demote) }
-func (r *repo) GetOmits(gid string) ([]string, error)    { return r.getTagsByOperation(gid, Omit) }
-func (r *repo) SetPromote(gid, s string) error           { return r.setTagOperation(gid, s, Promote) }
-func (r *repo) SetDemote(gid, s string) error            { return r.setTagOperation(gid, s, Demote) }
-func (r *repo) SetOmit(gid, s string) error              { return r.setTagOperation(gid, s, Omit) }
+func (r *repo) GetPromotes(ctx context.Context, gid string) ([]string, error) {
+	return r.getTagsByOperation(ctx, gid, Promote)
+}
+func (r *repo) GetDemotes(ctx context.Context, gid string) ([]string, error) {
+	return r.getTagsByOperation(ctx, gid, Demote)
+}
+func (r *repo) GetOmits(ctx context.Context, gid string) ([]string, error) {
+	return r.getTagsByOperation(ctx, gid, Omit)
+}
+func (r *repo) SetPromote(ctx context.Context, gid, s string) error {
+	return r.setTagOperation(ctx, gid, s, Promote)
+}
+func (r *repo) SetDemote(ctx context.Context, gid, s string) error {
+	return r.setTagOperation(ctx, gid, s, Demote)
+}
+func (r *repo) SetOmit(ctx context.Context, gid, s string) error {
+	return r.setTagOperation(ctx, gid, s, Omit)
+}
 
-func (r *repo) GetTagOperations(guildID string) (map[string]TagOperation, error) {
+func (r *repo) GetTagOperations(ctx context.Context, guildID string) (map[string]TagOperation, error) {
 	if cached, ok := r.peekOperationsMap(guildID); ok {
 		return cached, nil
 	}
 
 	mapping := make(map[string]TagOperation)
 
-	for _, oper := range []TagOperation{Promote, Demote, Omit} {
-		tags, err := r.getTagsByOperation(guildID, oper)
+	for _, oper := range tagOperations {
+		tags, err := r.getTagsByOperation(ctx, guildID, oper)
 		if err != nil {
 			return nil, err
 		}
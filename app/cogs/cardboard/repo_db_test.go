@@ -0,0 +1,82 @@
+package cardboard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/testfixtures/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_repo_backends(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		repo := NewRepository(db, nil)
+
+		assert.NoError(t, repo.SetAlias(ctx, "guild", "capy", "capybara"))
+		aliases, err := repo.GetAliases(ctx, "guild")
+		assert.NoError(t, err)
+		assert.Equal(t, map[Alias]Actual{"capy": "capybara"}, aliases)
+
+		aliases, err = repo.GetAliases(ctx, "other guild")
+		assert.NoError(t, err)
+		assert.Empty(t, aliases)
+
+		// Setting the same tag twice is not an error
+		assert.NoError(t, repo.SetPromote(ctx, "guild", "capybara"))
+		assert.NoError(t, repo.SetPromote(ctx, "guild", "capybara"))
+		assert.NoError(t, repo.SetOmit(ctx, "guild", "comic"))
+
+		promotes, err := repo.GetPromotes(ctx, "guild")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"capybara"}, promotes)
+
+		operations, err := repo.GetTagOperations(ctx, "guild")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]TagOperation{"capybara": Promote, "comic": Omit}, operations)
+
+		// Setting another operation adds to the tag's previous one, and the later operation wins
+		assert.NoError(t, repo.SetDemote(ctx, "guild", "capybara"))
+		promotes, err = repo.GetPromotes(ctx, "guild")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"capybara"}, promotes)
+		demotes, err := repo.GetDemotes(ctx, "guild")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"capybara"}, demotes)
+
+		operations, err = repo.GetTagOperations(ctx, "guild")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]TagOperation{"capybara": Demote, "comic": Omit}, operations)
+	}, &Cog{})
+}
+
+func Test_repo_backends_invalidatesReplicas(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		writer := NewRepository(db, nil)
+		reader := NewRepository(db, nil)
+
+		// Warm the reader's caches
+		aliases, err := reader.GetAliases(ctx, "guild")
+		assert.NoError(t, err)
+		assert.Empty(t, aliases)
+		operations, err := reader.GetTagOperations(ctx, "guild")
+		assert.NoError(t, err)
+		assert.Empty(t, operations)
+
+		assert.NoError(t, writer.SetAlias(ctx, "guild", "capy", "capybara"))
+		assert.NoError(t, writer.SetPromote(ctx, "guild", "capybara"))
+
+		// Notifications may arrive asynchronously, depending on the backend
+		assert.Eventually(t, func() bool {
+			aliases, err := reader.GetAliases(ctx, "guild")
+			return err == nil && len(aliases) == 1
+		}, 5*time.Second, 50*time.Millisecond)
+		assert.Eventually(t, func() bool {
+			operations, err := reader.GetTagOperations(ctx, "guild")
+			return err == nil && operations["capybara"] == Promote
+		}, 5*time.Second, 50*time.Millisecond)
+	}, &Cog{})
+}
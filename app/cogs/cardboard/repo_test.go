@@ -0,0 +1,134 @@
+package cardboard
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/lib"
+	"github.com/fiffu/arisa3/testfixtures/redistest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_setTagOperation_rollsBack(t *testing.T) {
+	db, dbMock, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+	repo := NewRepository(db, nil).(*repo)
+	repo.putOperationsMap("guild", OperationsMap{"capybara": Promote})
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO tag_omit")).
+		WithArgs("capybara", "guild").
+		WillReturnError(assert.AnError)
+	dbMock.ExpectRollback()
+
+	err = repo.SetOmit(context.Background(), "guild", "capybara")
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+
+	// Nothing changed, so the cache is still good
+	cached, ok := repo.peekOperationsMap("guild")
+	assert.True(t, ok)
+	assert.Equal(t, OperationsMap{"capybara": Promote}, cached)
+}
+
+func Test_GetTagOperations_cached(t *testing.T) {
+	db, dbMock, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+	repo := NewRepository(db, nil).(*repo)
+
+	for _, oper := range tagOperations {
+		rows := sqlmock.NewRows([]string{"tag"})
+		if oper == Promote {
+			rows.AddRow("capybara")
+		}
+		dbMock.ExpectQuery(regexp.QuoteMeta("SELECT tag FROM tag_" + string(oper))).
+			WithArgs("guild").
+			WillReturnRows(rows)
+	}
+
+	// Only the first lookup reaches the database
+	for i := 0; i < 2; i++ {
+		operations, err := repo.GetTagOperations(context.Background(), "guild")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]TagOperation{"capybara": Promote}, operations)
+	}
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_repo_evict_all(t *testing.T) {
+	db, _, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+	repo := NewRepository(db, nil).(*repo)
+	repo.putAliasesMap("guild", AliasesMap{"capy": "capybara"})
+	repo.putOperationsMap("other guild", OperationsMap{"capybara": Promote})
+
+	repo.evict(lib.InvalidateAll)
+	_, ok := repo.peekAliasesMap("guild")
+	assert.False(t, ok)
+	_, ok = repo.peekOperationsMap("other guild")
+	assert.False(t, ok)
+}
+
+func Test_repo_caches_bounded(t *testing.T) {
+	db, _, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+	repo := NewRepository(db, nil).(*repo)
+
+	// Guilds share each kind of cache, which evicts the least recently used guild when full
+	for i := 0; i <= aliasesCacheMaxEntries; i++ {
+		repo.putAliasesMap(strconv.Itoa(i), AliasesMap{"capy": "capybara"})
+	}
+	assert.Equal(t, aliasesCacheMaxEntries, repo.caches.aliases.Stats().Size)
+	_, ok := repo.peekAliasesMap("0")
+	assert.False(t, ok)
+	_, ok = repo.peekAliasesMap(strconv.Itoa(aliasesCacheMaxEntries))
+	assert.True(t, ok)
+
+	// Tags are kept apart by guild and operation
+	repo.putTagOperations("guild", TagsPerOperation{Promote, []string{"capybara"}})
+	repo.putTagOperations("other guild", TagsPerOperation{Promote, []string{"axolotl"}})
+	tags, ok := repo.peekTagOperation("guild", Promote)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"capybara"}, tags)
+	_, ok = repo.peekTagOperation("guild", Demote)
+	assert.False(t, ok)
+}
+
+func Test_repo_caches_store(t *testing.T) {
+	server := redistest.NewServer(t)
+	store, err := lib.NewRedisStore(server.URL())
+	assert.NoError(t, err)
+	db, _, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+	replica1 := NewRepository(db, store).(*repo)
+	replica2 := NewRepository(db, store).(*repo)
+
+	// Replicas share what either of them caches
+	replica1.putAliasesMap("guild", AliasesMap{"capy": "capybara"})
+	replica1.putOperationsMap("guild", OperationsMap{"capybara": Promote})
+	replica1.putTagOperations("guild", TagsPerOperation{Promote, []string{"capybara"}})
+
+	aliases, ok := replica2.peekAliasesMap("guild")
+	assert.True(t, ok)
+	assert.Equal(t, AliasesMap{"capy": "capybara"}, aliases)
+	operations, ok := replica2.peekOperationsMap("guild")
+	assert.True(t, ok)
+	assert.Equal(t, OperationsMap{"capybara": Promote}, operations)
+	tags, ok := replica2.peekTagOperation("guild", Promote)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"capybara"}, tags)
+	assert.Contains(t, server.Keys(), "cardboard.ops2tags:guild:promote")
+
+	// and what either of them evicts
+	replica2.evict(invalidationKey("guild", tagsKind))
+	_, ok = replica1.peekOperationsMap("guild")
+	assert.False(t, ok)
+	_, ok = replica1.peekTagOperation("guild", Promote)
+	assert.False(t, ok)
+	_, ok = replica1.peekAliasesMap("guild")
+	assert.True(t, ok)
+}
@@ -1,75 +1,150 @@
 package cardboard
 
 import (
+	"context"
+	"strings"
 	"time"
 
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
 	"github.com/fiffu/arisa3/lib"
 )
 
-type guildKey string
-
-const (
-	aliasKey      guildKey = "aliases"
-	operationsKey guildKey = "operations"
-)
-
 // AliasesMap indicates all aliases defined by a guild.
-// Cache hierarchy: global -> guildID -> AliasMap
 type AliasesMap map[Alias]Actual
 
 // OperationsMap indicates all tags defined by a guild to receive any kind of operation.
 // This mapping is derived from a union of all TagsPerOperation.
-// Cache hierarchy: global -> guildID -> OperationsMap
 type OperationsMap map[string]TagOperation
 
 // TagsPerOperation indicates the tags defined by a guild to receive a particular operation.
-// Cache hierarchy: global -> guildID -> operation -> TagsPerOperation
 type TagsPerOperation struct {
 	op   TagOperation
 	list []string
 }
 
-func (c AliasesMap) CacheKey() guildKey           { return aliasKey }
-func (t TagsPerOperation) CacheKey() TagOperation { return t.op }
-func (o OperationsMap) CacheKey() guildKey        { return operationsKey }
+// Each guild's aliases and operations are cached by guild ID, and its tags by guild ID and
+// operation, so that every kind of cache is bounded across guilds.
+const (
+	aliasesCacheExpiry     = 7 * 24 * time.Hour
+	aliasesCacheMaxEntries = 1000
 
-func (r *repo) newGuildCache(guildID string) perGuildCache {
-	cache := perGuildCache{
-		aliases:    lib.NewCache[AliasesMap, guildKey](7 * 24 * time.Hour),
-		ops2tags:   lib.NewCache[TagsPerOperation, TagOperation](7 * 24 * time.Hour),
-		operations: lib.NewCache[OperationsMap, guildKey](14 * 24 * time.Hour), // derived from ops2tags
-	}
-	r.caches[guildID] = cache
-	return cache
+	ops2tagsCacheExpiry     = 7 * 24 * time.Hour
+	ops2tagsCacheMaxEntries = 3000 // one entry per operation, for as many guilds as the others
+
+	operationsCacheExpiry     = 14 * 24 * time.Hour // derived from ops2tags
+	operationsCacheMaxEntries = 1000
+)
+
+type cachedAliases struct {
+	guildID string
+	aliases AliasesMap
+}
+
+func (c *cachedAliases) CacheKey() string { return c.guildID }
+
+type cachedOperations struct {
+	guildID    string
+	operations OperationsMap
+}
+
+func (c *cachedOperations) CacheKey() string { return c.guildID }
+
+// tagsKey identifies the tags given an operation in a guild.
+type tagsKey struct {
+	guildID string
+	op      TagOperation
 }
 
-func (r *repo) getGuildCache(guildID string) (perGuildCache, bool) {
-	guildCache, ok := r.caches[guildID]
-	return guildCache, ok
+type cachedTags struct {
+	guildID string
+	tags    TagsPerOperation
 }
 
-func (r *repo) ensureGuildCache(guildID string) perGuildCache {
-	if guildCache, ok := r.caches[guildID]; ok {
-		return guildCache
+func (c *cachedTags) CacheKey() tagsKey { return tagsKey{c.guildID, c.tags.op} }
+
+// String formats the key of the tags in a cache store, like "1234:promote".
+func (k tagsKey) String() string { return k.guildID + ":" + string(k.op) }
+
+// The cached types keep their fields unexported, so they are kept in a cache store as these.
+type (
+	aliasesWire struct {
+		GuildID string           `json:"guild_id"`
+		Aliases map[Alias]Actual `json:"aliases"`
+	}
+	operationsWire struct {
+		GuildID    string                  `json:"guild_id"`
+		Operations map[string]TagOperation `json:"operations"`
+	}
+	tagsWire struct {
+		GuildID string       `json:"guild_id"`
+		Op      TagOperation `json:"op"`
+		Tags    []string     `json:"tags"`
+	}
+)
+
+var (
+	aliasesCodec = lib.JSONCodec(
+		func(c *cachedAliases) aliasesWire { return aliasesWire{c.guildID, c.aliases} },
+		func(w aliasesWire) *cachedAliases { return &cachedAliases{w.GuildID, w.Aliases} },
+	)
+	operationsCodec = lib.JSONCodec(
+		func(c *cachedOperations) operationsWire { return operationsWire{c.guildID, c.operations} },
+		func(w operationsWire) *cachedOperations { return &cachedOperations{w.GuildID, w.Operations} },
+	)
+	tagsCodec = lib.JSONCodec(
+		func(c *cachedTags) tagsWire { return tagsWire{c.guildID, c.tags.op, c.tags.list} },
+		func(w tagsWire) *cachedTags { return &cachedTags{w.GuildID, TagsPerOperation{w.Op, w.Tags}} },
+	)
+)
+
+// newCaches keeps the caches in the store, shared by every replica, or in memory if store is nil.
+func newCaches(store lib.ICacheStore) caches {
+	if store != nil {
+		return caches{
+			aliases:    lib.NewStoreCache[*cachedAliases, string](store, "cardboard.aliases", aliasesCacheExpiry, aliasesCodec),
+			ops2tags:   lib.NewStoreCache[*cachedTags, tagsKey](store, "cardboard.ops2tags", ops2tagsCacheExpiry, tagsCodec),
+			operations: lib.NewStoreCache[*cachedOperations, string](store, "cardboard.operations", operationsCacheExpiry, operationsCodec),
+		}
+	}
+	return caches{
+		aliases: lib.NewCache[*cachedAliases, string](
+			aliasesCacheExpiry,
+			lib.WithMaxEntries(aliasesCacheMaxEntries),
+			lib.WithSweepInterval(time.Hour),
+		),
+		ops2tags: lib.NewCache[*cachedTags, tagsKey](
+			ops2tagsCacheExpiry,
+			lib.WithMaxEntries(ops2tagsCacheMaxEntries),
+			lib.WithSweepInterval(time.Hour),
+		),
+		operations: lib.NewCache[*cachedOperations, string](
+			operationsCacheExpiry,
+			lib.WithMaxEntries(operationsCacheMaxEntries),
+			lib.WithSweepInterval(time.Hour),
+		),
 	}
-	return r.newGuildCache(guildID)
+}
+
+func (r *repo) observeCaches() {
+	instrumentation.ObserveCache("cardboard.aliases", r.caches.aliases.Stats)
+	instrumentation.ObserveCache("cardboard.operations", r.caches.operations.Stats)
+	instrumentation.ObserveCache("cardboard.ops2tags", r.caches.ops2tags.Stats)
 }
 
 // AliasesMap
 
 func (r *repo) putAliasesMap(guildID string, mapping AliasesMap) {
-	r.ensureGuildCache(guildID).aliases.Put(mapping)
+	r.caches.aliases.Put(&cachedAliases{guildID, mapping})
 }
 
 func (r *repo) clearAliasesMap(guildID string) {
-	if guildCache, ok := r.caches[guildID]; ok {
-		guildCache.aliases.Drop()
-	}
+	r.caches.aliases.Delete(guildID)
 }
 
 func (r *repo) peekAliasesMap(guildID string) (AliasesMap, bool) {
-	if guildCache, ok := r.caches[guildID]; ok {
-		return guildCache.aliases.Peek(aliasKey)
+	if cached, ok := r.caches.aliases.Peek(guildID); ok {
+		return cached.aliases, true
 	}
 	return nil, false
 }
@@ -77,18 +152,16 @@ func (r *repo) peekAliasesMap(guildID string) (AliasesMap, bool) {
 // OperationsMap
 
 func (r *repo) putOperationsMap(guildID string, mapping OperationsMap) {
-	r.ensureGuildCache(guildID).operations.Put(mapping)
+	r.caches.operations.Put(&cachedOperations{guildID, mapping})
 }
 
 func (r *repo) clearOperationsMap(guildID string) {
-	if guildCache, ok := r.caches[guildID]; ok {
-		guildCache.operations.Drop()
-	}
+	r.caches.operations.Delete(guildID)
 }
 
 func (r *repo) peekOperationsMap(guildID string) (OperationsMap, bool) {
-	if guildCache, ok := r.caches[guildID]; ok {
-		return guildCache.operations.Peek(aliasKey)
+	if cached, ok := r.caches.operations.Peek(guildID); ok {
+		return cached.operations, true
 	}
 	return nil, false
 }
@@ -96,20 +169,71 @@ func (r *repo) peekOperationsMap(guildID string) (OperationsMap, bool) {
 // TagsPerOperation
 
 func (r *repo) putTagOperations(guildID string, tagList TagsPerOperation) {
-	r.ensureGuildCache(guildID).ops2tags.Put(tagList)
+	r.caches.ops2tags.Put(&cachedTags{guildID, tagList})
 }
 
 func (r *repo) clearTagOperation(guildID string, oper TagOperation) {
-	if guildCache, ok := r.caches[guildID]; ok {
-		guildCache.ops2tags.Drop()
-	}
+	r.caches.ops2tags.Delete(tagsKey{guildID, oper})
 }
 
 func (r *repo) peekTagOperation(guildID string, oper TagOperation) ([]string, bool) {
-	if guildCache, ok := r.getGuildCache(guildID); ok {
-		if tags, ok := guildCache.ops2tags.Peek(oper); ok {
-			return tags.list, true
-		}
+	if cached, ok := r.caches.ops2tags.Peek(tagsKey{guildID, oper}); ok {
+		return cached.tags.list, true
 	}
 	return nil, false
 }
+
+// Invalidation
+
+// invalidationChannel is the database notification channel that carries cache invalidations.
+const invalidationChannel = "cardboard_cache"
+
+// cacheKind names the caches of a guild that a write invalidates, as part of invalidation keys.
+type cacheKind string
+
+const (
+	aliasesKind cacheKind = "aliases"
+	tagsKind    cacheKind = "tags" // both ops2tags and operations, which is derived from it
+)
+
+// invalidationKey is published to evict a kind of cache for a guild, like "tags:1234".
+func invalidationKey(guildID string, kind cacheKind) string {
+	return string(kind) + ":" + guildID
+}
+
+// invalidate clears a guild's caches after a write, here and on every other replica. If the
+// other replicas can't be told, their caches go stale until they expire.
+func (r *repo) invalidate(ctx context.Context, guildID string, kind cacheKind) {
+	r.evict(invalidationKey(guildID, kind))
+	if err := r.invalidations.Publish(ctx, invalidationKey(guildID, kind)); err != nil {
+		log.Errorf(ctx, err, "Failed to publish cache invalidation for guild %s", guildID)
+	}
+}
+
+// subscribeInvalidations evicts caches as other replicas write, until ctx is done.
+func (r *repo) subscribeInvalidations(ctx context.Context) {
+	if err := r.invalidations.Subscribe(ctx, r.evict); err != nil {
+		log.Errorf(ctx, err, "Failed to subscribe to cache invalidations, caches may go stale")
+	}
+}
+
+// evict clears the caches named by an invalidation key.
+func (r *repo) evict(key string) {
+	if key == lib.InvalidateAll {
+		r.caches.aliases.Drop()
+		r.caches.operations.Drop()
+		r.caches.ops2tags.Drop()
+		return
+	}
+
+	kind, guildID, _ := strings.Cut(key, ":")
+	switch cacheKind(kind) {
+	case aliasesKind:
+		r.clearAliasesMap(guildID)
+	case tagsKind:
+		for _, oper := range tagOperations {
+			r.clearTagOperation(guildID, oper)
+		}
+		r.clearOperationsMap(guildID)
+	}
+}
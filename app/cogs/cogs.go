@@ -9,6 +9,7 @@ import (
 	"github.com/fiffu/arisa3/app/cogs/colours"
 	"github.com/fiffu/arisa3/app/cogs/general"
 	"github.com/fiffu/arisa3/app/cogs/rng"
+	"github.com/fiffu/arisa3/app/engine"
 	"github.com/fiffu/arisa3/app/log"
 	"github.com/fiffu/arisa3/app/types"
 )
@@ -46,6 +47,17 @@ func SetupCogs(ctx context.Context, app types.IApp) error {
 	return nil
 }
 
+// Repositories lists the cogs that have database migrations.
+func Repositories(app types.IApp) []engine.IRepository {
+	repos := make([]engine.IRepository, 0)
+	for _, c := range getCogsList(app) {
+		if repo, ok := c.(engine.IRepository); ok {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
 // findConfig retrieves raw cog config from the app's root config.
 func findConfig(cog types.ICog, cogConfigs map[string]interface{}) (types.CogConfig, error) {
 	name := cog.Name()
@@ -0,0 +1,168 @@
+package colours
+
+// cielab.go converts colours into CIELAB, where distances match how different colours look.
+
+import (
+	"math"
+)
+
+// Lab is a colour in CIELAB space, under the D65 illuminant that sRGB is defined by.
+type Lab struct {
+	L, A, B float64
+}
+
+// Reference white of D65, in XYZ.
+const (
+	whiteX = 0.95047
+	whiteY = 1.00000
+	whiteZ = 1.08883
+)
+
+// ToLab converts the colour from sRGB into CIELAB.
+func (c *Colour) ToLab() Lab {
+	r, g, b := linearise(c.R), linearise(c.G), linearise(c.B)
+
+	x := 0.4124564*r + 0.3575761*g + 0.1804375*b
+	y := 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z := 0.0193339*r + 0.1191920*g + 0.9503041*b
+
+	fx, fy, fz := labF(x/whiteX), labF(y/whiteY), labF(z/whiteZ)
+	return Lab{
+		L: 116*fy - 16,
+		A: 500 * (fx - fy),
+		B: 200 * (fy - fz),
+	}
+}
+
+// FromLab converts a colour from CIELAB into sRGB. Not every Lab colour can be shown in sRGB, so
+// it also reports whether the colour is in gamut. Colours out of gamut have their channels clamped.
+func (c *Colour) FromLab(lab Lab) (colour *Colour, inGamut bool) {
+	fy := (lab.L + 16) / 116
+	fx := fy + lab.A/500
+	fz := fy - lab.B/200
+	x, y, z := whiteX*labFInverse(fx), whiteY*labFInverse(fy), whiteZ*labFInverse(fz)
+
+	r := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	g := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	b := 0.0556434*x - 0.2040259*y + 1.0572252*z
+
+	// Allow for rounding in the conversions
+	const epsilon = 1e-6
+	inGamut = true
+	channel := func(linear float64) float64 {
+		if linear < -epsilon || linear > 1+epsilon {
+			inGamut = false
+		}
+		return delinearise(math.Max(0, math.Min(1, linear)))
+	}
+	return &Colour{channel(r), channel(g), channel(b)}, inGamut
+}
+
+// linearise undoes the gamma of an sRGB channel.
+func linearise(channel float64) float64 {
+	if channel <= 0.04045 {
+		return channel / 12.92
+	}
+	return math.Pow((channel+0.055)/1.055, 2.4)
+}
+
+// delinearise applies the gamma of an sRGB channel.
+func delinearise(channel float64) float64 {
+	if channel <= 0.0031308 {
+		return channel * 12.92
+	}
+	return 1.055*math.Pow(channel, 1/2.4) - 0.055
+}
+
+func labFInverse(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta {
+		return t * t * t
+	}
+	return 3 * delta * delta * (t - 4.0/29)
+}
+
+func labF(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29
+}
+
+// DeltaE is the CIEDE2000 colour difference between two colours. Differences below 1 are hard to
+// see, and differences above 10 or so are between clearly different colours.
+func DeltaE(x, y Lab) float64 {
+	// Adapted from Sharma, Wu and Dalal, "The CIEDE2000 Color-Difference Formula" (2005)
+	pow7 := func(v float64) float64 { return math.Pow(v, 7) }
+	const pow7of25 = 6103515625.0 // 25^7
+
+	cBar := (math.Hypot(x.A, x.B) + math.Hypot(y.A, y.B)) / 2
+	g := 0.5 * (1 - math.Sqrt(pow7(cBar)/(pow7(cBar)+pow7of25)))
+
+	a1, a2 := (1+g)*x.A, (1+g)*y.A
+	c1, c2 := math.Hypot(a1, x.B), math.Hypot(a2, y.B)
+	h1, h2 := hueDegrees(x.B, a1), hueDegrees(y.B, a2)
+
+	dL := y.L - x.L
+	dC := c2 - c1
+	dh := 0.0
+	if c1*c2 != 0 {
+		dh = h2 - h1
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(c1*c2) * math.Sin(radians(dh/2))
+
+	lBar := (x.L + y.L) / 2
+	cBarPrime := (c1 + c2) / 2
+	hBar := h1 + h2
+	if c1*c2 != 0 {
+		switch {
+		case math.Abs(h1-h2) <= 180:
+			hBar /= 2
+		case h1+h2 < 360:
+			hBar = (hBar + 360) / 2
+		default:
+			hBar = (hBar - 360) / 2
+		}
+	}
+
+	t := 1 -
+		0.17*math.Cos(radians(hBar-30)) +
+		0.24*math.Cos(radians(2*hBar)) +
+		0.32*math.Cos(radians(3*hBar+6)) -
+		0.20*math.Cos(radians(4*hBar-63))
+	dTheta := 30 * math.Exp(-math.Pow((hBar-275)/25, 2))
+	rC := 2 * math.Sqrt(pow7(cBarPrime)/(pow7(cBarPrime)+pow7of25))
+	sL := 1 + 0.015*math.Pow(lBar-50, 2)/math.Sqrt(20+math.Pow(lBar-50, 2))
+	sC := 1 + 0.045*cBarPrime
+	sH := 1 + 0.015*cBarPrime*t
+	rT := -math.Sin(radians(2*dTheta)) * rC
+
+	return math.Sqrt(
+		math.Pow(dL/sL, 2) +
+			math.Pow(dC/sC, 2) +
+			math.Pow(dH/sH, 2) +
+			rT*(dC/sC)*(dH/sH),
+	)
+}
+
+// hueDegrees is the hue angle of a colour in CIELAB, in [0, 360).
+func hueDegrees(b, a float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
@@ -0,0 +1,65 @@
+package colours
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ToLab(t *testing.T) {
+	testCases := []struct {
+		hexcode string
+		expect  Lab
+	}{
+		{"000000", Lab{0, 0, 0}},
+		{"ffffff", Lab{100, 0, 0}},
+		{"ff0000", Lab{53.24, 80.09, 67.20}},
+		{"00ff00", Lab{87.73, -86.18, 83.18}},
+		{"0000ff", Lab{32.30, 79.19, -107.86}},
+		{"808080", Lab{53.59, 0, 0}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.hexcode, func(t *testing.T) {
+			lab := (&Colour{}).FromRGBHex(tc.hexcode).ToLab()
+			assert.InDelta(t, tc.expect.L, lab.L, 0.01)
+			assert.InDelta(t, tc.expect.A, lab.A, 0.01)
+			assert.InDelta(t, tc.expect.B, lab.B, 0.01)
+		})
+	}
+}
+
+func Test_FromLab(t *testing.T) {
+	for _, hexcode := range []string{"000000", "ffffff", "ff0000", "00ff00", "0000ff", "808080", "1abc9c"} {
+		t.Run(hexcode, func(t *testing.T) {
+			colour, inGamut := (&Colour{}).FromLab((&Colour{}).FromRGBHex(hexcode).ToLab())
+			assert.True(t, inGamut)
+			assert.Equal(t, hexcode, colour.ToHexcode())
+		})
+	}
+
+	// Greens more vivid than sRGB's are out of gamut
+	colour, inGamut := (&Colour{}).FromLab(Lab{87.73, -120, 83.18})
+	assert.False(t, inGamut)
+	assert.Equal(t, 0.0, colour.R)
+}
+
+func Test_DeltaE(t *testing.T) {
+	// Test data from Sharma, Wu and Dalal (2005)
+	testCases := []struct {
+		x, y   Lab
+		expect float64
+	}{
+		{Lab{50, 2.6772, -79.7751}, Lab{50, 0, -82.7485}, 2.0425},
+		{Lab{50, 3.1571, -77.2803}, Lab{50, 0, -82.7485}, 2.8615},
+		{Lab{50, 2.8361, -74.0200}, Lab{50, 0, -82.7485}, 3.4412},
+		{Lab{50, -1.3802, -84.2814}, Lab{50, 0, -82.7485}, 1.0000},
+		{Lab{50, 0, 0}, Lab{50, -1, 2}, 2.3669},
+		{Lab{50, 2.5, 0}, Lab{73, 25, -18}, 27.1492},
+		{Lab{60.2574, -34.0099, 36.2677}, Lab{60.4626, -34.1751, 39.4387}, 1.2644},
+	}
+	for _, tc := range testCases {
+		assert.InDelta(t, tc.expect, DeltaE(tc.x, tc.y), 0.0001)
+		assert.InDelta(t, tc.expect, DeltaE(tc.y, tc.x), 0.0001, "should be symmetric")
+	}
+	assert.Zero(t, DeltaE(Lab{50, 10, 10}, Lab{50, 10, 10}))
+}
@@ -0,0 +1,113 @@
+package colours
+
+// cleanup.go reconciles colour roles with the members they were made for. Nothing deletes a colour
+// role when its member leaves or changes their name, so a scheduled job and /colours cleanup delete
+// roles that their members no longer have, and rename the ones whose member is now called something
+// else. Only tracked roles are touched, unless an admin asks /colours cleanup for untracked ones.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fiffu/arisa3/app/engine/scheduler"
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+)
+
+const cleanupJobName = "colours/cleanup"
+
+var ErrRoleCleanup = errors.New("invalid role cleanup setting")
+
+// RoleCleanup is what the scheduled cleanup does. /colours cleanup can be used whatever it is.
+type RoleCleanup string
+
+const (
+	// CleanupOn deletes and renames roles.
+	CleanupOn RoleCleanup = "on"
+	// CleanupDryRun only logs what would be deleted and renamed. It is the default.
+	CleanupDryRun RoleCleanup = "dry_run"
+	// CleanupOff doesn't schedule the cleanup.
+	CleanupOff RoleCleanup = "off"
+)
+
+func validateRoleCleanup(cleanup string) error {
+	switch RoleCleanup(cleanup) {
+	case "", CleanupOn, CleanupDryRun, CleanupOff:
+		return nil
+	default:
+		return fmt.Errorf("%w: '%s', wanted '%s', '%s' or '%s'", ErrRoleCleanup, cleanup, CleanupOn, CleanupDryRun, CleanupOff)
+	}
+}
+
+// CleanupOptions are how a cleanup goes.
+type CleanupOptions struct {
+	// DryRun only reports what would be done.
+	DryRun bool
+	// Untracked also deletes roles named like colour roles from before they were tracked, that
+	// nobody holds. Those may be roles that the bot didn't make, so only admins can ask for this.
+	Untracked bool
+}
+
+// RoleRename is a colour role that was renamed after the member holding it.
+type RoleRename struct {
+	From, To string
+}
+
+// CleanupReport is what a cleanup did to a guild's colour roles, or would have done on a dry run.
+// Roles are listed by name.
+type CleanupReport struct {
+	DryRun  bool
+	Deleted []string
+	Renamed []RoleRename
+	// Failed roles couldn't be deleted or renamed, usually because they are above the bot's role.
+	Failed []string
+}
+
+func (r *CleanupReport) String() string {
+	return fmt.Sprintf("dry_run=%t deleted=%d renamed=%d failed=%d",
+		r.DryRun, len(r.Deleted), len(r.Renamed), len(r.Failed))
+}
+
+func (c *Cog) cleanupJob() *scheduler.Job {
+	return &scheduler.Job{
+		Name:     cleanupJobName,
+		Schedule: scheduler.MustCron("@daily"),
+		Jitter:   30 * time.Minute,
+		Run:      c.runCleanup,
+	}
+}
+
+// runCleanup cleans up the colour roles of every guild the bot is in. Guilds are cleaned up
+// independently, so one failing doesn't stop the others.
+func (c *Cog) runCleanup(ctx context.Context) error {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Internal("CleanupColourRoles"))
+	defer span.End()
+
+	sess := c.session.Load()
+	if sess == nil {
+		log.Infof(ctx, "Not connected to Discord yet, skipping colour role cleanup")
+		return nil
+	}
+	sess.State.RLock()
+	guildIDs := make([]string, 0, len(sess.State.Guilds))
+	for _, g := range sess.State.Guilds {
+		guildIDs = append(guildIDs, g.ID)
+	}
+	sess.State.RUnlock()
+
+	opts := CleanupOptions{DryRun: c.cfg.roleCleanup() == CleanupDryRun}
+	s := NewDomainSession(sess)
+	var errs []error
+	for _, guildID := range guildIDs {
+		report, err := c.domain.CleanupColourRoles(ctx, s, NewDomainGuild(guildID), opts)
+		if err != nil {
+			log.Errorf(ctx, err, "Errored cleaning up colour roles, guild=%s", guildID)
+			errs = append(errs, fmt.Errorf("guild %s: %w", guildID, err))
+			continue
+		}
+		log.Infof(ctx, "Cleaned up colour roles, guild=%s %s", guildID, report)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,51 @@
+package colours
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Config_roleCleanup(t *testing.T) {
+	cfg := &Config{}
+	assert.NoError(t, cfg.validate())
+	assert.Equal(t, CleanupDryRun, cfg.roleCleanup())
+
+	cfg.RoleCleanup = "on"
+	assert.NoError(t, cfg.validate())
+	assert.Equal(t, CleanupOn, cfg.roleCleanup())
+
+	cfg.RoleCleanup = "sometimes"
+	assert.ErrorIs(t, cfg.validate(), ErrRoleCleanup)
+}
+
+func Test_cleanupResponse(t *testing.T) {
+	description := func(report *CleanupReport) string {
+		embeds := cleanupResponse(report).Data().Data.Embeds
+		assert.Len(t, embeds, 1)
+		return embeds[0].Description
+	}
+
+	assert.Equal(t,
+		"Every colour role belongs to someone, so there's nothing to clean up.",
+		description(&CleanupReport{}))
+
+	assert.Equal(t,
+		"Would delete 1 role that nobody has: `gone`\n\n"+
+			"Would rename 1 role after their members: `oldname` to `newname`\n\n"+
+			"Couldn't change 2 roles, maybe they're above my role: `stuck`, `also stuck`",
+		description(&CleanupReport{
+			DryRun:  true,
+			Deleted: []string{"gone"},
+			Renamed: []RoleRename{{From: "oldname", To: "newname"}},
+			Failed:  []string{"stuck", "also stuck"},
+		}))
+
+	// Long lists are cut short
+	many := make([]string, cleanupListed+5)
+	for i := range many {
+		many[i] = fmt.Sprint(i)
+	}
+	assert.Contains(t, description(&CleanupReport{Deleted: many}), "`19` and 5 more")
+}
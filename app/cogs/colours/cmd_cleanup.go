@@ -0,0 +1,121 @@
+package colours
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fiffu/arisa3/app/commandfilters"
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/fiffu/arisa3/app/types"
+)
+
+const (
+	OptionCleanupDryRun    = "dry_run"
+	OptionCleanupUntracked = "untracked"
+)
+
+// cleanupListed is how many roles of each kind the report names, before summing up the rest.
+const cleanupListed = 20
+
+func (c *Cog) cleanupCommand() *types.Command {
+	return types.NewCommand("cleanup").
+		Desc("Admins only: deletes colour roles that nobody has, and renames outdated ones").
+		Options(
+			types.NewOption(OptionCleanupDryRun).
+				Desc("Only show what would be cleaned up").
+				Bool(),
+			types.NewOption(OptionCleanupUntracked).
+				Desc("Also delete roles named like old colour roles (name#1234) that nobody has").
+				Bool(),
+		).
+		Handler(c.cleanup)
+}
+
+func (c *Cog) cleanup(ctx context.Context, req types.ICommandEvent) error {
+	if !commandfilters.IsFromGuild(req) {
+		return req.Respond(ctx, types.NewResponse().Content("You need to be in a guild to use this command."))
+	}
+	if !commandfilters.IsGuildAdmin(req) {
+		return req.Respond(ctx, types.NewResponse().Content("Only server admins can clean up colour roles."))
+	}
+	guild := NewDomainGuild(req.Interaction().GuildID)
+	var opts CleanupOptions
+	opts.DryRun, _ = req.Args().Bool(OptionCleanupDryRun)
+	opts.Untracked, _ = req.Args().Bool(OptionCleanupUntracked)
+
+	// Listing members and deleting roles can take longer than Discord waits for a response
+	if err := req.Respond(ctx, types.NewResponse().Deferred().Ephemeral()); err != nil {
+		return err
+	}
+	report, err := c.domain.CleanupColourRoles(ctx, NewDomainSession(req.Session()), guild, opts)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored cleaning up colour roles, guild=%s", guild.ID())
+		resp := types.NewResponse().Content("Hmm, something went wrong while looking through the colour roles. Try again later?")
+		if respErr := types.EditInteractionResponse(ctx, req.Session(), req.Interaction().Interaction, resp); respErr != nil {
+			log.Errorf(ctx, respErr, "Errored responding to cleanup")
+		}
+		return err
+	}
+	log.Infof(ctx, "Cleaned up colour roles, guild=%s user=%s %s", guild.ID(), req.User().ID, report)
+	return types.EditInteractionResponse(ctx, req.Session(), req.Interaction().Interaction, cleanupResponse(report))
+}
+
+// cleanupResponse describes what a cleanup did, naming the roles involved.
+func cleanupResponse(report *CleanupReport) *types.Response {
+	title := "Colour role cleanup"
+	deleted, renamed := "Deleted", "Renamed"
+	if report.DryRun {
+		title += " (dry run)"
+		deleted, renamed = "Would delete", "Would rename"
+	}
+
+	lines := make([]string, 0)
+	if len(report.Deleted) > 0 {
+		lines = append(lines, fmt.Sprintf("%s %s that nobody has: %s",
+			deleted, pluralRoles(len(report.Deleted)), listRoles(report.Deleted)))
+	}
+	if len(report.Renamed) > 0 {
+		renames := make([]string, len(report.Renamed))
+		for i, rename := range report.Renamed {
+			renames[i] = fmt.Sprintf("`%s` to `%s`", rename.From, rename.To)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s after their members: %s",
+			renamed, pluralRoles(len(report.Renamed)), joinListed(renames)))
+	}
+	if len(report.Failed) > 0 {
+		lines = append(lines, fmt.Sprintf("Couldn't change %s, maybe they're above my role: %s",
+			pluralRoles(len(report.Failed)), listRoles(report.Failed)))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "Every colour role belongs to someone, so there's nothing to clean up.")
+	}
+
+	embed := types.NewEmbed().
+		Title(title).
+		Description(strings.Join(lines, "\n\n"))
+	return types.NewResponse().Embeds(embed)
+}
+
+func pluralRoles(n int) string {
+	if n == 1 {
+		return "1 role"
+	}
+	return fmt.Sprintf("%d roles", n)
+}
+
+func listRoles(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "`" + name + "`"
+	}
+	return joinListed(quoted)
+}
+
+// joinListed joins the first few items, and counts the rest.
+func joinListed(items []string) string {
+	if len(items) <= cleanupListed {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:cleanupListed], ", "), len(items)-cleanupListed)
+}
@@ -3,6 +3,7 @@ package colours
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"image"
 	"image/color"
 	"image/png"
@@ -35,9 +36,13 @@ func (c *Cog) colInfo(ctx context.Context, req types.ICommandEvent) error {
 	guildID := mem.Guild().ID()
 	userID := mem.UserID()
 
-	role := c.domain.GetColourRole(ctx, mem)
+	role, err := c.domain.GetColourRole(ctx, mem)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored getting colour role, guild=%s user=%s", guildID, userID)
+		return err
+	}
 	if role == nil {
-		log.Errorf(ctx, err, "No colour role found, guild=%s user=%s", guildID, userID)
+		log.Infof(ctx, "No colour role found, guild=%s user=%s", guildID, userID)
 		return req.Respond(ctx, types.NewResponse().
 			Content("You don't have a colour role. Use /col to get a random colour!"))
 	}
@@ -78,6 +83,8 @@ func (c *Cog) colInfo(ctx context.Context, req types.ICommandEvent) error {
 
 	reply := types.NewResponse()
 	embed := newEmbed(role.Colour()).Description(info.desc)
+	dark, light := formatContrast(role.Colour().Contrast(), c.cfg.minContrast(guildID))
+	embed.Field("Contrast on dark", dark, true).Field("Contrast on light", light, true)
 	if info.img.ok {
 		img := info.img
 		reply.File(img.filename, img.contentType, img.file)
@@ -128,6 +135,9 @@ func (c *Cog) formatColInfo(
 	ret := &colInfo{}
 
 	if history != nil && len(history.records) > 0 {
+		desc = append(desc, "", "**Recent colours:**")
+		desc = append(desc, formatRecentColours(now, history, recentColoursShown)...)
+
 		buf, ext, mime, err := makeColHistoryImg(ctx, history, time.Duration(c.cfg.MutateCooldownMins)*time.Minute)
 		if err != nil {
 			return nil, err
@@ -145,6 +155,39 @@ func (c *Cog) formatColInfo(
 	return ret, nil
 }
 
+// formatContrast shows the contrast on each theme, marking any below the guild's minimum.
+func formatContrast(contrast Contrast, min float64) (dark, light string) {
+	format := func(ratio float64) string {
+		text := fmt.Sprintf("%.2f:1", ratio)
+		if ratio < min {
+			text += " ⚠️"
+		}
+		return text
+	}
+	return format(contrast.Dark), format(contrast.Light)
+}
+
+// How many of the latest history entries /colinfo lists.
+const recentColoursShown = 5
+
+// formatRecentColours lists the latest history entries, newest first, with their colours named.
+func formatRecentColours(now time.Time, h *History, limit int) []string {
+	lines := make([]string, 0, limit)
+	for i := len(h.records) - 1; i >= 0 && len(lines) < limit; i-- {
+		rec := h.records[i]
+		colour := (&Colour{}).FromRGBHex(rec.ColourHex)
+		line := fmt.Sprintf("`#%s` %s", colour.ToHexcode(), DescribeColour(colour))
+		if rec.Reason != "" {
+			line += " · " + rec.Reason
+		}
+		if now.After(rec.TStamp) {
+			line += ", " + utils.FormatDuration(now.Sub(rec.TStamp)) + " ago"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 func makeColHistoryImg(ctx context.Context, h *History, interval time.Duration) (file *bytes.Buffer, fileExt, fileContent string, err error) {
 	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Internal("makeColHistoryImg"))
 	defer span.End()
@@ -158,13 +201,22 @@ func makeColHistoryImg(ctx context.Context, h *History, interval time.Duration)
 	}
 
 	pixelsPerInterval := 4
+	return makePartitionsImg(colours, pixelsPerInterval, pixelsPerInterval*5)
+}
 
-	buf := bytes.NewBuffer(make([]byte, 0))
-	err = png.Encode(buf, horizontalPartitionImage{
+// makePartitionsImg draws the colours as a row of blocks, in a PNG.
+func makePartitionsImg(colours []*Colour, width, height int) (file *bytes.Buffer, fileExt, fileContent string, err error) {
+	return encodeImg(horizontalPartitionImage{
 		partitions:      colours,
-		partitionWidth:  pixelsPerInterval,
-		partitionHeight: pixelsPerInterval * 5,
+		partitionWidth:  width,
+		partitionHeight: height,
 	})
+}
+
+// encodeImg encodes the image as a PNG, to be attached to a response.
+func encodeImg(img image.Image) (file *bytes.Buffer, fileExt, fileContent string, err error) {
+	buf := bytes.NewBuffer(make([]byte, 0))
+	err = png.Encode(buf, img)
 	file = bytes.NewBuffer(buf.Bytes())
 	fileExt = "png"
 	fileContent = "image/png"
@@ -157,13 +157,16 @@ func Test_formatColInfo(t *testing.T) {
 				start: unix(00),
 				end:   unix(40),
 				records: []*ColoursLogRecord{
-					{TStamp: unix(00), ColourHex: "ffffff"},
-					{TStamp: unix(20), ColourHex: "000000"},
+					{TStamp: unix(00), ColourHex: "ffffff", Reason: "reroll"},
+					{TStamp: unix(20), ColourHex: "000000", Reason: "mutate"},
 				},
 			},
 			expectDesc: concat(
 				"**Reroll cooldown:**\n_(No cooldown, reroll available)_\n\n",
 				"**Last mutate:**\n1 hour ago\n\n",
+				"**Recent colours:**\n",
+				"`#000000` black · mutate, 19467 days ago\n",
+				"`#ffffff` white · reroll, 19467 days ago\n\n",
 				"**Image history, newest → oldest:**",
 			),
 		},
@@ -185,6 +188,31 @@ func Test_formatColInfo(t *testing.T) {
 	}
 }
 
+func Test_formatRecentColours(t *testing.T) {
+	now := time.Unix(1682000000, 0)
+	h := &History{records: []*ColoursLogRecord{
+		{TStamp: now.Add(-3 * time.Hour), ColourHex: "1abc9c", Reason: "pick"},
+		{TStamp: now.Add(-2 * time.Hour), ColourHex: "22c4a0", Reason: "mutate"},
+		{TStamp: now.Add(-1 * time.Hour), ColourHex: "ff0000", Reason: "reroll"},
+	}}
+
+	assert.Equal(t, []string{
+		"`#ff0000` red · reroll, 1 hour ago",
+		"`#22c4a0` ≈ teal · mutate, 2 hours ago",
+	}, formatRecentColours(now, h, 2))
+	assert.Len(t, formatRecentColours(now, h, 5), 3)
+}
+
+func Test_formatContrast(t *testing.T) {
+	dark, light := formatContrast(Contrast{Dark: 5.25, Light: 2.409}, 2.5)
+	assert.Equal(t, "5.25:1", dark)
+	assert.Equal(t, "2.41:1 ⚠️", light)
+
+	// Nothing is marked without a minimum
+	_, light = formatContrast(Contrast{Dark: 5.25, Light: 1.2}, 0)
+	assert.Equal(t, "1.20:1", light)
+}
+
 func Test_horizontalPartitionImage(t *testing.T) {
 	r, g, b := 0xff0000, 0x00ff00, 0x0000ff
 	hpi := horizontalPartitionImage{
@@ -0,0 +1,157 @@
+package colours
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/fiffu/arisa3/app/commandfilters"
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/fiffu/arisa3/app/types"
+)
+
+const (
+	OptionGallerySort = "sort"
+	OptionGalleryPage = "page"
+)
+
+const (
+	// How many colours are shown on each page of the gallery, and how many to a row.
+	galleryPerPage = 40
+	galleryColumns = 4
+
+	// How big each colour is, how far its labels are from its edges, and how many characters of a
+	// name fit on it.
+	galleryCellWidth  = 160
+	galleryCellHeight = 44
+	galleryPadding    = 8
+	galleryNameLength = (galleryCellWidth - 2*galleryPadding) / 7 // basicfont is 7 pixels wide
+)
+
+func (c *Cog) galleryCommand() *types.Command {
+	return types.NewCommand("gallery").
+		Desc("Shows everyone's colour").
+		Options(
+			types.NewOption(OptionGallerySort).
+				Desc("How to order the colours, by hue unless given").
+				String().
+				Choice("hue", string(GalleryByHue)).
+				Choice("recently changed", string(GalleryByRecent)),
+			types.NewOption(OptionGalleryPage).
+				Desc("Which page to show, in servers with many colours").
+				Int().
+				Min(1),
+		).
+		Handler(c.gallery)
+}
+
+func (c *Cog) gallery(ctx context.Context, req types.ICommandEvent) error {
+	if !commandfilters.IsFromGuild(req) {
+		return req.Respond(ctx, types.NewResponse().Content("You need to be in a guild to use this command."))
+	}
+	guild := NewDomainGuild(req.Interaction().GuildID)
+
+	entries, err := c.domain.GetGallery(ctx, NewDomainSession(req.Session()), guild)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored getting gallery, guild=%s", guild.ID())
+		return err
+	}
+
+	by := GalleryByHue
+	if sortBy, ok := req.Args().String(OptionGallerySort); ok && GallerySort(sortBy) == GalleryByRecent {
+		by = GalleryByRecent
+	}
+	page, ok := req.Args().Int(OptionGalleryPage)
+	if !ok {
+		page = 1
+	}
+
+	resp, err := galleryResponse(entries, by, page)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored drawing gallery, guild=%s", guild.ID())
+		return err
+	}
+	return req.Respond(ctx, resp)
+}
+
+// galleryResponse shows a page of the gallery, sorted as given.
+func galleryResponse(entries []*GalleryEntry, by GallerySort, page int) (*types.Response, error) {
+	if len(entries) == 0 {
+		return types.NewResponse().Content("Nobody here has a colour yet. Use /col to get one!"), nil
+	}
+
+	sortGallery(entries, by)
+	onPage, page, pages := galleryPage(entries, page, galleryPerPage)
+	file, ext, mime, err := makeGalleryImg(onPage)
+	if err != nil {
+		return nil, err
+	}
+
+	order := "by hue"
+	if by == GalleryByRecent {
+		order = "most recently changed first"
+	}
+	desc := fmt.Sprintf("%d colours, %s.", len(entries), order)
+	if pages > 1 {
+		desc += fmt.Sprintf(" Page %d of %d, use `page:` to see the others.", page, pages)
+	}
+
+	filename := "gallery." + ext
+	embed := types.NewEmbed().
+		Title("Gallery").
+		Description(desc).
+		Colour(onPage[0].Colour.ToDecimal()).
+		Image("attachment://" + filename)
+	return types.NewResponse().File(filename, mime, file).Embeds(embed), nil
+}
+
+// makeGalleryImg draws the entries in a grid, each labelled with its name and hexcode, in a PNG.
+func makeGalleryImg(entries []*GalleryEntry) (file *bytes.Buffer, fileExt, fileContent string, err error) {
+	rows := (len(entries) + galleryColumns - 1) / galleryColumns
+	columns := galleryColumns
+	if len(entries) < columns {
+		columns = len(entries)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, columns*galleryCellWidth, rows*galleryCellHeight))
+
+	face := basicfont.Face7x13
+	lineHeight := face.Metrics().Height
+	for i, entry := range entries {
+		corner := image.Pt((i%galleryColumns)*galleryCellWidth, (i/galleryColumns)*galleryCellHeight)
+		cell := image.Rectangle{corner, corner.Add(image.Pt(galleryCellWidth, galleryCellHeight))}
+		draw.Draw(img, cell, image.NewUniform(entry.Colour), image.Point{}, draw.Src)
+
+		drawer := &font.Drawer{Dst: img, Src: image.NewUniform(labelColour(entry.Colour)), Face: face}
+		baseline := fixed.P(corner.X+galleryPadding, corner.Y+galleryPadding).Add(fixed.Point26_6{Y: face.Metrics().Ascent})
+		for _, line := range []string{truncateLabel(entry.Name, galleryNameLength), "#" + entry.Colour.ToHexcode()} {
+			drawer.Dot = baseline
+			drawer.DrawString(line)
+			baseline.Y += lineHeight
+		}
+	}
+	return encodeImg(img)
+}
+
+// labelColour is black or white, whichever is more readable on the colour.
+func labelColour(c *Colour) color.Color {
+	if contrastRatio(c, &Colour{0, 0, 0}) > contrastRatio(c, &Colour{1, 1, 1}) {
+		return color.Black
+	}
+	return color.White
+}
+
+// truncateLabel shortens a label to the given number of characters, marking that it was cut.
+func truncateLabel(label string, length int) string {
+	runes := []rune(label)
+	if len(runes) <= length {
+		return label
+	}
+	return string(runes[:length-2]) + ".."
+}
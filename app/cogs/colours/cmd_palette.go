@@ -0,0 +1,114 @@
+package colours
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fiffu/arisa3/app/commandfilters"
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/fiffu/arisa3/app/types"
+)
+
+const (
+	OptionPaletteSet   = "set"
+	OptionPaletteClear = "clear"
+)
+
+const (
+	// How many colours the swatch shows from each entry of a palette, and how big each one is.
+	swatchesPerEntry = 6
+	swatchWidth      = 16
+	swatchHeight     = 48
+)
+
+func (c *Cog) paletteCommand() *types.Command {
+	return types.NewCommand("palette").ForChat().
+		Desc("Shows the colours that rerolls and mutations keep to in this server").
+		Options(
+			types.NewOption(OptionPaletteSet).
+				Desc("Admins only: colours, presets like pastel or neon, or ranges like hsv(200-260, 20-40, 90-100)").
+				String(),
+			types.NewOption(OptionPaletteClear).
+				Desc("Admins only: allow any colour again").
+				Bool(),
+		).
+		Handler(c.palette)
+}
+
+func (c *Cog) palette(ctx context.Context, req types.ICommandEvent) error {
+	if !commandfilters.IsFromGuild(req) {
+		return req.Respond(ctx, types.NewResponse().Content("You need to be in a guild to use this command."))
+	}
+	guild := NewDomainGuild(req.Interaction().GuildID)
+
+	spec, _ := req.Args().String(OptionPaletteSet)
+	clear, _ := req.Args().Bool(OptionPaletteClear)
+	if spec != "" || clear {
+		if !commandfilters.IsGuildAdmin(req) {
+			return req.Respond(ctx, types.NewResponse().Content("Only server admins can change the palette."))
+		}
+
+		var palette *Palette
+		if spec != "" {
+			var err error
+			if palette, err = ParsePalette(spec); err != nil {
+				msg := fmt.Sprintf("I can't use that palette: %s", err)
+				return req.Respond(ctx, types.NewResponse().Content(msg))
+			}
+		}
+		if err := c.domain.SetPalette(ctx, guild, palette); errors.Is(err, ErrInvalidPalette) {
+			msg := fmt.Sprintf("I can't use that palette: %s", err)
+			return req.Respond(ctx, types.NewResponse().Content(msg))
+		} else if err != nil {
+			log.Errorf(ctx, err, "Errored setting palette, guild=%s", guild.ID())
+			return err
+		}
+		log.Infof(ctx, "Set palette to '%s', guild=%s user=%s", palette, guild.ID(), req.User().ID)
+	}
+
+	palette, err := c.domain.GetPalette(ctx, guild)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored getting palette, guild=%s", guild.ID())
+		return err
+	}
+	resp, err := paletteResponse(palette)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored drawing palette, guild=%s", guild.ID())
+		return err
+	}
+	return req.Respond(ctx, resp)
+}
+
+// paletteResponse lists the palette's entries, with a swatch of colours from across them.
+func paletteResponse(palette *Palette) (*types.Response, error) {
+	if palette == nil {
+		return types.NewResponse().Content(
+			"This server has no palette, so colours can be anything. Admins can set one with `/palette set:`.",
+		), nil
+	}
+
+	lines := make([]string, len(palette.Entries))
+	for i, entry := range palette.Entries {
+		lines[i] = "`" + entry.Label + "`"
+		if preset, ok := palettePresets[entry.Label]; ok {
+			lines[i] += " · " + preset
+		} else if !strings.HasPrefix(entry.Label, "hsv(") {
+			lines[i] += " · " + DescribeColour((&Colour{}).FromRGBHex(strings.TrimPrefix(entry.Label, "#")))
+		}
+	}
+
+	swatch := palette.Swatch(swatchesPerEntry)
+	file, ext, mime, err := makePartitionsImg(swatch, swatchWidth, swatchHeight)
+	if err != nil {
+		return nil, err
+	}
+	filename := "palette." + ext
+	embed := types.NewEmbed().
+		Title("Palette").
+		Description(strings.Join(lines, "\n")).
+		Colour(swatch[0].ToDecimal()).
+		Image("attachment://" + filename)
+	return types.NewResponse().File(filename, mime, file).Embeds(embed), nil
+}
@@ -0,0 +1,229 @@
+package colours
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiffu/arisa3/app/commandfilters"
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/fiffu/arisa3/app/types"
+
+	dgo "github.com/bwmarrin/discordgo"
+)
+
+const OptionSwapMember = "member"
+
+const (
+	// Buttons on swap prompts have custom IDs like "colours.swap.accept:<prompt ID>".
+	swapButtonPrefix = "colours.swap."
+	swapAccept       = "accept"
+	swapDecline      = "decline"
+)
+
+// pendingSwap is a swap that is waiting for its target to answer the prompt.
+type pendingSwap struct {
+	guildID, fromID, toID string
+	// prompt is the /colours swap interaction, which was responded to with the prompt.
+	prompt *dgo.Interaction
+	timer  *time.Timer
+}
+
+// swapPrompts keeps the swaps that are waiting for an answer, by the ID of their prompt. The zero
+// value is ready to use.
+type swapPrompts struct {
+	mutex   sync.Mutex
+	pending map[string]*pendingSwap
+}
+
+func (p *swapPrompts) add(id string, swap *pendingSwap) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.pending == nil {
+		p.pending = make(map[string]*pendingSwap)
+	}
+	p.pending[id] = swap
+}
+
+func (p *swapPrompts) get(id string) (*pendingSwap, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	swap, ok := p.pending[id]
+	return swap, ok
+}
+
+// take removes the swap, so that it is answered or expires only once.
+func (p *swapPrompts) take(id string) (*pendingSwap, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	swap, ok := p.pending[id]
+	delete(p.pending, id)
+	return swap, ok
+}
+
+func (c *Cog) swapCommand() *types.Command {
+	return types.NewCommand("swap").
+		Desc("Offers to trade colours with someone").
+		Options(
+			types.NewOption(OptionSwapMember).
+				Desc("Who to trade colours with").
+				User().
+				Required(),
+		).
+		Handler(c.swap)
+}
+
+func (c *Cog) swap(ctx context.Context, req types.ICommandEvent) error {
+	if !commandfilters.IsFromGuild(req) {
+		return req.Respond(ctx, types.NewResponse().Content("You need to be in a guild to use this command."))
+	}
+	target, ok := req.Args().User(OptionSwapMember)
+	if !ok {
+		return req.Respond(ctx, types.NewResponse().Content("Who would you like to swap colours with?"))
+	}
+
+	s := NewDomainSession(req.Session())
+	guildID := req.Interaction().GuildID
+	userID := req.User().ID
+	if target.ID == userID {
+		return req.Respond(ctx, types.NewResponse().Content("You can't swap colours with yourself."))
+	}
+	from, err := s.GuildMember(ctx, guildID, userID)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored while retrieving member, guild=%s user=%s", guildID, userID)
+		return err
+	}
+	to, err := s.GuildMember(ctx, guildID, target.ID)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored while retrieving member, guild=%s user=%s", guildID, target.ID)
+		return err
+	}
+
+	fromRole, err := c.domain.GetColourRole(ctx, from)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored getting colour role, guild=%s user=%s", guildID, userID)
+		return err
+	}
+	toRole, err := c.domain.GetColourRole(ctx, to)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored getting colour role, guild=%s user=%s", guildID, target.ID)
+		return err
+	}
+	switch {
+	case fromRole == nil:
+		return req.Respond(ctx, types.NewResponse().Content("You don't have a colour to swap. Use /col to get one!"))
+	case toRole == nil:
+		return req.Respond(ctx, types.NewResponse().Contentf("<@%s> doesn't have a colour to swap.", target.ID))
+	}
+
+	promptID := req.Interaction().ID
+	timeout := c.cfg.swapTimeout()
+	pending := &pendingSwap{guildID: guildID, fromID: userID, toID: target.ID, prompt: req.Interaction().Interaction}
+	pending.timer = time.AfterFunc(timeout, func() {
+		c.expireSwap(context.WithoutCancel(ctx), req.Session(), promptID)
+	})
+	c.swaps.add(promptID, pending)
+
+	resp := types.NewResponse().
+		Contentf(
+			"<@%s>, <@%s> would like to swap colours with you. You'd get #%s, and they'd get #%s. "+
+				"This offer lasts %s.",
+			target.ID, userID, fromRole.Colour().ToHexcode(), toRole.Colour().ToHexcode(), timeout,
+		).
+		Button("Swap", swapButtonPrefix+swapAccept+":"+promptID, dgo.SuccessButton).
+		Button("No thanks", swapButtonPrefix+swapDecline+":"+promptID, dgo.SecondaryButton)
+	if err := req.Respond(ctx, resp); err != nil {
+		pending.timer.Stop()
+		c.swaps.take(promptID)
+		return err
+	}
+	log.Infof(ctx, "Offered swap, guild=%s from=%s to=%s", guildID, userID, target.ID)
+	return nil
+}
+
+// expireSwap takes the buttons off a prompt that wasn't answered in time.
+func (c *Cog) expireSwap(ctx context.Context, sess *dgo.Session, promptID string) {
+	pending, ok := c.swaps.take(promptID)
+	if !ok {
+		return
+	}
+	log.Infof(ctx, "Swap offer expired, guild=%s from=%s to=%s", pending.guildID, pending.fromID, pending.toID)
+	resp := types.NewResponse().Contentf(
+		"<@%s> didn't answer <@%s>'s offer to swap colours in time.", pending.toID, pending.fromID,
+	)
+	if err := types.EditInteractionResponse(ctx, sess, pending.prompt, resp); err != nil {
+		log.Errorf(ctx, err, "Errored expiring swap prompt, guild=%s", pending.guildID)
+	}
+}
+
+func (c *Cog) onInteractionCreate(ctx context.Context, s *dgo.Session, i *dgo.InteractionCreate) {
+	evt := types.NewComponentEvent(s, i)
+	if !strings.HasPrefix(evt.CustomID(), swapButtonPrefix) {
+		return
+	}
+	if err := c.answerSwap(ctx, evt); err != nil {
+		log.Errorf(ctx, err, "Errored answering swap, guild=%s user=%s", evt.GuildID(), evt.User().ID)
+	}
+}
+
+// answerSwap carries out or declines a swap, when its target clicks a button on the prompt. The
+// member who offered the swap can also withdraw it.
+func (c *Cog) answerSwap(ctx context.Context, evt types.IComponentEvent) error {
+	action, promptID, _ := strings.Cut(strings.TrimPrefix(evt.CustomID(), swapButtonPrefix), ":")
+	userID := evt.User().ID
+
+	pending, ok := c.swaps.get(promptID)
+	switch {
+	case !ok:
+		return evt.Respond(ctx, types.NewResponse().Content("This offer to swap colours has expired.").Update())
+	case userID != pending.toID && !(action == swapDecline && userID == pending.fromID):
+		return evt.Respond(ctx, types.NewResponse().Content("This offer isn't for you.").Ephemeral())
+	}
+	if pending, ok = c.swaps.take(promptID); !ok {
+		// Answered or expired in the meantime
+		return evt.Respond(ctx, types.NewResponse().Content("This offer to swap colours has expired.").Update())
+	}
+	pending.timer.Stop()
+
+	if action != swapAccept {
+		msg := fmt.Sprintf("<@%s> turned down <@%s>'s offer to swap colours.", pending.toID, pending.fromID)
+		if userID == pending.fromID {
+			msg = fmt.Sprintf("<@%s> withdrew their offer to swap colours with <@%s>.", pending.fromID, pending.toID)
+		}
+		return evt.Respond(ctx, types.NewResponse().Content(msg).Update())
+	}
+
+	// Look the members up again, since their colours may have changed while the offer was open
+	s := NewDomainSession(evt.Event().Session())
+	from, err := s.GuildMember(ctx, pending.guildID, pending.fromID)
+	if err != nil {
+		return err
+	}
+	to, err := s.GuildMember(ctx, pending.guildID, pending.toID)
+	if err != nil {
+		return err
+	}
+
+	swap, err := c.domain.Swap(ctx, s, from, to)
+	switch {
+	case errors.Is(err, ErrSwapNoColourRole):
+		return evt.Respond(ctx, types.NewResponse().
+			Content("One of you no longer has a colour, so there's nothing to swap.").Update())
+	case err != nil:
+		log.Errorf(ctx, err, "Errored swapping colours, guild=%s from=%s to=%s", pending.guildID, pending.fromID, pending.toID)
+		if respErr := evt.Respond(ctx, types.NewResponse().
+			Content("Hmm, something went wrong, so your colours were left as they were. Try again later?").Update()); respErr != nil {
+			log.Errorf(ctx, respErr, "Errored responding to swap")
+		}
+		return err
+	}
+
+	log.Infof(ctx, "Swapped colours, guild=%s from=%s to=%s", pending.guildID, pending.fromID, pending.toID)
+	return evt.Respond(ctx, types.NewResponse().
+		Contentf("<@%s> is now #%s, and <@%s> is now #%s. Enjoy!",
+			pending.fromID, swap.Colours[0].ToHexcode(), pending.toID, swap.Colours[1].ToHexcode()).
+		Update())
+}
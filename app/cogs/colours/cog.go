@@ -2,37 +2,206 @@ package colours
 
 import (
 	"context"
-	"path/filepath"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync/atomic"
+	"text/template"
+	"time"
 
 	"github.com/fiffu/arisa3/app/database"
 	"github.com/fiffu/arisa3/app/engine"
+	"github.com/fiffu/arisa3/app/engine/scheduler"
 	"github.com/fiffu/arisa3/app/log"
 	"github.com/fiffu/arisa3/app/types"
-	"github.com/fiffu/arisa3/lib"
 
 	dgo "github.com/bwmarrin/discordgo"
 )
 
 var (
-	migrationsDir = filepath.Join(lib.MustGetCallerDir(), "dbmigrations")
+	//go:embed dbmigrations
+	embeddedMigrations embed.FS
+
+	ErrLegacyStateUnclaimed = errors.New("legacy colour state has no guild to claim it")
 )
 
-// Cog implements ICog and IDefaultStartup
+// Cog implements ICog, IDefaultStartup and IScheduled
 type Cog struct {
 	commands *engine.CommandsRegistry
 	db       database.IDatabase
 
 	cfg *Config
 
+	repo   IDomainRepository
 	domain IColoursDomain
+
+	swaps swapPrompts
+
+	// session is set once connected, for jobs to use.
+	session atomic.Pointer[dgo.Session]
 }
 
 type Config struct {
 	MaxRoleHeightName string `mapstructure:"max_role_height_name"`
+	// RoleNameTemplate names colour roles after their members, using the fields of RoleNameFields,
+	// like "{{.Nick}}". Defaults to "{{.Username}}".
+	RoleNameTemplate string `mapstructure:"role_name_template"`
 
 	MutateCooldownMins int `mapstructure:"mutate_cooldown_mins"`
 	RerollCooldownMins int `mapstructure:"reroll_cooldown_mins"`
 	RerollPenaltyMins  int `mapstructure:"reroll_penalty_mins"`
+	PickCooldownMins   int `mapstructure:"pick_cooldown_mins"`
+
+	// PickPolicy applies to guilds that don't have a policy of their own in GuildPickPolicies.
+	PickPolicy        PickPolicy            `mapstructure:"pick_policy"`
+	GuildPickPolicies map[string]PickPolicy `mapstructure:"guild_pick_policies"`
+
+	// MinContrast is the least contrast that rerolled and mutated colours have on both Discord
+	// themes, up to about 3.55. Zero allows any colour. GuildMinContrast overrides it for a guild.
+	MinContrast      float64            `mapstructure:"min_contrast"`
+	GuildMinContrast map[string]float64 `mapstructure:"guild_min_contrast"`
+
+	// MutationStrategy is how mutations change colours: "rgb" (the default), "hue", "lab" or
+	// "reverting". GuildMutationStrategies overrides it for a guild.
+	MutationStrategy        string            `mapstructure:"mutation_strategy"`
+	GuildMutationStrategies map[string]string `mapstructure:"guild_mutation_strategies"`
+
+	// SwapCooldownPolicy is what /colours swap does to both members' reroll cooldowns: "restart"
+	// (the default), "clear" or "keep".
+	SwapCooldownPolicy string `mapstructure:"swap_cooldown_policy"`
+	// SwapTimeoutSecs is how long members have to accept a swap, up to 15 minutes. Defaults to 60.
+	SwapTimeoutSecs int `mapstructure:"swap_timeout_secs"`
+
+	// RoleCleanup is what the daily cleanup of orphaned colour roles does: "dry_run" (the default)
+	// to only log what it would do, "on", or "off".
+	RoleCleanup string `mapstructure:"role_cleanup"`
+
+	// PartitionInterval is "yearly" (the default) or "monthly", for new partitions of colours_log.
+	PartitionInterval string `mapstructure:"partition_interval"`
+	// PartitionsAhead is how many partitions to create beyond the current one. Defaults to 2.
+	PartitionsAhead int `mapstructure:"partitions_ahead"`
+	// LogRetentionMonths is how long to keep colours_log partitions before archiving them. Zero
+	// keeps them forever.
+	LogRetentionMonths int `mapstructure:"log_retention_months"`
+
+	// LegacyGuildID is the guild that claims colour state recorded before it was kept per guild.
+	// If unset and the bot is in exactly one guild, that guild claims it. It's required if there is
+	// unclaimed state and the bot is in several guilds.
+	LegacyGuildID string `mapstructure:"legacy_guild_id"`
+}
+
+func (cfg *Config) validate() error {
+	if _, err := parseRoleNameTemplate(cfg.RoleNameTemplate); err != nil {
+		return err
+	}
+	switch PartitionInterval(cfg.PartitionInterval) {
+	case "", Yearly, Monthly:
+	default:
+		return fmt.Errorf("%w: '%s', wanted '%s' or '%s'", ErrPartitionInterval, cfg.PartitionInterval, Yearly, Monthly)
+	}
+	if cfg.LogRetentionMonths != 0 && cfg.LogRetentionMonths < minLogRetentionMonths {
+		return fmt.Errorf("%w: %d months, wanted at least %d", ErrLogRetention, cfg.LogRetentionMonths, minLogRetentionMonths)
+	}
+	if err := cfg.PickPolicy.validate(); err != nil {
+		return err
+	}
+	for guildID, policy := range cfg.GuildPickPolicies {
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("guild %s: %w", guildID, err)
+		}
+	}
+	if err := validateMinContrast(cfg.MinContrast); err != nil {
+		return err
+	}
+	for guildID, min := range cfg.GuildMinContrast {
+		if err := validateMinContrast(min); err != nil {
+			return fmt.Errorf("guild %s: %w", guildID, err)
+		}
+	}
+	if err := validateMutationStrategy(cfg.MutationStrategy); err != nil {
+		return err
+	}
+	for guildID, strategy := range cfg.GuildMutationStrategies {
+		if err := validateMutationStrategy(strategy); err != nil {
+			return fmt.Errorf("guild %s: %w", guildID, err)
+		}
+	}
+	if err := validateSwapCooldownPolicy(cfg.SwapCooldownPolicy); err != nil {
+		return err
+	}
+	if err := validateSwapTimeout(cfg.SwapTimeoutSecs); err != nil {
+		return err
+	}
+	if err := validateRoleCleanup(cfg.RoleCleanup); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cfg *Config) roleNameTemplate() *template.Template {
+	tmpl, err := parseRoleNameTemplate(cfg.RoleNameTemplate)
+	if err != nil {
+		// Configs are validated, so only the default is left
+		tmpl, _ = parseRoleNameTemplate(defaultRoleNameTemplate)
+	}
+	return tmpl
+}
+
+func (cfg *Config) pickPolicy(guildID string) PickPolicy {
+	if policy, ok := cfg.GuildPickPolicies[guildID]; ok {
+		return policy
+	}
+	return cfg.PickPolicy
+}
+
+func (cfg *Config) minContrast(guildID string) float64 {
+	if min, ok := cfg.GuildMinContrast[guildID]; ok {
+		return min
+	}
+	return cfg.MinContrast
+}
+
+func (cfg *Config) mutator(guildID string) Mutator {
+	if strategy, ok := cfg.GuildMutationStrategies[guildID]; ok {
+		return mutatorFor(strategy)
+	}
+	return mutatorFor(cfg.MutationStrategy)
+}
+
+func (cfg *Config) swapCooldownPolicy() SwapCooldownPolicy {
+	if cfg.SwapCooldownPolicy == "" {
+		return SwapRestartsCooldown
+	}
+	return SwapCooldownPolicy(cfg.SwapCooldownPolicy)
+}
+
+func (cfg *Config) swapTimeout() time.Duration {
+	if cfg.SwapTimeoutSecs == 0 {
+		return defaultSwapTimeout
+	}
+	return time.Duration(cfg.SwapTimeoutSecs) * time.Second
+}
+
+func (cfg *Config) roleCleanup() RoleCleanup {
+	if cfg.RoleCleanup == "" {
+		return CleanupDryRun
+	}
+	return RoleCleanup(cfg.RoleCleanup)
+}
+
+func (cfg *Config) partitionInterval() PartitionInterval {
+	if cfg.PartitionInterval == "" {
+		return Yearly
+	}
+	return PartitionInterval(cfg.PartitionInterval)
+}
+
+func (cfg *Config) partitionsAhead() int {
+	if cfg.PartitionsAhead <= 0 {
+		return defaultPartitionsAhead
+	}
+	return cfg.PartitionsAhead
 }
 
 func NewCog(a types.IApp) types.ICog {
@@ -49,10 +218,14 @@ func (c *Cog) Configure(ctx context.Context, cfg types.CogConfig) error {
 	if !ok {
 		return engine.UnexpectedConfigType(c.ConfigPointer(), cfg)
 	}
+	if err := config.validate(); err != nil {
+		return err
+	}
 	c.cfg = config
+	c.repo = NewRepository(c.db)
 	c.domain = NewColoursDomain(
 		c,
-		NewRepository(c.db),
+		c.repo,
 		c.cfg,
 	)
 	log.Infof(ctx, "IColoursDomain loaded")
@@ -63,18 +236,93 @@ func (c *Cog) OnStartup(ctx context.Context, app types.IApp, rawConfig types.Cog
 	return engine.Bootstrap(ctx, app, rawConfig, c)
 }
 
-func (c *Cog) MigrationsDir() string {
-	return migrationsDir
+// Jobs implements IScheduled. colours_log is only partitioned on Postgres, so other databases
+// don't get the partitions job.
+func (c *Cog) Jobs() []*scheduler.Job {
+	jobs := make([]*scheduler.Job, 0)
+	if c.db.Dialect() == database.DialectPostgres {
+		jobs = append(jobs, newPartitioner(c.db, c.cfg).Job())
+	}
+	if c.cfg.roleCleanup() != CleanupOff {
+		jobs = append(jobs, c.cleanupJob())
+	}
+	return jobs
+}
+
+func (c *Cog) MigrationsFS() fs.FS {
+	// Sub only fails on invalid paths, and this one is fixed by the embed directive above
+	migrations, _ := fs.Sub(embeddedMigrations, "dbmigrations")
+	return migrations
 }
 
 func (c *Cog) ReadyCallback(ctx context.Context, s *dgo.Session, r *dgo.Ready) error {
+	c.session.Store(s)
+	c.adoptColourRoles(ctx, s, r.Guilds)
 	if err := c.registerCommands(ctx, s); err != nil {
 		return err
 	}
 	c.registerEvents(ctx, s)
+	// Legacy state is claimed last, so that the cog works while it can't be claimed. Members only
+	// miss their legacy cooldowns and history until then.
+	return c.claimLegacyState(ctx, r.Guilds)
+}
+
+// claimLegacyState assigns colour state from before it was kept per guild to the configured legacy
+// guild, or else to the only guild the bot is in. State is claimed once. A migration can't do
+// this, as the guild is only known from config or from the Ready event. If the state is unclaimed
+// and the bot is in several guilds without a legacy guild, this fails on every Ready, so that the
+// state isn't silently left unused.
+func (c *Cog) claimLegacyState(ctx context.Context, guilds []*dgo.Guild) error {
+	done, err := c.repo.FetchLegacyStateClaimed(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check legacy colour state: %w", err)
+	}
+	if done {
+		return nil
+	}
+	guildID := c.cfg.LegacyGuildID
+	if guildID == "" && len(guilds) == 1 {
+		guildID = guilds[0].ID
+	}
+	if guildID == "" {
+		return fmt.Errorf("%w: the bot is in %d guilds, set legacy_guild_id to the one that owns it",
+			ErrLegacyStateUnclaimed, len(guilds))
+	}
+	claimed, err := c.repo.ClaimLegacyState(ctx, guildID)
+	if err != nil {
+		return fmt.Errorf("failed to claim legacy colour state, guild=%s: %w", guildID, err)
+	}
+	log.Infof(ctx, "Claimed %d legacy colour states, guild=%s", claimed, guildID)
 	return nil
 }
 
+// adoptColourRoles starts tracking colour roles that were made before they were tracked by ID,
+// before commands can give anyone a second one. Guilds that fail are tried again on the next start.
+func (c *Cog) adoptColourRoles(ctx context.Context, sess *dgo.Session, guilds []*dgo.Guild) {
+	s := NewDomainSession(sess)
+	for _, g := range guilds {
+		adopted, err := c.domain.AdoptColourRoles(ctx, s, NewDomainGuild(g.ID))
+		if err != nil {
+			log.Errorf(ctx, err, "Failed to adopt colour roles, guild=%s", g.ID)
+			continue
+		}
+		if adopted > 0 {
+			log.Infof(ctx, "Adopted %d colour roles, guild=%s", adopted, g.ID)
+		}
+	}
+}
+
+// coloursCommand groups the commands about everyone's colours, like /colours gallery.
+func (c *Cog) coloursCommand() *types.Command {
+	return types.NewCommand("colours").ForChat().
+		Desc("Colour roles across the server").
+		SubCommands(
+			c.galleryCommand(),
+			c.swapCommand(),
+			c.cleanupCommand(),
+		)
+}
+
 func (c *Cog) registerCommands(ctx context.Context, s *dgo.Session) error {
 	err := c.commands.Register(
 		ctx,
@@ -83,6 +331,8 @@ func (c *Cog) registerCommands(ctx context.Context, s *dgo.Session) error {
 		c.freezeCommand(),
 		c.unfreezeCommand(),
 		c.colInfoCommand(),
+		c.paletteCommand(),
+		c.coloursCommand(),
 	)
 	if err != nil {
 		return err
@@ -95,6 +345,12 @@ func (c *Cog) registerEvents(_ context.Context, sess *dgo.Session) {
 	sess.AddHandler(engine.NewEventHandler(
 		c.onMessageCreate,
 	))
+	sess.AddHandler(engine.NewEventHandler(
+		c.onGuildRoleDelete,
+	))
+	sess.AddHandler(engine.NewEventHandler(
+		c.onInteractionCreate,
+	))
 }
 
 func (c *Cog) onMessageCreate(ctx context.Context, s *dgo.Session, m *dgo.MessageCreate) {
@@ -106,9 +362,22 @@ func (c *Cog) onMessageCreate(ctx context.Context, s *dgo.Session, m *dgo.Messag
 	c.mutate(ctx, evt)
 }
 
+func (c *Cog) onGuildRoleDelete(ctx context.Context, s *dgo.Session, r *dgo.GuildRoleDelete) {
+	evt := types.NewRoleDeleteEvent(s, r)
+	log.Infof(ctx, "Role deleted, guild=%s role=%s", evt.GuildID(), evt.RoleID())
+	if err := c.repo.DeleteColourRole(ctx, evt.GuildID(), evt.RoleID()); err != nil {
+		log.Errorf(ctx, err, "Errored forgetting deleted role, guild=%s role=%s", evt.GuildID(), evt.RoleID())
+	}
+}
+
 func (c *Cog) colCommand() *types.Command {
 	return types.NewCommand("col").ForChat().
 		Desc("Gives you a shiny new colour").
+		Options(
+			types.NewOption(OptionColour).
+				Desc("A hexcode like #1abc9c or a colour name, if you'd rather choose").
+				String(),
+		).
 		Handler(c.col)
 }
 
@@ -1,20 +1,549 @@
 package colours
 
 import (
-	"strings"
+	"context"
+	"io/fs"
 	"testing"
+	"time"
 
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/engine"
+	"github.com/fiffu/arisa3/app/engine/scheduler"
+	"github.com/fiffu/arisa3/testfixtures/discordtest"
+
+	dgo "github.com/bwmarrin/discordgo"
+	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
-func Test_migrationsDir(t *testing.T) {
-	here := migrationsDir
-	// assert that this path appears in this order in `migrationsDir`
-	expectPath := []string{"app", "cogs", "colours", "dbmigrations"}
-	prev := -1
-	for _, expect := range expectPath {
-		idx := strings.Index(here, expect)
-		assert.Less(t, prev, idx)
-		prev = idx
+func Test_MigrationsFS(t *testing.T) {
+	// Migrations are embedded at the root of the FS, so they load without the source tree
+	files, err := fs.Glob((&Cog{}).MigrationsFS(), "*.sql")
+	assert.NoError(t, err)
+	assert.Contains(t, files, "1650549317_create_table_colours.sql")
+}
+
+func Test_col_e2e(t *testing.T) {
+	const guildID = "1"
+	srv := discordtest.NewServer(t)
+	srv.AddRole(guildID, &dgo.Role{Name: "Colours go below here"})
+	srv.AddRole(guildID, &dgo.Role{Name: "Mods"})
+	member := srv.AddMember(guildID, &dgo.Member{
+		User: &dgo.User{ID: "2", Username: "someone", Discriminator: "0"},
+	})
+
+	ctrl := gomock.NewController(t)
+	repo := NewMockIDomainRepository(ctrl)
+	// The bot is only in one guild, so it claims any legacy state
+	repo.EXPECT().FetchLegacyStateClaimed(Any).Return(true, nil)
+	repo.EXPECT().FetchRolesAdopted(Any, guildID).Return(true, nil)
+	repo.EXPECT().FetchUserState(Any, Any, Reroll).Return(Never, nil)
+	repo.EXPECT().FetchPalette(Any, guildID).Return(nil, nil)
+	repo.EXPECT().FetchColourRole(Any, Any).Return("", nil)
+	repo.EXPECT().UpdateColourRole(Any, Any, Any).Return(nil)
+	repo.EXPECT().UpdateReroll(Any, Any, Any).Return(nil)
+
+	cfg := &Config{MaxRoleHeightName: "Colours go below here"}
+	cog := &Cog{commands: engine.NewCommandRegistry(), cfg: cfg, repo: repo}
+	cog.domain = NewColoursDomain(cog, repo, cfg)
+
+	sess := srv.NewSession()
+	ready := make(chan struct{})
+	sess.AddHandler(func(s *dgo.Session, r *dgo.Ready) {
+		assert.NoError(t, cog.ReadyCallback(context.Background(), s, r))
+		close(ready)
+	})
+	srv.Open(sess)
+	<-ready
+	assert.Len(t, srv.Commands(), 6)
+
+	itr := srv.SendInteraction(discordtest.SlashCommand(member, "col"))
+	resp := srv.WaitForCallback(itr)
+	assert.Len(t, resp.Data.Embeds, 1)
+
+	// New role is created, named after the member, moved below the max height role, and assigned to them
+	names := []string{}
+	for _, role := range srv.Roles(guildID) {
+		names = append(names, role.Name)
+	}
+	assert.Equal(t, []string{"@everyone", "someone", "Colours go below here", "Mods"}, names)
+
+	newRole := srv.Roles(guildID)[1]
+	assert.Equal(t, resp.Data.Embeds[0].Color, newRole.Color)
+	assert.Equal(t, []string{newRole.ID}, srv.Member(guildID, "2").Roles)
+
+	srv.WaitForCall("POST", `/guilds/1/roles`)
+	srv.WaitForCall("PATCH", `/guilds/1/roles`)
+	srv.WaitForCall("PUT", `/guilds/1/members/2/roles/\d+`)
+}
+
+func Test_col_e2e_pick(t *testing.T) {
+	const guildID = "1"
+	srv := discordtest.NewServer(t)
+	srv.AddRole(guildID, &dgo.Role{ID: "3", Name: "someone#0", Color: 0xffffff})
+	member := srv.AddMember(guildID, &dgo.Member{
+		User:  &dgo.User{ID: "2", Username: "someone", Discriminator: "0"},
+		Roles: []string{"3"},
+	})
+
+	ctrl := gomock.NewController(t)
+	repo := NewMockIDomainRepository(ctrl)
+	repo.EXPECT().FetchLegacyStateClaimed(Any).Return(true, nil)
+	// The role named after the member before roles were tracked by ID is adopted
+	repo.EXPECT().FetchRolesAdopted(Any, guildID).Return(false, nil)
+	repo.EXPECT().UpdateRolesAdopted(Any, guildID, map[string]string{"2": "3"}).Return(nil)
+	repo.EXPECT().FetchUserState(Any, Any, Pick).Return(Never, nil)
+	repo.EXPECT().FetchColourRole(Any, Any).Return("3", nil)
+	repo.EXPECT().UpdatePick(Any, Any, Any).Return(nil)
+
+	cfg := &Config{PickPolicy: PickPolicy{Deny: []string{"red"}}}
+	cog := &Cog{commands: engine.NewCommandRegistry(), cfg: cfg, repo: repo}
+	cog.domain = NewColoursDomain(cog, repo, cfg)
+
+	sess := srv.NewSession()
+	ready := make(chan struct{})
+	sess.AddHandler(func(s *dgo.Session, r *dgo.Ready) {
+		assert.NoError(t, cog.ReadyCallback(context.Background(), s, r))
+		close(ready)
+	})
+	srv.Open(sess)
+	<-ready
+
+	// Picking a denied colour is refused without touching the role
+	itr := srv.SendInteraction(discordtest.SlashCommand(member, "col", discordtest.StringOption(OptionColour, "red")))
+	resp := srv.WaitForCallback(itr)
+	assert.Contains(t, resp.Data.Content, "isn't a colour you can pick")
+
+	itr = srv.SendInteraction(discordtest.SlashCommand(member, "col", discordtest.StringOption(OptionColour, "#1abc9c")))
+	resp = srv.WaitForCallback(itr)
+	assert.Len(t, resp.Data.Embeds, 1)
+	assert.Equal(t, 0x1abc9c, resp.Data.Embeds[0].Color)
+	srv.WaitForCall("PATCH", `/guilds/1/roles/3`)
+	assert.Equal(t, 0x1abc9c, srv.Roles(guildID)[1].Color)
+}
+
+func Test_palette_e2e(t *testing.T) {
+	const guildID = "1"
+	srv := discordtest.NewServer(t)
+	member := srv.AddMember(guildID, &dgo.Member{
+		User: &dgo.User{ID: "2", Username: "someone", Discriminator: "0"},
+	})
+	admin := srv.AddMember(guildID, &dgo.Member{
+		User:        &dgo.User{ID: "3", Username: "admin", Discriminator: "0"},
+		Permissions: dgo.PermissionAdministrator,
+	})
+
+	ctrl := gomock.NewController(t)
+	repo := NewMockIDomainRepository(ctrl)
+	repo.EXPECT().FetchLegacyStateClaimed(Any).Return(true, nil)
+	repo.EXPECT().FetchRolesAdopted(Any, guildID).Return(true, nil)
+
+	cfg := &Config{MinContrast: 2}
+	cog := &Cog{commands: engine.NewCommandRegistry(), cfg: cfg, repo: repo}
+	cog.domain = NewColoursDomain(cog, repo, cfg)
+
+	sess := srv.NewSession()
+	ready := make(chan struct{})
+	sess.AddHandler(func(s *dgo.Session, r *dgo.Ready) {
+		assert.NoError(t, cog.ReadyCallback(context.Background(), s, r))
+		close(ready)
+	})
+	srv.Open(sess)
+	<-ready
+
+	send := func(from *dgo.Member, options ...*dgo.ApplicationCommandInteractionDataOption) (*dgo.InteractionResponse, map[string][]byte) {
+		itr := srv.SendInteraction(discordtest.SlashCommand(from, "palette", options...))
+		resp := srv.WaitForCallback(itr)
+		files, err := srv.WaitForCall("POST", "/interactions/"+itr.ID+"/"+itr.Token+"/callback").Files()
+		assert.NoError(t, err)
+		return resp, files
+	}
+
+	// Without a palette
+	repo.EXPECT().FetchPalette(Any, guildID).Return(nil, nil)
+	resp, _ := send(member)
+	assert.Contains(t, resp.Data.Content, "no palette")
+
+	// Only admins can set it
+	resp, _ = send(member, discordtest.StringOption(OptionPaletteSet, "pastel"))
+	assert.Contains(t, resp.Data.Content, "Only server admins")
+
+	resp, _ = send(admin, discordtest.StringOption(OptionPaletteSet, "pastel, not a colour"))
+	assert.Contains(t, resp.Data.Content, "I can't use that palette")
+
+	// Palettes whose colours can't meet the minimum contrast are refused
+	resp, _ = send(admin, discordtest.StringOption(OptionPaletteSet, "pastel, black"))
+	assert.Contains(t, resp.Data.Content, "I can't use that palette: invalid palette: '#000000' can't be readable")
+
+	palette, _ := ParsePalette("pastel, teal")
+	gomock.InOrder(
+		repo.EXPECT().UpdatePalette(Any, guildID, palette).Return(nil),
+		repo.EXPECT().FetchPalette(Any, guildID).Return(palette, nil),
+	)
+	resp, files := send(admin, discordtest.StringOption(OptionPaletteSet, "pastel, teal"))
+	if assert.Len(t, resp.Data.Embeds, 1) {
+		assert.Contains(t, resp.Data.Embeds[0].Description, "`pastel`")
+		assert.Contains(t, resp.Data.Embeds[0].Description, "`#1abc9c` · teal")
+		assert.Equal(t, "attachment://palette.png", resp.Data.Embeds[0].Image.URL)
+	}
+	assert.Contains(t, files, "palette.png")
+
+	gomock.InOrder(
+		repo.EXPECT().UpdatePalette(Any, guildID, nil).Return(nil),
+		repo.EXPECT().FetchPalette(Any, guildID).Return(nil, nil),
+	)
+	resp, _ = send(admin, discordtest.BoolOption(OptionPaletteClear, true))
+	assert.Contains(t, resp.Data.Content, "no palette")
+}
+
+func Test_gallery_e2e(t *testing.T) {
+	const guildID = "1"
+	srv := discordtest.NewServer(t)
+	member := srv.AddMember(guildID, &dgo.Member{
+		User: &dgo.User{ID: "2", Username: "someone", Discriminator: "0"},
+	})
+	teal := srv.AddRole(guildID, &dgo.Role{Name: "someone", Color: 0x1abc9c})
+	red := srv.AddRole(guildID, &dgo.Role{Name: "Someone Who Left", Color: 0xff0000})
+	srv.AddRole(guildID, &dgo.Role{Name: "Moderators", Color: 0x0000ff})
+
+	ctrl := gomock.NewController(t)
+	repo := NewMockIDomainRepository(ctrl)
+	repo.EXPECT().FetchLegacyStateClaimed(Any).Return(true, nil)
+	repo.EXPECT().FetchRolesAdopted(Any, guildID).Return(true, nil)
+	repo.EXPECT().FetchColourRoles(Any, guildID).Return(map[string]string{"2": teal.ID, "5": red.ID}, nil).Times(2)
+	repo.EXPECT().FetchLastChanges(Any, guildID).Return(map[string]time.Time{"2": time.Now()}, nil).Times(2)
+
+	cfg := &Config{}
+	cog := &Cog{commands: engine.NewCommandRegistry(), cfg: cfg, repo: repo}
+	cog.domain = NewColoursDomain(cog, repo, cfg)
+
+	sess := srv.NewSession()
+	ready := make(chan struct{})
+	sess.AddHandler(func(s *dgo.Session, r *dgo.Ready) {
+		assert.NoError(t, cog.ReadyCallback(context.Background(), s, r))
+		close(ready)
+	})
+	srv.Open(sess)
+	<-ready
+
+	send := func(options ...*dgo.ApplicationCommandInteractionDataOption) (*dgo.InteractionResponse, map[string][]byte) {
+		itr := srv.SendInteraction(discordtest.SlashCommand(member, "colours", discordtest.SubCommand("gallery", options...)))
+		resp := srv.WaitForCallback(itr)
+		files, err := srv.WaitForCall("POST", "/interactions/"+itr.ID+"/"+itr.Token+"/callback").Files()
+		assert.NoError(t, err)
+		return resp, files
+	}
+
+	// Only colour roles are shown, red first by hue
+	resp, files := send()
+	if assert.Len(t, resp.Data.Embeds, 1) {
+		assert.Equal(t, "2 colours, by hue.", resp.Data.Embeds[0].Description)
+		assert.Equal(t, 0xff0000, resp.Data.Embeds[0].Color)
+		assert.Equal(t, "attachment://gallery.png", resp.Data.Embeds[0].Image.URL)
+	}
+	assert.Contains(t, files, "gallery.png")
+
+	// The subcommand's options are passed to it
+	resp, _ = send(discordtest.StringOption(OptionGallerySort, string(GalleryByRecent)), discordtest.IntOption(OptionGalleryPage, 3))
+	if assert.Len(t, resp.Data.Embeds, 1) {
+		assert.Equal(t, "2 colours, most recently changed first.", resp.Data.Embeds[0].Description)
+		assert.Equal(t, 0x1abc9c, resp.Data.Embeds[0].Color)
+	}
+}
+
+func Test_swap_e2e(t *testing.T) {
+	const guildID = "1"
+	srv := discordtest.NewServer(t)
+	tealRole := srv.AddRole(guildID, &dgo.Role{Name: "someone#0", Color: 0x1abc9c})
+	coralRole := srv.AddRole(guildID, &dgo.Role{Name: "other#0", Color: 0xff7f50})
+	from := srv.AddMember(guildID, &dgo.Member{
+		User:  &dgo.User{ID: "2", Username: "someone", Discriminator: "0"},
+		Roles: []string{tealRole.ID},
+	})
+	to := srv.AddMember(guildID, &dgo.Member{
+		User:  &dgo.User{ID: "3", Username: "other", Discriminator: "0"},
+		Roles: []string{coralRole.ID},
+	})
+	bystander := srv.AddMember(guildID, &dgo.Member{
+		User: &dgo.User{ID: "4", Username: "bystander", Discriminator: "0"},
+	})
+
+	ctrl := gomock.NewController(t)
+	repo := NewMockIDomainRepository(ctrl)
+	repo.EXPECT().FetchLegacyStateClaimed(Any).Return(true, nil)
+	repo.EXPECT().FetchRolesAdopted(Any, guildID).Return(false, nil)
+	adopted := map[string]string{"2": tealRole.ID, "3": coralRole.ID}
+	repo.EXPECT().UpdateRolesAdopted(Any, guildID, adopted).Return(nil)
+	repo.EXPECT().FetchColourRole(Any, Any).DoAndReturn(func(_ context.Context, mem IDomainMember) (string, error) {
+		return adopted[mem.UserID()], nil
+	}).AnyTimes()
+
+	cfg := &Config{SwapTimeoutSecs: 1}
+	cog := &Cog{commands: engine.NewCommandRegistry(), cfg: cfg, repo: repo}
+	cog.domain = NewColoursDomain(cog, repo, cfg)
+
+	sess := srv.NewSession()
+	ready := make(chan struct{})
+	sess.AddHandler(func(s *dgo.Session, r *dgo.Ready) {
+		assert.NoError(t, cog.ReadyCallback(context.Background(), s, r))
+		close(ready)
+	})
+	srv.Open(sess)
+	<-ready
+
+	// offer returns the custom IDs of the prompt's buttons, to accept and to decline.
+	offer := func() (itr *dgo.Interaction, accept, decline string) {
+		itr = srv.SendInteraction(discordtest.SlashCommand(from, "colours",
+			discordtest.SubCommand("swap", discordtest.UserOption(OptionSwapMember, to.User.ID))))
+		resp := srv.WaitForCallback(itr)
+		assert.Contains(t, resp.Data.Content, "<@3>, <@2> would like to swap colours with you")
+		if assert.Len(t, resp.Data.Components, 1) {
+			buttons := resp.Data.Components[0].(*dgo.ActionsRow).Components
+			return itr, buttons[0].(*dgo.Button).CustomID, buttons[1].(*dgo.Button).CustomID
+		}
+		return itr, "", ""
+	}
+	click := func(by *dgo.Member, customID string) *dgo.InteractionResponse {
+		return srv.WaitForCallback(srv.SendInteraction(discordtest.ButtonClick(by, customID)))
+	}
+
+	// Only the target can accept
+	_, accept, decline := offer()
+	resp := click(bystander, accept)
+	assert.Equal(t, "This offer isn't for you.", resp.Data.Content)
+	assert.Equal(t, dgo.MessageFlagsEphemeral, resp.Data.Flags)
+
+	repo.EXPECT().UpdateSwap(Any, Any).DoAndReturn(func(_ context.Context, swap *ColourSwap) error {
+		assert.Equal(t, SwapRestartsCooldown, swap.Cooldown)
+		assert.Equal(t, "ff7f50", swap.Colours[0].ToHexcode())
+		return nil
+	})
+	resp = click(to, accept)
+	assert.Equal(t, dgo.InteractionResponseUpdateMessage, resp.Type)
+	assert.Equal(t, "<@2> is now #ff7f50, and <@3> is now #1abc9c. Enjoy!", resp.Data.Content)
+	assert.Empty(t, resp.Data.Components)
+	colours := map[string]int{}
+	for _, role := range srv.Roles(guildID) {
+		colours[role.Name] = role.Color
+	}
+	assert.Equal(t, 0xff7f50, colours["someone#0"])
+	assert.Equal(t, 0x1abc9c, colours["other#0"])
+
+	// Answered offers can't be answered again
+	resp = click(to, decline)
+	assert.Equal(t, "This offer to swap colours has expired.", resp.Data.Content)
+
+	_, _, decline = offer()
+	resp = click(to, decline)
+	assert.Equal(t, "<@3> turned down <@2>'s offer to swap colours.", resp.Data.Content)
+
+	// Unanswered offers expire
+	itr, accept, _ := offer()
+	edit := srv.WaitForCall("PATCH", "/webhooks/"+discordtest.AppID+"/"+itr.Token+"/messages/@original")
+	var expired dgo.WebhookEdit
+	assert.NoError(t, edit.JSON(&expired))
+	if assert.NotNil(t, expired.Content) {
+		assert.Equal(t, "<@3> didn't answer <@2>'s offer to swap colours in time.", *expired.Content)
+	}
+	assert.Empty(t, *expired.Components)
+	resp = click(to, accept)
+	assert.Equal(t, "This offer to swap colours has expired.", resp.Data.Content)
+}
+
+func Test_cleanup_e2e(t *testing.T) {
+	const guildID = "1"
+	srv := discordtest.NewServer(t)
+	srv.AddRole(guildID, &dgo.Role{Name: "gone#0"})
+	renamed := srv.AddRole(guildID, &dgo.Role{Name: "admin#0"})
+	left := srv.AddRole(guildID, &dgo.Role{Name: "left"})
+	admin := srv.AddMember(guildID, &dgo.Member{
+		User:        &dgo.User{ID: "2", Username: "admin", Discriminator: "0"},
+		Roles:       []string{renamed.ID},
+		Permissions: dgo.PermissionAdministrator,
+	})
+	member := srv.AddMember(guildID, &dgo.Member{
+		User: &dgo.User{ID: "3", Username: "someone", Discriminator: "0"},
+	})
+
+	ctrl := gomock.NewController(t)
+	repo := NewMockIDomainRepository(ctrl)
+	repo.EXPECT().FetchLegacyStateClaimed(Any).Return(true, nil)
+	// Only the role that someone has is adopted, and a member who left still has one recorded
+	tracked := map[string]string{"9": left.ID}
+	repo.EXPECT().FetchRolesAdopted(Any, guildID).Return(false, nil)
+	repo.EXPECT().UpdateRolesAdopted(Any, guildID, map[string]string{"2": renamed.ID}).DoAndReturn(
+		func(_ context.Context, _ string, roles map[string]string) error {
+			for userID, roleID := range roles {
+				tracked[userID] = roleID
+			}
+			return nil
+		})
+	repo.EXPECT().FetchColourRoles(Any, guildID).AnyTimes().DoAndReturn(
+		func(context.Context, string) (map[string]string, error) {
+			roles := make(map[string]string, len(tracked))
+			for userID, roleID := range tracked {
+				roles[userID] = roleID
+			}
+			return roles, nil
+		})
+	repo.EXPECT().DeleteColourRole(Any, guildID, Any).AnyTimes().DoAndReturn(
+		func(_ context.Context, _, roleID string) error {
+			for userID, trackedID := range tracked {
+				if trackedID == roleID {
+					delete(tracked, userID)
+				}
+			}
+			return nil
+		})
+
+	cfg := &Config{RoleCleanup: string(CleanupOn)}
+	cog := &Cog{commands: engine.NewCommandRegistry(), cfg: cfg, repo: repo}
+	cog.domain = NewColoursDomain(cog, repo, cfg)
+
+	// The job waits for the bot to connect
+	assert.NoError(t, cog.runCleanup(context.Background()))
+	assert.Empty(t, srv.FindCalls("GET", "/guilds/"+guildID+"/members"))
+
+	sess := srv.NewSession()
+	ready := make(chan struct{})
+	sess.AddHandler(func(s *dgo.Session, r *dgo.Ready) {
+		assert.NoError(t, cog.ReadyCallback(context.Background(), s, r))
+		close(ready)
+	})
+	srv.Open(sess)
+	<-ready
+
+	roleNames := func() []string {
+		names := []string{}
+		for _, role := range srv.Roles(guildID) {
+			names = append(names, role.Name)
+		}
+		return names
+	}
+	send := func(from *dgo.Member, options ...*dgo.ApplicationCommandInteractionDataOption) *dgo.Interaction {
+		return srv.SendInteraction(discordtest.SlashCommand(from, "colours", discordtest.SubCommand("cleanup", options...)))
+	}
+	report := func(itr *dgo.Interaction) string {
+		edit := srv.WaitForCall("PATCH", "/webhooks/"+discordtest.AppID+"/"+itr.Token+"/messages/@original")
+		var body dgo.WebhookEdit
+		assert.NoError(t, edit.JSON(&body))
+		if assert.NotNil(t, body.Embeds) && assert.Len(t, *body.Embeds, 1) {
+			return (*body.Embeds)[0].Description
+		}
+		return ""
+	}
+
+	// Only admins can clean up
+	resp := srv.WaitForCallback(send(member))
+	assert.Equal(t, "Only server admins can clean up colour roles.", resp.Data.Content)
+
+	// Dry runs change nothing
+	itr := send(admin, discordtest.BoolOption(OptionCleanupDryRun, true))
+	resp = srv.WaitForCallback(itr)
+	assert.Equal(t, dgo.InteractionResponseDeferredChannelMessageWithSource, resp.Type)
+	assert.Equal(t, dgo.MessageFlagsEphemeral, resp.Data.Flags)
+	assert.Equal(t,
+		"Would delete 1 role that nobody has: `left`\n\nWould rename 1 role after their members: `admin#0` to `admin`",
+		report(itr))
+	assert.Equal(t, []string{"@everyone", "gone#0", "admin#0", "left"}, roleNames())
+
+	// Untracked roles are only deleted when asked for
+	itr = send(admin, discordtest.BoolOption(OptionCleanupUntracked, true))
+	srv.WaitForCallback(itr)
+	assert.Equal(t,
+		"Deleted 2 roles that nobody has: `gone#0`, `left`\n\nRenamed 1 role after their members: `admin#0` to `admin`",
+		report(itr))
+	assert.Equal(t, []string{"@everyone", "admin"}, roleNames())
+	assert.Equal(t, map[string]string{"2": renamed.ID}, tracked)
+
+	// The job cleans up every guild the bot is in, leaving untracked roles alone
+	srv.AddRole(guildID, &dgo.Role{Name: "stray#0"})
+	quitter := srv.AddRole(guildID, &dgo.Role{Name: "quitter"})
+	tracked["4"] = quitter.ID
+	assert.NoError(t, cog.runCleanup(context.Background()))
+	assert.Equal(t, []string{"@everyone", "admin", "stray#0"}, roleNames())
+}
+
+func Test_Jobs(t *testing.T) {
+	jobNames := func(jobs []*scheduler.Job) []string {
+		names := make([]string, len(jobs))
+		for i, job := range jobs {
+			names[i] = job.Name
+		}
+		return names
+	}
+
+	pg, _, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+	cog := &Cog{db: pg, cfg: &Config{}}
+	assert.Equal(t, []string{partitionsJobName, cleanupJobName}, jobNames(cog.Jobs()))
+
+	// colours_log is not partitioned on SQLite
+	sqlite, err := database.NewDBClient(context.Background(), "sqlite::memory:", time.Minute)
+	assert.NoError(t, err)
+	defer sqlite.Close(context.Background())
+	cog = &Cog{db: sqlite, cfg: &Config{}}
+	assert.Equal(t, []string{cleanupJobName}, jobNames(cog.Jobs()))
+
+	cog = &Cog{db: sqlite, cfg: &Config{RoleCleanup: string(CleanupOff)}}
+	assert.Empty(t, cog.Jobs())
+}
+
+func Test_ReadyCallback_legacyStateUnclaimed(t *testing.T) {
+	srv := discordtest.NewServer(t)
+	srv.AddRole("1", &dgo.Role{Name: "Mods"})
+	srv.AddRole("2", &dgo.Role{Name: "Mods"})
+
+	ctrl := gomock.NewController(t)
+	repo := NewMockIDomainRepository(ctrl)
+	repo.EXPECT().FetchRolesAdopted(Any, "1").Return(true, nil)
+	repo.EXPECT().FetchRolesAdopted(Any, "2").Return(true, nil)
+	// The bot is in several guilds, and none is configured to claim the legacy state
+	repo.EXPECT().FetchLegacyStateClaimed(Any).Return(false, nil)
+
+	cfg := &Config{}
+	cog := &Cog{commands: engine.NewCommandRegistry(), cfg: cfg, repo: repo}
+	cog.domain = NewColoursDomain(cog, repo, cfg)
+
+	sess := srv.NewSession()
+	ready := make(chan struct{})
+	sess.AddHandler(func(s *dgo.Session, r *dgo.Ready) {
+		assert.ErrorIs(t, cog.ReadyCallback(context.Background(), s, r), ErrLegacyStateUnclaimed)
+		close(ready)
+	})
+	srv.Open(sess)
+	<-ready
+
+	// Commands still work while the legacy state is unclaimed
+	assert.Len(t, srv.Commands(), 6)
+}
+
+func Test_claimLegacyState(t *testing.T) {
+	guilds := []*dgo.Guild{{ID: "1"}, {ID: "2"}}
+	testCases := []struct {
+		desc          string
+		legacyGuildID string
+		guilds        []*dgo.Guild
+		alreadyDone   bool
+		expectClaimBy string
+		expectErr     error
+	}{
+		{"configured guild claims", "2", guilds, false, "2", nil},
+		{"only guild claims", "", guilds[:1], false, "1", nil},
+		{"no claim when unsure", "", guilds, false, "", ErrLegacyStateUnclaimed},
+		{"claimed once", "2", guilds, true, "", nil},
+		{"claimed once, even when unsure", "", guilds, true, "", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			repo := NewMockIDomainRepository(gomock.NewController(t))
+			repo.EXPECT().FetchLegacyStateClaimed(Any).Return(tc.alreadyDone, nil)
+			if tc.expectClaimBy != "" {
+				repo.EXPECT().ClaimLegacyState(Any, tc.expectClaimBy).Return(int64(1), nil)
+			}
+			cog := &Cog{cfg: &Config{LegacyGuildID: tc.legacyGuildID}, repo: repo}
+			err := cog.claimLegacyState(context.Background(), tc.guilds)
+			assert.ErrorIs(t, err, tc.expectErr)
+		})
 	}
 }
@@ -11,6 +11,10 @@ import (
 	"github.com/fiffu/arisa3/app/utils"
 )
 
+const (
+	OptionColour = "colour"
+)
+
 func (c *Cog) col(ctx context.Context, req types.ICommandEvent) error {
 	from := req.Interaction().Member
 	if from == nil {
@@ -27,6 +31,10 @@ func (c *Cog) col(ctx context.Context, req types.ICommandEvent) error {
 		return err
 	}
 
+	if input, ok := req.Args().String(OptionColour); ok && input != "" {
+		return c.pick(ctx, req, s, mem, input)
+	}
+
 	// reroll here
 	newColour, err := c.domain.Reroll(ctx, s, mem)
 	if errors.Is(err, ErrRerollCooldownPending) {
@@ -42,12 +50,50 @@ func (c *Cog) col(ctx context.Context, req types.ICommandEvent) error {
 	} else if err != nil {
 		return err
 	}
-	log.Infof(ctx, "Generated colour: #%s", newColour.ToHexcode())
+	log.Infof(ctx, "Generated colour: #%s (%s)", newColour.ToHexcode(), DescribeColour(newColour))
 
 	embed := newEmbed(newColour)
 	return req.Respond(ctx, types.NewResponse().Embeds(embed))
 }
 
+// pick gives the member the colour they asked for.
+func (c *Cog) pick(ctx context.Context, req types.ICommandEvent, s IDomainSession, mem IDomainMember, input string) error {
+	guildID, userID := mem.Guild().ID(), mem.UserID()
+	colour, err := ParseColour(input)
+	if err != nil {
+		msg := fmt.Sprintf("I don't know the colour '%s'. Try a hexcode like #1abc9c, or a name like teal.", input)
+		return req.Respond(ctx, types.NewResponse().Content(msg))
+	}
+
+	newColour, err := c.domain.Pick(ctx, s, mem, colour)
+	var msg string
+	switch {
+	case err == nil:
+		log.Infof(ctx, "Picked colour: #%s (%s), guild=%s user=%s", newColour.ToHexcode(), DescribeColour(newColour), guildID, userID)
+		return req.Respond(ctx, types.NewResponse().Embeds(newEmbed(newColour)))
+
+	case errors.Is(err, ErrPickCooldownPending):
+		log.Infof(ctx, "Blocked pick due to cooldown pending, guild=%s user=%s", guildID, userID)
+		endTime, err := c.domain.GetPickCooldownEndTime(ctx, mem)
+		if err != nil {
+			return err
+		}
+		delta := utils.FormatDuration(time.Until(endTime))
+		msg = fmt.Sprintf("You cannot pick a new colour yet! Cooldown remaining: %s", delta)
+
+	case errors.Is(err, ErrPickDisabled):
+		msg = "Picking colours isn't allowed here, but you can still reroll with /col."
+
+	case errors.Is(err, ErrPickNotAllowed), errors.Is(err, ErrPickDenied):
+		log.Infof(ctx, "Blocked pick of #%s by policy, guild=%s user=%s", colour.ToHexcode(), guildID, userID)
+		msg = fmt.Sprintf("Sorry, #%s isn't a colour you can pick here.", colour.ToHexcode())
+
+	default:
+		return err
+	}
+	return req.Respond(ctx, types.NewResponse().Content(msg))
+}
+
 // setFreeze will freeze or unfreeze a member's colour role.
 func (c *Cog) setFreeze(ctx context.Context, req types.ICommandEvent, toFrozen bool) error {
 	mem, resp, err := c.fetchMember(ctx, req)
@@ -68,7 +114,11 @@ func (c *Cog) setFreeze(ctx context.Context, req types.ICommandEvent, toFrozen b
 		un = "un"
 	}
 
-	role := c.domain.GetColourRole(ctx, mem)
+	role, err := c.domain.GetColourRole(ctx, mem)
+	if err != nil {
+		log.Errorf(ctx, err, "Errored getting colour role, guild=%s user=%s", guildID, userID)
+		return err
+	}
 	if role == nil {
 		// user has no colour role
 		log.Warnf(ctx, "User has no role to %sfreeze, guild=%s user=%s", un, guildID, userID)
@@ -142,6 +192,6 @@ func newEmbed(colour *Colour) types.IEmbed {
 	r, g, b := colour.scale255()
 	hex := colour.ToHexcode()
 	return types.NewEmbed().
-		Titlef("#%s · rgb(%d, %d, %d)", hex, r, g, b).
+		Titlef("#%s · rgb(%d, %d, %d) · %s", hex, r, g, b, DescribeColour(colour)).
 		Colour(colour.ToDecimal())
 }
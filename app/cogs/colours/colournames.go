@@ -0,0 +1,148 @@
+package colours
+
+// colournames.go names colours, using datasets of named colours embedded from colournames/.
+
+import (
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var (
+	//go:embed colournames
+	embeddedColourNames embed.FS
+
+	// colourNameSources are the datasets in colournames/, in order of precedence. Where datasets
+	// give a name different colours, the name refers to the colour in the first dataset.
+	colourNameSources = []string{"arisa", "css", "xkcd"}
+
+	colourNames = mustLoadColourNames(colourNameSources)
+
+	sixDigitHexcode = regexp.MustCompile(`^[0-9a-f]{6}$`)
+)
+
+// exactColourName is the ΔE below which a colour is taken to be the named colour itself.
+const exactColourName = 1.0
+
+// ColourName is a named colour from one of the datasets.
+type ColourName struct {
+	Name   string
+	Source string
+	Colour *Colour
+
+	lab Lab
+}
+
+// namedColours is an index of named colours, for looking them up by name or by colour.
+type namedColours struct {
+	all    []*ColourName
+	byName map[string]*ColourName // by canonical name
+}
+
+func mustLoadColourNames(sources []string) *namedColours {
+	names, err := loadColourNames(sources)
+	if err != nil {
+		// The datasets are embedded, so this is a bug rather than a runtime failure
+		panic(err)
+	}
+	return names
+}
+
+func loadColourNames(sources []string) (*namedColours, error) {
+	names := &namedColours{byName: make(map[string]*ColourName)}
+	for _, source := range sources {
+		if err := names.load(source); err != nil {
+			return nil, fmt.Errorf("colour names from %s: %w", source, err)
+		}
+	}
+	return names, nil
+}
+
+// load adds the named colours from a dataset, leaving names already taken to earlier datasets.
+func (n *namedColours) load(source string) error {
+	file, err := embeddedColourNames.Open("colournames/" + source + ".csv")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, hexcode := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if !sixDigitHexcode.MatchString(hexcode) {
+			return fmt.Errorf("%w: '%s' for %s", ErrInvalidColour, hexcode, name)
+		}
+
+		colour := (&Colour{}).FromRGBHex(hexcode)
+		entry := &ColourName{name, source, colour, colour.ToLab()}
+		n.all = append(n.all, entry)
+		if _, taken := n.byName[canonicalColourName(name)]; !taken {
+			n.byName[canonicalColourName(name)] = entry
+		}
+	}
+}
+
+// canonicalColourName folds case and spacing, so that "Sky Blue", "sky-blue" and "skyblue" are
+// all the same name.
+func canonicalColourName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer("-", "", "_", "", " ", "").Replace(name)
+	return name
+}
+
+// lookup finds a named colour by name.
+func (n *namedColours) lookup(name string) (*ColourName, bool) {
+	entry, ok := n.byName[canonicalColourName(name)]
+	return entry, ok
+}
+
+// nearest finds the named colour that looks most like the colour, and how far it is by ΔE.
+// Earlier datasets win ties.
+func (n *namedColours) nearest(colour *Colour) (*ColourName, float64) {
+	lab := colour.ToLab()
+	var best *ColourName
+	bestDistance := math.Inf(1)
+	for _, entry := range n.all {
+		if distance := DeltaE(lab, entry.lab); distance < bestDistance {
+			best, bestDistance = entry, distance
+		}
+	}
+	return best, bestDistance
+}
+
+// LookupColourName finds a named colour by name.
+func LookupColourName(name string) (*Colour, bool) {
+	entry, ok := colourNames.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return entry.Colour, true
+}
+
+// NearestColourName finds the named colour that looks most like the colour, and how far it is by
+// ΔE.
+func NearestColourName(colour *Colour) (*ColourName, float64) {
+	return colourNames.nearest(colour)
+}
+
+// DescribeColour names the colour, or the named colour nearest to it, like "teal" or "≈ teal".
+func DescribeColour(colour *Colour) string {
+	entry, distance := NearestColourName(colour)
+	if distance < exactColourName {
+		return entry.Name
+	}
+	return "≈ " + entry.Name
+}
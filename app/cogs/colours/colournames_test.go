@@ -0,0 +1,66 @@
+package colours
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_loadColourNames(t *testing.T) {
+	names, err := loadColourNames(colourNameSources)
+	assert.NoError(t, err)
+	assert.Len(t, names.all, 240)
+
+	// Earlier datasets take precedence for names that several datasets have
+	teal, ok := names.lookup("teal")
+	assert.True(t, ok)
+	assert.Equal(t, "arisa", teal.Source)
+	assert.Equal(t, "1abc9c", teal.Colour.ToHexcode())
+
+	purple, ok := names.lookup("Purple")
+	assert.True(t, ok)
+	assert.Equal(t, "9b59b6", purple.Colour.ToHexcode())
+
+	rebecca, ok := names.lookup("rebecca purple")
+	assert.True(t, ok)
+	assert.Equal(t, "css", rebecca.Source)
+
+	_, err = loadColourNames([]string{"missing"})
+	assert.Error(t, err)
+}
+
+func Test_NearestColourName(t *testing.T) {
+	testCases := []struct {
+		hexcode        string
+		expectName     string
+		expectDescribe string
+	}{
+		{"1abc9c", "teal", "teal"},
+		{"22c4a0", "teal", "≈ teal"},
+		{"663399", "rebeccapurple", "rebeccapurple"},
+		{"000001", "black", "black"},
+		{"fefefe", "white", "white"},
+		{"ff0000", "red", "red"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.hexcode, func(t *testing.T) {
+			colour := (&Colour{}).FromRGBHex(tc.hexcode)
+			entry, _ := NearestColourName(colour)
+			assert.Equal(t, tc.expectName, entry.Name)
+			assert.Equal(t, tc.expectDescribe, DescribeColour(colour))
+		})
+	}
+}
+
+func Test_LookupColourName(t *testing.T) {
+	colour, ok := LookupColourName("Cornflower Blue")
+	assert.True(t, ok)
+	assert.Equal(t, "6495ed", colour.ToHexcode())
+
+	colour, ok = LookupColourName("olive green")
+	assert.True(t, ok)
+	assert.Equal(t, "677a04", colour.ToHexcode())
+
+	_, ok = LookupColourName("not a colour")
+	assert.False(t, ok)
+}
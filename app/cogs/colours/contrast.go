@@ -0,0 +1,121 @@
+package colours
+
+// contrast.go measures how readable a colour is as a name on Discord, by WCAG contrast ratio.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrMinContrast = errors.New("invalid minimum contrast")
+
+	// Backgrounds of Discord's dark and light themes, which names are shown on.
+	darkThemeBackground  = (&Colour{}).FromRGBHex("313338")
+	lightThemeBackground = (&Colour{}).FromRGBHex("ffffff")
+
+	// maxMinContrast is the best contrast that any colour has against both themes. Stricter
+	// minimums can't be met, such as WCAG's 4.5 for text.
+	maxMinContrast = math.Sqrt((1 + 0.05) / (darkThemeBackground.luminance() + 0.05))
+)
+
+// How many colours to draw while looking for a readable one, before correcting the last.
+const readableAttempts = 10
+
+// Contrast is a colour's WCAG contrast ratio against each theme's background, from 1 (none) to
+// 21 (black on white).
+type Contrast struct {
+	Dark, Light float64
+}
+
+// Min is the contrast on the theme where the colour is least readable.
+func (c Contrast) Min() float64 {
+	return math.Min(c.Dark, c.Light)
+}
+
+// Contrast measures the colour's contrast on each theme.
+func (c *Colour) Contrast() Contrast {
+	return Contrast{
+		Dark:  contrastRatio(c, darkThemeBackground),
+		Light: contrastRatio(c, lightThemeBackground),
+	}
+}
+
+// luminance is the colour's relative luminance, as WCAG defines it.
+func (c *Colour) luminance() float64 {
+	return 0.2126*linearise(c.R) + 0.7152*linearise(c.G) + 0.0722*linearise(c.B)
+}
+
+func contrastRatio(x, y *Colour) float64 {
+	lx, ly := x.luminance(), y.luminance()
+	if lx < ly {
+		lx, ly = ly, lx
+	}
+	return (lx + 0.05) / (ly + 0.05)
+}
+
+func validateMinContrast(min float64) error {
+	if min < 0 || min > maxMinContrast {
+		return fmt.Errorf("%w: %.2f, wanted at most %.2f, or 0 to allow any colour", ErrMinContrast, min, maxMinContrast)
+	}
+	return nil
+}
+
+// Readable returns the colour if it has at least the minimum contrast on both themes. Otherwise,
+// it returns the colour lightened or darkened just enough to have it, keeping its hue.
+func (c *Colour) Readable(min float64) *Colour {
+	if c.Contrast().Min() >= min {
+		return c
+	}
+	lowest, highest := readableLuminances(min)
+	reached := func(colour *Colour) bool { return colour.luminance() >= lowest }
+	towards := &Colour{1, 1, 1}
+	if c.luminance() >= lowest {
+		reached = func(colour *Colour) bool { return colour.luminance() <= highest }
+		towards = &Colour{0, 0, 0}
+	}
+
+	// Luminance changes monotonically as the colour is mixed towards white or black, so search for
+	// the least mix that is readable. Role colours are rounded to 8 bits per channel, so the result
+	// is checked after rounding.
+	lo, hi := 0.0, 1.0
+	for i := 0; i < 32; i++ {
+		mid := (lo + hi) / 2
+		if reached(c.mix(towards, mid).rounded()) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return c.mix(towards, hi).rounded()
+}
+
+// readableLuminances are the least and greatest luminances that have the minimum contrast against
+// both backgrounds.
+func readableLuminances(min float64) (lowest, highest float64) {
+	lowest = min*(darkThemeBackground.luminance()+0.05) - 0.05
+	highest = (lightThemeBackground.luminance()+0.05)/min - 0.05
+	return lowest, highest
+}
+
+// rounded is the colour as Discord stores it, with 8 bits per channel.
+func (c *Colour) rounded() *Colour {
+	return (&Colour{}).FromDecimal(c.ToDecimal())
+}
+
+// mix blends the colour with another, by the given weight of the other.
+func (c *Colour) mix(other *Colour, weight float64) *Colour {
+	blend := func(x, y float64) float64 { return x + (y-x)*weight }
+	return &Colour{blend(c.R, other.R), blend(c.G, other.G), blend(c.B, other.B)}
+}
+
+// readableColour draws colours from the palette until one has the minimum contrast, and corrects
+// the last one within the palette if none of them do.
+func readableColour(min float64, palette *Palette) *Colour {
+	colour := palette.Random()
+	for i := 1; i < readableAttempts && colour.Contrast().Min() < min; i++ {
+		colour = palette.Random()
+	}
+	return palette.Readable(colour, min)
+}
@@ -0,0 +1,106 @@
+package colours
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_contrastRatio(t *testing.T) {
+	black, white := &Colour{0, 0, 0}, &Colour{1, 1, 1}
+	assert.InDelta(t, 21, contrastRatio(black, white), 0.001)
+	assert.InDelta(t, 21, contrastRatio(white, black), 0.001)
+	assert.InDelta(t, 1, contrastRatio(white, white), 0.001)
+
+	// Examples from WebAIM's contrast checker
+	assert.InDelta(t, 4.54, contrastRatio((&Colour{}).FromRGBHex("767676"), white), 0.01)
+	assert.InDelta(t, 3.99, contrastRatio((&Colour{}).FromRGBHex("ff0000"), white), 0.01)
+}
+
+func Test_Contrast(t *testing.T) {
+	contrast := (&Colour{}).FromRGBHex("ffffff").Contrast()
+	assert.InDelta(t, 1, contrast.Light, 0.001)
+	assert.Greater(t, contrast.Dark, 10.0)
+	assert.Equal(t, contrast.Light, contrast.Min())
+
+	assert.InDelta(t, 3.55, maxMinContrast, 0.01)
+}
+
+func Test_Readable(t *testing.T) {
+	const min = 2.5
+	testCases := []struct {
+		hexcode         string
+		expectLuminance string // whether it gets "lighter", "darker" or stays the "same"
+	}{
+		{"000000", "lighter"},
+		{"1a1a40", "lighter"},
+		{"ffffff", "darker"},
+		{"fff44f", "darker"},
+		{"3498db", "same"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.hexcode, func(t *testing.T) {
+			colour := (&Colour{}).FromRGBHex(tc.hexcode)
+			readable := colour.Readable(min)
+			assert.GreaterOrEqual(t, readable.rounded().Contrast().Min(), min)
+
+			switch tc.expectLuminance {
+			case "lighter":
+				assert.Greater(t, readable.luminance(), colour.luminance())
+			case "darker":
+				assert.Less(t, readable.luminance(), colour.luminance())
+			case "same":
+				assert.Equal(t, colour, readable)
+			}
+		})
+	}
+
+	// Hues are kept when darkening
+	yellow := (&Colour{}).FromRGBHex("fff44f").Readable(min)
+	assert.Greater(t, yellow.R, yellow.B)
+	assert.Greater(t, yellow.G, yellow.B)
+}
+
+func Test_readableColour(t *testing.T) {
+	rand.Seed(1)
+	for _, spec := range []string{"", "earth", "pastel, #1abc9c"} {
+		t.Run(spec, func(t *testing.T) {
+			var palette *Palette
+			if spec != "" {
+				var err error
+				palette, err = ParsePalette(spec)
+				assert.NoError(t, err)
+			}
+			for i := 0; i < 50; i++ {
+				colour := readableColour(2.5, palette)
+				assert.GreaterOrEqual(t, colour.Contrast().Min(), 2.5)
+				assert.True(t, palette.Contains(colour), "%s is outside the palette", colour)
+			}
+		})
+	}
+
+	// A minimum of zero accepts anything from the palette
+	dark, err := ParsePalette("hsv(0-360, 0-100, 0-5)")
+	assert.NoError(t, err)
+	colour := readableColour(0, dark)
+	assert.True(t, dark.Contains(colour))
+	assert.Less(t, colour.Contrast().Min(), 2.0)
+}
+
+func Test_validateMinContrast(t *testing.T) {
+	assert.NoError(t, validateMinContrast(0))
+	assert.NoError(t, validateMinContrast(3))
+	assert.ErrorIs(t, validateMinContrast(4.5), ErrMinContrast)
+	assert.ErrorIs(t, validateMinContrast(-1), ErrMinContrast)
+}
+
+func Test_Config_minContrast(t *testing.T) {
+	cfg := &Config{MinContrast: 2, GuildMinContrast: map[string]float64{"1": 0}}
+	assert.NoError(t, cfg.validate())
+	assert.Equal(t, 0.0, cfg.minContrast("1"))
+	assert.Equal(t, 2.0, cfg.minContrast("2"))
+
+	cfg.GuildMinContrast["3"] = 4.5
+	assert.ErrorIs(t, cfg.validate(), ErrMinContrast)
+}
@@ -0,0 +1,13 @@
+-- SQLite has no table partitioning, see the Postgres migration for the partitioned table
+CREATE TABLE "colours_log" (
+    userid   TEXT,
+    username TEXT,
+    colour   TEXT,
+    reason   TEXT,  -- 'mutate' or 'reroll' or 'frozen'
+    tstamp   TIMESTAMP NOT NULL
+);
+
+-- 2-year sliding window view of the table
+CREATE VIEW "colours_logview" AS
+    SELECT * FROM "colours_log"
+    WHERE tstamp > datetime('now', '-2 years');
@@ -0,0 +1,2 @@
+-- There are no partitions to create on SQLite, but the index still helps the sliding window view
+CREATE INDEX "colours_log_tstamp_idx" ON colours_log (tstamp);
@@ -0,0 +1,35 @@
+-- Archived partitions can't be put back from here, so the rollback fails rather than drop them
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM "colours_log_archive") THEN
+        RAISE EXCEPTION 'colours_log_archive has archived partitions, restore or drop them first';
+    END IF;
+END $$;
+DROP TABLE "colours_log_archive";
+
+-- colours_log_future takes back the default partition's rows, and those of any partitions the
+-- partitions job created from 2031 on. Rows that no partition covered before this migration
+-- have nowhere to go, and fail the rollback.
+DO $$
+DECLARE
+    part   TEXT;
+    folded TEXT[] := ARRAY['colours_log_default'];
+BEGIN
+    FOR part IN
+        SELECT c.relname
+        FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+        WHERE i.inhparent = 'colours_log'::regclass
+        AND substring(pg_get_expr(c.relpartbound, c.oid) FROM 'FROM \(''([^'']+)''\)')::timestamp >= '2031-01-01'
+    LOOP
+        EXECUTE format('ALTER TABLE colours_log DETACH PARTITION %I', part);
+        folded := folded || part;
+    END LOOP;
+
+    ALTER TABLE colours_log DETACH PARTITION "colours_log_default";
+    CREATE TABLE "colours_log_future" PARTITION OF colours_log FOR VALUES FROM ('2031-01-01') TO ('2222-01-01');
+
+    FOREACH part IN ARRAY folded LOOP
+        EXECUTE format('INSERT INTO colours_log SELECT * FROM %I', part);
+        EXECUTE format('DROP TABLE %I', part);
+    END LOOP;
+END $$;
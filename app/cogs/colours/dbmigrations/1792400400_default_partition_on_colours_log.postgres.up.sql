@@ -0,0 +1,18 @@
+-- colours_log_future spans 2031 to 2222, which leaves the partitions job no room to create yearly
+-- or monthly partitions after 2030. A default partition takes over as the catch-all instead, and
+-- the job moves rows out of it whenever it creates a partition that they belong to.
+CREATE TABLE "colours_log_default" PARTITION OF colours_log DEFAULT;
+
+ALTER TABLE colours_log DETACH PARTITION "colours_log_future";
+INSERT INTO colours_log SELECT * FROM "colours_log_future";
+DROP TABLE "colours_log_future";
+
+-- Partitions past the retention window are detached and kept here as gzipped CSV
+CREATE TABLE "colours_log_archive" (
+    partition   TEXT PRIMARY KEY,
+    range_from  TIMESTAMP NOT NULL,
+    range_to    TIMESTAMP NOT NULL,
+    row_count   BIGINT NOT NULL,
+    rows_csv_gz BYTEA NOT NULL,
+    archived_at TIMESTAMP NOT NULL
+);
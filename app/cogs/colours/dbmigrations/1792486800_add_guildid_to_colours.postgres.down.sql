@@ -0,0 +1,13 @@
+-- Rows are kept, but those of different guilds can no longer be told apart. The view selects
+-- guildid, so it has to be dropped before the column can be.
+DROP VIEW "colours_logview";
+
+DROP INDEX "colours_guildid_userid_idx";
+DROP INDEX "colours_log_guildid_userid_idx";
+
+ALTER TABLE "colours"     DROP COLUMN guildid;
+ALTER TABLE "colours_log" DROP COLUMN guildid;
+
+CREATE VIEW "colours_logview" AS
+    SELECT * FROM "colours_log"
+    WHERE tstamp > current_timestamp - INTERVAL '2 years';
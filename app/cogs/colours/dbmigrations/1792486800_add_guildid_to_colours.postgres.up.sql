@@ -0,0 +1,12 @@
+-- Colour state and history are kept per guild. Rows from before this are left with an empty
+-- guildid, and are claimed by a guild when the bot starts up (see the legacy_guild_id config).
+ALTER TABLE "colours"     ADD COLUMN guildid TEXT NOT NULL DEFAULT '';
+ALTER TABLE "colours_log" ADD COLUMN guildid TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX ON colours (guildid, userid);
+CREATE INDEX ON colours_log (guildid, userid);
+
+-- The view's columns were fixed when it was created, so it has to be redefined to pick up guildid
+CREATE OR REPLACE VIEW "colours_logview" AS
+    SELECT * FROM "colours_log"
+    WHERE tstamp > current_timestamp - INTERVAL '2 years';
@@ -0,0 +1,13 @@
+-- Rows are kept, but those of different guilds can no longer be told apart. SQLite won't drop a
+-- column that is indexed or used by a view, so those go first.
+DROP VIEW "colours_logview";
+
+DROP INDEX "colours_guildid_userid_idx";
+DROP INDEX "colours_log_guildid_userid_idx";
+
+ALTER TABLE "colours"     DROP COLUMN guildid;
+ALTER TABLE "colours_log" DROP COLUMN guildid;
+
+CREATE VIEW "colours_logview" AS
+    SELECT * FROM "colours_log"
+    WHERE tstamp > datetime('now', '-2 years');
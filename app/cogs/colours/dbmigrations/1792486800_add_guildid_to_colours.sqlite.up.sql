@@ -0,0 +1,12 @@
+-- Colour state and history are kept per guild. Rows from before this are left with an empty
+-- guildid, and are claimed by a guild when the bot starts up (see the legacy_guild_id config).
+ALTER TABLE "colours"     ADD COLUMN guildid TEXT NOT NULL DEFAULT '';
+ALTER TABLE "colours_log" ADD COLUMN guildid TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX "colours_guildid_userid_idx" ON colours (guildid, userid);
+CREATE INDEX "colours_log_guildid_userid_idx" ON colours_log (guildid, userid);
+
+DROP VIEW "colours_logview";
+CREATE VIEW "colours_logview" AS
+    SELECT * FROM "colours_log"
+    WHERE tstamp > datetime('now', '-2 years');
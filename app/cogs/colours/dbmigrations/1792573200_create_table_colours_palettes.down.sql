@@ -0,0 +1 @@
+DROP TABLE "colours_palettes";
@@ -0,0 +1,6 @@
+-- Each guild's palette, as written for ParsePalette. Guilds without a row allow any colour.
+CREATE TABLE "colours_palettes" (
+    guildid TEXT PRIMARY KEY,
+    palette TEXT NOT NULL,
+    tstamp  TIMESTAMP NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE "colours_roles_adoptions";
+DROP TABLE "colours_roles";
@@ -0,0 +1,16 @@
+-- Each member's colour role in each guild. Roles are tracked by ID, as their names follow members'.
+CREATE TABLE "colours_roles" (
+    guildid TEXT NOT NULL,
+    userid  TEXT NOT NULL,
+    roleid  TEXT NOT NULL UNIQUE,
+    tstamp  TIMESTAMP NOT NULL,
+    PRIMARY KEY (guildid, userid)
+);
+
+-- Guilds whose colour roles from before they were tracked have been adopted, by name. Each guild
+-- is adopted once, the first time the bot starts up in it with this table.
+CREATE TABLE "colours_roles_adoptions" (
+    guildid  TEXT PRIMARY KEY,
+    adopted  INTEGER NOT NULL,
+    tstamp   TIMESTAMP NOT NULL
+);
@@ -0,0 +1,13 @@
+-- The guild that claimed colour state recorded before it was kept per guild. Legacy state is
+-- claimed once, so this has at most one row. Databases with no legacy state left have nothing to
+-- claim, which is recorded here with an empty guildid.
+CREATE TABLE "colours_legacy_claims" (
+    guildid  TEXT PRIMARY KEY,
+    claimed  INTEGER NOT NULL,
+    tstamp   TIMESTAMP NOT NULL
+);
+
+INSERT INTO "colours_legacy_claims"(guildid, claimed, tstamp)
+    SELECT '', 0, CURRENT_TIMESTAMP
+    WHERE NOT EXISTS (SELECT 1 FROM "colours" WHERE guildid = '')
+    AND NOT EXISTS (SELECT 1 FROM "colours_log" WHERE guildid = '');
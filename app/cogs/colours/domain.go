@@ -3,7 +3,8 @@ package colours
 import (
 	"context"
 	"errors"
-	"regexp"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/fiffu/arisa3/app/log"
@@ -17,9 +18,8 @@ var (
 	ErrMutateFrozen          = errors.New("colour is frozen")
 	ErrMutateCooldownPending = errors.New("mutate cooldown is still in progress")
 	ErrRerollCooldownPending = errors.New("reroll cooldown is still in progress")
+	ErrPickCooldownPending   = errors.New("pick cooldown is still in progress")
 	ErrInvalidRoleHeight     = errors.New("invalid target role height, it should be >=0")
-
-	rolePattern = regexp.MustCompile(`\w+#(0|\d{4})`)
 )
 
 type domain struct {
@@ -27,11 +27,19 @@ type domain struct {
 	cog               types.ICog
 	repo              IDomainRepository
 	maxHeightRoleName string
-	maxRoleHeight     int
+	maxRoleHeights    roleHeights
+	roleName          *template.Template
 
 	mutateCooldownMins int
 	rerollCooldownMins int
 	rerollPenaltyMins  int
+	pickCooldownMins   int
+
+	pickPolicy  func(guildID string) PickPolicy
+	minContrast func(guildID string) float64
+	mutator     func(guildID string) Mutator
+
+	swapCooldown SwapCooldownPolicy
 }
 
 // NewColoursDomain implements IColoursDomain
@@ -41,11 +49,18 @@ func NewColoursDomain(c types.ICog, repo IDomainRepository, cfg *Config) IColour
 		cog:               c,
 		repo:              repo,
 		maxHeightRoleName: cfg.MaxRoleHeightName,
-		maxRoleHeight:     -1,
+		roleName:          cfg.roleNameTemplate(),
 
 		mutateCooldownMins: cfg.MutateCooldownMins,
 		rerollCooldownMins: cfg.RerollCooldownMins,
 		rerollPenaltyMins:  cfg.RerollPenaltyMins,
+		pickCooldownMins:   cfg.PickCooldownMins,
+
+		pickPolicy:  cfg.pickPolicy,
+		minContrast: cfg.minContrast,
+		mutator:     cfg.mutator,
+
+		swapCooldown: cfg.swapCooldownPolicy(),
 	}
 }
 
@@ -71,6 +86,15 @@ func (d *domain) GetLastReroll(ctx context.Context, mem IDomainMember) (time.Tim
 	return last, d.hasCooldownFinished(last, cooldownPeriod), nil
 }
 
+func (d *domain) GetLastPick(ctx context.Context, mem IDomainMember) (time.Time, bool, error) {
+	last, err := d.repo.FetchUserState(ctx, mem, Pick)
+	if err != nil {
+		return last, false, err
+	}
+	cooldownPeriod := time.Duration(d.pickCooldownMins) * time.Minute
+	return last, d.hasCooldownFinished(last, cooldownPeriod), nil
+}
+
 func (d *domain) hasCooldownFinished(cooldownStartTime time.Time, cooldownPeriod time.Duration) bool {
 	if cooldownStartTime == Never {
 		return true
@@ -97,6 +121,15 @@ func (d *domain) GetRerollCooldownEndTime(ctx context.Context, mem IDomainMember
 	return endTime, nil
 }
 
+func (d *domain) GetPickCooldownEndTime(ctx context.Context, mem IDomainMember) (time.Time, error) {
+	last, _, err := d.GetLastPick(ctx, mem)
+	if err != nil {
+		return time.Time{}, err
+	}
+	cooldownPeriod := time.Duration(d.pickCooldownMins) * time.Minute
+	return d.offsetTime(last, cooldownPeriod), nil
+}
+
 func (d *domain) GetHistory(ctx context.Context, mem IDomainMember) (*History, error) {
 	endTime := d.now()
 	startTime := endTime.Add(-14 * 24 * time.Hour)
@@ -115,9 +148,9 @@ func (d *domain) GetHistory(ctx context.Context, mem IDomainMember) (*History, e
 
 func (d *domain) Mutate(ctx context.Context, s IDomainSession, mem IDomainMember) (*Colour, error) {
 	// No role, no mutate
-	role := d.GetColourRole(ctx, mem)
-	if role == nil {
-		return nil, nil
+	role, err := d.GetColourRole(ctx, mem)
+	if err != nil || role == nil {
+		return nil, err
 	}
 
 	// Check frozen
@@ -134,23 +167,36 @@ func (d *domain) Mutate(ctx context.Context, s IDomainSession, mem IDomainMember
 		return nil, ErrMutateCooldownPending
 	}
 
-	// Generate new colour, apply cooldown
-	newColour := role.Colour().Nudge()
-	if err := d.repo.UpdateMutate(ctx, mem, newColour); err != nil {
-		return newColour, err
+	palette, err := d.GetPalette(ctx, mem.Guild())
+	if err != nil {
+		return nil, err
 	}
 
-	// API call
-	if err := s.GuildRoleEdit(
-		ctx,
-		mem.Guild().ID(),
-		role.ID(),
-		role.Name(),
-		newColour.ToDecimal(),
-	); err != nil {
+	// Recolour the role first, so that the cooldown only applies if the API call succeeds
+	oldColour := role.Colour()
+	guildID := mem.Guild().ID()
+	newColour := palette.Readable(palette.Nudge(oldColour, d.mutator(guildID)), d.minContrast(guildID))
+	uow := &unitOfWork{}
+	if err := d.recolourRole(ctx, s, uow, mem, role, oldColour, newColour); err != nil {
 		return newColour, err
 	}
-	return newColour, nil
+
+	// Apply cooldown
+	return newColour, uow.commit(ctx, func(ctx context.Context) error {
+		return d.repo.UpdateMutate(ctx, mem, newColour)
+	})
+}
+
+// recolourRole edits the role's colour, and registers a compensation on uow to change it back.
+func (d *domain) recolourRole(ctx context.Context, s IDomainSession, uow *unitOfWork, mem IDomainMember, role IDomainRole, oldColour, newColour *Colour) error {
+	guildID := mem.Guild().ID()
+	if err := s.GuildRoleEdit(ctx, guildID, role.ID(), role.Name(), newColour.ToDecimal()); err != nil {
+		return err
+	}
+	uow.onAbort("restoring role colour", func(ctx context.Context) error {
+		return s.GuildRoleEdit(ctx, guildID, role.ID(), role.Name(), oldColour.ToDecimal())
+	})
+	return nil
 }
 
 func (d *domain) Reroll(ctx context.Context, s IDomainSession, mem IDomainMember) (*Colour, error) {
@@ -177,35 +223,312 @@ func (d *domain) Reroll(ctx context.Context, s IDomainSession, mem IDomainMember
 		return nil, ErrRerollCooldownPending
 	}
 
-	// Generate new colour, apply cooldown
-	newColour := (&Colour{}).Random()
-	if err := d.repo.UpdateReroll(ctx, mem, newColour); err != nil {
-		return newColour, err
+	palette, err := d.GetPalette(ctx, mem.Guild())
+	if err != nil {
+		return nil, err
 	}
 
-	// Edit existing role or assign a new role
-	if d.HasColourRole(ctx, mem) {
-		role := d.GetColourRole(ctx, mem)
-		err = s.GuildRoleEdit(
-			ctx,
-			mem.Guild().ID(),
-			role.ID(),
-			role.Name(),
-			newColour.ToDecimal(),
-		)
+	// Edit existing role or assign a new role, before applying the cooldown
+	newColour := readableColour(d.minContrast(mem.Guild().ID()), palette)
+	uow := &unitOfWork{}
+	if err := d.applyColour(ctx, s, uow, mem, newColour); err != nil {
 		return newColour, err
+	}
 
-	} else {
-		role, err := d.CreateColourRole(ctx, s, mem, newColour)
-		if err != nil {
-			return newColour, err
+	// Apply cooldown
+	return newColour, uow.commit(ctx, func(ctx context.Context) error {
+		return d.repo.UpdateReroll(ctx, mem, newColour)
+	})
+}
+
+// Pick has a cooldown of its own, but unlike Reroll, there is no penalty for trying too soon.
+func (d *domain) Pick(ctx context.Context, s IDomainSession, mem IDomainMember, colour *Colour) (*Colour, error) {
+	if err := d.pickPolicy(mem.Guild().ID()).check(colour); err != nil {
+		return nil, err
+	}
+
+	// Check cooldown
+	if _, isCooldownDone, err := d.GetLastPick(ctx, mem); err != nil {
+		return nil, err
+	} else if !isCooldownDone {
+		return nil, ErrPickCooldownPending
+	}
+
+	// Edit existing role or assign a new role, before applying the cooldown
+	uow := &unitOfWork{}
+	if err := d.applyColour(ctx, s, uow, mem, colour); err != nil {
+		return colour, err
+	}
+
+	// Apply cooldown
+	return colour, uow.commit(ctx, func(ctx context.Context) error {
+		return d.repo.UpdatePick(ctx, mem, colour)
+	})
+}
+
+// applyColour recolours the member's colour role, or creates and assigns one with the colour if
+// they have none. Compensations are registered on uow to undo it.
+func (d *domain) applyColour(ctx context.Context, s IDomainSession, uow *unitOfWork, mem IDomainMember, colour *Colour) error {
+	guildID := mem.Guild().ID()
+	roleID, role, err := d.storedColourRole(ctx, mem)
+	if err != nil {
+		return err
+	}
+	if role == nil && roleID != "" {
+		// The member lost their colour role, so give it back if it's still around
+		if role, err = s.GuildRole(ctx, guildID, roleID); err != nil {
+			return err
 		}
-		err = d.AssignColourRole(ctx, s, mem, role)
-		if err != nil {
-			return newColour, err
+		if role != nil {
+			if err := d.AssignColourRole(ctx, s, mem, role); err != nil {
+				return err
+			}
+			uow.onAbort("unassigning role", func(ctx context.Context) error {
+				return s.GuildMemberRoleRemove(ctx, guildID, mem.UserID(), roleID)
+			})
+		}
+	}
+	if role != nil {
+		if err := d.recolourRole(ctx, s, uow, mem, role, role.Colour(), colour); err != nil {
+			return uow.abort(ctx, err)
+		}
+		return nil
+	}
+
+	role, err = d.CreateColourRole(ctx, s, mem, colour)
+	if err != nil {
+		return err
+	}
+	uow.onAbort("deleting role", func(ctx context.Context) error {
+		return s.GuildRoleDelete(ctx, guildID, role.ID())
+	})
+	if err := d.AssignColourRole(ctx, s, mem, role); err != nil {
+		return uow.abort(ctx, err)
+	}
+	if err := d.repo.UpdateColourRole(ctx, mem, role.ID()); err != nil {
+		return uow.abort(ctx, err)
+	}
+	uow.onAbort("forgetting role", func(ctx context.Context) error {
+		return d.repo.DeleteColourRole(ctx, guildID, role.ID())
+	})
+	return nil
+}
+
+func (d *domain) Swap(ctx context.Context, s IDomainSession, from, to IDomainMember) (*ColourSwap, error) {
+	if from.UserID() == to.UserID() {
+		return nil, ErrSwapSelf
+	}
+	fromRole, err := d.GetColourRole(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toRole, err := d.GetColourRole(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if fromRole == nil || toRole == nil {
+		return nil, ErrSwapNoColourRole
+	}
+	swap := &ColourSwap{
+		Members:  [2]IDomainMember{from, to},
+		Colours:  [2]*Colour{toRole.Colour(), fromRole.Colour()},
+		Cooldown: d.swapCooldown,
+	}
+
+	// Recolour both roles before recording the swap. If the second edit fails, the first is undone.
+	uow := &unitOfWork{}
+	if err := d.recolourRole(ctx, s, uow, from, fromRole, fromRole.Colour(), swap.Colours[0]); err != nil {
+		return nil, err
+	}
+	if err := d.recolourRole(ctx, s, uow, to, toRole, toRole.Colour(), swap.Colours[1]); err != nil {
+		return nil, uow.abort(ctx, err)
+	}
+	if err := uow.commit(ctx, func(ctx context.Context) error {
+		return d.repo.UpdateSwap(ctx, swap)
+	}); err != nil {
+		return nil, err
+	}
+	return swap, nil
+}
+
+func (d *domain) GetPalette(ctx context.Context, guild IDomainGuild) (*Palette, error) {
+	return d.repo.FetchPalette(ctx, guild.ID())
+}
+
+// SetPalette refuses palettes with entries that can't meet the guild's minimum contrast, since
+// their colours would have to leave the palette to be readable.
+func (d *domain) SetPalette(ctx context.Context, guild IDomainGuild, palette *Palette) error {
+	if err := palette.CheckContrast(d.minContrast(guild.ID())); err != nil {
+		return err
+	}
+	return d.repo.UpdatePalette(ctx, guild.ID(), palette)
+}
+
+// GetGallery lists the guild's colour roles, in no particular order. Roles whose members have
+// left are listed until they are cleaned up.
+func (d *domain) GetGallery(ctx context.Context, s IDomainSession, guild IDomainGuild) ([]*GalleryEntry, error) {
+	roles, err := s.GuildRoles(ctx, guild.ID())
+	if err != nil {
+		return nil, err
+	}
+	owners, err := d.colourRoleOwners(ctx, guild)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := d.repo.FetchLastChanges(ctx, guild.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*GalleryEntry, 0)
+	for _, role := range roles {
+		userID, ok := owners[role.ID()]
+		if !ok {
+			continue
+		}
+		lastChange, ok := changes[userID]
+		if !ok {
+			lastChange = Never
 		}
-		return newColour, nil
+		entries = append(entries, &GalleryEntry{
+			Name:       role.Name(),
+			Colour:     role.Colour(),
+			LastChange: lastChange,
+		})
 	}
+	return entries, nil
+}
+
+// colourRoleOwners returns the user IDs of the guild's colour role members, by role ID.
+func (d *domain) colourRoleOwners(ctx context.Context, guild IDomainGuild) (map[string]string, error) {
+	stored, err := d.repo.FetchColourRoles(ctx, guild.ID())
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string]string, len(stored))
+	for userID, roleID := range stored {
+		owners[roleID] = userID
+	}
+	return owners, nil
+}
+
+// CleanupColourRoles deletes colour roles whose members no longer have them or have left, and
+// renames those whose members' names changed. Roles named as colour roles were before they were
+// tracked, but that nobody holds, are only deleted if asked for. Roles that fail to be deleted or
+// renamed are reported rather than stopping the cleanup.
+func (d *domain) CleanupColourRoles(ctx context.Context, s IDomainSession, guild IDomainGuild, opts CleanupOptions) (*CleanupReport, error) {
+	roles, err := s.GuildRoles(ctx, guild.ID())
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.GuildMembers(ctx, guild.ID())
+	if err != nil {
+		return nil, err
+	}
+	owners, err := d.colourRoleOwners(ctx, guild)
+	if err != nil {
+		return nil, err
+	}
+	membersByID := make(map[string]IDomainMember, len(members))
+	held := make(map[string]bool)
+	for _, mem := range members {
+		membersByID[mem.UserID()] = mem
+		for _, role := range mem.Roles() {
+			held[role.ID()] = true
+		}
+	}
+
+	report := &CleanupReport{DryRun: opts.DryRun}
+	remove := func(role IDomainRole, tracked bool) {
+		if !opts.DryRun {
+			if err := s.GuildRoleDelete(ctx, guild.ID(), role.ID()); err != nil {
+				log.Errorf(ctx, err, "Errored deleting colour role, guild=%s role=%s", guild.ID(), role.ID())
+				report.Failed = append(report.Failed, role.Name())
+				return
+			}
+			if tracked {
+				d.forgetColourRole(ctx, guild, role.ID())
+			}
+		}
+		report.Deleted = append(report.Deleted, role.Name())
+	}
+
+	exists := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		exists[role.ID()] = true
+		userID, tracked := owners[role.ID()]
+		if !tracked {
+			if opts.Untracked && legacyRolePattern.MatchString(role.Name()) && !held[role.ID()] {
+				remove(role, false)
+			}
+			continue
+		}
+
+		mem, ok := membersByID[userID]
+		if !ok || !hasRole(mem, role.ID()) {
+			remove(role, true)
+			continue
+		}
+		name := d.GetColourRoleName(ctx, mem)
+		if name == role.Name() {
+			continue
+		}
+		if !opts.DryRun {
+			if err := s.GuildRoleEdit(ctx, guild.ID(), role.ID(), name, role.Colour().ToDecimal()); err != nil {
+				log.Errorf(ctx, err, "Errored renaming colour role, guild=%s role=%s", guild.ID(), role.ID())
+				report.Failed = append(report.Failed, role.Name())
+				continue
+			}
+		}
+		report.Renamed = append(report.Renamed, RoleRename{From: role.Name(), To: name})
+	}
+
+	// Roles deleted while the bot was away are still recorded
+	if !opts.DryRun {
+		for roleID := range owners {
+			if !exists[roleID] {
+				d.forgetColourRole(ctx, guild, roleID)
+			}
+		}
+	}
+	return report, nil
+}
+
+// forgetColourRole stops tracking a role that was deleted. Failures are only logged, since the role
+// is gone either way, and members whose role is missing are given a new one.
+func (d *domain) forgetColourRole(ctx context.Context, guild IDomainGuild, roleID string) {
+	if err := d.repo.DeleteColourRole(ctx, guild.ID(), roleID); err != nil {
+		log.Errorf(ctx, err, "Errored forgetting colour role, guild=%s role=%s", guild.ID(), roleID)
+	}
+}
+
+// AdoptColourRoles starts tracking the colour roles that members were given before roles were
+// tracked by ID, which were named after their username and discriminator. Each guild is adopted
+// once; later calls adopt nothing. Only roles named exactly after the member who has them are
+// adopted, so that roles that merely look like colour roles aren't recoloured or renamed.
+func (d *domain) AdoptColourRoles(ctx context.Context, s IDomainSession, guild IDomainGuild) (int, error) {
+	adopted, err := d.repo.FetchRolesAdopted(ctx, guild.ID())
+	if err != nil || adopted {
+		return 0, err
+	}
+	members, err := s.GuildMembers(ctx, guild.ID())
+	if err != nil {
+		return 0, err
+	}
+	roles := make(map[string]string)
+	for _, mem := range members {
+		name := legacyRoleName(mem)
+		for _, role := range mem.Roles() {
+			if role.Name() == name {
+				roles[mem.UserID()] = role.ID()
+				break
+			}
+		}
+	}
+	if err := d.repo.UpdateRolesAdopted(ctx, guild.ID(), roles); err != nil {
+		return 0, err
+	}
+	return len(roles), nil
 }
 
 func (d *domain) Freeze(ctx context.Context, mem IDomainMember) error {
@@ -216,24 +539,44 @@ func (d *domain) Unfreeze(ctx context.Context, mem IDomainMember) error {
 	return d.repo.UpdateUnfreeze(ctx, mem)
 }
 
-func (d *domain) HasColourRole(ctx context.Context, mem IDomainMember) bool {
-	hasRole := d.GetColourRole(ctx, mem) != nil
-	return hasRole
+func (d *domain) HasColourRole(ctx context.Context, mem IDomainMember) (bool, error) {
+	role, err := d.GetColourRole(ctx, mem)
+	return role != nil, err
 }
 
-func (d *domain) GetColourRole(ctx context.Context, mem IDomainMember) IDomainRole {
+// GetColourRole finds the member's colour role by its recorded ID. It is nil if they have none, or
+// no longer have it.
+func (d *domain) GetColourRole(ctx context.Context, mem IDomainMember) (IDomainRole, error) {
+	_, role, err := d.storedColourRole(ctx, mem)
+	return role, err
+}
+
+// storedColourRole returns the recorded ID of the member's colour role, and the role if they have it.
+func (d *domain) storedColourRole(ctx context.Context, mem IDomainMember) (string, IDomainRole, error) {
+	roleID, err := d.repo.FetchColourRole(ctx, mem)
+	if err != nil || roleID == "" {
+		return "", nil, err
+	}
 	for _, role := range mem.Roles() {
-		roleName := role.Name()
-		if rolePattern.MatchString(roleName) {
-			return role
+		if role.ID() == roleID {
+			return roleID, role, nil
 		}
 	}
-	return nil
+	return roleID, nil, nil
 }
 
+func hasRole(mem IDomainMember, roleID string) bool {
+	for _, role := range mem.Roles() {
+		if role.ID() == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetColourRoleName names a colour role after the member, following the configured template.
 func (d *domain) GetColourRoleName(ctx context.Context, mem IDomainMember) string {
-	roleName := mem.Username()
-	return roleName
+	return renderRoleName(d.roleName, mem)
 }
 
 func (d *domain) CreateColourRole(ctx context.Context, s IDomainSession, mem IDomainMember, colour *Colour) (IDomainRole, error) {
@@ -246,16 +589,20 @@ func (d *domain) CreateColourRole(ctx context.Context, s IDomainSession, mem IDo
 	if err != nil {
 		return nil, err
 	}
+	uow := &unitOfWork{}
+	uow.onAbort("deleting role", func(ctx context.Context) error {
+		return s.GuildRoleDelete(ctx, guildID, id)
+	})
 
 	// Set height
 	height, err := d.GetColourRoleHeight(ctx, s, mem.Guild())
 	if err != nil {
-		return nil, err
+		return nil, uow.abort(ctx, err)
 	}
 	if height > -1 {
 		err = d.SetRoleHeight(ctx, s, mem.Guild(), id, height)
 		if err != nil {
-			return nil, err
+			return nil, uow.abort(ctx, err)
 		}
 	}
 	return NewDomainRole(id, roleName, col), nil
@@ -266,10 +613,11 @@ func (d *domain) AssignColourRole(ctx context.Context, s IDomainSession, mem IDo
 }
 
 func (d *domain) GetColourRoleHeight(ctx context.Context, s IDomainSession, guild IDomainGuild) (int, error) {
-	if d.maxRoleHeight > -1 {
-		return d.maxRoleHeight, nil
+	guildID := guild.ID()
+	if height, ok := d.maxRoleHeights.get(guildID); ok {
+		return height, nil
 	}
-	roles, err := s.GuildRoles(ctx, guild.ID())
+	roles, err := s.GuildRoles(ctx, guildID)
 	if err != nil {
 		return -1, err
 	}
@@ -277,7 +625,7 @@ func (d *domain) GetColourRoleHeight(ctx context.Context, s IDomainSession, guil
 	log.Debugf(ctx, "Checking height of role: %s", d.maxHeightRoleName)
 	for i, role := range roles {
 		if role.Name() == d.maxHeightRoleName {
-			d.maxRoleHeight = i
+			d.maxRoleHeights.set(guildID, i)
 			log.Debugf(ctx, "Found height of role: %s (= %d)", d.maxHeightRoleName, i)
 			return i, nil
 		}
@@ -285,6 +633,29 @@ func (d *domain) GetColourRoleHeight(ctx context.Context, s IDomainSession, guil
 	return -1, nil
 }
 
+// roleHeights keeps the height of each guild's max height role, by guild ID. The zero value is
+// ready to use.
+type roleHeights struct {
+	mutex   sync.Mutex
+	heights map[string]int
+}
+
+func (h *roleHeights) get(guildID string) (int, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	height, ok := h.heights[guildID]
+	return height, ok
+}
+
+func (h *roleHeights) set(guildID string, height int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.heights == nil {
+		h.heights = make(map[string]int)
+	}
+	h.heights[guildID] = height
+}
+
 func (d *domain) SetRoleHeight(ctx context.Context, s IDomainSession, g IDomainGuild, newRoleID string, height int) error {
 	if height <= -1 {
 		return ErrInvalidRoleHeight
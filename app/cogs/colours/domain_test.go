@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/fiffu/arisa3/app/types"
+
+	dgo "github.com/bwmarrin/discordgo"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -23,6 +25,16 @@ func newTestingDomain(t *testing.T, cfg *Config) (
 	cog.EXPECT().Name().AnyTimes().Return("test cog")
 
 	repo := NewMockIDomainRepository(ctrl)
+	// Guilds have no palette unless a test says otherwise
+	repo.EXPECT().FetchPalette(Any, Any).AnyTimes().Return(nil, nil)
+	// The first of a member's roles is recorded as their colour role
+	repo.EXPECT().FetchColourRole(Any, Any).AnyTimes().DoAndReturn(
+		func(_ context.Context, mem IDomainMember) (string, error) {
+			if roles := mem.Roles(); len(roles) > 0 {
+				return roles[0].ID(), nil
+			}
+			return "", nil
+		})
 
 	return ctrl, cog, repo, NewColoursDomain(cog, repo, cfg)
 }
@@ -31,13 +43,13 @@ func newTestingMember(ctrl *gomock.Controller, hasColourRole bool) *MockIDomainM
 	mem := NewMockIDomainMember(ctrl)
 	mem.EXPECT().UserID().AnyTimes().Return("123123123123")
 	mem.EXPECT().Username().AnyTimes().Return("test#1234")
+	mem.EXPECT().DisplayName().AnyTimes().Return("Test")
+	mem.EXPECT().Nick().AnyTimes().Return("")
 	mem.EXPECT().Guild().AnyTimes().Return(NewDomainGuild("87979878098098908"))
 
 	roles := make([]IDomainRole, 0)
 	if hasColourRole {
-		roleName := (&domain{}).GetColourRoleName(context.Background(), mem)
-		roleColour := (&Colour{}).Random()
-		role := &colourRole{name: roleName, colour: roleColour}
+		role := &colourRole{roleID: "colour-role", name: "test#1234", colour: (&Colour{}).Random()}
 		roles = append(roles, role)
 	}
 	mem.EXPECT().Roles().AnyTimes().Return(roles)
@@ -54,61 +66,31 @@ func newTestingConfig() *Config {
 }
 
 func Test_GetColourRole(t *testing.T) {
-	const testUsername = "test#1234"
-
+	role := NewDomainRole("10", "someone", 0x1abc9c)
 	testCases := []struct {
-		desc            string
-		currentRoleName string
-		expectFound     bool
+		desc       string
+		roles      []IDomainRole
+		recorded   string
+		err        error
+		expectRole IDomainRole
 	}{
-		{
-			desc:        "no roles at all",
-			expectFound: false,
-		},
-		{
-			desc:            "have roles, wrong pattern",
-			currentRoleName: "asd",
-			expectFound:     false,
-		},
-		{
-			desc:            "have roles, role name matches username",
-			currentRoleName: testUsername,
-			expectFound:     true,
-		},
-		{
-			desc:            "have roles, role name matches regex",
-			currentRoleName: "abcd#1234",
-			expectFound:     true,
-		},
-		{
-			desc:            "have roles, role name has new #0 denominator",
-			currentRoleName: "abcd#0",
-			expectFound:     true,
-		},
+		{desc: "nothing recorded", roles: []IDomainRole{role}},
+		{desc: "recorded and held", roles: []IDomainRole{NewDomainRole("9", "Mods", 0), role}, recorded: "10", expectRole: role},
+		{desc: "recorded but no longer held", recorded: "10"},
+		{desc: "lookup fails", roles: []IDomainRole{role}, err: assert.AnError},
 	}
 	for _, tc := range testCases {
-		ctrl := gomock.NewController(t)
 		t.Run(tc.desc, func(t *testing.T) {
-			roles := make([]IDomainRole, 0)
-			if tc.currentRoleName != "" {
-				role := NewMockIDomainRole(ctrl)
-				role.EXPECT().Name().AnyTimes().Return(tc.currentRoleName)
-				roles = append(roles, role)
-			}
-
+			ctrl := gomock.NewController(t)
+			repo := NewMockIDomainRepository(ctrl)
+			d := &domain{repo: repo}
 			mem := NewMockIDomainMember(ctrl)
-			mem.EXPECT().Username().AnyTimes().Return(testUsername)
-			mem.EXPECT().Roles().AnyTimes().Return(roles)
-
-			_, _, _, d := newTestingDomain(t, newTestingConfig())
-			role := d.GetColourRole(context.Background(), mem)
-
-			if tc.expectFound {
-				assert.NotNil(t, role)
-			} else {
-				assert.Nil(t, role)
-			}
+			mem.EXPECT().Roles().AnyTimes().Return(tc.roles)
+			repo.EXPECT().FetchColourRole(Any, mem).Return(tc.recorded, tc.err)
 
+			actual, err := d.GetColourRole(context.Background(), mem)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expectRole, actual)
 		})
 	}
 }
@@ -192,15 +174,21 @@ func Test_Reroll(t *testing.T) {
 				expectError = ErrRerollCooldownPending
 
 			case Provision:
-				repo.EXPECT().UpdateReroll(Any, Any, Any).Return(nil)
-				s.EXPECT().GuildRoleCreate(Any, Any, Any, Any)
-				s.EXPECT().GuildRoles(Any, Any)
-				// s.EXPECT().GuildRoleReorder(Any, Any)  // commented out; lazy to mock guild roles
-				s.EXPECT().GuildMemberRoleAdd(Any, Any, Any, Any)
+				// Cooldown is applied only after the API calls succeed
+				gomock.InOrder(
+					s.EXPECT().GuildRoleCreate(Any, Any, "test#1234", Any).Return("456", nil),
+					s.EXPECT().GuildRoles(Any, Any),
+					// s.EXPECT().GuildRoleReorder(Any, Any)  // commented out; lazy to mock guild roles
+					s.EXPECT().GuildMemberRoleAdd(Any, Any, Any, "456"),
+					repo.EXPECT().UpdateColourRole(Any, Any, "456").Return(nil),
+					repo.EXPECT().UpdateReroll(Any, Any, Any).Return(nil),
+				)
 
 			case Reuse:
-				repo.EXPECT().UpdateReroll(Any, Any, Any).Return(nil)
-				s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, Any)
+				gomock.InOrder(
+					s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, Any),
+					repo.EXPECT().UpdateReroll(Any, Any, Any).Return(nil),
+				)
 			}
 
 			_, err := d.Reroll(context.Background(), s, mem)
@@ -219,6 +207,130 @@ func Test_Reroll(t *testing.T) {
 
 }
 
+func Test_Pick(t *testing.T) {
+	teal := (&Colour{}).FromRGBHex("1abc9c")
+	cfg := newTestingConfig()
+	cfg.PickCooldownMins = 10080
+	cfg.PickPolicy = PickPolicy{Deny: []string{"red"}}
+
+	t.Run("denied by policy", func(t *testing.T) {
+		ctrl, _, _, d := newTestingDomain(t, cfg)
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, true)
+
+		_, err := d.Pick(context.Background(), s, mem, (&Colour{}).FromRGBHex("e74c3c"))
+		assert.ErrorIs(t, err, ErrPickDenied)
+	})
+
+	t.Run("cooldown unfinished, no penalty", func(t *testing.T) {
+		ctrl, _, repo, d := newTestingDomain(t, cfg)
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, true)
+		repo.EXPECT().FetchUserState(Any, Any, Pick).Return(time.Now().Add(-1*time.Hour), nil)
+
+		_, err := d.Pick(context.Background(), s, mem, teal)
+		assert.ErrorIs(t, err, ErrPickCooldownPending)
+	})
+
+	t.Run("has colourRole: recolour", func(t *testing.T) {
+		ctrl, _, repo, d := newTestingDomain(t, cfg)
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, true)
+		repo.EXPECT().FetchUserState(Any, Any, Pick).Return(time.Now().Add(-30*24*time.Hour), nil)
+		gomock.InOrder(
+			s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, teal.ToDecimal()),
+			repo.EXPECT().UpdatePick(Any, mem, teal).Return(nil),
+		)
+
+		colour, err := d.Pick(context.Background(), s, mem, teal)
+		assert.NoError(t, err)
+		assert.Equal(t, teal, colour)
+	})
+
+	t.Run("no colourRole: provision", func(t *testing.T) {
+		ctrl, _, repo, d := newTestingDomain(t, cfg)
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, false)
+		repo.EXPECT().FetchUserState(Any, Any, Pick).Return(Never, nil)
+		gomock.InOrder(
+			s.EXPECT().GuildRoleCreate(Any, Any, Any, teal.ToDecimal()).Return("456", nil),
+			s.EXPECT().GuildRoles(Any, Any),
+			s.EXPECT().GuildMemberRoleAdd(Any, Any, Any, "456"),
+			repo.EXPECT().UpdateColourRole(Any, mem, "456").Return(nil),
+			repo.EXPECT().UpdatePick(Any, mem, teal).Return(assert.AnError),
+			repo.EXPECT().DeleteColourRole(Any, Any, "456").Return(nil),
+			s.EXPECT().GuildRoleDelete(Any, Any, "456").Return(nil),
+		)
+
+		_, err := d.Pick(context.Background(), s, mem, teal)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("lost colourRole: given back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := NewMockIDomainRepository(ctrl)
+		d := NewColoursDomain(types.NewMockICog(ctrl), repo, cfg)
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, false)
+		lost := NewDomainRole("456", "test#1234", 0xff0000)
+		repo.EXPECT().FetchUserState(Any, Any, Pick).Return(Never, nil)
+		repo.EXPECT().FetchColourRole(Any, mem).AnyTimes().Return("456", nil)
+		gomock.InOrder(
+			s.EXPECT().GuildRole(Any, Any, "456").Return(lost, nil),
+			s.EXPECT().GuildMemberRoleAdd(Any, Any, Any, "456"),
+			s.EXPECT().GuildRoleEdit(Any, Any, "456", Any, teal.ToDecimal()),
+			repo.EXPECT().UpdatePick(Any, mem, teal).Return(nil),
+		)
+
+		colour, err := d.Pick(context.Background(), s, mem, teal)
+		assert.NoError(t, err)
+		assert.Equal(t, teal, colour)
+	})
+
+	t.Run("lost colourRole: taken away again if the pick fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := NewMockIDomainRepository(ctrl)
+		d := NewColoursDomain(types.NewMockICog(ctrl), repo, cfg)
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, false)
+		lost := NewDomainRole("456", "test#1234", 0xff0000)
+		repo.EXPECT().FetchUserState(Any, Any, Pick).Return(Never, nil)
+		repo.EXPECT().FetchColourRole(Any, mem).AnyTimes().Return("456", nil)
+		gomock.InOrder(
+			s.EXPECT().GuildRole(Any, Any, "456").Return(lost, nil),
+			s.EXPECT().GuildMemberRoleAdd(Any, Any, Any, "456"),
+			s.EXPECT().GuildRoleEdit(Any, Any, "456", Any, teal.ToDecimal()),
+			repo.EXPECT().UpdatePick(Any, mem, teal).Return(assert.AnError),
+			s.EXPECT().GuildRoleEdit(Any, Any, "456", Any, 0xff0000),
+			s.EXPECT().GuildMemberRoleRemove(Any, Any, Any, "456"),
+		)
+
+		_, err := d.Pick(context.Background(), s, mem, teal)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("lost colourRole: taken away again if recolouring fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := NewMockIDomainRepository(ctrl)
+		d := NewColoursDomain(types.NewMockICog(ctrl), repo, cfg)
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, false)
+		lost := NewDomainRole("456", "test#1234", 0xff0000)
+		repo.EXPECT().FetchUserState(Any, Any, Pick).Return(Never, nil)
+		repo.EXPECT().FetchColourRole(Any, mem).AnyTimes().Return("456", nil)
+		gomock.InOrder(
+			s.EXPECT().GuildRole(Any, Any, "456").Return(lost, nil),
+			s.EXPECT().GuildMemberRoleAdd(Any, Any, Any, "456"),
+			s.EXPECT().GuildRoleEdit(Any, Any, "456", Any, teal.ToDecimal()).Return(assert.AnError),
+			s.EXPECT().GuildMemberRoleRemove(Any, Any, Any, "456"),
+		)
+		// repo.UpdatePick is not expected
+
+		_, err := d.Pick(context.Background(), s, mem, teal)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
 func Test_Mutate(t *testing.T) {
 	const (
 		Noop = iota
@@ -315,8 +427,10 @@ func Test_Mutate(t *testing.T) {
 			case Frozen:
 				expectError = ErrMutateFrozen
 			case Allow:
-				repo.EXPECT().UpdateMutate(Any, Any, Any).Return(nil)
-				s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, Any)
+				gomock.InOrder(
+					s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, Any),
+					repo.EXPECT().UpdateMutate(Any, Any, Any).Return(nil),
+				)
 			}
 
 			_, err := d.Mutate(context.Background(), s, mem)
@@ -336,6 +450,177 @@ func Test_Mutate(t *testing.T) {
 
 }
 
+func Test_Mutate_compensates(t *testing.T) {
+	t.Run("failed API call does not apply cooldown", func(t *testing.T) {
+		ctrl, _, repo, d := newTestingDomain(t, newTestingConfig())
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, true)
+		repo.EXPECT().FetchUserState(Any, Any, Any).AnyTimes().Return(Never, nil)
+
+		s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, Any).Return(assert.AnError)
+		// repo.UpdateMutate is not expected
+
+		_, err := d.Mutate(context.Background(), s, mem)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("failed save restores role colour", func(t *testing.T) {
+		ctrl, _, repo, d := newTestingDomain(t, newTestingConfig())
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, true)
+		oldColour := mem.Roles()[0].Colour().ToDecimal()
+		repo.EXPECT().FetchUserState(Any, Any, Any).AnyTimes().Return(Never, nil)
+
+		gomock.InOrder(
+			s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, Any).Return(nil),
+			repo.EXPECT().UpdateMutate(Any, Any, Any).Return(assert.AnError),
+			s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, oldColour).Return(nil),
+		)
+
+		_, err := d.Mutate(context.Background(), s, mem)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func Test_readableColours(t *testing.T) {
+	cfg := newTestingConfig()
+	cfg.MinContrast = 1
+	cfg.GuildMinContrast = map[string]float64{"87979878098098908": 3}
+
+	for i := 0; i < 20; i++ {
+		ctrl, _, repo, d := newTestingDomain(t, cfg)
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, true)
+		repo.EXPECT().FetchUserState(Any, Any, Any).AnyTimes().Return(Never, nil)
+		s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, Any).Times(2)
+		repo.EXPECT().UpdateReroll(Any, Any, Any).Return(nil)
+		repo.EXPECT().UpdateMutate(Any, Any, Any).Return(nil)
+
+		rerolled, err := d.Reroll(context.Background(), s, mem)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, rerolled.Contrast().Min(), 3.0)
+
+		mutated, err := d.Mutate(context.Background(), s, mem)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, mutated.Contrast().Min(), 3.0)
+	}
+}
+
+func Test_paletteColours(t *testing.T) {
+	palette, err := ParsePalette("hsv(200-260, 40-60, 60-80)")
+	assert.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		ctrl := gomock.NewController(t)
+		cog := types.NewMockICog(ctrl)
+		repo := NewMockIDomainRepository(ctrl)
+		d := NewColoursDomain(cog, repo, newTestingConfig())
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, true)
+		repo.EXPECT().FetchPalette(Any, mem.Guild().ID()).AnyTimes().Return(palette, nil)
+		repo.EXPECT().FetchUserState(Any, Any, Any).AnyTimes().Return(Never, nil)
+		repo.EXPECT().FetchColourRole(Any, mem).AnyTimes().Return("colour-role", nil)
+		s.EXPECT().GuildRoleEdit(Any, Any, Any, Any, Any).Times(2)
+		repo.EXPECT().UpdateReroll(Any, Any, Any).Return(nil)
+		repo.EXPECT().UpdateMutate(Any, Any, Any).Return(nil)
+
+		rerolled, err := d.Reroll(context.Background(), s, mem)
+		assert.NoError(t, err)
+		assert.True(t, palette.Contains(rerolled), "%s is outside the palette", rerolled)
+
+		// The member's role may have any colour from before the palette
+		mutated, err := d.Mutate(context.Background(), s, mem)
+		assert.NoError(t, err)
+		assert.True(t, palette.Contains(mutated), "%s is outside the palette", mutated)
+	}
+
+	t.Run("palette errors stop rerolls", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := NewMockIDomainRepository(ctrl)
+		d := NewColoursDomain(types.NewMockICog(ctrl), repo, newTestingConfig())
+		mem := newTestingMember(ctrl, true)
+		repo.EXPECT().FetchUserState(Any, Any, Any).AnyTimes().Return(Never, nil)
+		repo.EXPECT().FetchPalette(Any, Any).Return(nil, assert.AnError)
+
+		_, err := d.Reroll(context.Background(), NewMockIDomainSession(ctrl), mem)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func Test_Reroll_compensates(t *testing.T) {
+	t.Run("failed role assignment deletes provisioned role", func(t *testing.T) {
+		ctrl, _, repo, d := newTestingDomain(t, newTestingConfig())
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, false)
+		repo.EXPECT().FetchUserState(Any, Any, Reroll).AnyTimes().Return(Never, nil)
+
+		gomock.InOrder(
+			s.EXPECT().GuildRoleCreate(Any, Any, Any, Any).Return("456", nil),
+			s.EXPECT().GuildRoles(Any, Any),
+			s.EXPECT().GuildMemberRoleAdd(Any, Any, Any, "456").Return(assert.AnError),
+			s.EXPECT().GuildRoleDelete(Any, Any, "456").Return(nil),
+		)
+		// repo.UpdateReroll is not expected
+
+		_, err := d.Reroll(context.Background(), s, mem)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("failed save deletes provisioned role", func(t *testing.T) {
+		ctrl, _, repo, d := newTestingDomain(t, newTestingConfig())
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, false)
+		repo.EXPECT().FetchUserState(Any, Any, Reroll).AnyTimes().Return(Never, nil)
+
+		gomock.InOrder(
+			s.EXPECT().GuildRoleCreate(Any, Any, Any, Any).Return("456", nil),
+			s.EXPECT().GuildRoles(Any, Any),
+			s.EXPECT().GuildMemberRoleAdd(Any, Any, Any, "456").Return(nil),
+			repo.EXPECT().UpdateColourRole(Any, Any, "456").Return(nil),
+			repo.EXPECT().UpdateReroll(Any, Any, Any).Return(assert.AnError),
+			repo.EXPECT().DeleteColourRole(Any, Any, "456").Return(nil),
+			s.EXPECT().GuildRoleDelete(Any, Any, "456").Return(nil),
+		)
+
+		_, err := d.Reroll(context.Background(), s, mem)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("failed record deletes provisioned role", func(t *testing.T) {
+		ctrl, _, repo, d := newTestingDomain(t, newTestingConfig())
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, false)
+		repo.EXPECT().FetchUserState(Any, Any, Reroll).AnyTimes().Return(Never, nil)
+
+		gomock.InOrder(
+			s.EXPECT().GuildRoleCreate(Any, Any, Any, Any).Return("456", nil),
+			s.EXPECT().GuildRoles(Any, Any),
+			s.EXPECT().GuildMemberRoleAdd(Any, Any, Any, "456").Return(nil),
+			repo.EXPECT().UpdateColourRole(Any, Any, "456").Return(assert.AnError),
+			s.EXPECT().GuildRoleDelete(Any, Any, "456").Return(nil),
+		)
+
+		_, err := d.Reroll(context.Background(), s, mem)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("failed role height deletes created role", func(t *testing.T) {
+		ctrl, _, repo, d := newTestingDomain(t, newTestingConfig())
+		s := NewMockIDomainSession(ctrl)
+		mem := newTestingMember(ctrl, false)
+		repo.EXPECT().FetchUserState(Any, Any, Reroll).AnyTimes().Return(Never, nil)
+
+		gomock.InOrder(
+			s.EXPECT().GuildRoleCreate(Any, Any, Any, Any).Return("456", nil),
+			s.EXPECT().GuildRoles(Any, Any).Return(nil, assert.AnError),
+			s.EXPECT().GuildRoleDelete(Any, Any, "456").Return(nil),
+		)
+
+		_, err := d.Reroll(context.Background(), s, mem)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
 func Test_GetLastFrozen(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	repo := NewMockIDomainRepository(ctrl)
@@ -449,6 +734,230 @@ func Test_GetRerollCooldownEndTime(t *testing.T) {
 	}
 }
 
+func Test_Swap(t *testing.T) {
+	ctx := context.Background()
+	teal, coral := (&Colour{}).FromRGBHex("1abc9c"), (&Colour{}).FromRGBHex("ff7f50")
+	member := func(ctrl *gomock.Controller, userID string, role IDomainRole) *MockIDomainMember {
+		mem := NewMockIDomainMember(ctrl)
+		mem.EXPECT().UserID().AnyTimes().Return(userID)
+		mem.EXPECT().Guild().AnyTimes().Return(NewDomainGuild("1"))
+		roles := []IDomainRole{}
+		if role != nil {
+			roles = append(roles, role)
+		}
+		mem.EXPECT().Roles().AnyTimes().Return(roles)
+		return mem
+	}
+	setup := func(t *testing.T) (*MockIDomainRepository, *MockIDomainSession, IColoursDomain, IDomainMember, IDomainMember) {
+		cfg := newTestingConfig()
+		cfg.SwapCooldownPolicy = "clear"
+		ctrl, _, repo, d := newTestingDomain(t, cfg)
+		from := member(ctrl, "10", NewDomainRole("11", "from#0", teal.ToDecimal()))
+		to := member(ctrl, "20", NewDomainRole("21", "to#0", coral.ToDecimal()))
+		return repo, NewMockIDomainSession(ctrl), d, from, to
+	}
+
+	t.Run("both roles recoloured", func(t *testing.T) {
+		repo, s, d, from, to := setup(t)
+		expect := &ColourSwap{
+			Members:  [2]IDomainMember{from, to},
+			Colours:  [2]*Colour{coral, teal},
+			Cooldown: SwapClearsCooldown,
+		}
+		gomock.InOrder(
+			s.EXPECT().GuildRoleEdit(Any, "1", "11", "from#0", coral.ToDecimal()).Return(nil),
+			s.EXPECT().GuildRoleEdit(Any, "1", "21", "to#0", teal.ToDecimal()).Return(nil),
+			repo.EXPECT().UpdateSwap(Any, expect).Return(nil),
+		)
+		swap, err := d.Swap(ctx, s, from, to)
+		assert.NoError(t, err)
+		assert.Equal(t, expect, swap)
+	})
+
+	t.Run("second edit fails: first is rolled back", func(t *testing.T) {
+		_, s, d, from, to := setup(t)
+		gomock.InOrder(
+			s.EXPECT().GuildRoleEdit(Any, "1", "11", "from#0", coral.ToDecimal()).Return(nil),
+			s.EXPECT().GuildRoleEdit(Any, "1", "21", "to#0", teal.ToDecimal()).Return(assert.AnError),
+			s.EXPECT().GuildRoleEdit(Any, "1", "11", "from#0", teal.ToDecimal()).Return(nil),
+		)
+		_, err := d.Swap(ctx, s, from, to)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("recording fails: both are rolled back", func(t *testing.T) {
+		repo, s, d, from, to := setup(t)
+		gomock.InOrder(
+			s.EXPECT().GuildRoleEdit(Any, "1", "11", "from#0", coral.ToDecimal()).Return(nil),
+			s.EXPECT().GuildRoleEdit(Any, "1", "21", "to#0", teal.ToDecimal()).Return(nil),
+			repo.EXPECT().UpdateSwap(Any, Any).Return(assert.AnError),
+			s.EXPECT().GuildRoleEdit(Any, "1", "21", "to#0", coral.ToDecimal()).Return(nil),
+			s.EXPECT().GuildRoleEdit(Any, "1", "11", "from#0", teal.ToDecimal()).Return(nil),
+		)
+		_, err := d.Swap(ctx, s, from, to)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("with yourself, or without a colour role", func(t *testing.T) {
+		_, s, d, from, _ := setup(t)
+		_, err := d.Swap(ctx, s, from, from)
+		assert.ErrorIs(t, err, ErrSwapSelf)
+
+		_, err = d.Swap(ctx, s, from, member(gomock.NewController(t), "30", nil))
+		assert.ErrorIs(t, err, ErrSwapNoColourRole)
+	})
+}
+
+func Test_GetGallery(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	repo := NewMockIDomainRepository(ctrl)
+	s := NewMockIDomainSession(ctrl)
+	d := &domain{repo: repo}
+	guild := NewDomainGuild("1")
+
+	changed := time.Now()
+	s.EXPECT().GuildRoles(Any, "1").Return([]IDomainRole{
+		NewDomainRole("10", "someone", 0x1abc9c),
+		NewDomainRole("11", "Someone Else", 0xff0000),
+		NewDomainRole("12", "Moderators", 0x0000ff),
+	}, nil)
+	repo.EXPECT().FetchColourRoles(Any, "1").Return(map[string]string{"2": "10", "3": "11"}, nil)
+	repo.EXPECT().FetchLastChanges(Any, "1").Return(map[string]time.Time{"2": changed}, nil)
+
+	entries, err := d.GetGallery(ctx, s, guild)
+	assert.NoError(t, err)
+	assert.Equal(t, []*GalleryEntry{
+		{Name: "someone", Colour: (&Colour{}).FromDecimal(0x1abc9c), LastChange: changed},
+		{Name: "Someone Else", Colour: (&Colour{}).FromDecimal(0xff0000), LastChange: Never},
+	}, entries)
+
+	s.EXPECT().GuildRoles(Any, "1").Return(nil, assert.AnError)
+	_, err = d.GetGallery(ctx, s, guild)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func Test_CleanupColourRoles(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	s := NewMockIDomainSession(ctrl)
+	repo := NewMockIDomainRepository(ctrl)
+	d := &domain{repo: repo, roleName: newTestingConfig().roleNameTemplate()}
+	guild := NewDomainGuild("1")
+
+	current := NewDomainRole("10", "someone", 0xff0000)
+	renamed := NewDomainRole("11", "oldname", 0x00ff00)
+	dropped := NewDomainRole("12", "dropped", 0x1abc9c)
+	left := NewDomainRole("13", "left", 0x1abc9c)
+	stuck := NewDomainRole("14", "stuck", 0x1abc9c)
+	legacy := NewDomainRole("15", "legacy#0", 0x1abc9c)
+	legacyHeld := NewDomainRole("16", "held#0", 0x1abc9c)
+	mods := NewDomainRole("17", "Moderators", 0)
+	lookalike := NewDomainRole("18", "Team#0 leads", 0)
+	member := func(id, username string, roles ...IDomainRole) IDomainMember {
+		return NewDomainMember(&dgo.Member{GuildID: "1", User: &dgo.User{ID: id, Username: username, Discriminator: "0"}}, roles)
+	}
+	roles := []IDomainRole{current, renamed, dropped, left, stuck, legacy, legacyHeld, mods, lookalike}
+	members := []IDomainMember{
+		member("2", "someone", current, mods),
+		member("3", "newname", renamed),
+		member("4", "dropper", legacyHeld),
+		member("6", "stuck"),
+	}
+	recorded := map[string]string{"2": "10", "3": "11", "4": "12", "5": "13", "6": "14", "7": "99"}
+
+	// Dry runs report without changing anything
+	s.EXPECT().GuildRoles(Any, "1").Return(roles, nil)
+	s.EXPECT().GuildMembers(Any, "1").Return(members, nil)
+	repo.EXPECT().FetchColourRoles(Any, "1").Return(recorded, nil)
+	report, err := d.CleanupColourRoles(ctx, s, guild, CleanupOptions{DryRun: true, Untracked: true})
+	assert.NoError(t, err)
+	assert.Equal(t, &CleanupReport{
+		DryRun:  true,
+		Deleted: []string{"dropped", "left", "stuck", "legacy#0"},
+		Renamed: []RoleRename{{From: "oldname", To: "newname"}},
+	}, report)
+
+	s.EXPECT().GuildRoles(Any, "1").Return(roles, nil)
+	s.EXPECT().GuildMembers(Any, "1").Return(members, nil)
+	repo.EXPECT().FetchColourRoles(Any, "1").Return(recorded, nil)
+	s.EXPECT().GuildRoleEdit(Any, "1", "11", "newname", 0x00ff00).Return(nil)
+	for _, roleID := range []string{"12", "13", "15"} {
+		s.EXPECT().GuildRoleDelete(Any, "1", roleID).Return(nil)
+	}
+	s.EXPECT().GuildRoleDelete(Any, "1", "14").Return(assert.AnError)
+	// Deleted roles are forgotten, as are roles deleted while the bot was away
+	for _, roleID := range []string{"12", "13", "99"} {
+		repo.EXPECT().DeleteColourRole(Any, "1", roleID).Return(nil)
+	}
+	report, err = d.CleanupColourRoles(ctx, s, guild, CleanupOptions{Untracked: true})
+	assert.NoError(t, err)
+	assert.Equal(t, &CleanupReport{
+		Deleted: []string{"dropped", "left", "legacy#0"},
+		Renamed: []RoleRename{{From: "oldname", To: "newname"}},
+		Failed:  []string{"stuck"},
+	}, report)
+
+	// Untracked roles are left alone unless asked for
+	s.EXPECT().GuildRoles(Any, "1").Return(roles, nil)
+	s.EXPECT().GuildMembers(Any, "1").Return(members, nil)
+	repo.EXPECT().FetchColourRoles(Any, "1").Return(recorded, nil)
+	report, err = d.CleanupColourRoles(ctx, s, guild, CleanupOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dropped", "left", "stuck"}, report.Deleted)
+
+	s.EXPECT().GuildRoles(Any, "1").Return(roles, nil)
+	s.EXPECT().GuildMembers(Any, "1").Return(nil, assert.AnError)
+	_, err = d.CleanupColourRoles(ctx, s, guild, CleanupOptions{})
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func Test_AdoptColourRoles(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	s := NewMockIDomainSession(ctrl)
+	repo := NewMockIDomainRepository(ctrl)
+	d := &domain{repo: repo}
+	guild := NewDomainGuild("1")
+
+	mods := NewDomainRole("10", "Moderators", 0)
+	own := NewDomainRole("11", "someone#0", 0x1abc9c)
+	legacy := NewDomainRole("12", "old#1234", 0xff0000)
+	shared := NewDomainRole("13", "sharer#0", 0x00ff00)
+	// Roles that look like colour roles but aren't named after their only holder aren't adopted
+	lookalike := NewDomainRole("14", "someone#0", 0x0000ff)
+	team := NewDomainRole("15", "Team#0 leads", 0)
+	member := func(id, username, discriminator string, roles ...IDomainRole) IDomainMember {
+		return NewDomainMember(&dgo.Member{GuildID: "1", User: &dgo.User{ID: id, Username: username, Discriminator: discriminator}}, roles)
+	}
+	members := []IDomainMember{
+		member("2", "someone", "0", mods, own),
+		member("3", "old", "1234", legacy),
+		member("4", "sharer", "0", shared),
+		member("5", "friend", "0", shared),
+		member("6", "nobody", "0", mods),
+		member("7", "alone", "0", lookalike, team),
+	}
+
+	repo.EXPECT().FetchRolesAdopted(Any, "1").Return(false, nil)
+	s.EXPECT().GuildMembers(Any, "1").Return(members, nil)
+	repo.EXPECT().UpdateRolesAdopted(Any, "1", map[string]string{"2": "11", "3": "12", "4": "13"}).Return(nil)
+	adopted, err := d.AdoptColourRoles(ctx, s, guild)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, adopted)
+
+	// Guilds are only adopted once
+	repo.EXPECT().FetchRolesAdopted(Any, "1").Return(true, nil)
+	adopted, err = d.AdoptColourRoles(ctx, s, guild)
+	assert.NoError(t, err)
+	assert.Zero(t, adopted)
+
+	repo.EXPECT().FetchRolesAdopted(Any, "1").Return(false, nil)
+	s.EXPECT().GuildMembers(Any, "1").Return(nil, assert.AnError)
+	_, err = d.AdoptColourRoles(ctx, s, guild)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
 func Test_Freeze(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	repo := NewMockIDomainRepository(ctrl)
@@ -509,7 +1018,8 @@ func Test_HasColourRole(t *testing.T) {
 	for _, hasColourRole := range []bool{true, false} {
 		ctrl, _, _, d := newTestingDomain(t, newTestingConfig())
 		mem := newTestingMember(ctrl, hasColourRole)
-		actual := d.HasColourRole(context.Background(), mem)
+		actual, err := d.HasColourRole(context.Background(), mem)
+		assert.NoError(t, err)
 		assert.Equal(t, hasColourRole, actual)
 	}
 }
@@ -565,3 +1075,25 @@ func Test_SetRoleHeight(t *testing.T) {
 		})
 	}
 }
+
+func Test_GetColourRoleHeight(t *testing.T) {
+	ctrl, _, _, d := newTestingDomain(t, newTestingConfig())
+	s := NewMockIDomainSession(ctrl)
+	maxHeightRole := NewDomainRole("max", newTestingConfig().MaxRoleHeightName, 0)
+	r := NewDomainRole("", "roleName", 0)
+
+	guildA, guildB := NewDomainGuild("a"), NewDomainGuild("b")
+	s.EXPECT().GuildRoles(Any, "a").Return([]IDomainRole{r, maxHeightRole}, nil).Times(1)
+	s.EXPECT().GuildRoles(Any, "b").Return([]IDomainRole{r, r, r, maxHeightRole}, nil).Times(1)
+
+	// Each guild's height is looked up once, and kept apart from other guilds'
+	for i := 0; i < 2; i++ {
+		height, err := d.GetColourRoleHeight(context.Background(), s, guildA)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, height)
+
+		height, err = d.GetColourRoleHeight(context.Background(), s, guildB)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, height)
+	}
+}
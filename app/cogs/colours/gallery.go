@@ -0,0 +1,86 @@
+package colours
+
+// gallery.go lists every colour role in a guild, so that they can be seen all at once.
+
+import (
+	"sort"
+	"time"
+)
+
+// GalleryEntry is a colour role in the gallery.
+type GalleryEntry struct {
+	// Name is the name of the member that the role belongs to.
+	Name   string
+	Colour *Colour
+	// LastChange is when the role last changed colour, or Never if there's no record of it.
+	LastChange time.Time
+}
+
+// GallerySort is an order that the gallery can be shown in.
+type GallerySort string
+
+const (
+	// GalleryByHue goes around the hue circle from red, then from light greys to dark ones.
+	GalleryByHue GallerySort = "hue"
+	// GalleryByRecent starts with the colours that changed most recently.
+	GalleryByRecent GallerySort = "recent"
+)
+
+// Colours less saturated than this are sorted with the greys, since their hue is hard to see.
+const galleryGreySaturation = 0.1
+
+// sortGallery sorts the entries in place. Entries that tie are sorted by name.
+func sortGallery(entries []*GalleryEntry, by GallerySort) {
+	type hueKey struct {
+		grey       bool
+		hue, value float64
+	}
+	hueKeys := make(map[*GalleryEntry]hueKey, len(entries))
+	for _, entry := range entries {
+		h, s, v := entry.Colour.ToHSV()
+		hueKeys[entry] = hueKey{s < galleryGreySaturation, h, v}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		x, y := entries[i], entries[j]
+		switch by {
+		case GalleryByRecent:
+			if !x.LastChange.Equal(y.LastChange) {
+				return x.LastChange.After(y.LastChange)
+			}
+		default:
+			kx, ky := hueKeys[x], hueKeys[y]
+			switch {
+			case kx.grey != ky.grey:
+				return ky.grey
+			case kx.grey && kx.value != ky.value:
+				return kx.value > ky.value
+			case !kx.grey && kx.hue != ky.hue:
+				return kx.hue < ky.hue
+			}
+		}
+		return x.Name < y.Name
+	})
+}
+
+// galleryPage returns the entries on the given page, counting from 1, along with that page and
+// how many pages there are. Pages before the first or after the last give the nearest one.
+func galleryPage(entries []*GalleryEntry, page, perPage int) (onPage []*GalleryEntry, actualPage, pages int) {
+	pages = (len(entries) + perPage - 1) / perPage
+	if pages == 0 {
+		pages = 1
+	}
+	actualPage = page
+	if actualPage < 1 {
+		actualPage = 1
+	}
+	if actualPage > pages {
+		actualPage = pages
+	}
+	start := (actualPage - 1) * perPage
+	end := start + perPage
+	if end > len(entries) {
+		end = len(entries)
+	}
+	return entries[start:end], actualPage, pages
+}
@@ -0,0 +1,120 @@
+package colours
+
+import (
+	"fmt"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func galleryNames(entries []*GalleryEntry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name
+	}
+	return names
+}
+
+func Test_sortGallery(t *testing.T) {
+	now := time.Now()
+	entry := func(name, hexcode string, lastChange time.Time) *GalleryEntry {
+		return &GalleryEntry{Name: name, Colour: (&Colour{}).FromRGBHex(hexcode), LastChange: lastChange}
+	}
+	entries := []*GalleryEntry{
+		entry("black", "000000", Never),
+		entry("blue", "0000ff", now.Add(-time.Hour)),
+		entry("white", "ffffff", now.Add(-2*time.Hour)),
+		entry("green", "00ff00", Never),
+		entry("red", "ff0000", now),
+		entry("also red", "ff0000", now),
+	}
+
+	sortGallery(entries, GalleryByHue)
+	assert.Equal(t, []string{"also red", "red", "green", "blue", "white", "black"}, galleryNames(entries))
+
+	sortGallery(entries, GalleryByRecent)
+	assert.Equal(t, []string{"also red", "red", "blue", "white", "black", "green"}, galleryNames(entries))
+}
+
+func Test_galleryPage(t *testing.T) {
+	entries := make([]*GalleryEntry, 5)
+	for i := range entries {
+		entries[i] = &GalleryEntry{Name: fmt.Sprint(i)}
+	}
+	testCases := []struct {
+		page       int
+		expect     []string
+		expectPage int
+	}{
+		{1, []string{"0", "1"}, 1},
+		{3, []string{"4"}, 3},
+		{0, []string{"0", "1"}, 1},
+		{9, []string{"4"}, 3},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.page), func(t *testing.T) {
+			onPage, page, pages := galleryPage(entries, tc.page, 2)
+			assert.Equal(t, tc.expect, galleryNames(onPage))
+			assert.Equal(t, tc.expectPage, page)
+			assert.Equal(t, 3, pages)
+		})
+	}
+
+	onPage, page, pages := galleryPage(nil, 1, 2)
+	assert.Empty(t, onPage)
+	assert.Equal(t, 1, page)
+	assert.Equal(t, 1, pages)
+}
+
+func Test_makeGalleryImg(t *testing.T) {
+	entries := make([]*GalleryEntry, galleryColumns+1)
+	for i := range entries {
+		entries[i] = &GalleryEntry{Name: "someone", Colour: (&Colour{}).FromRGBHex("1abc9c")}
+	}
+	file, ext, mime, err := makeGalleryImg(entries)
+	assert.NoError(t, err)
+	assert.Equal(t, "png", ext)
+	assert.Equal(t, "image/png", mime)
+
+	img, err := png.Decode(file)
+	assert.NoError(t, err)
+	assert.Equal(t, galleryColumns*galleryCellWidth, img.Bounds().Dx())
+	assert.Equal(t, 2*galleryCellHeight, img.Bounds().Dy())
+
+	// Each cell is filled with its colour, and labelled over it
+	labelled := false
+	for x := 0; x < galleryCellWidth; x++ {
+		for y := 0; y < galleryCellHeight; y++ {
+			if r, g, b, _ := img.At(x, y).RGBA(); r>>8 == 0 && g>>8 == 0 && b>>8 == 0 {
+				labelled = true
+			}
+		}
+	}
+	assert.True(t, labelled)
+	r, g, b, _ := img.At(galleryCellWidth-1, galleryCellHeight-1).RGBA()
+	assert.Equal(t, "1abc9c", fmt.Sprintf("%02x%02x%02x", r>>8, g>>8, b>>8))
+}
+
+func Test_truncateLabel(t *testing.T) {
+	assert.Equal(t, "someone", truncateLabel("someone", 7))
+	assert.Equal(t, "some..", truncateLabel("someone", 6))
+}
+
+func Test_galleryResponse(t *testing.T) {
+	resp, err := galleryResponse(nil, GalleryByHue, 1)
+	assert.NoError(t, err)
+	assert.Contains(t, resp.Data().Data.Content, "Nobody here has a colour")
+
+	entries := make([]*GalleryEntry, galleryPerPage+1)
+	for i := range entries {
+		entries[i] = &GalleryEntry{Name: fmt.Sprint(i), Colour: (&Colour{}).Random()}
+	}
+	resp, err = galleryResponse(entries, GalleryByRecent, 2)
+	assert.NoError(t, err)
+	if data := resp.Data().Data; assert.Len(t, data.Embeds, 1) {
+		assert.Contains(t, data.Embeds[0].Description, "Page 2 of 2")
+		assert.Len(t, data.Files, 1)
+	}
+}
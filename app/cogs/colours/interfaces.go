@@ -19,8 +19,13 @@ type IColoursDomain interface {
 	// Get a member's last reroll time.
 	GetLastReroll(context.Context, IDomainMember) (time.Time, bool, error)
 
+	// Get a member's last pick time.
+	GetLastPick(context.Context, IDomainMember) (time.Time, bool, error)
+
 	// Get the reroll cooldown end time for member.
 	GetRerollCooldownEndTime(context.Context, IDomainMember) (time.Time, error)
+	// Get the pick cooldown end time for member.
+	GetPickCooldownEndTime(context.Context, IDomainMember) (time.Time, error)
 	// Get history of colours associated with member.
 	GetHistory(context.Context, IDomainMember) (*History, error)
 
@@ -28,6 +33,23 @@ type IColoursDomain interface {
 	Mutate(context.Context, IDomainSession, IDomainMember) (*Colour, error)
 	// Reroll the colour for a member's colour role.
 	Reroll(context.Context, IDomainSession, IDomainMember) (*Colour, error)
+	// Give a member's colour role a colour of their choosing, if the guild's policy allows it.
+	Pick(context.Context, IDomainSession, IDomainMember, *Colour) (*Colour, error)
+
+	// Trade the colours of two members' colour roles. Both roles are recoloured, or neither is.
+	Swap(context.Context, IDomainSession, IDomainMember, IDomainMember) (*ColourSwap, error)
+
+	// Get the guild's palette, which rerolls and mutations keep to. Nil if the guild has none.
+	GetPalette(context.Context, IDomainGuild) (*Palette, error)
+	// Set the guild's palette. A nil palette removes it. Palettes that can't meet the guild's
+	// minimum contrast are refused with ErrInvalidPalette.
+	SetPalette(context.Context, IDomainGuild, *Palette) error
+
+	// Get every colour role in the guild, with when each last changed colour.
+	GetGallery(context.Context, IDomainSession, IDomainGuild) ([]*GalleryEntry, error)
+	// Delete colour roles that their members no longer have, and rename those named after a
+	// member's old name. On a dry run, only report what would be done.
+	CleanupColourRoles(ctx context.Context, s IDomainSession, guild IDomainGuild, opts CleanupOptions) (*CleanupReport, error)
 
 	// Freeze a member's colour role, i.e. disable mutations.
 	Freeze(context.Context, IDomainMember) error
@@ -35,12 +57,14 @@ type IColoursDomain interface {
 	Unfreeze(context.Context, IDomainMember) error
 
 	// Returns whether member has colour role.
-	HasColourRole(context.Context, IDomainMember) bool
-	// Search member's roles for one that looks like a colour role, based on name.
-	GetColourRole(context.Context, IDomainMember) IDomainRole
-	// Derive a role name based on the member's properties, like username.
+	HasColourRole(context.Context, IDomainMember) (bool, error)
+	// Find the member's colour role among their roles, by the role ID recorded for them.
+	GetColourRole(context.Context, IDomainMember) (IDomainRole, error)
+	// Derive a role name based on the member's properties, following the role name template.
 	GetColourRoleName(context.Context, IDomainMember) string
-	// Generate a colour role name based on the member's nickname or username.
+	// Start tracking colour roles made before they were recorded by ID, once for each guild.
+	AdoptColourRoles(context.Context, IDomainSession, IDomainGuild) (int, error)
+	// Create a colour role named after the member, at the colour role height.
 	CreateColourRole(context.Context, IDomainSession, IDomainMember, *Colour) (IDomainRole, error)
 	// Get height that colour roles should be at, based on position of role with maxHeightRoleName
 	GetColourRoleHeight(context.Context, IDomainSession, IDomainGuild) (int, error)
@@ -53,11 +77,14 @@ type IColoursDomain interface {
 // IDomainSession wraps methods of discordgo.Session that IColoursDomain will use.
 type IDomainSession interface {
 	GuildMember(ctx context.Context, guildID, userID string) (IDomainMember, error)
+	GuildMembers(ctx context.Context, guildID string) ([]IDomainMember, error)
 	GuildMemberRoleAdd(ctx context.Context, guildID, userID, roleID string) error
+	GuildMemberRoleRemove(ctx context.Context, guildID, userID, roleID string) error
 	GuildRole(ctx context.Context, guildID, roleID string) (IDomainRole, error)
 	GuildRoles(ctx context.Context, guildID string) ([]IDomainRole, error)
 	GuildRoleCreate(ctx context.Context, guildID string, name string, colour int) (roleID string, err error)
 	GuildRoleEdit(ctx context.Context, guildID, roleID, name string, colour int) error
+	GuildRoleDelete(ctx context.Context, guildID, roleID string) error
 	GuildRoleReorder(ctx context.Context, guildID string, roles []IDomainRole) error
 }
 
@@ -72,6 +99,7 @@ type IDomainMember interface {
 	Guild() IDomainGuild
 	UserID() string
 	Username() string
+	DisplayName() string
 	Nick() string
 	Roles() []IDomainRole
 
@@ -88,13 +116,27 @@ type IDomainRole interface {
 	CacheKey() string
 }
 
-// IDomainRepository describes methods that IColoursDomain uses to fetch/store data.
+// IDomainRepository describes methods that IColoursDomain uses to fetch/store data. A user's
+// state and history are kept separately in each guild they are a member of.
 type IDomainRepository interface {
 	FetchUserState(context.Context, IDomainMember, Reason) (time.Time, error)
 	FetchUserHistory(context.Context, IDomainMember, time.Time) ([]*ColoursLogRecord, error)
+	FetchLastChanges(context.Context, string) (map[string]time.Time, error)
 	UpdateMutate(context.Context, IDomainMember, *Colour) error
 	UpdateReroll(context.Context, IDomainMember, *Colour) error
+	UpdatePick(context.Context, IDomainMember, *Colour) error
+	UpdateSwap(context.Context, *ColourSwap) error
 	UpdateRerollPenalty(context.Context, IDomainMember, time.Time) error
 	UpdateFreeze(context.Context, IDomainMember) error
 	UpdateUnfreeze(context.Context, IDomainMember) error
+	FetchLegacyStateClaimed(context.Context) (bool, error)
+	ClaimLegacyState(context.Context, string) (int64, error)
+	FetchPalette(context.Context, string) (*Palette, error)
+	UpdatePalette(context.Context, string, *Palette) error
+	FetchColourRole(context.Context, IDomainMember) (string, error)
+	FetchColourRoles(context.Context, string) (map[string]string, error)
+	UpdateColourRole(context.Context, IDomainMember, string) error
+	DeleteColourRole(ctx context.Context, guildID, roleID string) error
+	FetchRolesAdopted(context.Context, string) (bool, error)
+	UpdateRolesAdopted(ctx context.Context, guildID string, roles map[string]string) error
 }
@@ -35,6 +35,21 @@ func (m *MockIColoursDomain) EXPECT() *MockIColoursDomainMockRecorder {
 	return m.recorder
 }
 
+// AdoptColourRoles mocks base method.
+func (m *MockIColoursDomain) AdoptColourRoles(arg0 context.Context, arg1 IDomainSession, arg2 IDomainGuild) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdoptColourRoles", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdoptColourRoles indicates an expected call of AdoptColourRoles.
+func (mr *MockIColoursDomainMockRecorder) AdoptColourRoles(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdoptColourRoles", reflect.TypeOf((*MockIColoursDomain)(nil).AdoptColourRoles), arg0, arg1, arg2)
+}
+
 // AssignColourRole mocks base method.
 func (m *MockIColoursDomain) AssignColourRole(arg0 context.Context, arg1 IDomainSession, arg2 IDomainMember, arg3 IDomainRole) error {
 	m.ctrl.T.Helper()
@@ -49,6 +64,21 @@ func (mr *MockIColoursDomainMockRecorder) AssignColourRole(arg0, arg1, arg2, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignColourRole", reflect.TypeOf((*MockIColoursDomain)(nil).AssignColourRole), arg0, arg1, arg2, arg3)
 }
 
+// CleanupColourRoles mocks base method.
+func (m *MockIColoursDomain) CleanupColourRoles(ctx context.Context, s IDomainSession, guild IDomainGuild, opts CleanupOptions) (*CleanupReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CleanupColourRoles", ctx, s, guild, opts)
+	ret0, _ := ret[0].(*CleanupReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CleanupColourRoles indicates an expected call of CleanupColourRoles.
+func (mr *MockIColoursDomainMockRecorder) CleanupColourRoles(ctx, s, guild, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanupColourRoles", reflect.TypeOf((*MockIColoursDomain)(nil).CleanupColourRoles), ctx, s, guild, opts)
+}
+
 // CreateColourRole mocks base method.
 func (m *MockIColoursDomain) CreateColourRole(arg0 context.Context, arg1 IDomainSession, arg2 IDomainMember, arg3 *Colour) (IDomainRole, error) {
 	m.ctrl.T.Helper()
@@ -79,11 +109,12 @@ func (mr *MockIColoursDomainMockRecorder) Freeze(arg0, arg1 interface{}) *gomock
 }
 
 // GetColourRole mocks base method.
-func (m *MockIColoursDomain) GetColourRole(arg0 context.Context, arg1 IDomainMember) IDomainRole {
+func (m *MockIColoursDomain) GetColourRole(arg0 context.Context, arg1 IDomainMember) (IDomainRole, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetColourRole", arg0, arg1)
 	ret0, _ := ret[0].(IDomainRole)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetColourRole indicates an expected call of GetColourRole.
@@ -121,6 +152,21 @@ func (mr *MockIColoursDomainMockRecorder) GetColourRoleName(arg0, arg1 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetColourRoleName", reflect.TypeOf((*MockIColoursDomain)(nil).GetColourRoleName), arg0, arg1)
 }
 
+// GetGallery mocks base method.
+func (m *MockIColoursDomain) GetGallery(arg0 context.Context, arg1 IDomainSession, arg2 IDomainGuild) ([]*GalleryEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGallery", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*GalleryEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGallery indicates an expected call of GetGallery.
+func (mr *MockIColoursDomainMockRecorder) GetGallery(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGallery", reflect.TypeOf((*MockIColoursDomain)(nil).GetGallery), arg0, arg1, arg2)
+}
+
 // GetHistory mocks base method.
 func (m *MockIColoursDomain) GetHistory(arg0 context.Context, arg1 IDomainMember) (*History, error) {
 	m.ctrl.T.Helper()
@@ -167,6 +213,22 @@ func (mr *MockIColoursDomainMockRecorder) GetLastMutate(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastMutate", reflect.TypeOf((*MockIColoursDomain)(nil).GetLastMutate), arg0, arg1)
 }
 
+// GetLastPick mocks base method.
+func (m *MockIColoursDomain) GetLastPick(arg0 context.Context, arg1 IDomainMember) (time.Time, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastPick", arg0, arg1)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetLastPick indicates an expected call of GetLastPick.
+func (mr *MockIColoursDomainMockRecorder) GetLastPick(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastPick", reflect.TypeOf((*MockIColoursDomain)(nil).GetLastPick), arg0, arg1)
+}
+
 // GetLastReroll mocks base method.
 func (m *MockIColoursDomain) GetLastReroll(arg0 context.Context, arg1 IDomainMember) (time.Time, bool, error) {
 	m.ctrl.T.Helper()
@@ -183,6 +245,36 @@ func (mr *MockIColoursDomainMockRecorder) GetLastReroll(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastReroll", reflect.TypeOf((*MockIColoursDomain)(nil).GetLastReroll), arg0, arg1)
 }
 
+// GetPalette mocks base method.
+func (m *MockIColoursDomain) GetPalette(arg0 context.Context, arg1 IDomainGuild) (*Palette, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPalette", arg0, arg1)
+	ret0, _ := ret[0].(*Palette)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPalette indicates an expected call of GetPalette.
+func (mr *MockIColoursDomainMockRecorder) GetPalette(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPalette", reflect.TypeOf((*MockIColoursDomain)(nil).GetPalette), arg0, arg1)
+}
+
+// GetPickCooldownEndTime mocks base method.
+func (m *MockIColoursDomain) GetPickCooldownEndTime(arg0 context.Context, arg1 IDomainMember) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPickCooldownEndTime", arg0, arg1)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPickCooldownEndTime indicates an expected call of GetPickCooldownEndTime.
+func (mr *MockIColoursDomainMockRecorder) GetPickCooldownEndTime(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPickCooldownEndTime", reflect.TypeOf((*MockIColoursDomain)(nil).GetPickCooldownEndTime), arg0, arg1)
+}
+
 // GetRerollCooldownEndTime mocks base method.
 func (m *MockIColoursDomain) GetRerollCooldownEndTime(arg0 context.Context, arg1 IDomainMember) (time.Time, error) {
 	m.ctrl.T.Helper()
@@ -199,11 +291,12 @@ func (mr *MockIColoursDomainMockRecorder) GetRerollCooldownEndTime(arg0, arg1 in
 }
 
 // HasColourRole mocks base method.
-func (m *MockIColoursDomain) HasColourRole(arg0 context.Context, arg1 IDomainMember) bool {
+func (m *MockIColoursDomain) HasColourRole(arg0 context.Context, arg1 IDomainMember) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "HasColourRole", arg0, arg1)
 	ret0, _ := ret[0].(bool)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // HasColourRole indicates an expected call of HasColourRole.
@@ -227,6 +320,21 @@ func (mr *MockIColoursDomainMockRecorder) Mutate(arg0, arg1, arg2 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mutate", reflect.TypeOf((*MockIColoursDomain)(nil).Mutate), arg0, arg1, arg2)
 }
 
+// Pick mocks base method.
+func (m *MockIColoursDomain) Pick(arg0 context.Context, arg1 IDomainSession, arg2 IDomainMember, arg3 *Colour) (*Colour, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pick", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*Colour)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Pick indicates an expected call of Pick.
+func (mr *MockIColoursDomainMockRecorder) Pick(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pick", reflect.TypeOf((*MockIColoursDomain)(nil).Pick), arg0, arg1, arg2, arg3)
+}
+
 // Reroll mocks base method.
 func (m *MockIColoursDomain) Reroll(arg0 context.Context, arg1 IDomainSession, arg2 IDomainMember) (*Colour, error) {
 	m.ctrl.T.Helper()
@@ -242,6 +350,20 @@ func (mr *MockIColoursDomainMockRecorder) Reroll(arg0, arg1, arg2 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reroll", reflect.TypeOf((*MockIColoursDomain)(nil).Reroll), arg0, arg1, arg2)
 }
 
+// SetPalette mocks base method.
+func (m *MockIColoursDomain) SetPalette(arg0 context.Context, arg1 IDomainGuild, arg2 *Palette) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPalette", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPalette indicates an expected call of SetPalette.
+func (mr *MockIColoursDomainMockRecorder) SetPalette(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPalette", reflect.TypeOf((*MockIColoursDomain)(nil).SetPalette), arg0, arg1, arg2)
+}
+
 // SetRoleHeight mocks base method.
 func (m *MockIColoursDomain) SetRoleHeight(arg0 context.Context, arg1 IDomainSession, arg2 IDomainGuild, arg3 string, arg4 int) error {
 	m.ctrl.T.Helper()
@@ -256,6 +378,21 @@ func (mr *MockIColoursDomainMockRecorder) SetRoleHeight(arg0, arg1, arg2, arg3,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRoleHeight", reflect.TypeOf((*MockIColoursDomain)(nil).SetRoleHeight), arg0, arg1, arg2, arg3, arg4)
 }
 
+// Swap mocks base method.
+func (m *MockIColoursDomain) Swap(arg0 context.Context, arg1 IDomainSession, arg2, arg3 IDomainMember) (*ColourSwap, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Swap", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*ColourSwap)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Swap indicates an expected call of Swap.
+func (mr *MockIColoursDomainMockRecorder) Swap(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Swap", reflect.TypeOf((*MockIColoursDomain)(nil).Swap), arg0, arg1, arg2, arg3)
+}
+
 // Unfreeze mocks base method.
 func (m *MockIColoursDomain) Unfreeze(arg0 context.Context, arg1 IDomainMember) error {
 	m.ctrl.T.Helper()
@@ -322,6 +459,35 @@ func (mr *MockIDomainSessionMockRecorder) GuildMemberRoleAdd(ctx, guildID, userI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildMemberRoleAdd", reflect.TypeOf((*MockIDomainSession)(nil).GuildMemberRoleAdd), ctx, guildID, userID, roleID)
 }
 
+// GuildMemberRoleRemove mocks base method.
+func (m *MockIDomainSession) GuildMemberRoleRemove(ctx context.Context, guildID, userID, roleID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuildMemberRoleRemove", ctx, guildID, userID, roleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GuildMemberRoleRemove indicates an expected call of GuildMemberRoleRemove.
+func (mr *MockIDomainSessionMockRecorder) GuildMemberRoleRemove(ctx, guildID, userID, roleID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildMemberRoleRemove", reflect.TypeOf((*MockIDomainSession)(nil).GuildMemberRoleRemove), ctx, guildID, userID, roleID)
+}
+
+// GuildMembers mocks base method.
+func (m *MockIDomainSession) GuildMembers(ctx context.Context, guildID string) ([]IDomainMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuildMembers", ctx, guildID)
+	ret0, _ := ret[0].([]IDomainMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GuildMembers indicates an expected call of GuildMembers.
+func (mr *MockIDomainSessionMockRecorder) GuildMembers(ctx, guildID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildMembers", reflect.TypeOf((*MockIDomainSession)(nil).GuildMembers), ctx, guildID)
+}
+
 // GuildRole mocks base method.
 func (m *MockIDomainSession) GuildRole(ctx context.Context, guildID, roleID string) (IDomainRole, error) {
 	m.ctrl.T.Helper()
@@ -352,6 +518,20 @@ func (mr *MockIDomainSessionMockRecorder) GuildRoleCreate(ctx, guildID, name, co
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildRoleCreate", reflect.TypeOf((*MockIDomainSession)(nil).GuildRoleCreate), ctx, guildID, name, colour)
 }
 
+// GuildRoleDelete mocks base method.
+func (m *MockIDomainSession) GuildRoleDelete(ctx context.Context, guildID, roleID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuildRoleDelete", ctx, guildID, roleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GuildRoleDelete indicates an expected call of GuildRoleDelete.
+func (mr *MockIDomainSessionMockRecorder) GuildRoleDelete(ctx, guildID, roleID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildRoleDelete", reflect.TypeOf((*MockIDomainSession)(nil).GuildRoleDelete), ctx, guildID, roleID)
+}
+
 // GuildRoleEdit mocks base method.
 func (m *MockIDomainSession) GuildRoleEdit(ctx context.Context, guildID, roleID, name string, colour int) error {
 	m.ctrl.T.Helper()
@@ -469,6 +649,20 @@ func (mr *MockIDomainMemberMockRecorder) CacheKey() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CacheKey", reflect.TypeOf((*MockIDomainMember)(nil).CacheKey))
 }
 
+// DisplayName mocks base method.
+func (m *MockIDomainMember) DisplayName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisplayName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// DisplayName indicates an expected call of DisplayName.
+func (mr *MockIDomainMemberMockRecorder) DisplayName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisplayName", reflect.TypeOf((*MockIDomainMember)(nil).DisplayName))
+}
+
 // Guild mocks base method.
 func (m *MockIDomainMember) Guild() IDomainGuild {
 	m.ctrl.T.Helper()
@@ -641,6 +835,125 @@ func (m *MockIDomainRepository) EXPECT() *MockIDomainRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ClaimLegacyState mocks base method.
+func (m *MockIDomainRepository) ClaimLegacyState(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimLegacyState", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimLegacyState indicates an expected call of ClaimLegacyState.
+func (mr *MockIDomainRepositoryMockRecorder) ClaimLegacyState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimLegacyState", reflect.TypeOf((*MockIDomainRepository)(nil).ClaimLegacyState), arg0, arg1)
+}
+
+// DeleteColourRole mocks base method.
+func (m *MockIDomainRepository) DeleteColourRole(ctx context.Context, guildID, roleID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteColourRole", ctx, guildID, roleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteColourRole indicates an expected call of DeleteColourRole.
+func (mr *MockIDomainRepositoryMockRecorder) DeleteColourRole(ctx, guildID, roleID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteColourRole", reflect.TypeOf((*MockIDomainRepository)(nil).DeleteColourRole), ctx, guildID, roleID)
+}
+
+// FetchColourRole mocks base method.
+func (m *MockIDomainRepository) FetchColourRole(arg0 context.Context, arg1 IDomainMember) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchColourRole", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchColourRole indicates an expected call of FetchColourRole.
+func (mr *MockIDomainRepositoryMockRecorder) FetchColourRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchColourRole", reflect.TypeOf((*MockIDomainRepository)(nil).FetchColourRole), arg0, arg1)
+}
+
+// FetchColourRoles mocks base method.
+func (m *MockIDomainRepository) FetchColourRoles(arg0 context.Context, arg1 string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchColourRoles", arg0, arg1)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchColourRoles indicates an expected call of FetchColourRoles.
+func (mr *MockIDomainRepositoryMockRecorder) FetchColourRoles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchColourRoles", reflect.TypeOf((*MockIDomainRepository)(nil).FetchColourRoles), arg0, arg1)
+}
+
+// FetchLastChanges mocks base method.
+func (m *MockIDomainRepository) FetchLastChanges(arg0 context.Context, arg1 string) (map[string]time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchLastChanges", arg0, arg1)
+	ret0, _ := ret[0].(map[string]time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchLastChanges indicates an expected call of FetchLastChanges.
+func (mr *MockIDomainRepositoryMockRecorder) FetchLastChanges(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchLastChanges", reflect.TypeOf((*MockIDomainRepository)(nil).FetchLastChanges), arg0, arg1)
+}
+
+// FetchLegacyStateClaimed mocks base method.
+func (m *MockIDomainRepository) FetchLegacyStateClaimed(arg0 context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchLegacyStateClaimed", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchLegacyStateClaimed indicates an expected call of FetchLegacyStateClaimed.
+func (mr *MockIDomainRepositoryMockRecorder) FetchLegacyStateClaimed(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchLegacyStateClaimed", reflect.TypeOf((*MockIDomainRepository)(nil).FetchLegacyStateClaimed), arg0)
+}
+
+// FetchPalette mocks base method.
+func (m *MockIDomainRepository) FetchPalette(arg0 context.Context, arg1 string) (*Palette, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchPalette", arg0, arg1)
+	ret0, _ := ret[0].(*Palette)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchPalette indicates an expected call of FetchPalette.
+func (mr *MockIDomainRepositoryMockRecorder) FetchPalette(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchPalette", reflect.TypeOf((*MockIDomainRepository)(nil).FetchPalette), arg0, arg1)
+}
+
+// FetchRolesAdopted mocks base method.
+func (m *MockIDomainRepository) FetchRolesAdopted(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchRolesAdopted", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchRolesAdopted indicates an expected call of FetchRolesAdopted.
+func (mr *MockIDomainRepositoryMockRecorder) FetchRolesAdopted(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchRolesAdopted", reflect.TypeOf((*MockIDomainRepository)(nil).FetchRolesAdopted), arg0, arg1)
+}
+
 // FetchUserHistory mocks base method.
 func (m *MockIDomainRepository) FetchUserHistory(arg0 context.Context, arg1 IDomainMember, arg2 time.Time) ([]*ColoursLogRecord, error) {
 	m.ctrl.T.Helper()
@@ -671,6 +984,20 @@ func (mr *MockIDomainRepositoryMockRecorder) FetchUserState(arg0, arg1, arg2 int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchUserState", reflect.TypeOf((*MockIDomainRepository)(nil).FetchUserState), arg0, arg1, arg2)
 }
 
+// UpdateColourRole mocks base method.
+func (m *MockIDomainRepository) UpdateColourRole(arg0 context.Context, arg1 IDomainMember, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateColourRole", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateColourRole indicates an expected call of UpdateColourRole.
+func (mr *MockIDomainRepositoryMockRecorder) UpdateColourRole(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateColourRole", reflect.TypeOf((*MockIDomainRepository)(nil).UpdateColourRole), arg0, arg1, arg2)
+}
+
 // UpdateFreeze mocks base method.
 func (m *MockIDomainRepository) UpdateFreeze(arg0 context.Context, arg1 IDomainMember) error {
 	m.ctrl.T.Helper()
@@ -699,6 +1026,34 @@ func (mr *MockIDomainRepositoryMockRecorder) UpdateMutate(arg0, arg1, arg2 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMutate", reflect.TypeOf((*MockIDomainRepository)(nil).UpdateMutate), arg0, arg1, arg2)
 }
 
+// UpdatePalette mocks base method.
+func (m *MockIDomainRepository) UpdatePalette(arg0 context.Context, arg1 string, arg2 *Palette) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePalette", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePalette indicates an expected call of UpdatePalette.
+func (mr *MockIDomainRepositoryMockRecorder) UpdatePalette(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePalette", reflect.TypeOf((*MockIDomainRepository)(nil).UpdatePalette), arg0, arg1, arg2)
+}
+
+// UpdatePick mocks base method.
+func (m *MockIDomainRepository) UpdatePick(arg0 context.Context, arg1 IDomainMember, arg2 *Colour) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePick", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePick indicates an expected call of UpdatePick.
+func (mr *MockIDomainRepositoryMockRecorder) UpdatePick(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePick", reflect.TypeOf((*MockIDomainRepository)(nil).UpdatePick), arg0, arg1, arg2)
+}
+
 // UpdateReroll mocks base method.
 func (m *MockIDomainRepository) UpdateReroll(arg0 context.Context, arg1 IDomainMember, arg2 *Colour) error {
 	m.ctrl.T.Helper()
@@ -727,6 +1082,34 @@ func (mr *MockIDomainRepositoryMockRecorder) UpdateRerollPenalty(arg0, arg1, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRerollPenalty", reflect.TypeOf((*MockIDomainRepository)(nil).UpdateRerollPenalty), arg0, arg1, arg2)
 }
 
+// UpdateRolesAdopted mocks base method.
+func (m *MockIDomainRepository) UpdateRolesAdopted(ctx context.Context, guildID string, roles map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRolesAdopted", ctx, guildID, roles)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRolesAdopted indicates an expected call of UpdateRolesAdopted.
+func (mr *MockIDomainRepositoryMockRecorder) UpdateRolesAdopted(ctx, guildID, roles interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRolesAdopted", reflect.TypeOf((*MockIDomainRepository)(nil).UpdateRolesAdopted), ctx, guildID, roles)
+}
+
+// UpdateSwap mocks base method.
+func (m *MockIDomainRepository) UpdateSwap(arg0 context.Context, arg1 *ColourSwap) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSwap", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSwap indicates an expected call of UpdateSwap.
+func (mr *MockIDomainRepositoryMockRecorder) UpdateSwap(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSwap", reflect.TypeOf((*MockIDomainRepository)(nil).UpdateSwap), arg0, arg1)
+}
+
 // UpdateUnfreeze mocks base method.
 func (m *MockIDomainRepository) UpdateUnfreeze(arg0 context.Context, arg1 IDomainMember) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,164 @@
+package colours
+
+// mutation.go has the strategies that mutations change colours by.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/fiffu/arisa3/lib"
+)
+
+var ErrMutationStrategy = errors.New("invalid mutation strategy")
+
+// Mutator makes the small change to a colour that a mutation applies.
+type Mutator interface {
+	Mutate(*Colour) *Colour
+}
+
+// MutationStrategy names a Mutator in the config.
+type MutationStrategy string
+
+const (
+	// RGBMutation steps each of red, green and blue separately. It drifts towards greys and the
+	// corners of the RGB cube, where channels stick at 0 or 1, and some steps look much bigger
+	// than others.
+	RGBMutation MutationStrategy = "rgb"
+	// HueMutation walks around the hue circle, keeping saturation and value.
+	HueMutation MutationStrategy = "hue"
+	// LabMutation steps in a random direction in CIELAB, by the same ΔE every time.
+	LabMutation MutationStrategy = "lab"
+	// RevertingMutation walks around the hue circle while pulling saturation and value back
+	// towards the middle of the ranges that rerolls draw from.
+	RevertingMutation MutationStrategy = "reverting"
+)
+
+var mutators = map[MutationStrategy]Mutator{
+	RGBMutation:       rgbMutator{},
+	HueMutation:       hueMutator{},
+	LabMutation:       labMutator{step: labStep},
+	RevertingMutation: revertingMutator{},
+}
+
+func validateMutationStrategy(strategy string) error {
+	if _, ok := mutators[MutationStrategy(strategy)]; ok || strategy == "" {
+		return nil
+	}
+	names := make([]string, 0, len(mutators))
+	for name := range mutators {
+		names = append(names, "'"+string(name)+"'")
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%w: '%s', wanted one of %s", ErrMutationStrategy, strategy, strings.Join(names, ", "))
+}
+
+// mutatorFor returns the Mutator of a valid strategy, and the RGB one if the strategy is unset.
+func mutatorFor(strategy string) Mutator {
+	if mutator, ok := mutators[MutationStrategy(strategy)]; ok {
+		return mutator
+	}
+	return mutators[RGBMutation]
+}
+
+// rgbMutator implements Mutator with Colour.Nudge.
+type rgbMutator struct{}
+
+func (rgbMutator) Mutate(c *Colour) *Colour { return c.Nudge() }
+
+// hueMutator implements Mutator by turning the hue 7 to 18 degrees either way.
+type hueMutator struct{}
+
+func (hueMutator) Mutate(c *Colour) *Colour {
+	h, s, v := c.ToHSV()
+	step := lib.UniformRange(0.02, 0.05)
+	if lib.CoinFlip() {
+		step *= -1
+	}
+	return (&Colour{}).FromHSV(math.Mod(h+step+1, 1), s, v)
+}
+
+// The ΔE of each step that labMutator takes, which is about the size of an average RGB step.
+const labStep = 8.0
+
+// How many directions labMutator tries before settling for a shorter step in one of them.
+const labMutatorAttempts = 20
+
+// labMutator implements Mutator with steps of a fixed ΔE in random directions.
+type labMutator struct {
+	step float64
+}
+
+func (m labMutator) Mutate(c *Colour) *Colour {
+	from := c.ToLab()
+	best := c
+	bestStep := 0.0
+	for i := 0; i < labMutatorAttempts; i++ {
+		// Normally distributed coordinates give a direction uniformly at random
+		dx, dy, dz := rand.NormFloat64(), rand.NormFloat64(), rand.NormFloat64()
+		norm := math.Sqrt(dx*dx + dy*dy + dz*dz)
+		along := func(t float64) Lab {
+			return Lab{from.L + t*dx/norm, from.A + t*dy/norm, from.B + t*dz/norm}
+		}
+
+		// ΔE grows with distance along the direction, so search for the distance that gives the step.
+		// Colours are clamped into the gamut, which can move them further, so measure them as clamped.
+		clamped := func(t float64) (*Colour, bool) {
+			return (&Colour{}).FromLab(along(t))
+		}
+		t := searchDistance(func(t float64) bool {
+			colour, _ := clamped(t)
+			return DeltaE(from, colour.ToLab()) <= m.step
+		}, 4*m.step)
+
+		colour, inGamut := clamped(t)
+		if inGamut {
+			return colour
+		}
+		// Steps that leave the gamut near its edges are shorter, so keep the longest
+		if step := DeltaE(from, colour.ToLab()); step > bestStep {
+			best, bestStep = colour, step
+		}
+	}
+	return best
+}
+
+// searchDistance finds the furthest distance up to limit where below holds, assuming that it holds
+// up to some distance and not beyond.
+func searchDistance(below func(t float64) bool, limit float64) float64 {
+	lo, hi := 0.0, limit
+	for j := 0; j < 32; j++ {
+		mid := (lo + hi) / 2
+		if below(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// Where revertingMutator pulls saturation and value towards, how strongly, and how far they
+// wander from it on each step.
+const (
+	revertingMean       = 0.7
+	revertingPull       = 0.15
+	revertingVolatility = 0.05
+)
+
+// revertingMutator implements Mutator with a hue walk, like hueMutator, and a mean-reverting walk
+// on saturation and value, so that colours neither fade to grey nor stick at full brightness.
+type revertingMutator struct{}
+
+func (revertingMutator) Mutate(c *Colour) *Colour {
+	h, s, v := c.ToHSV()
+	clamp := lib.Clamper(0, 1)
+	revert := func(x float64) float64 {
+		return clamp(x + revertingPull*(revertingMean-x) + revertingVolatility*rand.NormFloat64())
+	}
+	h = math.Mod(h+0.03*rand.NormFloat64()+1, 1)
+	return (&Colour{}).FromHSV(h, revert(s), revert(v))
+}
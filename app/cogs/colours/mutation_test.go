@@ -0,0 +1,137 @@
+package colours
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateMutationStrategy(t *testing.T) {
+	for _, strategy := range []string{"", "rgb", "hue", "lab", "reverting"} {
+		assert.NoError(t, validateMutationStrategy(strategy))
+	}
+	err := validateMutationStrategy("wiggle")
+	assert.ErrorIs(t, err, ErrMutationStrategy)
+	assert.Contains(t, err.Error(), "'hue', 'lab', 'reverting', 'rgb'")
+}
+
+func Test_Config_mutator(t *testing.T) {
+	cfg := &Config{GuildMutationStrategies: map[string]string{"1": "lab"}}
+	assert.NoError(t, cfg.validate())
+	assert.Equal(t, mutators[LabMutation], cfg.mutator("1"))
+	assert.Equal(t, mutators[RGBMutation], cfg.mutator("2"))
+
+	cfg.MutationStrategy = "hue"
+	assert.Equal(t, mutators[HueMutation], cfg.mutator("2"))
+
+	cfg.GuildMutationStrategies["3"] = "wiggle"
+	assert.ErrorIs(t, cfg.validate(), ErrMutationStrategy)
+}
+
+func Test_labMutator(t *testing.T) {
+	rand.Seed(1)
+	m := labMutator{step: labStep}
+	for _, hexcode := range []string{"1abc9c", "808080", "ff0000", "000000", "ffffff"} {
+		t.Run(hexcode, func(t *testing.T) {
+			from := (&Colour{}).FromRGBHex(hexcode)
+			for i := 0; i < 500; i++ {
+				// Steps that leave the gamut near its edges are clamped back into it, and shortened
+				// to stay within the step
+				step := DeltaE(from.ToLab(), m.Mutate(from).ToLab())
+				assert.LessOrEqual(t, step, labStep)
+				assert.Greater(t, step, labStep/2)
+			}
+		})
+	}
+}
+
+// walkStats describes where colours end up after many mutations, and how big the steps were.
+type walkStats struct {
+	meanSaturation, meanValue float64
+	// greys have saturation below 0.2, and corners have two or more channels at 0 or 1.
+	greys, corners float64
+	// The mean ΔE of each step, and its coefficient of variation, which is 0 if all steps look
+	// the same size.
+	meanStep, stepVariation float64
+}
+
+// simulateWalks mutates many colours from rerolls, many times over, and describes the result.
+func simulateWalks(m Mutator, walkers, steps int) walkStats {
+	var stats walkStats
+	var stepSum, stepSquares float64
+	for w := 0; w < walkers; w++ {
+		colour := (&Colour{}).Random()
+		for i := 0; i < steps; i++ {
+			next := m.Mutate(colour)
+			step := DeltaE(colour.ToLab(), next.ToLab())
+			stepSum += step
+			stepSquares += step * step
+			colour = next
+		}
+
+		_, s, v := colour.ToHSV()
+		stats.meanSaturation += s / float64(walkers)
+		stats.meanValue += v / float64(walkers)
+		if s < 0.2 {
+			stats.greys += 1 / float64(walkers)
+		}
+		stuck := 0
+		for _, channel := range []float64{colour.R, colour.G, colour.B} {
+			if channel < 0.005 || channel > 0.995 {
+				stuck++
+			}
+		}
+		if stuck >= 2 {
+			stats.corners += 1 / float64(walkers)
+		}
+	}
+	n := float64(walkers * steps)
+	stats.meanStep = stepSum / n
+	stats.stepVariation = math.Sqrt(stepSquares/n-stats.meanStep*stats.meanStep) / stats.meanStep
+	return stats
+}
+
+func Test_mutators_simulation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("simulation is slow")
+	}
+	const walkers, steps = 200, 300
+	rand.Seed(1)
+
+	results := make(map[MutationStrategy]walkStats)
+	for _, strategy := range []MutationStrategy{RGBMutation, HueMutation, LabMutation, RevertingMutation} {
+		stats := simulateWalks(mutators[strategy], walkers, steps)
+		results[strategy] = stats
+		t.Logf("%-9s saturation %.2f, value %.2f, greys %3.0f%%, corners %3.0f%%, step ΔE %.1f ± %.0f%%",
+			strategy, stats.meanSaturation, stats.meanValue, stats.greys*100, stats.corners*100,
+			stats.meanStep, stats.stepVariation*100)
+	}
+
+	// RGB steps get stuck in the corners of the cube, and vary a lot in size
+	rgb := results[RGBMutation]
+	assert.Greater(t, rgb.corners, 0.01)
+	assert.Greater(t, rgb.stepVariation, 0.3)
+
+	// Hue walks keep the saturation and value that colours were rerolled with
+	hue := results[HueMutation]
+	assert.InDelta(t, 0.7, hue.meanSaturation, 0.05)
+	assert.InDelta(t, 0.7, hue.meanValue, 0.05)
+	assert.Zero(t, hue.greys)
+	assert.Zero(t, hue.corners)
+
+	// CIELAB steps are all the same size. Unlike the others, the walk wanders evenly through the
+	// whole gamut, which has plenty of greys and muted colours.
+	lab := results[LabMutation]
+	assert.InDelta(t, labStep, lab.meanStep, 0.1)
+	assert.Less(t, lab.stepVariation, 0.05)
+	assert.Less(t, lab.corners, 0.02)
+
+	// Mean-reverting walks settle around the middle of the reroll ranges
+	reverting := results[RevertingMutation]
+	assert.InDelta(t, revertingMean, reverting.meanSaturation, 0.05)
+	assert.InDelta(t, revertingMean, reverting.meanValue, 0.05)
+	assert.Less(t, reverting.greys, 0.02)
+	assert.Less(t, reverting.corners, 0.02)
+}
@@ -0,0 +1,453 @@
+package colours
+
+// palette.go models guild palettes, which keep the colours given by rerolls and mutations to a
+// guild's chosen ranges.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fiffu/arisa3/lib"
+)
+
+var (
+	ErrInvalidPalette = errors.New("invalid palette")
+
+	// Like "hsv(200-260, 20-40, 90)", with hue in degrees and saturation and value in percent.
+	hsvRangePattern = regexp.MustCompile(`^hsv\(([^,]+),([^,]+),([^,]+)\)$`)
+
+	// palettePresets are ranges that can be named in a palette instead of spelled out.
+	palettePresets = map[string]string{
+		"pastel": "hsv(0-360, 20-40, 90-100)",
+		"neon":   "hsv(0-360, 90-100, 95-100)",
+		"earth":  "hsv(20-50, 35-65, 35-70)",
+		"cool":   "hsv(160-260, 45-80, 55-90)",
+		"warm":   "hsv(330-50, 55-85, 65-95)",
+	}
+)
+
+const (
+	// How many entries a palette can have.
+	maxPaletteEntries = 16
+
+	// How far the colours around an anchor colour can stray from it, by hue, and by saturation
+	// and value.
+	anchorHueSpread = 8.0 / 360
+	anchorSpread    = 0.08
+
+	// How far outside an entry a colour can be and still count as in it, since role colours are
+	// rounded to 8 bits per channel.
+	paletteTolerance = 1.0 / 255
+
+	// How many hues and saturations across each entry to try, when looking for readable colours.
+	readableGridSteps = 16
+)
+
+// Palette is a set of colour ranges. Colours are drawn from its entries with equal chance,
+// whatever their size. A nil Palette allows any colour.
+type Palette struct {
+	Entries []*PaletteEntry
+}
+
+// PaletteEntry is a range of colours, by hue, saturation and value, each from 0 to 1. Where
+// Hue[0] is greater than Hue[1], the range wraps around through red.
+type PaletteEntry struct {
+	// Label is the entry as written in a palette, like "pastel", "#1abc9c" or "hsv(0-30, 50-80, 90)".
+	Label string
+
+	Hue, Saturation, Value [2]float64
+}
+
+// ParsePalette reads a palette from a comma-separated list of entries. Each entry is a preset
+// (such as "pastel"), a colour for ParseColour to read, which stands for the colours close to it,
+// or an HSV range like "hsv(200-260, 20-40, 90-100)".
+func ParsePalette(spec string) (*Palette, error) {
+	palette := &Palette{}
+	for _, part := range splitPalette(spec) {
+		entry, err := parsePaletteEntry(part)
+		if err != nil {
+			return nil, err
+		}
+		palette.Entries = append(palette.Entries, entry)
+	}
+	switch {
+	case len(palette.Entries) == 0:
+		return nil, fmt.Errorf("%w: it has no colours", ErrInvalidPalette)
+	case len(palette.Entries) > maxPaletteEntries:
+		return nil, fmt.Errorf("%w: it has %d entries, wanted at most %d", ErrInvalidPalette, len(palette.Entries), maxPaletteEntries)
+	}
+	return palette, nil
+}
+
+// splitPalette splits a palette at the commas between its entries, leaving those within hsv().
+func splitPalette(spec string) []string {
+	parts := make([]string, 0)
+	depth, start := 0, 0
+	for i, char := range spec {
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, spec[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, spec[start:])
+
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return nonEmpty
+}
+
+func parsePaletteEntry(input string) (*PaletteEntry, error) {
+	lower := strings.ToLower(strings.TrimSpace(input))
+	if preset, ok := palettePresets[lower]; ok {
+		entry, err := parseHSVRange(preset)
+		if err != nil {
+			return nil, err
+		}
+		entry.Label = lower
+		return entry, nil
+	}
+	if strings.HasPrefix(lower, "hsv(") {
+		return parseHSVRange(lower)
+	}
+	colour, err := ParseColour(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPalette, err)
+	}
+	return anchorEntry(colour), nil
+}
+
+// parseHSVRange reads an entry like "hsv(200-260, 20-40, 90-100)". Any part can be a single value.
+func parseHSVRange(input string) (*PaletteEntry, error) {
+	match := hsvRangePattern.FindStringSubmatch(strings.ReplaceAll(input, " ", ""))
+	if match == nil {
+		return nil, fmt.Errorf("%w: '%s', wanted a range like hsv(200-260, 20-40, 90-100)", ErrInvalidPalette, input)
+	}
+	hue, err := parseRange(match[1], 360)
+	if err != nil {
+		return nil, err
+	}
+	saturation, err := parseRange(match[2], 100)
+	if err != nil {
+		return nil, err
+	}
+	value, err := parseRange(match[3], 100)
+	if err != nil {
+		return nil, err
+	}
+	if saturation[0] > saturation[1] || value[0] > value[1] {
+		return nil, fmt.Errorf("%w: '%s', saturation and value should go from low to high", ErrInvalidPalette, input)
+	}
+	entry := &PaletteEntry{Hue: hue, Saturation: saturation, Value: value}
+	entry.Label = fmt.Sprintf("hsv(%s, %s, %s)",
+		formatRange(hue, 360), formatRange(saturation, 100), formatRange(value, 100))
+	return entry, nil
+}
+
+// parseRange reads a range like "20-40", or a single value, from 0 to max. The range is scaled to
+// go from 0 to 1.
+func parseRange(input string, max float64) ([2]float64, error) {
+	lowText, highText, isRange := strings.Cut(input, "-")
+	if !isRange {
+		highText = lowText
+	}
+	low, errLow := strconv.ParseFloat(lowText, 64)
+	high, errHigh := strconv.ParseFloat(highText, 64)
+	if errLow != nil || errHigh != nil || low < 0 || low > max || high < 0 || high > max {
+		return [2]float64{}, fmt.Errorf("%w: '%s', wanted numbers from 0 to %g", ErrInvalidPalette, input, max)
+	}
+	return [2]float64{low / max, high / max}, nil
+}
+
+func formatRange(r [2]float64, max float64) string {
+	low := strconv.FormatFloat(math.Round(r[0]*max*10)/10, 'f', -1, 64)
+	high := strconv.FormatFloat(math.Round(r[1]*max*10)/10, 'f', -1, 64)
+	if low == high {
+		return low
+	}
+	return low + "-" + high
+}
+
+// anchorEntry is the range of colours close to the given colour.
+func anchorEntry(colour *Colour) *PaletteEntry {
+	h, s, v := colour.ToHSV()
+	clamp := lib.Clamper(0, 1)
+	return &PaletteEntry{
+		Label:      "#" + colour.ToHexcode(),
+		Hue:        [2]float64{math.Mod(h-anchorHueSpread+1, 1), math.Mod(h+anchorHueSpread, 1)},
+		Saturation: [2]float64{clamp(s - anchorSpread), clamp(s + anchorSpread)},
+		Value:      [2]float64{clamp(v - anchorSpread), clamp(v + anchorSpread)},
+	}
+}
+
+// String writes the palette in the form that ParsePalette reads.
+func (p *Palette) String() string {
+	if p == nil {
+		return ""
+	}
+	labels := make([]string, len(p.Entries))
+	for i, entry := range p.Entries {
+		labels[i] = entry.Label
+	}
+	return strings.Join(labels, ", ")
+}
+
+// Random draws a colour from a random entry of the palette.
+func (p *Palette) Random() *Colour {
+	if p == nil {
+		return (&Colour{}).Random()
+	}
+	return p.Entries[rand.Intn(len(p.Entries))].random()
+}
+
+// Nudge slightly adjusts the colour with the mutator, and then brings it into the palette if it
+// strayed out. A colour from before the palette was set is brought into it on its next nudge.
+func (p *Palette) Nudge(c *Colour, m Mutator) *Colour {
+	nudged := m.Mutate(c)
+	if p == nil || p.Contains(nudged) {
+		return nudged
+	}
+	return p.nearest(nudged).clamp(nudged)
+}
+
+// Readable returns a colour in the palette with at least the minimum contrast, close to the given
+// one. The colour is lightened or darkened if that keeps it in the palette, and otherwise its value
+// is corrected within the nearest entry. Where no value will do at its hue and saturation, the
+// nearest readable colour across the palette is used. Colours stay in the palette even if none of
+// its colours are readable, as when the minimum was raised after the palette was set.
+func (p *Palette) Readable(c *Colour, min float64) *Colour {
+	if c.Contrast().Min() >= min {
+		return c
+	}
+	if corrected := c.Readable(min); p.Contains(corrected) {
+		return corrected
+	}
+	entry := p.nearest(c)
+	if corrected := entry.readableValue(c, min); corrected != nil {
+		return corrected
+	}
+
+	_, _, v := c.ToHSV()
+	lab := c.ToLab()
+	best := entry.clamp(c)
+	bestDistance := math.Inf(1)
+	for _, entry := range p.Entries {
+		entry.grid(func(h, s float64) {
+			corrected := entry.readableValue((&Colour{}).FromHSV(h, s, v), min)
+			if corrected == nil {
+				return
+			}
+			if distance := DeltaE(lab, corrected.ToLab()); distance < bestDistance {
+				best, bestDistance = corrected, distance
+			}
+		})
+	}
+	return best
+}
+
+// CheckContrast returns ErrInvalidPalette if any of the palette's entries has no colours with the
+// minimum contrast.
+func (p *Palette) CheckContrast(min float64) error {
+	if p == nil {
+		return nil
+	}
+	unreadable := make([]string, 0)
+	for _, entry := range p.Entries {
+		if !entry.canBeReadable(min) {
+			unreadable = append(unreadable, "'"+entry.Label+"'")
+		}
+	}
+	if len(unreadable) > 0 {
+		return fmt.Errorf("%w: %s can't be readable on both Discord themes, which needs a contrast of %.2f here",
+			ErrInvalidPalette, strings.Join(unreadable, ", "), min)
+	}
+	return nil
+}
+
+// Contains reports whether the colour falls in any of the palette's entries.
+func (p *Palette) Contains(c *Colour) bool {
+	if p == nil {
+		return true
+	}
+	for _, entry := range p.Entries {
+		if entry.contains(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// nearest finds the entry with the colour that looks most like the given one, by ΔE.
+func (p *Palette) nearest(c *Colour) *PaletteEntry {
+	lab := c.ToLab()
+	var best *PaletteEntry
+	bestDistance := math.Inf(1)
+	for _, entry := range p.Entries {
+		if distance := DeltaE(lab, entry.clamp(c).ToLab()); distance < bestDistance {
+			best, bestDistance = entry, distance
+		}
+	}
+	return best
+}
+
+// Swatch is a row of colours from across each of the palette's entries, to preview the palette.
+func (p *Palette) Swatch(perEntry int) []*Colour {
+	if p == nil {
+		return nil
+	}
+	colours := make([]*Colour, 0, len(p.Entries)*perEntry)
+	for _, entry := range p.Entries {
+		colours = append(colours, entry.samples(perEntry)...)
+	}
+	return colours
+}
+
+// hueWidth is how much of the hue circle the entry covers.
+func (e *PaletteEntry) hueWidth() float64 {
+	if e.Hue[0] > e.Hue[1] {
+		return e.Hue[1] + 1 - e.Hue[0]
+	}
+	return e.Hue[1] - e.Hue[0]
+}
+
+// hueAt is the hue at the given fraction of the way through the entry's hues.
+func (e *PaletteEntry) hueAt(t float64) float64 {
+	if e.Hue[0] > e.Hue[1] {
+		return math.Mod(e.Hue[0]+t*e.hueWidth(), 1)
+	}
+	return e.Hue[0] + t*e.hueWidth()
+}
+
+func (e *PaletteEntry) random() *Colour {
+	return (&Colour{}).FromHSV(
+		e.hueAt(rand.Float64()),
+		lib.UniformRange(e.Saturation[0], e.Saturation[1]),
+		lib.UniformRange(e.Value[0], e.Value[1]),
+	)
+}
+
+// hueDistance is how far apart two hues are, going whichever way round the hue circle is shorter.
+func hueDistance(x, y float64) float64 {
+	d := math.Abs(x - y)
+	return math.Min(d, 1-d)
+}
+
+func (e *PaletteEntry) containsHue(h float64) bool {
+	if e.Hue[0] > e.Hue[1] {
+		return h >= e.Hue[0] || h <= e.Hue[1]
+	}
+	return h >= e.Hue[0] && h <= e.Hue[1]
+}
+
+func (e *PaletteEntry) contains(c *Colour) bool {
+	within := func(x float64, r [2]float64) bool {
+		return x >= r[0]-paletteTolerance && x <= r[1]+paletteTolerance
+	}
+	h, s, v := c.ToHSV()
+	nearHue := e.containsHue(h) ||
+		hueDistance(h, e.Hue[0]) <= paletteTolerance || hueDistance(h, e.Hue[1]) <= paletteTolerance
+	return nearHue && within(s, e.Saturation) && within(v, e.Value)
+}
+
+// clamp moves each of the colour's hue, saturation and value that falls outside the entry to the
+// nearest end of its range.
+func (e *PaletteEntry) clamp(c *Colour) *Colour {
+	h, s, v := c.ToHSV()
+	if !e.containsHue(h) {
+		if hueDistance(h, e.Hue[0]) <= hueDistance(h, e.Hue[1]) {
+			h = e.Hue[0]
+		} else {
+			h = e.Hue[1]
+		}
+	}
+	s = lib.Clamper(e.Saturation[0], e.Saturation[1])(s)
+	v = lib.Clamper(e.Value[0], e.Value[1])(v)
+	return (&Colour{}).FromHSV(h, s, v)
+}
+
+// readableValue corrects the value of the colour, as clamped into the entry, to the nearest value in
+// the entry that has the minimum contrast. It is nil if there is none at the colour's hue and
+// saturation.
+func (e *PaletteEntry) readableValue(c *Colour, min float64) *Colour {
+	h, s, v := e.clamp(c).ToHSV()
+	at := func(v float64) *Colour { return (&Colour{}).FromHSV(h, s, v).rounded() }
+	lowest, highest := readableLuminances(min)
+
+	// Luminance grows with value, so search from the colour's value towards the end of the entry's
+	// values that it needs, for the nearest value that is readable
+	near, far := v, e.Value[1]
+	readable := func(colour *Colour) bool { return colour.luminance() >= lowest }
+	if at(v).luminance() > highest {
+		far = e.Value[0]
+		readable = func(colour *Colour) bool { return colour.luminance() <= highest }
+	}
+	if !readable(at(far)) {
+		return nil
+	}
+	for i := 0; i < 32; i++ {
+		mid := (near + far) / 2
+		if readable(at(mid)) {
+			far = mid
+		} else {
+			near = mid
+		}
+	}
+	// Rounding can move colours at the edges of the entry out of it
+	if corrected := at(far); corrected.Contrast().Min() >= min && e.contains(corrected) {
+		return corrected
+	}
+	return nil
+}
+
+// canBeReadable reports whether any of the entry's colours has the minimum contrast. Luminance
+// grows with value, so the entry's darkest and brightest colours are found among its least and
+// greatest values, across a grid of its hues and saturations.
+func (e *PaletteEntry) canBeReadable(min float64) bool {
+	lowest, highest := readableLuminances(min)
+	darkest, brightest := math.Inf(1), math.Inf(-1)
+	e.grid(func(h, s float64) {
+		darkest = math.Min(darkest, (&Colour{}).FromHSV(h, s, e.Value[0]).luminance())
+		brightest = math.Max(brightest, (&Colour{}).FromHSV(h, s, e.Value[1]).luminance())
+	})
+	return brightest >= lowest && darkest <= highest
+}
+
+// grid calls f with hues and saturations spread evenly across the entry, ends included.
+func (e *PaletteEntry) grid(f func(h, s float64)) {
+	for i := 0; i <= readableGridSteps; i++ {
+		h := e.hueAt(float64(i) / readableGridSteps)
+		for j := 0; j <= readableGridSteps; j++ {
+			f(h, e.Saturation[0]+float64(j)/readableGridSteps*(e.Saturation[1]-e.Saturation[0]))
+		}
+	}
+}
+
+// samples are colours spread evenly through the entry, from its first hue, least saturation and
+// greatest value to its last hue, greatest saturation and least value.
+func (e *PaletteEntry) samples(n int) []*Colour {
+	colours := make([]*Colour, n)
+	for i := range colours {
+		t := (float64(i) + 0.5) / float64(n)
+		colours[i] = (&Colour{}).FromHSV(
+			e.hueAt(t),
+			e.Saturation[0]+t*(e.Saturation[1]-e.Saturation[0]),
+			e.Value[1]-t*(e.Value[1]-e.Value[0]),
+		)
+	}
+	return colours
+}
@@ -0,0 +1,170 @@
+package colours
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParsePalette(t *testing.T) {
+	testCases := []struct {
+		spec         string
+		expectLabels string // as written back by String
+		expectErr    bool
+	}{
+		{"pastel", "pastel", false},
+		{" Neon , #1abc9c,coral", "neon, #1abc9c, #ff7f50", false},
+		{"hsv(200-260, 20-40, 90-100)", "hsv(200-260, 20-40, 90-100)", false},
+		{"hsv( 350 - 20 ,50,90), teal", "hsv(350-20, 50, 90), #1abc9c", false},
+		{"hsv(0-360, 0-100, 0-100)", "hsv(0-360, 0-100, 0-100)", false},
+
+		{"", "", true},
+		{" , ", "", true},
+		{"not a colour", "", true},
+		{"hsv(200-260, 20-40)", "", true},
+		{"hsv(200-400, 20-40, 90)", "", true},
+		{"hsv(200, 40-20, 90)", "", true},
+		{"hsv(200, -20, 90)", "", true},
+		{"red, red, red, red, red, red, red, red, red, red, red, red, red, red, red, red, red", "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			palette, err := ParsePalette(tc.spec)
+			if tc.expectErr {
+				assert.ErrorIs(t, err, ErrInvalidPalette)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectLabels, palette.String())
+
+			// What String writes can be read back
+			reparsed, err := ParsePalette(palette.String())
+			assert.NoError(t, err)
+			assert.Equal(t, palette, reparsed)
+		})
+	}
+}
+
+func Test_Palette_Random(t *testing.T) {
+	for _, spec := range []string{"pastel", "hsv(330-30, 60-80, 70-90)", "#1abc9c, black, white"} {
+		t.Run(spec, func(t *testing.T) {
+			palette, err := ParsePalette(spec)
+			assert.NoError(t, err)
+			for i := 0; i < 200; i++ {
+				colour := palette.Random()
+				assert.True(t, palette.Contains(colour), "%s is outside the palette", colour)
+			}
+		})
+	}
+
+	// Without a palette, colours are drawn as usual
+	var none *Palette
+	assert.NotNil(t, none.Random())
+	assert.True(t, none.Contains(&Colour{0, 0, 0}))
+}
+
+func Test_Palette_Nudge(t *testing.T) {
+	palette, err := ParsePalette("hsv(200-260, 40-60, 60-80), #ff7f50")
+	assert.NoError(t, err)
+
+	// Colours from outside the palette are brought into it, whatever the mutation strategy
+	for strategy, mutator := range mutators {
+		colour := &Colour{0, 1, 0}
+		for i := 0; i < 200; i++ {
+			colour = palette.Nudge(colour, mutator)
+			assert.True(t, palette.Contains(colour), "%s is outside the palette with %s", colour, strategy)
+		}
+	}
+
+	// Without a palette, nudges go anywhere
+	var none *Palette
+	assert.NotEqual(t, &Colour{0.5, 0.5, 0.5}, none.Nudge(&Colour{0.5, 0.5, 0.5}, mutators[RGBMutation]))
+}
+
+func Test_Palette_Readable(t *testing.T) {
+	rand.Seed(1)
+	earth, err := ParsePalette("earth")
+	assert.NoError(t, err)
+
+	// Colours are made readable without leaving the palette, from anywhere in it, including its edges
+	for _, min := range []float64{2, 3, 3.5} {
+		for i := 0; i < 200; i++ {
+			colour := earth.Random()
+			if i%2 == 1 {
+				colour = earth.Nudge((&Colour{}).Random(), mutators[RGBMutation])
+			}
+			readable := earth.Readable(colour, min)
+			assert.True(t, earth.Contains(readable), "%s is outside the palette", readable)
+			assert.GreaterOrEqual(t, readable.Contrast().Min(), min, "%s isn't readable", readable)
+		}
+	}
+
+	// Palettes stay put if they can't be readable, as when the minimum was raised after setting them
+	dark, err := ParsePalette("hsv(20-50, 35-65, 35-45)")
+	assert.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		assert.True(t, dark.Contains(dark.Readable(dark.Random(), 3)))
+	}
+
+	// Without a palette, colours are corrected as usual
+	var none *Palette
+	black := &Colour{0, 0, 0}
+	assert.Equal(t, black.Readable(2.5), none.Readable(black, 2.5))
+}
+
+func Test_Palette_CheckContrast(t *testing.T) {
+	palette, err := ParsePalette("pastel, black")
+	assert.NoError(t, err)
+	assert.NoError(t, palette.CheckContrast(0))
+
+	err = palette.CheckContrast(2.5)
+	assert.ErrorIs(t, err, ErrInvalidPalette)
+	assert.Contains(t, err.Error(), "'#000000' can't be readable")
+	assert.NotContains(t, err.Error(), "pastel")
+
+	// Pastels are too light for the strictest minimums
+	assert.ErrorContains(t, palette.CheckContrast(3), "'pastel', '#000000'")
+
+	var none *Palette
+	assert.NoError(t, none.CheckContrast(maxMinContrast))
+}
+
+func Test_PaletteEntry_clamp(t *testing.T) {
+	entry, err := parseHSVRange("hsv(330-30, 50-60, 70-80)")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		hexcode   string
+		expectHue float64
+	}{
+		{"00ff00", 30.0 / 360},  // 120°, nearer 30° than 330°
+		{"0000ff", 330.0 / 360}, // 240°, nearer 330°
+		{"ff0000", 0},           // 0° is within the range, through red
+	}
+	for _, tc := range testCases {
+		t.Run(tc.hexcode, func(t *testing.T) {
+			clamped := entry.clamp((&Colour{}).FromRGBHex(tc.hexcode))
+			assert.True(t, entry.contains(clamped))
+			h, s, v := clamped.ToHSV()
+			assert.InDelta(t, tc.expectHue, h, 0.001)
+			assert.InDelta(t, 0.6, s, 0.001)
+			assert.InDelta(t, 0.8, v, 0.001)
+		})
+	}
+}
+
+func Test_Palette_Swatch(t *testing.T) {
+	palette, err := ParsePalette("warm, #1abc9c")
+	assert.NoError(t, err)
+	swatch := palette.Swatch(4)
+	assert.Len(t, swatch, 8)
+	for _, colour := range swatch {
+		assert.True(t, palette.Contains(colour))
+	}
+	// Anchors show colours close to themselves
+	assert.Less(t, DeltaE(swatch[5].ToLab(), (&Colour{}).FromRGBHex("1abc9c").ToLab()), 10.0)
+
+	var none *Palette
+	assert.Empty(t, none.Swatch(4))
+}
@@ -0,0 +1,420 @@
+package colours
+
+// partitions.go maintains the partitions of colours_log on Postgres. A scheduled job creates yearly
+// or monthly partitions ahead of time, and archives partitions that fall out of the retention window.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/engine/scheduler"
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	partitionsJobName = "colours/partitions"
+
+	defaultPartitionsAhead = 2
+	// colours_logview reads two years back, so partitions must be kept at least that long
+	minLogRetentionMonths = 24
+
+	partitionBoundLayout = "2006-01-02 15:04:05"
+
+	listPartitions = `SELECT c.relname, pg_get_expr(c.relpartbound, c.oid) AS bound
+		FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'colours_log'::regclass
+		ORDER BY c.relname;`
+	insertArchive = `INSERT INTO colours_log_archive(partition, range_from, range_to, row_count, rows_csv_gz, archived_at)
+		VALUES ($1, $2, $3, $4, $5, $6);`
+)
+
+var (
+	ErrPartitionInterval = errors.New("invalid partition interval")
+	ErrLogRetention      = errors.New("log retention is too short")
+	ErrPartitionBound    = errors.New("unrecognised partition bound")
+
+	partitionBound = regexp.MustCompile(`^FOR VALUES FROM \('([^']+)'\) TO \('([^']+)'\)$`)
+	archiveHeader  = []string{"guildid", "userid", "username", "colour", "reason", "tstamp"}
+)
+
+// PartitionInterval is the span of time that each new partition of colours_log covers.
+type PartitionInterval string
+
+const (
+	Yearly  PartitionInterval = "yearly"
+	Monthly PartitionInterval = "monthly"
+)
+
+// start truncates t to the start of the interval containing it.
+func (i PartitionInterval) start(t time.Time) time.Time {
+	t = t.UTC()
+	if i == Monthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// next returns the start of the interval after the one starting at from.
+func (i PartitionInterval) next(from time.Time) time.Time {
+	if i == Monthly {
+		return from.AddDate(0, 1, 0)
+	}
+	return from.AddDate(1, 0, 0)
+}
+
+// partitionName follows the names of the partitions created by migrations, like colours_log_2022.
+func (i PartitionInterval) partitionName(from time.Time) string {
+	if i == Monthly {
+		return fmt.Sprintf("colours_log_%04d_%02d", from.Year(), from.Month())
+	}
+	return fmt.Sprintf("colours_log_%04d", from.Year())
+}
+
+// partition is a partition of colours_log, holding tstamps from From up to but excluding To.
+type partition struct {
+	Name      string
+	From, To  time.Time
+	IsDefault bool
+}
+
+func (p partition) String() string {
+	if p.IsDefault {
+		return p.Name + " (default)"
+	}
+	return fmt.Sprintf("%s [%s, %s)", p.Name, p.From.Format(time.DateOnly), p.To.Format(time.DateOnly))
+}
+
+// parsePartition reads a partition's bounds, as printed by pg_get_expr().
+func parsePartition(name, bound string) (partition, error) {
+	if bound == "DEFAULT" {
+		return partition{Name: name, IsDefault: true}, nil
+	}
+	match := partitionBound.FindStringSubmatch(bound)
+	if match == nil {
+		return partition{}, fmt.Errorf("%w: %s %s", ErrPartitionBound, name, bound)
+	}
+	from, err := time.Parse(partitionBoundLayout, match[1])
+	if err != nil {
+		return partition{}, fmt.Errorf("%w: %s %v", ErrPartitionBound, name, err)
+	}
+	to, err := time.Parse(partitionBoundLayout, match[2])
+	if err != nil {
+		return partition{}, fmt.Errorf("%w: %s %v", ErrPartitionBound, name, err)
+	}
+	return partition{Name: name, From: from, To: to}, nil
+}
+
+// uncovered returns whether any part of the range is not in one of the partitions.
+func uncovered(layout []partition, from, to time.Time) bool {
+	ranges := make([]partition, 0)
+	for _, p := range layout {
+		if !p.IsDefault && p.From.Before(to) && from.Before(p.To) {
+			ranges = append(ranges, p)
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].From.Before(ranges[j].From) })
+
+	cursor := from
+	for _, p := range ranges {
+		if p.From.After(cursor) {
+			return true
+		}
+		if p.To.After(cursor) {
+			cursor = p.To
+		}
+	}
+	return cursor.Before(to)
+}
+
+// overlapped returns whether any part of the range is in one of the partitions.
+func overlapped(layout []partition, from, to time.Time) bool {
+	for _, p := range layout {
+		if !p.IsDefault && p.From.Before(to) && from.Before(p.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// partitioner implements the job that maintains colours_log partitions.
+type partitioner struct {
+	db       database.IDatabase
+	interval PartitionInterval
+	ahead    int
+	// retention is in months. Zero keeps every partition.
+	retention int
+	clock     func() time.Time
+
+	mutex  sync.Mutex
+	layout []partition
+}
+
+func newPartitioner(db database.IDatabase, cfg *Config) *partitioner {
+	p := &partitioner{
+		db:        db,
+		interval:  cfg.partitionInterval(),
+		ahead:     cfg.partitionsAhead(),
+		retention: cfg.LogRetentionMonths,
+		clock:     time.Now,
+	}
+	p.registerMetrics()
+	return p
+}
+
+func (p *partitioner) Job() *scheduler.Job {
+	return &scheduler.Job{
+		Name:     partitionsJobName,
+		Schedule: scheduler.MustCron("@daily"),
+		Jitter:   30 * time.Minute,
+		Run:      p.Run,
+	}
+}
+
+// registerMetrics reports the layout from the job's latest run as gauges.
+func (p *partitioner) registerMetrics() {
+	meter := instrumentation.Meter("arisa3/colours")
+	count, errCount := meter.Int64ObservableGauge(
+		"colours_log.partitions",
+		metric.WithDescription("Number of partitions attached to colours_log"),
+	)
+	horizon, errHorizon := meter.Float64ObservableGauge(
+		"colours_log.partitions.horizon",
+		metric.WithDescription("Days until the last partition of colours_log ends"),
+		metric.WithUnit("d"),
+	)
+	if err := errors.Join(errCount, errHorizon); err != nil {
+		log.Errorf(context.Background(), err, "Failed to create colours_log metrics")
+		return
+	}
+
+	_, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if p.layout == nil {
+			return nil
+		}
+		o.ObserveInt64(count, int64(len(p.layout)))
+		if end := lastPartitionEnd(p.layout); !end.IsZero() {
+			o.ObserveFloat64(horizon, end.Sub(p.clock()).Hours()/24)
+		}
+		return nil
+	}, count, horizon)
+	if err != nil {
+		log.Errorf(context.Background(), err, "Failed to register colours_log metrics")
+	}
+}
+
+// Run creates upcoming partitions and archives expired ones, then reports the resulting layout.
+func (p *partitioner) Run(ctx context.Context) error {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Internal("PartitionColoursLog"))
+	defer span.End()
+
+	layout, err := p.listPartitions(ctx)
+	if err != nil {
+		return err
+	}
+	now := p.clock()
+
+	created, err := p.createUpcoming(ctx, layout, now)
+	if err != nil {
+		return err
+	}
+	archived, err := p.archiveExpired(ctx, layout, now)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(
+		instrumentation.KV.PartitionsCreated(created),
+		instrumentation.KV.PartitionsArchived(archived),
+	)
+
+	if created+archived > 0 {
+		if layout, err = p.listPartitions(ctx); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(layout))
+	for _, part := range layout {
+		names = append(names, part.String())
+	}
+	span.SetAttributes(instrumentation.KV.Partitions(names))
+	log.Infof(ctx, "colours_log has %d partitions up to %s: %s",
+		len(layout), lastPartitionEnd(layout).Format(time.DateOnly), strings.Join(names, ", "))
+
+	p.mutex.Lock()
+	p.layout = layout
+	p.mutex.Unlock()
+	return nil
+}
+
+func (p *partitioner) listPartitions(ctx context.Context) ([]partition, error) {
+	type partitionRow struct {
+		Name  string `db:"relname"`
+		Bound string `db:"bound"`
+	}
+	rows, err := database.QueryAll[partitionRow](ctx, p.db, listPartitions)
+	if err != nil {
+		return nil, err
+	}
+	layout := make([]partition, 0, len(rows))
+	for _, row := range rows {
+		part, err := parsePartition(row.Name, row.Bound)
+		if err != nil {
+			return nil, err
+		}
+		layout = append(layout, part)
+	}
+	return layout, nil
+}
+
+// createUpcoming creates partitions for the current interval and the next few after it. Intervals
+// that existing partitions already reach into are left alone.
+func (p *partitioner) createUpcoming(ctx context.Context, layout []partition, now time.Time) (int, error) {
+	defaultPartition := ""
+	for _, part := range layout {
+		if part.IsDefault {
+			defaultPartition = part.Name
+		}
+	}
+
+	created := 0
+	from := p.interval.start(now)
+	for n := 0; n <= p.ahead; n++ {
+		to := p.interval.next(from)
+		name := p.interval.partitionName(from)
+		switch {
+		case !overlapped(layout, from, to):
+			if err := p.createPartition(ctx, name, from, to, defaultPartition); err != nil {
+				return created, fmt.Errorf("failed to create partition %s: %w", name, err)
+			}
+			log.Infof(ctx, "Created partition %s", name)
+			created++
+		case uncovered(layout, from, to):
+			log.Warnf(ctx, "Partition %s would overlap existing partitions, rows in its gaps go to the default partition", name)
+		}
+		from = to
+	}
+	return created, nil
+}
+
+// createPartition attaches a new partition, moving in any rows for it from the default partition.
+// Postgres refuses to create a partition over rows that are still in the default partition.
+func (p *partitioner) createPartition(ctx context.Context, name string, from, to time.Time, defaultPartition string) error {
+	return database.WithTransaction(ctx, p.db, func(ctx context.Context, tx database.ITransaction) error {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`CREATE TABLE "%s" (LIKE colours_log INCLUDING DEFAULTS);`, name)); err != nil {
+			return err
+		}
+		if defaultPartition != "" {
+			move := fmt.Sprintf(
+				`WITH moved AS (DELETE FROM "%s" WHERE tstamp >= $1 AND tstamp < $2 RETURNING *) INSERT INTO "%s" SELECT * FROM moved;`,
+				defaultPartition, name,
+			)
+			if _, err := tx.Exec(ctx, move, from, to); err != nil {
+				return err
+			}
+		}
+		attach := fmt.Sprintf(
+			`ALTER TABLE colours_log ATTACH PARTITION "%s" FOR VALUES FROM ('%s') TO ('%s');`,
+			name, from.Format(partitionBoundLayout), to.Format(partitionBoundLayout),
+		)
+		_, err := tx.Exec(ctx, attach)
+		return err
+	})
+}
+
+// archiveExpired archives every partition that ended before the retention window.
+func (p *partitioner) archiveExpired(ctx context.Context, layout []partition, now time.Time) (int, error) {
+	if p.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := now.AddDate(0, -p.retention, 0)
+
+	archived := 0
+	for _, part := range layout {
+		if part.IsDefault || part.To.After(cutoff) {
+			continue
+		}
+		count, err := p.archivePartition(ctx, part)
+		if err != nil {
+			return archived, fmt.Errorf("failed to archive partition %s: %w", part.Name, err)
+		}
+		log.Infof(ctx, "Archived partition %s with %d rows", part.Name, count)
+		archived++
+	}
+	return archived, nil
+}
+
+// archivePartition detaches the partition and exports its rows into colours_log_archive.
+func (p *partitioner) archivePartition(ctx context.Context, part partition) (count int64, err error) {
+	err = database.WithTransaction(ctx, p.db, func(ctx context.Context, tx database.ITransaction) error {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE colours_log DETACH PARTITION "%s";`, part.Name)); err != nil {
+			return err
+		}
+		records, err := database.QueryAll[*ColoursLogRecord](ctx, tx, fmt.Sprintf(
+			`SELECT guildid, userid, username, colour, reason, tstamp FROM "%s" ORDER BY tstamp;`, part.Name,
+		))
+		if err != nil {
+			return err
+		}
+		data, err := exportRecords(records)
+		if err != nil {
+			return err
+		}
+		count = int64(len(records))
+		if _, err := tx.Exec(ctx, insertArchive, part.Name, part.From, part.To, count, data, p.clock()); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, fmt.Sprintf(`DROP TABLE "%s";`, part.Name))
+		return err
+	})
+	return count, err
+}
+
+// exportRecords writes colours_log records as gzipped CSV, with a header row.
+func exportRecords(records []*ColoursLogRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := csv.NewWriter(gz)
+	if err := w.Write(archiveHeader); err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		row := []string{rec.GuildID, rec.UserID, rec.Username, rec.ColourHex, rec.Reason, rec.TStamp.Format(time.RFC3339Nano)}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// lastPartitionEnd returns when the latest partition ends, or the zero time if there are none.
+func lastPartitionEnd(layout []partition) time.Time {
+	var end time.Time
+	for _, part := range layout {
+		if part.To.After(end) {
+			end = part.To
+		}
+	}
+	return end
+}
@@ -0,0 +1,61 @@
+package colours
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/testfixtures/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_partitioner_backends(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		if db.Dialect() != database.DialectPostgres {
+			t.Skip("colours_log is only partitioned on Postgres")
+		}
+		ctx := context.Background()
+		insert := func(tstamp time.Time) {
+			_, err := db.Exec(ctx,
+				"INSERT INTO colours_log(userid, username, colour, reason, tstamp) VALUES ($1, $2, $3, $4, $5)",
+				"123", "someone", "ff8000", "reroll", tstamp,
+			)
+			assert.NoError(t, err)
+		}
+		insert(date(2022, time.March, 1))
+		// No partition covers 2032 yet, so this goes to the default partition
+		insert(date(2032, time.March, 1))
+
+		now := date(2031, time.June, 15)
+		p := &partitioner{db: db, interval: Yearly, ahead: 1, retention: 60, clock: func() time.Time { return now }}
+		assert.NoError(t, p.Run(ctx))
+
+		names := []string{}
+		for _, part := range p.layout {
+			names = append(names, part.Name)
+		}
+		assert.Equal(t, []string{
+			"colours_log_2026", "colours_log_2027", "colours_log_2028", "colours_log_2029",
+			"colours_log_2030", "colours_log_2031", "colours_log_2032", "colours_log_default",
+		}, names)
+
+		// Partitions that ended more than 60 months ago are archived
+		type archiveRow struct {
+			Partition string `db:"partition"`
+			RowCount  int64  `db:"row_count"`
+		}
+		rows, err := database.QueryAll[archiveRow](ctx, db, "SELECT partition, row_count FROM colours_log_archive ORDER BY partition")
+		assert.NoError(t, err)
+		assert.Equal(t, []archiveRow{
+			{"colours_log_2022", 1}, {"colours_log_2023", 0}, {"colours_log_2024", 0}, {"colours_log_2025", 0},
+		}, rows)
+
+		// The new partition took over its rows from the default partition
+		table, err := database.QueryOne[string](ctx, db,
+			"SELECT tableoid::regclass::text FROM colours_log WHERE tstamp = $1", date(2032, time.March, 1),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, "colours_log_2032", table)
+	}, &Cog{})
+}
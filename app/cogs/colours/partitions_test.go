@@ -0,0 +1,202 @@
+package colours
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func Test_PartitionInterval(t *testing.T) {
+	now := time.Date(2031, time.March, 15, 12, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		interval   PartitionInterval
+		start      time.Time
+		next       time.Time
+		expectName string
+	}{
+		{Yearly, date(2031, time.January, 1), date(2032, time.January, 1), "colours_log_2031"},
+		{Monthly, date(2031, time.March, 1), date(2031, time.April, 1), "colours_log_2031_03"},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.interval), func(t *testing.T) {
+			start := tc.interval.start(now)
+			assert.Equal(t, tc.start, start)
+			assert.Equal(t, tc.next, tc.interval.next(start))
+			assert.Equal(t, tc.expectName, tc.interval.partitionName(start))
+		})
+	}
+}
+
+func Test_parsePartition(t *testing.T) {
+	p, err := parsePartition("colours_log_2022", "FOR VALUES FROM ('2022-01-01 00:00:00') TO ('2023-01-01 00:00:00')")
+	assert.NoError(t, err)
+	assert.Equal(t, partition{Name: "colours_log_2022", From: date(2022, 1, 1), To: date(2023, 1, 1)}, p)
+	assert.Equal(t, "colours_log_2022 [2022-01-01, 2023-01-01)", p.String())
+
+	p, err = parsePartition("colours_log_default", "DEFAULT")
+	assert.NoError(t, err)
+	assert.True(t, p.IsDefault)
+
+	_, err = parsePartition("colours_log_x", "FOR VALUES FROM (MINVALUE) TO ('2023-01-01 00:00:00')")
+	assert.ErrorIs(t, err, ErrPartitionBound)
+}
+
+func Test_uncovered(t *testing.T) {
+	layout := []partition{
+		{Name: "colours_log_2031_01", From: date(2031, 1, 1), To: date(2031, 2, 1)},
+		{Name: "colours_log_2031_03", From: date(2031, 3, 1), To: date(2031, 4, 1)},
+		{Name: "colours_log_default", IsDefault: true},
+	}
+	testCases := []struct {
+		desc             string
+		from, to         time.Time
+		expectOverlapped bool
+		expectUncovered  bool
+	}{
+		{"inside a partition", date(2031, 1, 1), date(2031, 2, 1), true, false},
+		{"across a gap", date(2031, 1, 1), date(2032, 1, 1), true, true},
+		{"in a gap", date(2031, 2, 1), date(2031, 3, 1), false, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expectOverlapped, overlapped(layout, tc.from, tc.to))
+			assert.Equal(t, tc.expectUncovered, uncovered(layout, tc.from, tc.to))
+		})
+	}
+}
+
+func Test_Config_validate(t *testing.T) {
+	testCases := []struct {
+		cfg       Config
+		expectErr error
+	}{
+		{Config{}, nil},
+		{Config{PartitionInterval: "monthly", LogRetentionMonths: 36}, nil},
+		{Config{PartitionInterval: "weekly"}, ErrPartitionInterval},
+		{Config{LogRetentionMonths: 6}, ErrLogRetention},
+	}
+	for _, tc := range testCases {
+		err := tc.cfg.validate()
+		if tc.expectErr == nil {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorIs(t, err, tc.expectErr)
+		}
+	}
+}
+
+// capture is a sqlmock.Argument that keeps the value it was matched against.
+type capture struct {
+	value driver.Value
+}
+
+func (c *capture) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+func layoutRows(bounds map[string]string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"relname", "bound"})
+	for _, name := range []string{"colours_log_2028", "colours_log_2029", "colours_log_2030", "colours_log_2031", "colours_log_2032", "colours_log_default"} {
+		if bound, ok := bounds[name]; ok {
+			rows.AddRow(name, bound)
+		}
+	}
+	return rows
+}
+
+func Test_partitioner_Run(t *testing.T) {
+	db, dbMock, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+
+	now := time.Date(2031, time.June, 15, 0, 0, 0, 0, time.UTC)
+	p := &partitioner{db: db, interval: Yearly, ahead: 1, retention: 24, clock: func() time.Time { return now }}
+
+	bounds := map[string]string{
+		"colours_log_2028":    "FOR VALUES FROM ('2028-01-01 00:00:00') TO ('2029-01-01 00:00:00')",
+		"colours_log_2029":    "FOR VALUES FROM ('2029-01-01 00:00:00') TO ('2030-01-01 00:00:00')",
+		"colours_log_2030":    "FOR VALUES FROM ('2030-01-01 00:00:00') TO ('2031-01-01 00:00:00')",
+		"colours_log_2031":    "FOR VALUES FROM ('2031-01-01 00:00:00') TO ('2032-01-01 00:00:00')",
+		"colours_log_default": "DEFAULT",
+	}
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT c.relname, pg_get_expr")).WillReturnRows(layoutRows(bounds))
+
+	// 2031 exists, so only 2032 is created, moving its rows out of the default partition
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE "colours_log_2032" (LIKE colours_log`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "colours_log_default"`)).
+		WithArgs(date(2032, 1, 1), date(2033, 1, 1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE colours_log ATTACH PARTITION "colours_log_2032" FOR VALUES FROM ('2032-01-01 00:00:00') TO ('2033-01-01 00:00:00')`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectCommit()
+
+	// 2028 ended more than 24 months ago
+	archived := &capture{}
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE colours_log DETACH PARTITION "colours_log_2028"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT guildid, userid, username, colour, reason, tstamp FROM "colours_log_2028"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"guildid", "userid", "username", "colour", "reason", "tstamp"}).
+			AddRow("456", "123", "someone", "ff8000", "reroll", date(2028, 5, 1)).
+			AddRow("456", "123", "someone", nil, "freeze", date(2028, 6, 1)))
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO colours_log_archive")).
+		WithArgs("colours_log_2028", date(2028, 1, 1), date(2029, 1, 1), int64(2), archived, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(regexp.QuoteMeta(`DROP TABLE "colours_log_2028"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectCommit()
+
+	delete(bounds, "colours_log_2028")
+	bounds["colours_log_2032"] = "FOR VALUES FROM ('2032-01-01 00:00:00') TO ('2033-01-01 00:00:00')"
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT c.relname, pg_get_expr")).WillReturnRows(layoutRows(bounds))
+
+	assert.NoError(t, p.Run(context.Background()))
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+	assert.Len(t, p.layout, 5)
+	assert.Equal(t, date(2033, 1, 1), lastPartitionEnd(p.layout))
+
+	gz, err := gzip.NewReader(bytes.NewReader(archived.value.([]byte)))
+	assert.NoError(t, err)
+	records, err := csv.NewReader(gz).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		archiveHeader,
+		{"456", "123", "someone", "ff8000", "reroll", "2028-05-01T00:00:00Z"},
+		{"456", "123", "someone", "", "freeze", "2028-06-01T00:00:00Z"},
+	}, records)
+}
+
+func Test_partitioner_Run_rollsBackOnError(t *testing.T) {
+	db, dbMock, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+
+	now := time.Date(2031, time.June, 15, 0, 0, 0, 0, time.UTC)
+	p := &partitioner{db: db, interval: Monthly, clock: func() time.Time { return now }}
+
+	dbMock.ExpectQuery(regexp.QuoteMeta("SELECT c.relname, pg_get_expr")).
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "bound"}))
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE "colours_log_2031_06"`)).
+		WillReturnError(assert.AnError)
+	dbMock.ExpectRollback()
+
+	err = p.Run(context.Background())
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.ErrorContains(t, err, "colours_log_2031_06")
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
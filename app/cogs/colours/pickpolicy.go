@@ -0,0 +1,60 @@
+package colours
+
+// pickpolicy.go decides which colours members may pick for themselves.
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrPickDisabled   = errors.New("picking colours is disabled in this guild")
+	ErrPickNotAllowed = errors.New("colour is not one of the guild's allowed colours")
+	ErrPickDenied     = errors.New("colour is denied in this guild")
+	ErrPickPolicy     = errors.New("invalid pick policy")
+)
+
+// PickPolicy restricts the colours that members of a guild can pick with /col. Colours are given as
+// hexcodes or names from the built-in palette, and must match the picked colour exactly.
+type PickPolicy struct {
+	// Disabled stops members from picking colours at all.
+	Disabled bool `mapstructure:"disabled"`
+	// Allow, if not empty, is the only colours that can be picked.
+	Allow []string `mapstructure:"allow"`
+	// Deny is colours that can't be picked, such as those of staff roles.
+	Deny []string `mapstructure:"deny"`
+}
+
+func (p PickPolicy) validate() error {
+	for _, entry := range append(p.Allow, p.Deny...) {
+		if _, err := ParseColour(entry); err != nil {
+			return fmt.Errorf("%w: %w", ErrPickPolicy, err)
+		}
+	}
+	return nil
+}
+
+// check returns an error if the policy doesn't let the colour be picked.
+func (p PickPolicy) check(colour *Colour) error {
+	if p.Disabled {
+		return ErrPickDisabled
+	}
+	if len(p.Allow) > 0 && !containsColour(p.Allow, colour) {
+		return ErrPickNotAllowed
+	}
+	if containsColour(p.Deny, colour) {
+		return ErrPickDenied
+	}
+	return nil
+}
+
+// containsColour checks for the colour in a list of colours, which have been validated.
+func containsColour(entries []string, colour *Colour) bool {
+	hexcode := colour.ToHexcode()
+	for _, entry := range entries {
+		if c, err := ParseColour(entry); err == nil && c.ToHexcode() == hexcode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package colours
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PickPolicy_check(t *testing.T) {
+	teal := (&Colour{}).FromRGBHex("1abc9c")
+	red := (&Colour{}).FromRGBHex("e74c3c")
+
+	testCases := []struct {
+		desc      string
+		policy    PickPolicy
+		colour    *Colour
+		expectErr error
+	}{
+		{"no restrictions", PickPolicy{}, teal, nil},
+		{"disabled", PickPolicy{Disabled: true}, teal, ErrPickDisabled},
+		{"allowed by name", PickPolicy{Allow: []string{"teal"}}, teal, nil},
+		{"not in allow list", PickPolicy{Allow: []string{"teal"}}, red, ErrPickNotAllowed},
+		{"denied by hexcode", PickPolicy{Deny: []string{"#E74C3C"}}, red, ErrPickDenied},
+		{"not in deny list", PickPolicy{Deny: []string{"red"}}, teal, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.policy.check(tc.colour)
+			if tc.expectErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.expectErr)
+			}
+		})
+	}
+}
+
+func Test_Config_pickPolicy(t *testing.T) {
+	cfg := &Config{
+		PickPolicy:        PickPolicy{Deny: []string{"red"}},
+		GuildPickPolicies: map[string]PickPolicy{"1": {Disabled: true}},
+	}
+	assert.NoError(t, cfg.validate())
+	assert.True(t, cfg.pickPolicy("1").Disabled)
+	assert.Equal(t, []string{"red"}, cfg.pickPolicy("2").Deny)
+
+	cfg.GuildPickPolicies["3"] = PickPolicy{Allow: []string{"not a colour"}}
+	assert.ErrorIs(t, cfg.validate(), ErrPickPolicy)
+}
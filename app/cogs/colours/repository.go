@@ -5,130 +5,211 @@ package colours
 import (
 	"context"
 	"errors"
-	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/fiffu/arisa3/lib"
 )
 
 // ColoursRecord models table 'colours'.
 type ColoursRecord struct {
-	UserID string
-	Reason string
-	TStamp time.Time
+	GuildID string    `db:"guildid"`
+	UserID  string    `db:"userid"`
+	Reason  string    `db:"reason"`
+	TStamp  time.Time `db:"tstamp"`
 }
 
 // ColoursLogRecord models table 'colours_log'.
 type ColoursLogRecord struct {
-	UserID    string
-	Username  string
-	ColourHex string
-	Reason    string
-	TStamp    time.Time
+	GuildID   string    `db:"guildid"`
+	UserID    string    `db:"userid"`
+	Username  string    `db:"username"`
+	ColourHex string    `db:"colour"`
+	Reason    string    `db:"reason"`
+	TStamp    time.Time `db:"tstamp"`
 }
 
+// memberKey identifies a user's colour state, which is kept separately in each guild.
+type memberKey struct {
+	guildID string
+	userID  string
+}
+
+func keyOf(mem IDomainMember) memberKey {
+	return memberKey{mem.Guild().ID(), mem.UserID()}
+}
+
+// cachedState is a member's state, by reason. Cached states are replaced rather than modified, so
+// that concurrent readers never see them change.
+type cachedState struct {
+	key     memberKey
+	tstamps map[Reason]time.Time
+}
+
+func (s *cachedState) CacheKey() memberKey { return s.key }
+
+// cachedPalette is a guild's palette, which is nil if the guild has none.
+type cachedPalette struct {
+	guildID string
+	palette *Palette
+}
+
+func (p *cachedPalette) CacheKey() string { return p.guildID }
+
+// cachedRole is the ID of a member's colour role, which is empty if they have none.
+type cachedRole struct {
+	key    memberKey
+	roleID string
+}
+
+func (r *cachedRole) CacheKey() memberKey { return r.key }
+
+const (
+	stateCacheExpiry     = 24 * time.Hour
+	stateCacheMaxEntries = 10000
+
+	paletteCacheExpiry     = 24 * time.Hour
+	paletteCacheMaxEntries = 1000
+
+	roleCacheExpiry     = 24 * time.Hour
+	roleCacheMaxEntries = 10000
+
+	// invalidationChannel is the database notification channel that carries cache invalidations.
+	invalidationChannel = "colours_cache"
+)
+
 // repo implements IDomainRepository.
 type repo struct {
-	db    database.IDatabase
-	cache map[string]map[Reason]time.Time
+	db         database.IDatabase
+	cache      lib.ICache[*cachedState, memberKey]
+	patchMutex sync.Mutex
+
+	// Colour roles are looked up on every message, to mutate them.
+	roles lib.ICache[*cachedRole, memberKey]
+
+	// Palettes are read on every mutate, and rarely change.
+	palettes lib.ICache[*cachedPalette, string]
+
+	// Every cache is kept on each replica, so writes evict their entries on the others.
+	invalidations lib.IInvalidationChannel
 }
 
 func NewRepository(db database.IDatabase) IDomainRepository {
-	return newRepo(db)
+	r := newRepo(db)
+	instrumentation.ObserveCache("colours.state", r.cache.Stats)
+	instrumentation.ObserveCache("colours.palettes", r.palettes.Stats)
+	instrumentation.ObserveCache("colours.roles", r.roles.Stats)
+	r.subscribeInvalidations(context.Background())
+	return r
 }
 
 func newRepo(db database.IDatabase) *repo {
 	return &repo{
-		db:    db,
-		cache: make(map[string]map[Reason]time.Time),
+		db: db,
+		cache: lib.NewCache[*cachedState, memberKey](
+			stateCacheExpiry,
+			lib.WithMaxEntries(stateCacheMaxEntries),
+			lib.WithSweepInterval(time.Hour),
+		),
+		roles: lib.NewCache[*cachedRole, memberKey](
+			roleCacheExpiry,
+			lib.WithMaxEntries(roleCacheMaxEntries),
+			lib.WithSweepInterval(time.Hour),
+		),
+		palettes: lib.NewCache[*cachedPalette, string](
+			paletteCacheExpiry,
+			lib.WithMaxEntries(paletteCacheMaxEntries),
+		),
+		invalidations: database.NewInvalidationChannel(db, invalidationChannel),
 	}
 }
 
 /* Cache functions */
 
-// cachePeek checks a value belonging to the given userID in the cache.
-func (r *repo) cachePeek(userID string, reason Reason) (tstamp time.Time, ok bool) {
-	state, ok := r.cache[userID]
+// cachePeek checks a value belonging to the given member in the cache.
+func (r *repo) cachePeek(key memberKey, reason Reason) (tstamp time.Time, ok bool) {
+	state, ok := r.cache.Peek(key)
 	if !ok {
 		return
 	}
-	tstamp, ok = state[reason]
+	tstamp, ok = state.tstamps[reason]
 	return
 }
 
-// cachePut upserts all values associated to the given userID into the cache.
+// cachePut upserts all values associated to the given member into the cache.
 func (r *repo) cachePut(state *ColourState) {
-	r.cache[state.UserID] = map[Reason]time.Time{
-		Mutate: state.LastMutate,
-		Reroll: state.LastReroll,
-		Freeze: state.LastFrozen,
-	}
+	r.cache.Put(&cachedState{
+		key: memberKey{state.GuildID, state.UserID},
+		tstamps: map[Reason]time.Time{
+			Mutate: state.LastMutate,
+			Reroll: state.LastReroll,
+			Pick:   state.LastPick,
+			Freeze: state.LastFrozen,
+		},
+	})
 }
 
-// cachePatch partially updates one of the given userID's values in the cache.
-func (r *repo) cachePatch(userID string, reason Reason, tstamp time.Time) {
-	if state, ok := r.cache[userID]; ok {
-		state[reason] = tstamp
-	}
-	r.cache[userID] = map[Reason]time.Time{
-		reason: tstamp,
+// cachePatch partially updates one of the given member's values in the cache.
+func (r *repo) cachePatch(key memberKey, reason Reason, tstamp time.Time) {
+	r.patchMutex.Lock()
+	defer r.patchMutex.Unlock()
+
+	patched := &cachedState{key, map[Reason]time.Time{reason: tstamp}}
+	if state, ok := r.cache.Peek(key); ok {
+		for other, t := range state.tstamps {
+			if other != reason {
+				patched.tstamps[other] = t
+			}
+		}
 	}
+	r.cache.Put(patched)
 }
 
-func (r *repo) cacheDelete(userID string, reason Reason) {
-	if state, ok := r.cache[userID]; ok {
-		state[reason] = Never
-	}
-	r.cache[userID] = map[Reason]time.Time{
-		reason: Never,
-	}
+func (r *repo) cacheDelete(key memberKey, reason Reason) {
+	r.cachePatch(key, reason, Never)
 }
 
 /* Exported methods for IDomainRepository, and their supporting internals. */
 
-// FetchUserState returns the user's state for the given Reason.
+// FetchUserState returns the member's state in their guild for the given Reason.
 func (r *repo) FetchUserState(ctx context.Context, user IDomainMember, reason Reason) (time.Time, error) {
-	userID := user.UserID()
-	if state, ok := r.cachePeek(userID, reason); ok {
+	key := keyOf(user)
+	if state, ok := r.cachePeek(key, reason); ok {
 		return state, nil
 	}
-	if state, err := r.queryUserState(ctx, userID); err != nil {
+	if state, err := r.queryUserState(ctx, key); err != nil {
 		if errors.Is(err, database.ErrNoRecords) {
 			return Never, nil
 		}
 		return Never, err
 	} else {
 		r.cachePut(state)
-		tstamp, _ := r.cachePeek(userID, reason)
+		tstamp, _ := r.cachePeek(key, reason)
 		return tstamp, nil
 	}
 }
 
-func (r *repo) queryUserState(ctx context.Context, userID string) (*ColourState, error) {
-	// Pull records with the given userID.
-	rows, err := r.db.Query(
-		ctx,
-		"SELECT userid, tstamp, reason FROM colours WHERE userid = $1",
-		userID,
+func (r *repo) queryUserState(ctx context.Context, key memberKey) (*ColourState, error) {
+	// Pull records with the given guildID and userID.
+	records, err := database.QueryAll[ColoursRecord](
+		ctx, r.db,
+		"SELECT guildid, userid, tstamp, reason FROM colours WHERE guildid = $1 AND userid = $2",
+		key.guildID, key.userID,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parsing records.
-	records := make([]ColoursRecord, 0)
-	for rows.Next() {
-		rec := ColoursRecord{}
-		if err := rows.Scan(&rec.UserID, &rec.TStamp, &rec.Reason); err != nil {
-			return nil, err
-		}
-		records = append(records, rec)
-	}
-
 	state := ColourState{
-		UserID:     userID,
+		GuildID:    key.guildID,
+		UserID:     key.userID,
 		LastMutate: Never,
 		LastReroll: Never,
+		LastPick:   Never,
 		LastFrozen: Never,
 	}
 	for _, rec := range records {
@@ -137,6 +218,8 @@ func (r *repo) queryUserState(ctx context.Context, userID string) (*ColourState,
 			state.LastMutate = rec.TStamp
 		case Reroll:
 			state.LastReroll = rec.TStamp
+		case Pick:
+			state.LastPick = rec.TStamp
 		case Freeze:
 			state.LastFrozen = rec.TStamp
 		}
@@ -153,12 +236,40 @@ func (r *repo) FetchUserHistory(ctx context.Context, user IDomainMember, since t
 	return history, err
 }
 
+// LastChangeRecord is when a member's colour last changed.
+type LastChangeRecord struct {
+	UserID string    `db:"userid"`
+	TStamp time.Time `db:"tstamp"`
+}
+
+// FetchLastChanges returns when each member of the guild last had their colour changed, by user
+// ID, as far back as colours_logview goes.
+func (r *repo) FetchLastChanges(ctx context.Context, guildID string) (map[string]time.Time, error) {
+	records, err := database.QueryAll[LastChangeRecord](ctx, r.db, `
+		SELECT userid, MAX(tstamp) AS tstamp FROM colours_logview
+		WHERE guildid = $1 AND colour != ''
+		GROUP BY userid`,
+		guildID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[string]time.Time, len(records))
+	for _, rec := range records {
+		changes[rec.UserID] = rec.TStamp
+	}
+	return changes, nil
+}
+
 func (r *repo) UpdateMutate(ctx context.Context, user IDomainMember, c *Colour) error {
 	return r.update(ctx, user, Mutate, c, time.Now())
 }
 func (r *repo) UpdateReroll(ctx context.Context, user IDomainMember, c *Colour) error {
 	return r.update(ctx, user, Reroll, c, time.Now())
 }
+func (r *repo) UpdatePick(ctx context.Context, user IDomainMember, c *Colour) error {
+	return r.update(ctx, user, Pick, c, time.Now())
+}
 func (r *repo) UpdateFreeze(ctx context.Context, user IDomainMember) error {
 	return r.update(ctx, user, Freeze, nil, time.Now())
 }
@@ -166,142 +277,440 @@ func (r *repo) UpdateUnfreeze(ctx context.Context, user IDomainMember) error {
 	return r.unset(ctx, user, Freeze)
 }
 
+// UpdateSwap logs each member's new colour with the swap reason, and applies the swap's cooldown
+// policy to both their reroll states, all in one transaction.
+func (r *repo) UpdateSwap(ctx context.Context, swap *ColourSwap) error {
+	tstamp := time.Now()
+	if err := database.WithTransaction(ctx, r.db, func(ctx context.Context, tx database.ITransaction) error {
+		for i, mem := range swap.Members {
+			key := keyOf(mem)
+			switch swap.Cooldown {
+			case SwapRestartsCooldown:
+				if err := r.upsert(ctx, tx, key, Reroll.String(), tstamp); err != nil {
+					return err
+				}
+			case SwapClearsCooldown:
+				if err := r.delete(ctx, tx, key, Reroll.String()); err != nil {
+					return err
+				}
+			}
+			if err := r.log(ctx, tx, key, mem.Username(), Swap.String(), swap.Colours[i].ToHexcode(), tstamp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, mem := range swap.Members {
+		switch swap.Cooldown {
+		case SwapRestartsCooldown:
+			r.cachePatch(keyOf(mem), Reroll, tstamp)
+		case SwapClearsCooldown:
+			r.cacheDelete(keyOf(mem), Reroll)
+		}
+		r.publishInvalidation(ctx, stateKind, mem.Guild().ID(), mem.UserID())
+	}
+	return nil
+}
+
 func (r *repo) getLogs(ctx context.Context, user IDomainMember, since time.Time) ([]*ColoursLogRecord, error) {
-	rows, err := r.db.Query(ctx, `
+	return database.QueryAll[*ColoursLogRecord](ctx, r.db, `
 		SELECT colour, tstamp, reason FROM colours_logview
-		WHERE userid = $1
-			AND tstamp > $2
+		WHERE guildid = $1
+			AND userid = $2
+			AND tstamp > $3
 			AND colour != ''
 		ORDER BY tstamp ASC`,
-		user.UserID(), since,
+		user.Guild().ID(), user.UserID(), since,
 	)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parsing records.
-	records := make([]*ColoursLogRecord, 0)
-	for rows.Next() {
-		rec := ColoursLogRecord{}
-		if err := rows.Scan(&rec.ColourHex, &rec.TStamp, &rec.Reason); err != nil {
-			return nil, err
-		}
-		records = append(records, &rec)
-	}
-	return records, nil
 }
 
+// update replaces the user's state for the given reason, and logs the change to the audit table in
+// the same transaction, so that neither is written without the other.
 func (r *repo) update(ctx context.Context, user IDomainMember, reason Reason, colour *Colour, tstamp time.Time) error {
-	userID := user.UserID()
-	// Insert the change inside a transaction
-	if err := r.upsert(ctx, userID, reason.String(), tstamp); err != nil {
-		return err
-	}
-
-	// Log the change to the audit db
+	key := keyOf(user)
 	hexcode := ""
 	if colour != nil {
 		hexcode = colour.ToHexcode()
 	}
-	if err := r.log(ctx, userID, user.Username(), reason.String(), hexcode, tstamp); err != nil {
+	if err := database.WithTransaction(ctx, r.db, func(ctx context.Context, tx database.ITransaction) error {
+		if err := r.upsert(ctx, tx, key, reason.String(), tstamp); err != nil {
+			return err
+		}
+		return r.log(ctx, tx, key, user.Username(), reason.String(), hexcode, tstamp)
+	}); err != nil {
 		return err
 	}
-	r.cachePatch(userID, reason, tstamp)
+	r.cachePatch(key, reason, tstamp)
+	r.publishInvalidation(ctx, stateKind, key.guildID, key.userID)
 	return nil
 }
 
+// unset clears the user's state for the given reason, logging the change in the same transaction.
 func (r *repo) unset(ctx context.Context, user IDomainMember, reason Reason) error {
-	userID := user.UserID()
-
-	if err := r.delete(ctx, userID, reason.String()); err != nil {
-		return err
-	}
-
-	// Log the change to the audit db
+	key := keyOf(user)
 	auditReason := reason.String() + " deleted"
-	if err := r.log(ctx, userID, user.Username(), auditReason, "", time.Now()); err != nil {
+	if err := database.WithTransaction(ctx, r.db, func(ctx context.Context, tx database.ITransaction) error {
+		if err := r.delete(ctx, tx, key, reason.String()); err != nil {
+			return err
+		}
+		return r.log(ctx, tx, key, user.Username(), auditReason, "", time.Now())
+	}); err != nil {
 		return err
 	}
-	r.cacheDelete(userID, reason)
+	r.cacheDelete(key, reason)
+	r.publishInvalidation(ctx, stateKind, key.guildID, key.userID)
 	return nil
 }
 
-func (r *repo) delete(ctx context.Context, userID string, reason string) error {
-	_, err := r.db.Exec(
+func (r *repo) delete(ctx context.Context, q database.IQuerier, key memberKey, reason string) error {
+	_, err := q.Exec(
 		ctx,
-		"DELETE FROM colours WHERE userid = $1 AND reason = $2",
-		userID, reason,
+		"DELETE FROM colours WHERE guildid = $1 AND userid = $2 AND reason = $3",
+		key.guildID, key.userID, reason,
 	)
 	return err
 }
 
-func (r *repo) upsert(ctx context.Context, userID string, reason string, tstamp time.Time) error {
+func (r *repo) upsert(ctx context.Context, q database.IQuerier, key memberKey, reason string, tstamp time.Time) error {
 	rec := ColoursRecord{
-		UserID: userID,
-		Reason: reason,
-		TStamp: tstamp,
-	}
-	ctx, tx, err := r.db.Begin(ctx)
-	if err != nil {
-		return err
+		GuildID: key.guildID,
+		UserID:  key.userID,
+		Reason:  reason,
+		TStamp:  tstamp,
 	}
 
 	// Drop any records for the given reason
-	if _, err := tx.Exec(
-		ctx,
-		"DELETE FROM colours WHERE userid = $1 AND reason = $2",
-		rec.UserID, rec.Reason,
-	); err != nil {
-		if rbErr := tx.Rollback(ctx); rbErr != nil {
-			return fmt.Errorf("got another error while rolling back due to '%v': %v", err, rbErr)
-		}
+	if err := r.delete(ctx, q, key, rec.Reason); err != nil {
 		return err
 	}
 
 	// Put a new record for the given reason
-	if _, err := tx.Exec(
+	_, err := q.Exec(
 		ctx,
-		"INSERT INTO colours(userid, tstamp, reason) VALUES ($1, $2, $3)",
-		rec.UserID, rec.TStamp, rec.Reason,
-	); err != nil {
-		if rbErr := tx.Rollback(ctx); rbErr != nil {
-			return fmt.Errorf("got another error while rolling back due to '%v': %v", err, rbErr)
-		}
-		return err
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return err
-	}
-	return nil
+		"INSERT INTO colours(guildid, userid, tstamp, reason) VALUES ($1, $2, $3, $4)",
+		rec.GuildID, rec.UserID, rec.TStamp, rec.Reason,
+	)
+	return err
 }
 
-func (r *repo) log(ctx context.Context, userID string, name string, reason string, hexcode string, tstamp time.Time) error {
+func (r *repo) log(ctx context.Context, q database.IQuerier, key memberKey, name string, reason string, hexcode string, tstamp time.Time) error {
 	rec := ColoursLogRecord{
-		UserID:    userID,
+		GuildID:   key.guildID,
+		UserID:    key.userID,
 		Username:  name,
 		Reason:    reason,
 		ColourHex: hexcode,
 		TStamp:    tstamp,
 	}
-	_, err := r.db.Exec(
+	_, err := q.Exec(
 		ctx,
-		"INSERT INTO colours_log(userid, username, colour, reason, tstamp) VALUES ($1, $2, $3, $4, $5)",
-		rec.UserID, rec.Username, rec.ColourHex, rec.Reason, rec.TStamp,
+		"INSERT INTO colours_log(guildid, userid, username, colour, reason, tstamp) VALUES ($1, $2, $3, $4, $5, $6)",
+		rec.GuildID, rec.UserID, rec.Username, rec.ColourHex, rec.Reason, rec.TStamp,
 	)
 	return err
 }
 
 func (r *repo) UpdateRerollPenalty(ctx context.Context, user IDomainMember, tstamp time.Time) error {
 	rec := ColoursRecord{
-		UserID: user.UserID(),
-		Reason: Reroll.String(),
-		TStamp: tstamp,
+		GuildID: user.Guild().ID(),
+		UserID:  user.UserID(),
+		Reason:  Reroll.String(),
+		TStamp:  tstamp,
 	}
 	_, err := r.db.Exec(
 		ctx,
-		"UPDATE colours SET tstamp=$1 WHERE userid=$2 AND reason=$3",
-		rec.TStamp, rec.UserID, rec.Reason,
+		"UPDATE colours SET tstamp=$1 WHERE guildid=$2 AND userid=$3 AND reason=$4",
+		rec.TStamp, rec.GuildID, rec.UserID, rec.Reason,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	// The update only applies if there was a reroll, so the state is read again rather than patched
+	r.cache.Delete(memberKey{rec.GuildID, rec.UserID})
+	r.publishInvalidation(ctx, stateKind, rec.GuildID, rec.UserID)
+	return nil
+}
+
+/* Colour roles */
+
+// ColourRoleRecord models table 'colours_roles'.
+type ColourRoleRecord struct {
+	GuildID string    `db:"guildid"`
+	UserID  string    `db:"userid"`
+	RoleID  string    `db:"roleid"`
+	TStamp  time.Time `db:"tstamp"`
+}
+
+// FetchColourRole returns the ID of the member's colour role, or "" if they have none.
+func (r *repo) FetchColourRole(ctx context.Context, mem IDomainMember) (string, error) {
+	key := keyOf(mem)
+	if cached, ok := r.roles.Peek(key); ok {
+		return cached.roleID, nil
+	}
+	rec, err := database.QueryOne[ColourRoleRecord](ctx, r.db,
+		"SELECT guildid, userid, roleid, tstamp FROM colours_roles WHERE guildid = $1 AND userid = $2",
+		key.guildID, key.userID,
+	)
+	var roleID string
+	switch {
+	case errors.Is(err, database.ErrNoRecords):
+	case err != nil:
+		return "", err
+	default:
+		roleID = rec.RoleID
+	}
+	r.roles.Put(&cachedRole{key, roleID})
+	return roleID, nil
+}
+
+// FetchColourRoles returns the IDs of the guild's colour roles, by the user ID of their members.
+func (r *repo) FetchColourRoles(ctx context.Context, guildID string) (map[string]string, error) {
+	records, err := database.QueryAll[ColourRoleRecord](ctx, r.db,
+		"SELECT guildid, userid, roleid, tstamp FROM colours_roles WHERE guildid = $1",
+		guildID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[string]string, len(records))
+	for _, rec := range records {
+		roles[rec.UserID] = rec.RoleID
+	}
+	return roles, nil
+}
+
+// UpdateColourRole records the member's colour role, replacing any they had before.
+func (r *repo) UpdateColourRole(ctx context.Context, mem IDomainMember, roleID string) error {
+	key := keyOf(mem)
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO colours_roles(guildid, userid, roleid, tstamp) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (guildid, userid) DO UPDATE SET roleid = excluded.roleid, tstamp = excluded.tstamp`,
+		key.guildID, key.userID, roleID, time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+	r.roles.Put(&cachedRole{key, roleID})
+	r.publishInvalidation(ctx, roleKind, key.guildID, key.userID)
+	return nil
+}
+
+// DeleteColourRole forgets a colour role, which does nothing if it isn't one.
+func (r *repo) DeleteColourRole(ctx context.Context, guildID, roleID string) error {
+	userIDs, err := database.ExecReturning[string](ctx, r.db,
+		"DELETE FROM colours_roles WHERE guildid = $1 AND roleid = $2 RETURNING userid",
+		guildID, roleID,
+	)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		r.roles.Delete(memberKey{guildID, userID})
+		r.publishInvalidation(ctx, roleKind, guildID, userID)
+	}
+	return nil
+}
+
+// FetchRolesAdopted returns whether the guild's colour roles from before they were tracked have
+// been adopted.
+func (r *repo) FetchRolesAdopted(ctx context.Context, guildID string) (bool, error) {
+	_, err := database.QueryOne[string](ctx, r.db,
+		"SELECT guildid FROM colours_roles_adoptions WHERE guildid = $1",
+		guildID,
+	)
+	switch {
+	case errors.Is(err, database.ErrNoRecords):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateRolesAdopted records the guild's adopted colour roles, by the user ID of their members,
+// and that the guild has been adopted, in one transaction. Members who already have a colour role
+// keep it.
+func (r *repo) UpdateRolesAdopted(ctx context.Context, guildID string, roles map[string]string) error {
+	tstamp := time.Now()
+	err := database.WithTransaction(ctx, r.db, func(ctx context.Context, tx database.ITransaction) error {
+		for userID, roleID := range roles {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO colours_roles(guildid, userid, roleid, tstamp) VALUES ($1, $2, $3, $4)
+				ON CONFLICT DO NOTHING`,
+				guildID, userID, roleID, tstamp,
+			); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(ctx,
+			"INSERT INTO colours_roles_adoptions(guildid, adopted, tstamp) VALUES ($1, $2, $3)",
+			guildID, len(roles), tstamp,
+		)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for userID := range roles {
+		r.roles.Delete(memberKey{guildID, userID})
+		r.publishInvalidation(ctx, roleKind, guildID, userID)
+	}
+	return nil
+}
+
+/* Palettes */
+
+// PaletteRecord models table 'colours_palettes'.
+type PaletteRecord struct {
+	GuildID string    `db:"guildid"`
+	Palette string    `db:"palette"`
+	TStamp  time.Time `db:"tstamp"`
+}
+
+// FetchPalette returns the guild's palette, or nil if it has none. A stored palette that no
+// longer parses is logged and ignored, so that rerolls and mutations carry on without it.
+func (r *repo) FetchPalette(ctx context.Context, guildID string) (*Palette, error) {
+	if cached, ok := r.palettes.Peek(guildID); ok {
+		return cached.palette, nil
+	}
+	rec, err := database.QueryOne[PaletteRecord](ctx, r.db,
+		"SELECT guildid, palette, tstamp FROM colours_palettes WHERE guildid = $1",
+		guildID,
+	)
+	var palette *Palette
+	switch {
+	case errors.Is(err, database.ErrNoRecords):
+	case err != nil:
+		return nil, err
+	default:
+		if palette, err = ParsePalette(rec.Palette); err != nil {
+			log.Errorf(ctx, err, "Ignoring stored palette '%s', guild=%s", rec.Palette, guildID)
+		}
+	}
+	r.palettes.Put(&cachedPalette{guildID, palette})
+	return palette, nil
+}
+
+// UpdatePalette replaces the guild's palette. A nil palette removes it.
+func (r *repo) UpdatePalette(ctx context.Context, guildID string, palette *Palette) error {
+	var err error
+	if palette == nil {
+		_, err = r.db.Exec(ctx, "DELETE FROM colours_palettes WHERE guildid = $1", guildID)
+	} else {
+		_, err = r.db.Exec(ctx, `
+			INSERT INTO colours_palettes(guildid, palette, tstamp) VALUES ($1, $2, $3)
+			ON CONFLICT (guildid) DO UPDATE SET palette = excluded.palette, tstamp = excluded.tstamp`,
+			guildID, palette.String(), time.Now(),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	r.palettes.Delete(guildID)
+	r.publishInvalidation(ctx, paletteKind, guildID)
+	return nil
+}
+
+/* Invalidation */
+
+// cacheKind names a cache in invalidation keys.
+type cacheKind string
+
+const (
+	paletteKind cacheKind = "palette" // keyed by guild
+	roleKind    cacheKind = "role"    // keyed by guild and user
+	stateKind   cacheKind = "state"   // keyed by guild and user, or all of it if neither is given
+)
+
+// invalidationKey names the entry of a cache to evict, like "role:1234:5678".
+func invalidationKey(kind cacheKind, ids ...string) string {
+	return strings.Join(append([]string{string(kind)}, ids...), ":")
+}
+
+// publishInvalidation tells the other replicas to evict a cache entry after a write. If they can't
+// be told, they keep the old entry until it expires.
+func (r *repo) publishInvalidation(ctx context.Context, kind cacheKind, ids ...string) {
+	key := invalidationKey(kind, ids...)
+	if err := r.invalidations.Publish(ctx, key); err != nil {
+		log.Errorf(ctx, err, "Failed to publish cache invalidation, key=%s", key)
+	}
+}
+
+// subscribeInvalidations evicts cache entries as other replicas write them, until ctx is done.
+func (r *repo) subscribeInvalidations(ctx context.Context) {
+	if err := r.invalidations.Subscribe(ctx, r.evict); err != nil {
+		log.Errorf(ctx, err, "Failed to subscribe to cache invalidations, caches may go stale")
+	}
+}
+
+// evict clears the cache entry named by an invalidation key.
+func (r *repo) evict(key string) {
+	if key == lib.InvalidateAll {
+		r.cache.Drop()
+		r.roles.Drop()
+		r.palettes.Drop()
+		return
+	}
+
+	kind, ids, _ := strings.Cut(key, ":")
+	guildID, userID, _ := strings.Cut(ids, ":")
+	switch cacheKind(kind) {
+	case paletteKind:
+		r.palettes.Delete(guildID)
+	case roleKind:
+		r.roles.Delete(memberKey{guildID, userID})
+	case stateKind:
+		if ids == "" {
+			r.cache.Drop()
+			return
+		}
+		r.cache.Delete(memberKey{guildID, userID})
+	}
+}
+
+// FetchLegacyStateClaimed returns whether state recorded before colours were kept per guild has
+// been claimed, or there was none to claim.
+func (r *repo) FetchLegacyStateClaimed(ctx context.Context) (bool, error) {
+	_, err := database.QueryOne[string](ctx, r.db, "SELECT guildid FROM colours_legacy_claims")
+	switch {
+	case errors.Is(err, database.ErrNoRecords):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
+
+// ClaimLegacyState assigns state and history recorded before colours were kept per guild, which
+// have no guild ID, to the given guild, and records the claim so that it is only done once. It
+// returns how many rows of state were claimed.
+func (r *repo) ClaimLegacyState(ctx context.Context, guildID string) (int64, error) {
+	var claimed int64
+	err := database.WithTransaction(ctx, r.db, func(ctx context.Context, tx database.ITransaction) error {
+		res, err := tx.Exec(ctx, "UPDATE colours SET guildid = $1 WHERE guildid = ''", guildID)
+		if err != nil {
+			return err
+		}
+		if claimed, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, "UPDATE colours_log SET guildid = $1 WHERE guildid = ''", guildID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			"INSERT INTO colours_legacy_claims(guildid, claimed, tstamp) VALUES ($1, $2, $3)",
+			guildID, claimed, time.Now(),
+		)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	// Members of the guild may have been cached without their legacy state
+	r.cache.Drop()
+	r.publishInvalidation(ctx, stateKind)
+	return claimed, nil
 }
@@ -0,0 +1,347 @@
+package colours
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/testfixtures/dbtest"
+
+	dgo "github.com/bwmarrin/discordgo"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_repo_backends(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		mem := newTestMember(gomock.NewController(t))
+		colour := &Colour{R: 1, G: 0.5, B: 0}
+
+		// Each read uses a new repo, so that it comes from the database instead of the cache
+		fetch := func(reason Reason) time.Time {
+			state, err := newRepo(db).FetchUserState(ctx, mem, reason)
+			assert.NoError(t, err)
+			return state
+		}
+
+		assert.Equal(t, Never, fetch(Reroll))
+		assert.NoError(t, newRepo(db).UpdateReroll(ctx, mem, colour))
+		assert.WithinDuration(t, time.Now(), fetch(Reroll), time.Minute)
+
+		penalty := time.Now().Add(time.Hour)
+		assert.NoError(t, newRepo(db).UpdateRerollPenalty(ctx, mem, penalty))
+		assert.WithinDuration(t, penalty, fetch(Reroll), time.Second)
+
+		assert.NoError(t, newRepo(db).UpdateFreeze(ctx, mem))
+		assert.WithinDuration(t, time.Now(), fetch(Freeze), time.Minute)
+		assert.NoError(t, newRepo(db).UpdateUnfreeze(ctx, mem))
+		assert.Equal(t, Never, fetch(Freeze))
+
+		// Only changes of colour are listed in the history
+		history, err := newRepo(db).FetchUserHistory(ctx, mem, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		if assert.Len(t, history, 1) {
+			assert.Equal(t, colour.ToHexcode(), history[0].ColourHex)
+			assert.Equal(t, Reroll.String(), history[0].Reason)
+		}
+	}, &Cog{})
+}
+
+func Test_repo_backends_guildScoped(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		ctrl := gomock.NewController(t)
+		inGuildA := newTestMemberInGuild(ctrl, "1")
+		inGuildB := newTestMemberInGuild(ctrl, "2")
+		r := newRepo(db)
+
+		done, err := r.FetchLegacyStateClaimed(ctx)
+		assert.NoError(t, err)
+		assert.True(t, done, "a new database has no legacy state to claim")
+
+		// Rows from before guild IDs were recorded, in a database migrated with them
+		_, err = db.Exec(ctx, "DELETE FROM colours_legacy_claims")
+		assert.NoError(t, err)
+		_, err = db.Exec(ctx, "INSERT INTO colours(userid, tstamp, reason) VALUES ($1, $2, $3)",
+			inGuildA.UserID(), time.Now(), Freeze.String())
+		assert.NoError(t, err)
+		_, err = db.Exec(ctx, "INSERT INTO colours_log(userid, username, colour, reason, tstamp) VALUES ($1, $2, $3, $4, $5)",
+			inGuildA.UserID(), inGuildA.Username(), "ff8000", Reroll.String(), time.Now())
+		assert.NoError(t, err)
+
+		frozen, err := r.FetchUserState(ctx, inGuildA, Freeze)
+		assert.NoError(t, err)
+		assert.Equal(t, Never, frozen, "legacy state should not apply until it is claimed")
+
+		done, err = r.FetchLegacyStateClaimed(ctx)
+		assert.NoError(t, err)
+		assert.False(t, done)
+
+		claimed, err := r.ClaimLegacyState(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), claimed)
+
+		done, err = r.FetchLegacyStateClaimed(ctx)
+		assert.NoError(t, err)
+		assert.True(t, done)
+
+		frozen, err = r.FetchUserState(ctx, inGuildA, Freeze)
+		assert.NoError(t, err)
+		assert.NotEqual(t, Never, frozen)
+
+		// The same user has separate state and history in another guild
+		assert.NoError(t, r.UpdateReroll(ctx, inGuildB, &Colour{R: 0, G: 0, B: 1}))
+		for _, fresh := range []*repo{r, newRepo(db)} {
+			frozen, err = fresh.FetchUserState(ctx, inGuildB, Freeze)
+			assert.NoError(t, err)
+			assert.Equal(t, Never, frozen)
+		}
+
+		since := time.Now().Add(-time.Hour)
+		history, err := r.FetchUserHistory(ctx, inGuildA, since)
+		assert.NoError(t, err)
+		if assert.Len(t, history, 1) {
+			assert.Equal(t, "ff8000", history[0].ColourHex)
+		}
+		history, err = r.FetchUserHistory(ctx, inGuildB, since)
+		assert.NoError(t, err)
+		if assert.Len(t, history, 1) {
+			assert.Equal(t, "0000ff", history[0].ColourHex)
+		}
+	}, &Cog{})
+}
+
+func Test_repo_backends_palettes(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		writer := NewRepository(db)
+		reader := NewRepository(db)
+
+		// Warm the reader's cache
+		palette, err := reader.FetchPalette(ctx, "1")
+		assert.NoError(t, err)
+		assert.Nil(t, palette)
+
+		pastel, err := ParsePalette("pastel, #1abc9c")
+		assert.NoError(t, err)
+		assert.NoError(t, writer.UpdatePalette(ctx, "1", pastel))
+		palette, err = writer.FetchPalette(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, pastel, palette)
+
+		// Notifications may arrive asynchronously, depending on the backend
+		assert.Eventually(t, func() bool {
+			palette, err := reader.FetchPalette(ctx, "1")
+			return err == nil && palette.String() == "pastel, #1abc9c"
+		}, 5*time.Second, 50*time.Millisecond)
+
+		// Palettes are replaced, and only apply to their guild
+		neon, err := ParsePalette("neon")
+		assert.NoError(t, err)
+		assert.NoError(t, writer.UpdatePalette(ctx, "1", neon))
+		palette, err = newRepo(db).FetchPalette(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, neon, palette)
+		palette, err = newRepo(db).FetchPalette(ctx, "2")
+		assert.NoError(t, err)
+		assert.Nil(t, palette)
+
+		assert.NoError(t, writer.UpdatePalette(ctx, "1", nil))
+		palette, err = newRepo(db).FetchPalette(ctx, "1")
+		assert.NoError(t, err)
+		assert.Nil(t, palette)
+	}, &Cog{})
+}
+
+func Test_repo_backends_invalidatesReplicas(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		mem := newTestMember(gomock.NewController(t))
+		writer := NewRepository(db)
+		reader := NewRepository(db)
+
+		// Warm the reader's caches
+		role, err := reader.FetchColourRole(ctx, mem)
+		assert.NoError(t, err)
+		assert.Empty(t, role)
+		reroll, err := reader.FetchUserState(ctx, mem, Reroll)
+		assert.NoError(t, err)
+		assert.Equal(t, Never, reroll)
+
+		assert.NoError(t, writer.UpdateColourRole(ctx, mem, "789"))
+		assert.NoError(t, writer.UpdateReroll(ctx, mem, &Colour{R: 1}))
+
+		// Notifications may arrive asynchronously, depending on the backend
+		assert.Eventually(t, func() bool {
+			role, err := reader.FetchColourRole(ctx, mem)
+			return err == nil && role == "789"
+		}, 5*time.Second, 50*time.Millisecond)
+		assert.Eventually(t, func() bool {
+			reroll, err := reader.FetchUserState(ctx, mem, Reroll)
+			return err == nil && reroll != Never
+		}, 5*time.Second, 50*time.Millisecond)
+
+		assert.NoError(t, writer.DeleteColourRole(ctx, mem.Guild().ID(), "789"))
+		assert.NoError(t, writer.UpdateFreeze(ctx, mem))
+		assert.Eventually(t, func() bool {
+			role, err := reader.FetchColourRole(ctx, mem)
+			return err == nil && role == ""
+		}, 5*time.Second, 50*time.Millisecond)
+		assert.Eventually(t, func() bool {
+			frozen, err := reader.FetchUserState(ctx, mem, Freeze)
+			return err == nil && frozen != Never
+		}, 5*time.Second, 50*time.Millisecond)
+	}, &Cog{})
+}
+
+func Test_repo_backends_lastChanges(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		ctrl := gomock.NewController(t)
+		inGuild := newTestMemberInGuild(ctrl, "1")
+		inOtherGuild := newTestMemberInGuild(ctrl, "2")
+		r := newRepo(db)
+
+		before := time.Now().Add(-time.Hour)
+		assert.NoError(t, r.update(ctx, inGuild, Reroll, &Colour{R: 1}, before))
+		assert.NoError(t, r.update(ctx, inGuild, Mutate, &Colour{G: 1}, before.Add(time.Minute)))
+		// Freezes don't change the colour
+		assert.NoError(t, r.update(ctx, inGuild, Freeze, nil, time.Now()))
+		assert.NoError(t, r.update(ctx, inOtherGuild, Reroll, &Colour{B: 1}, time.Now()))
+
+		changes, err := r.FetchLastChanges(ctx, "1")
+		assert.NoError(t, err)
+		if assert.Len(t, changes, 1) {
+			assert.WithinDuration(t, before.Add(time.Minute), changes[inGuild.UserID()], time.Second)
+		}
+
+		changes, err = r.FetchLastChanges(ctx, "3")
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	}, &Cog{})
+}
+
+func Test_repo_backends_colourRoles(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		member := func(userID string) IDomainMember {
+			return NewDomainMember(&dgo.Member{GuildID: "1", User: &dgo.User{ID: userID}}, nil)
+		}
+		writer := newRepo(db)
+
+		roleID, err := writer.FetchColourRole(ctx, member("2"))
+		assert.NoError(t, err)
+		assert.Empty(t, roleID)
+
+		assert.NoError(t, writer.UpdateColourRole(ctx, member("2"), "10"))
+		assert.NoError(t, writer.UpdateColourRole(ctx, member("2"), "11"))
+		assert.NoError(t, writer.UpdateColourRole(ctx, member("3"), "12"))
+		roleID, err = writer.FetchColourRole(ctx, member("2"))
+		assert.NoError(t, err)
+		assert.Equal(t, "11", roleID)
+		roles, err := newRepo(db).FetchColourRoles(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"2": "11", "3": "12"}, roles)
+
+		// Deleting forgets the role, including from the cache
+		assert.NoError(t, writer.DeleteColourRole(ctx, "1", "11"))
+		assert.NoError(t, writer.DeleteColourRole(ctx, "1", "not a colour role"))
+		roleID, err = writer.FetchColourRole(ctx, member("2"))
+		assert.NoError(t, err)
+		assert.Empty(t, roleID)
+
+		// Adopting keeps roles that are already recorded
+		adopted, err := writer.FetchRolesAdopted(ctx, "1")
+		assert.NoError(t, err)
+		assert.False(t, adopted)
+		assert.NoError(t, writer.UpdateRolesAdopted(ctx, "1", map[string]string{"2": "20", "3": "21"}))
+		adopted, err = newRepo(db).FetchRolesAdopted(ctx, "1")
+		assert.NoError(t, err)
+		assert.True(t, adopted)
+		roleID, err = writer.FetchColourRole(ctx, member("2"))
+		assert.NoError(t, err)
+		assert.Equal(t, "20", roleID)
+		roles, err = newRepo(db).FetchColourRoles(ctx, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"2": "20", "3": "12"}, roles)
+
+		adopted, err = writer.FetchRolesAdopted(ctx, "2")
+		assert.NoError(t, err)
+		assert.False(t, adopted)
+	}, &Cog{})
+}
+
+func Test_repo_backends_swap(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		ctrl := gomock.NewController(t)
+		for _, policy := range []SwapCooldownPolicy{SwapRestartsCooldown, SwapClearsCooldown, SwapKeepsCooldown} {
+			t.Run(string(policy), func(t *testing.T) {
+				from := newTestMemberInGuild(ctrl, string(policy)+"-from")
+				to := newTestMemberInGuild(ctrl, string(policy)+"-to")
+				rerolled := time.Now().Add(-time.Hour)
+				for _, mem := range []IDomainMember{from, to} {
+					assert.NoError(t, newRepo(db).update(ctx, mem, Reroll, &Colour{R: 1}, rerolled))
+				}
+
+				swap := &ColourSwap{
+					Members:  [2]IDomainMember{from, to},
+					Colours:  [2]*Colour{{G: 1}, {B: 1}},
+					Cooldown: policy,
+				}
+				assert.NoError(t, newRepo(db).UpdateSwap(ctx, swap))
+
+				for i, mem := range swap.Members {
+					reroll, err := newRepo(db).FetchUserState(ctx, mem, Reroll)
+					assert.NoError(t, err)
+					switch policy {
+					case SwapRestartsCooldown:
+						assert.WithinDuration(t, time.Now(), reroll, time.Minute)
+					case SwapClearsCooldown:
+						assert.Equal(t, Never, reroll)
+					case SwapKeepsCooldown:
+						assert.WithinDuration(t, rerolled, reroll, time.Second)
+					}
+
+					// Swaps are part of the history
+					history, err := newRepo(db).FetchUserHistory(ctx, mem, rerolled.Add(time.Second))
+					assert.NoError(t, err)
+					if assert.Len(t, history, 1) {
+						assert.Equal(t, Swap.String(), history[0].Reason)
+						assert.Equal(t, swap.Colours[i].ToHexcode(), history[0].ColourHex)
+					}
+				}
+			})
+		}
+	}, &Cog{})
+}
+
+func Test_migrations_backends_rollBack(t *testing.T) {
+	dbtest.ForEachBackend(t, func(t *testing.T, db database.IDatabase) {
+		ctx := context.Background()
+		cog := &Cog{}
+		schemas, err := database.LoadMigrations(ctx, db, cog.Name(), cog.MigrationsFS())
+		assert.NoError(t, err)
+		mem := newTestMember(gomock.NewController(t))
+		assert.NoError(t, newRepo(db).UpdateReroll(ctx, mem, &Colour{R: 1}))
+
+		// Migrations since colour state was kept per guild can be reverted, and applied again
+		rolledBack, err := db.Rollback(ctx, "1650861605", schemas)
+		assert.NoError(t, err)
+		assert.Contains(t, rolledBack, "colours/1792486800")
+		assert.Equal(t, "colours/1792746000", rolledBack[0])
+		records, err := db.Migrations(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, records, 3)
+
+		for _, schema := range schemas {
+			_, err := db.Migrate(ctx, schema)
+			assert.NoError(t, err)
+		}
+		state, err := newRepo(db).FetchUserState(ctx, mem, Reroll)
+		assert.NoError(t, err)
+		assert.Equal(t, Never, state, "state left without a guild is not the member's")
+	}, &Cog{})
+}
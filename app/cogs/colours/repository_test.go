@@ -8,14 +8,20 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/lib"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
 func newTestMember(ctrl *gomock.Controller) *MockIDomainMember {
+	return newTestMemberInGuild(ctrl, "456456456456")
+}
+
+func newTestMemberInGuild(ctrl *gomock.Controller, guildID string) *MockIDomainMember {
 	mem := NewMockIDomainMember(ctrl)
 	mem.EXPECT().UserID().Return("123123123123").AnyTimes()
 	mem.EXPECT().Username().Return("Username").AnyTimes()
+	mem.EXPECT().Guild().Return(NewDomainGuild(guildID)).AnyTimes()
 	return mem
 }
 
@@ -37,6 +43,7 @@ func Test_FetchUserState_whenCached_returnCachedWithoutDatabaseCall(t *testing.T
 
 	mutateTime := time.Now()
 	repo.cachePut(&ColourState{
+		GuildID:    mem.Guild().ID(),
 		UserID:     mem.UserID(),
 		LastMutate: mutateTime,
 		LastReroll: Never,
@@ -67,7 +74,7 @@ func Test_FetchUserState_whenNoTimestampInDB_returnNever(t *testing.T) {
 		Reroll: Never,
 		Freeze: Never,
 	} {
-		dbMock.ExpectQuery(`SELECT userid, tstamp, reason FROM colours WHERE userid = \$1`).
+		dbMock.ExpectQuery(`SELECT guildid, userid, tstamp, reason FROM colours WHERE guildid = \$1 AND userid = \$2`).
 			WillReturnError(sql.ErrNoRows)
 		actual, err := repo.FetchUserState(context.Background(), mem, reason)
 		assert.NoError(t, err)
@@ -84,7 +91,7 @@ func Test_FetchUserState_cacheReadThrough(t *testing.T) {
 	repo := newRepo(db)
 	lastReroll := time.Now()
 
-	dbMock.ExpectQuery(`SELECT userid, tstamp, reason FROM colours WHERE userid = \$1`).
+	dbMock.ExpectQuery(`SELECT guildid, userid, tstamp, reason FROM colours WHERE guildid = \$1 AND userid = \$2`).
 		WillReturnRows(sqlmock.
 			NewRows([]string{"userid", "tstamp", "reason"}).
 			AddRow(mem.UserID(), lastReroll, "reroll"))
@@ -108,10 +115,10 @@ func Test_queryUserState_whenNoTimestampInDB_returnError(t *testing.T) {
 
 	repo := newRepo(db)
 
-	dbMock.ExpectQuery(`SELECT userid, tstamp, reason FROM colours WHERE userid = \$1`).
+	dbMock.ExpectQuery(`SELECT guildid, userid, tstamp, reason FROM colours WHERE guildid = \$1 AND userid = \$2`).
 		WillReturnError(sql.ErrNoRows)
 
-	state, err := repo.queryUserState(context.Background(), mem.UserID())
+	state, err := repo.queryUserState(context.Background(), keyOf(mem))
 
 	assert.ErrorIs(t, err, database.ErrNoRecords)
 	assert.Nil(t, state)
@@ -128,20 +135,23 @@ func Test_queryUserState_whenTimestampsInDB_returnTimestamps(t *testing.T) {
 	lastMutate := time.Now().Add(-5 * time.Hour)
 	lastReroll := time.Now().Add(-1 * time.Hour)
 	lastFrozen := time.Now().Add(-2 * time.Hour)
+	lastPick := time.Now().Add(-3 * time.Hour)
 
-	dbMock.ExpectQuery(`SELECT userid, tstamp, reason FROM colours WHERE userid = \$1`).
+	dbMock.ExpectQuery(`SELECT guildid, userid, tstamp, reason FROM colours WHERE guildid = \$1 AND userid = \$2`).
 		WillReturnRows(sqlmock.
 			NewRows([]string{"userid", "tstamp", "reason"}).
 			AddRow(mem.UserID(), lastMutate, "mutate").
 			AddRow(mem.UserID(), lastReroll, "reroll").
-			AddRow(mem.UserID(), lastFrozen, "freeze"))
-	state, err := repo.queryUserState(context.Background(), mem.UserID())
+			AddRow(mem.UserID(), lastFrozen, "freeze").
+			AddRow(mem.UserID(), lastPick, "pick"))
+	state, err := repo.queryUserState(context.Background(), keyOf(mem))
 
 	assert.NoError(t, err)
 	assert.Equal(t, mem.UserID(), state.UserID)
 	assert.Equal(t, lastMutate, state.LastMutate, "lastMutate does not match expected")
 	assert.Equal(t, lastReroll, state.LastReroll, "lastReroll does not match expected")
 	assert.Equal(t, lastFrozen, state.LastFrozen, "lastFrozen does not match expected")
+	assert.Equal(t, lastPick, state.LastPick, "lastPick does not match expected")
 }
 
 func Test_queryUserState_whenSomeTimestampsInDB_returnNeverForLackingRecords(t *testing.T) {
@@ -154,11 +164,11 @@ func Test_queryUserState_whenSomeTimestampsInDB_returnNeverForLackingRecords(t *
 
 	lastMutate := time.Now().Add(-5 * time.Hour)
 
-	dbMock.ExpectQuery(`SELECT userid, tstamp, reason FROM colours WHERE userid = \$1`).
+	dbMock.ExpectQuery(`SELECT guildid, userid, tstamp, reason FROM colours WHERE guildid = \$1 AND userid = \$2`).
 		WillReturnRows(sqlmock.
 			NewRows([]string{"userid", "tstamp", "reason"}).
 			AddRow(mem.UserID(), lastMutate, "mutate"))
-	state, err := repo.queryUserState(context.Background(), mem.UserID())
+	state, err := repo.queryUserState(context.Background(), keyOf(mem))
 
 	assert.NoError(t, err)
 	assert.Equal(t, mem.UserID(), state.UserID)
@@ -182,18 +192,18 @@ func Test_UpdateFoo(t *testing.T) {
 		Freeze: func() error { return repo.UpdateFreeze(context.Background(), mem) },
 	} {
 		dbMock.ExpectBegin()
-		dbMock.ExpectExec(`DELETE FROM colours WHERE userid = \$1 AND reason = \$2`).
+		dbMock.ExpectExec(`DELETE FROM colours WHERE guildid = \$1 AND userid = \$2 AND reason = \$3`).
 			WillReturnResult(sqlmock.NewResult(1, 0))
-		dbMock.ExpectExec(`INSERT INTO colours\(userid, tstamp, reason\) VALUES \(\$1, \$2, \$3\)`).
+		dbMock.ExpectExec(`INSERT INTO colours\(guildid, userid, tstamp, reason\) VALUES \(\$1, \$2, \$3, \$4\)`).
 			WillReturnResult(sqlmock.NewResult(1, 0))
-		dbMock.ExpectCommit()
 		dbMock.ExpectExec(`INSERT INTO colours_log\(.+\) VALUES \(.+\)`).
 			WillReturnResult(sqlmock.NewResult(1, 0))
+		dbMock.ExpectCommit()
 
 		err = method()
 		assert.NoError(t, err)
 
-		tstamp, ok := repo.cachePeek(mem.UserID(), reason)
+		tstamp, ok := repo.cachePeek(keyOf(mem), reason)
 		assert.True(t, ok)
 		assert.False(t, tstamp.IsZero())
 	}
@@ -204,16 +214,18 @@ func Test_UpdateUnfreeze(t *testing.T) {
 	mem := newTestMember(ctrl)
 	db, dbMock, err := database.NewMockDBClient(t)
 	assert.NoError(t, err)
-	dbMock.ExpectExec(`DELETE FROM colours WHERE userid = \$1 AND reason = \$2`).
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(`DELETE FROM colours WHERE guildid = \$1 AND userid = \$2 AND reason = \$3`).
 		WillReturnResult(sqlmock.NewResult(1, 0))
 	dbMock.ExpectExec(`INSERT INTO colours_log\(.+\) VALUES \(.+\)`).
 		WillReturnResult(sqlmock.NewResult(1, 0))
+	dbMock.ExpectCommit()
 
 	repo := newRepo(db)
 	err = repo.UpdateUnfreeze(context.Background(), mem)
 	assert.NoError(t, err)
 
-	freezeTime, ok := repo.cachePeek(mem.UserID(), Freeze)
+	freezeTime, ok := repo.cachePeek(keyOf(mem), Freeze)
 	assert.True(t, ok)
 	assert.Equal(t, Never, freezeTime)
 }
@@ -227,17 +239,42 @@ func Test_UpdateReroll(t *testing.T) {
 	repo := newRepo(db)
 
 	dbMock.ExpectBegin()
-	dbMock.ExpectExec(`DELETE FROM colours WHERE userid = \$1 AND reason = \$2`).
+	dbMock.ExpectExec(`DELETE FROM colours WHERE guildid = \$1 AND userid = \$2 AND reason = \$3`).
 		WillReturnResult(sqlmock.NewResult(1, 0))
-	dbMock.ExpectExec(`INSERT INTO colours\(userid, tstamp, reason\) VALUES \(\$1, \$2, \$3\)`).
+	dbMock.ExpectExec(`INSERT INTO colours\(guildid, userid, tstamp, reason\) VALUES \(\$1, \$2, \$3, \$4\)`).
 		WillReturnResult(sqlmock.NewResult(1, 0))
-	dbMock.ExpectCommit()
 	dbMock.ExpectExec(`INSERT INTO colours_log\(.+\) VALUES \(.+\)`).
 		WillReturnResult(sqlmock.NewResult(1, 0))
+	dbMock.ExpectCommit()
 	err = repo.UpdateReroll(context.Background(), mem, &Colour{1, 1, 1})
 	assert.NoError(t, err)
 }
 
+func Test_UpdateReroll_rollsBackWhenLogFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mem := newTestMember(ctrl)
+	db, dbMock, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+
+	repo := newRepo(db)
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(`DELETE FROM colours WHERE guildid = \$1 AND userid = \$2 AND reason = \$3`).
+		WillReturnResult(sqlmock.NewResult(1, 0))
+	dbMock.ExpectExec(`INSERT INTO colours\(guildid, userid, tstamp, reason\) VALUES \(\$1, \$2, \$3, \$4\)`).
+		WillReturnResult(sqlmock.NewResult(1, 0))
+	dbMock.ExpectExec(`INSERT INTO colours_log\(.+\) VALUES \(.+\)`).
+		WillReturnError(assert.AnError)
+	dbMock.ExpectRollback()
+	err = repo.UpdateReroll(context.Background(), mem, &Colour{1, 1, 1})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+
+	// The cooldown isn't cached, since it was never saved
+	_, ok := repo.cachePeek(keyOf(mem), Reroll)
+	assert.False(t, ok)
+}
+
 func Test_UpdateRerollPenalty(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mem := newTestMember(ctrl)
@@ -246,9 +283,86 @@ func Test_UpdateRerollPenalty(t *testing.T) {
 
 	repo := newRepo(db)
 
-	dbMock.ExpectExec(`UPDATE colours SET tstamp=\$1 WHERE userid=\$2 AND reason=\$3`).
+	dbMock.ExpectExec(`UPDATE colours SET tstamp=\$1 WHERE guildid=\$2 AND userid=\$3 AND reason=\$4`).
 		WillReturnResult(sqlmock.NewResult(1, 0))
 
 	err = repo.UpdateRerollPenalty(context.Background(), mem, time.Now())
 	assert.NoError(t, err)
 }
+
+func Test_FetchPalette_ignoresUnreadablePalette(t *testing.T) {
+	db, dbMock, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+	repo := newRepo(db)
+
+	dbMock.ExpectQuery(`SELECT guildid, palette, tstamp FROM colours_palettes WHERE guildid = \$1`).
+		WillReturnRows(sqlmock.
+			NewRows([]string{"guildid", "palette", "tstamp"}).
+			AddRow("1", "a preset that was removed", time.Now()))
+
+	// Colours carry on as if there were no palette, and the database is not asked again
+	for i := 0; i < 2; i++ {
+		palette, err := repo.FetchPalette(context.Background(), "1")
+		assert.NoError(t, err)
+		assert.Nil(t, palette)
+	}
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_FetchPalette_error(t *testing.T) {
+	db, dbMock, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+	repo := newRepo(db)
+
+	dbMock.ExpectQuery(`SELECT guildid, palette, tstamp FROM colours_palettes`).WillReturnError(sql.ErrConnDone)
+	_, err = repo.FetchPalette(context.Background(), "1")
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+
+	// Failures are not cached
+	_, cached := repo.palettes.Peek("1")
+	assert.False(t, cached)
+}
+
+func Test_repo_evict(t *testing.T) {
+	db, _, err := database.NewMockDBClient(t)
+	assert.NoError(t, err)
+	r := newRepo(db)
+	inGuild := memberKey{"1", "2"}
+	inOtherGuild := memberKey{"3", "2"}
+	for _, key := range []memberKey{inGuild, inOtherGuild} {
+		r.cache.Put(&cachedState{key, map[Reason]time.Time{Reroll: time.Now()}})
+		r.roles.Put(&cachedRole{key, "789"})
+		r.palettes.Put(&cachedPalette{key.guildID, nil})
+	}
+
+	r.evict(invalidationKey(roleKind, "1", "2"))
+	r.evict(invalidationKey(stateKind, "1", "2"))
+	r.evict(invalidationKey(paletteKind, "1"))
+	_, ok := r.roles.Peek(inGuild)
+	assert.False(t, ok)
+	_, ok = r.cache.Peek(inGuild)
+	assert.False(t, ok)
+	_, ok = r.palettes.Peek("1")
+	assert.False(t, ok)
+
+	// Only the named entries are evicted
+	_, ok = r.roles.Peek(inOtherGuild)
+	assert.True(t, ok)
+	_, ok = r.cache.Peek(inOtherGuild)
+	assert.True(t, ok)
+	_, ok = r.palettes.Peek("3")
+	assert.True(t, ok)
+
+	// Claiming legacy state evicts every member's state
+	r.evict(invalidationKey(stateKind))
+	_, ok = r.cache.Peek(inOtherGuild)
+	assert.False(t, ok)
+	_, ok = r.roles.Peek(inOtherGuild)
+	assert.True(t, ok)
+
+	r.evict(lib.InvalidateAll)
+	_, ok = r.roles.Peek(inOtherGuild)
+	assert.False(t, ok)
+	_, ok = r.palettes.Peek("3")
+	assert.False(t, ok)
+}
@@ -0,0 +1,88 @@
+package colours
+
+// rolename.go names colour roles after their members, following a configurable template. Colour
+// roles are tracked by ID, so their names are only for show, and can change along with members'.
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultRoleNameTemplate = "{{.Username}}"
+	// Discord doesn't allow longer role names.
+	maxRoleNameLength = 100
+)
+
+var (
+	ErrRoleNameTemplate = errors.New("invalid role name template")
+
+	// legacyRolePattern matches the names that colour roles had before they were tracked by ID,
+	// the member's username and discriminator. It is only used to find those that weren't adopted.
+	legacyRolePattern = regexp.MustCompile(`^\w+#(0|\d{4})$`)
+)
+
+// RoleNameFields are the fields available to role name templates, like "{{.Nick}}'s colour".
+type RoleNameFields struct {
+	// Username is unique, like name or name#1234 for users who still have a discriminator.
+	Username string
+	// DisplayName is the name the member shows across Discord, or else their username.
+	DisplayName string
+	// Nick is the member's nickname in the guild, or else their display name.
+	Nick string
+}
+
+func roleNameFields(mem IDomainMember) RoleNameFields {
+	fields := RoleNameFields{
+		Username:    mem.Username(),
+		DisplayName: mem.DisplayName(),
+		Nick:        mem.Nick(),
+	}
+	if fields.Nick == "" {
+		fields.Nick = fields.DisplayName
+	}
+	return fields
+}
+
+// legacyRoleName is what the member's colour role was named before roles were tracked by ID, like
+// name#1234, or name#0 for users without a discriminator.
+func legacyRoleName(mem IDomainMember) string {
+	username := mem.Username()
+	if strings.Contains(username, "#") {
+		return username
+	}
+	return username + "#0"
+}
+
+// parseRoleNameTemplate parses the template, and tries it out so that unknown fields are caught.
+func parseRoleNameTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultRoleNameTemplate
+	}
+	tmpl, err := template.New("role name").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRoleNameTemplate, err)
+	}
+	sample := RoleNameFields{Username: "name", DisplayName: "Name", Nick: "Nick"}
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRoleNameTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// renderRoleName names a colour role after the member. Names that come out blank fall back to the
+// username, and long ones are cut to what Discord allows.
+func renderRoleName(tmpl *template.Template, mem IDomainMember) string {
+	var name strings.Builder
+	if err := tmpl.Execute(&name, roleNameFields(mem)); err != nil || strings.TrimSpace(name.String()) == "" {
+		return mem.Username()
+	}
+	runes := []rune(strings.TrimSpace(name.String()))
+	if len(runes) > maxRoleNameLength {
+		runes = runes[:maxRoleNameLength]
+	}
+	return string(runes)
+}
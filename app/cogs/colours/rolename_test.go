@@ -0,0 +1,63 @@
+package colours
+
+import (
+	"strings"
+	"testing"
+
+	dgo "github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Config_roleNameTemplate(t *testing.T) {
+	cfg := &Config{}
+	assert.NoError(t, cfg.validate())
+
+	cfg.RoleNameTemplate = "{{.Nick}}'s colour"
+	assert.NoError(t, cfg.validate())
+
+	for _, invalid := range []string{"{{.Nick", "{{.Surname}}"} {
+		cfg.RoleNameTemplate = invalid
+		assert.ErrorIs(t, cfg.validate(), ErrRoleNameTemplate)
+	}
+}
+
+func Test_renderRoleName(t *testing.T) {
+	member := func(user dgo.User, nick string) IDomainMember {
+		return NewDomainMember(&dgo.Member{User: &user, Nick: nick}, nil)
+	}
+	pomelo := member(dgo.User{Username: "some.one", Discriminator: "0", GlobalName: "Someone"}, "Sam")
+	legacy := member(dgo.User{Username: "old", Discriminator: "1234"}, "")
+
+	testCases := []struct {
+		template string
+		mem      IDomainMember
+		expect   string
+	}{
+		{"", pomelo, "some.one"},
+		{"", legacy, "old#1234"},
+		{"{{.DisplayName}}", pomelo, "Someone"},
+		{"{{.DisplayName}}", legacy, "old#1234"},
+		{"{{.Nick}}'s colour", pomelo, "Sam's colour"},
+		{"{{.Nick}}'s colour", legacy, "old#1234's colour"},
+		// Blank names fall back to the username
+		{"{{if false}}x{{end}} ", pomelo, "some.one"},
+		{strings.Repeat("x", 2*maxRoleNameLength), pomelo, strings.Repeat("x", maxRoleNameLength)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			tmpl, err := parseRoleNameTemplate(tc.template)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, renderRoleName(tmpl, tc.mem))
+		})
+	}
+}
+
+func Test_legacyRoleName(t *testing.T) {
+	member := func(discriminator string) IDomainMember {
+		return NewDomainMember(&dgo.Member{User: &dgo.User{Username: "name", Discriminator: discriminator}}, nil)
+	}
+	assert.Equal(t, "name#0", legacyRoleName(member("0")))
+	assert.Equal(t, "name#1234", legacyRoleName(member("1234")))
+	assert.True(t, legacyRolePattern.MatchString(legacyRoleName(member("0"))))
+	assert.False(t, legacyRolePattern.MatchString("Team#0 leads"))
+}
@@ -0,0 +1,61 @@
+package colours
+
+// swap.go models swaps, where two members trade colours.
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrSwapSelf           = errors.New("can't swap colours with yourself")
+	ErrSwapNoColourRole   = errors.New("member has no colour role to swap")
+	ErrSwapCooldownPolicy = errors.New("invalid swap cooldown policy")
+	ErrSwapTimeout        = errors.New("invalid swap timeout")
+)
+
+const (
+	// How long the target of a swap has to accept it, unless configured otherwise.
+	defaultSwapTimeout = time.Minute
+	// Prompts are edited when they time out, which Discord only allows for 15 minutes.
+	maxSwapTimeout = 15 * time.Minute
+)
+
+// SwapCooldownPolicy is what a swap does to the reroll cooldowns of both members.
+type SwapCooldownPolicy string
+
+const (
+	// SwapRestartsCooldown starts both reroll cooldowns over, as though both members had just
+	// rerolled. This is the default.
+	SwapRestartsCooldown SwapCooldownPolicy = "restart"
+	// SwapClearsCooldown lets both members reroll straight away.
+	SwapClearsCooldown SwapCooldownPolicy = "clear"
+	// SwapKeepsCooldown leaves both reroll cooldowns as they were.
+	SwapKeepsCooldown SwapCooldownPolicy = "keep"
+)
+
+func validateSwapCooldownPolicy(policy string) error {
+	switch SwapCooldownPolicy(policy) {
+	case "", SwapRestartsCooldown, SwapClearsCooldown, SwapKeepsCooldown:
+		return nil
+	}
+	return fmt.Errorf("%w: '%s', wanted '%s', '%s' or '%s'",
+		ErrSwapCooldownPolicy, policy, SwapRestartsCooldown, SwapClearsCooldown, SwapKeepsCooldown)
+}
+
+func validateSwapTimeout(secs int) error {
+	if secs < 0 || time.Duration(secs)*time.Second > maxSwapTimeout {
+		return fmt.Errorf("%w: %d seconds, wanted at most %d, or 0 for the default",
+			ErrSwapTimeout, secs, int(maxSwapTimeout.Seconds()))
+	}
+	return nil
+}
+
+// ColourSwap is a trade of colours between two members, who each end up with the other's colour.
+type ColourSwap struct {
+	Members [2]IDomainMember
+	// Colours are the colours that each of the members has after the swap.
+	Colours  [2]*Colour
+	Cooldown SwapCooldownPolicy
+}
@@ -0,0 +1,45 @@
+package colours
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Config_swap(t *testing.T) {
+	cfg := &Config{}
+	assert.NoError(t, cfg.validate())
+	assert.Equal(t, SwapRestartsCooldown, cfg.swapCooldownPolicy())
+	assert.Equal(t, time.Minute, cfg.swapTimeout())
+
+	cfg.SwapCooldownPolicy, cfg.SwapTimeoutSecs = "keep", 900
+	assert.NoError(t, cfg.validate())
+	assert.Equal(t, SwapKeepsCooldown, cfg.swapCooldownPolicy())
+	assert.Equal(t, 15*time.Minute, cfg.swapTimeout())
+
+	cfg.SwapCooldownPolicy = "forget"
+	assert.ErrorIs(t, cfg.validate(), ErrSwapCooldownPolicy)
+
+	cfg.SwapCooldownPolicy, cfg.SwapTimeoutSecs = "", 901
+	assert.ErrorIs(t, cfg.validate(), ErrSwapTimeout)
+}
+
+func Test_swapPrompts(t *testing.T) {
+	var prompts swapPrompts
+	_, ok := prompts.get("1")
+	assert.False(t, ok)
+
+	pending := &pendingSwap{fromID: "2", toID: "3"}
+	prompts.add("1", pending)
+	got, ok := prompts.get("1")
+	assert.True(t, ok)
+	assert.Equal(t, pending, got)
+
+	// Swaps are only taken once
+	got, ok = prompts.take("1")
+	assert.True(t, ok)
+	assert.Equal(t, pending, got)
+	_, ok = prompts.take("1")
+	assert.False(t, ok)
+}
@@ -2,12 +2,21 @@ package colours
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math"
+	"regexp"
+	"strings"
 
 	"github.com/fiffu/arisa3/lib"
 )
 
+var (
+	ErrInvalidColour = errors.New("not a hexcode or a known colour name")
+
+	hexcodePattern = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+)
+
 // Colour encodes a Colour Role's RGB value.
 type Colour struct {
 	R, G, B float64
@@ -84,6 +93,23 @@ func (c *Colour) FromRGBHex(rgbHex string) *Colour {
 	}
 }
 
+// ParseColour reads a colour given by a member, as a hexcode like "#1abc9c" or "1abc9c", the
+// shorthand "#1b9", or a colour name like "teal" or "cornflower blue".
+func ParseColour(input string) (*Colour, error) {
+	input = strings.TrimSpace(input)
+	if match := hexcodePattern.FindStringSubmatch(input); match != nil {
+		hexcode := match[1]
+		if len(hexcode) == 3 {
+			hexcode = string([]byte{hexcode[0], hexcode[0], hexcode[1], hexcode[1], hexcode[2], hexcode[2]})
+		}
+		return (&Colour{}).FromRGBHex(hexcode), nil
+	}
+	if colour, ok := LookupColourName(input); ok {
+		return colour, nil
+	}
+	return nil, fmt.Errorf("%w: '%s'", ErrInvalidColour, input)
+}
+
 // Random returns a new instance of Colour with freshly-seeded values.
 func (c *Colour) Random() *Colour {
 	return c.FromHSV(
@@ -110,6 +136,36 @@ func (c *Colour) Nudge() *Colour {
 	}
 }
 
+// ToHSV returns the Colour's hue, saturation and value, each from 0 to 1.
+func (c *Colour) ToHSV() (h, s, v float64) {
+	return rgbToHSV(c.R, c.G, c.B)
+}
+
+// rgbToHSV converts from RGB tuple to HSV colour space.
+func rgbToHSV(r, g, b float64) (h, s, v float64) {
+	// Adapted from Python stdlib, like hsvToRGB
+	maxc := math.Max(r, math.Max(g, b))
+	minc := math.Min(r, math.Min(g, b))
+	v = maxc
+	if minc == maxc {
+		return 0, 0, v
+	}
+	s = (maxc - minc) / maxc
+	rc := (maxc - r) / (maxc - minc)
+	gc := (maxc - g) / (maxc - minc)
+	bc := (maxc - b) / (maxc - minc)
+	switch maxc {
+	case r:
+		h = bc - gc
+	case g:
+		h = 2.0 + rc - bc
+	default:
+		h = 4.0 + gc - rc
+	}
+	h = math.Mod(h/6.0+1, 1)
+	return h, s, v
+}
+
 // hsvToRGB converts from HSV tuple to RGB colour space.
 func hsvToRGB(h, s, v float64) (r, g, b float64) {
 	// Adapted from Python stdlib
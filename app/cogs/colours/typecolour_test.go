@@ -116,3 +116,58 @@ func Test_ToHexcode(t *testing.T) {
 		r.ToHexcode(),
 	)
 }
+
+func Test_ParseColour(t *testing.T) {
+	testCases := []struct {
+		input     string
+		expectHex string
+		expectErr error
+	}{
+		{"#1abc9c", "1abc9c", nil},
+		{"1ABC9C", "1abc9c", nil},
+		{"#1b9", "11bb99", nil},
+		{"  teal ", "1abc9c", nil},
+		{"Sky-Blue", "87ceeb", nil},
+		{"skyblue", "87ceeb", nil},
+		{"#1abc9", "", ErrInvalidColour},
+		{"#zzzzzz", "", ErrInvalidColour},
+		{"not a colour", "", ErrInvalidColour},
+		{"", "", ErrInvalidColour},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			colour, err := ParseColour(tc.input)
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectHex, colour.ToHexcode())
+		})
+	}
+}
+
+func Test_ToHSV(t *testing.T) {
+	testCases := []struct {
+		hexcode string
+		h, s, v float64
+	}{
+		{"ff0000", 0, 1, 1},
+		{"00ff00", 1.0 / 3, 1, 1},
+		{"0000ff", 2.0 / 3, 1, 1},
+		{"808080", 0, 0, 128.0 / 255},
+		{"ff00bf", 315.0 / 360, 1, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.hexcode, func(t *testing.T) {
+			colour := (&Colour{}).FromRGBHex(tc.hexcode)
+			h, s, v := colour.ToHSV()
+			assert.InDelta(t, tc.h, h, 0.002)
+			assert.InDelta(t, tc.s, s, 0.002)
+			assert.InDelta(t, tc.v, v, 0.002)
+
+			// And back again
+			assert.Equal(t, tc.hexcode, (&Colour{}).FromHSV(h, s, v).ToHexcode())
+		})
+	}
+}
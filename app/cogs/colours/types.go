@@ -19,7 +19,9 @@ func (r Reason) String() string { return string(r) }
 const (
 	Mutate Reason = "mutate"
 	Reroll Reason = "reroll"
+	Pick   Reason = "pick"
 	Freeze Reason = "freeze"
+	Swap   Reason = "swap"
 )
 
 /* Sentinel values */
@@ -29,10 +31,12 @@ var Never = time.Time{} // the zero value
 
 // ColourState models a participant's state in the Colour Roles domain.
 type ColourState struct {
+	GuildID    string
 	UserID     string
 	LastFrozen time.Time
 	LastMutate time.Time
 	LastReroll time.Time
+	LastPick   time.Time
 }
 
 // History models a participant's history of colours.
@@ -43,13 +47,16 @@ type History struct {
 
 func isPartOfHistory(r Reason) bool {
 	switch r {
-	case Reroll, Mutate:
+	case Reroll, Mutate, Pick, Swap:
 		return true
 	default:
 		return false
 	}
 }
 
+// guildMembersPageSize is the most members that Discord lists in one request.
+const guildMembersPageSize = 1000
+
 // session implements IDomainSession
 type session struct {
 	sess         *discordgo.Session
@@ -70,7 +77,7 @@ func (s *session) GuildMember(ctx context.Context, guildID, userID string) (IDom
 	defer span.End()
 
 	// Cache lookup
-	if cached, ok := s.cacheMembers.Peek(userID); ok {
+	if cached, ok := s.cacheMembers.Peek(memberCacheKey(guildID, userID)); ok {
 		return cached, nil
 	}
 
@@ -101,6 +108,45 @@ func (s *session) GuildMember(ctx context.Context, guildID, userID string) (IDom
 	return d, nil
 }
 
+// GuildMembers lists every member of the guild, which needs the GUILD_MEMBERS intent.
+func (s *session) GuildMembers(ctx context.Context, guildID string) ([]IDomainMember, error) {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Vendor(s.sess.GuildMembers))
+	defer span.End()
+
+	allRoles, err := s.GuildRoles(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	rolesByID := make(map[string]IDomainRole)
+	for _, role := range allRoles {
+		rolesByID[role.ID()] = role
+	}
+
+	members := make([]IDomainMember, 0)
+	after := ""
+	for {
+		page, err := s.sess.GuildMembers(guildID, after, guildMembersPageSize, discordgo.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, mem := range page {
+			// Listed members don't carry their guild ID
+			mem.GuildID = guildID
+			roles := make([]IDomainRole, 0)
+			for _, roleID := range mem.Roles {
+				if role, ok := rolesByID[roleID]; ok {
+					roles = append(roles, role)
+				}
+			}
+			members = append(members, NewDomainMember(mem, roles))
+		}
+		if len(page) < guildMembersPageSize {
+			return members, nil
+		}
+		after = page[len(page)-1].User.ID
+	}
+}
+
 func (s *session) GuildRole(ctx context.Context, guildID, roleID string) (IDomainRole, error) {
 	if cached, ok := s.cacheRoles.Peek(roleID); ok {
 		return cached, nil
@@ -156,12 +202,14 @@ func (s *session) GuildRoleReorder(ctx context.Context, guildID string, roles []
 	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Vendor(s.sess.GuildRoleReorder))
 	defer span.End()
 
+	// Discord orders roles by the position field rather than by their order in the payload.
 	nativeRoles := make([]*discordgo.Role, 0)
-	for _, role := range roles {
+	for i, role := range roles {
 		nativeRole, err := s.guildRoleNative(ctx, guildID, role.ID())
 		if err != nil {
 			return err
 		}
+		nativeRole.Position = i
 		nativeRoles = append(nativeRoles, nativeRole)
 	}
 	_, err := s.sess.GuildRoleReorder(guildID, nativeRoles, discordgo.WithContext(ctx))
@@ -193,6 +241,17 @@ func (s *session) GuildRoleEdit(ctx context.Context, guildID, roleID, name strin
 	return nil
 }
 
+func (s *session) GuildRoleDelete(ctx context.Context, guildID, roleID string) error {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Vendor(s.sess.GuildRoleDelete))
+	defer span.End()
+
+	if err := s.sess.GuildRoleDelete(guildID, roleID, discordgo.WithContext(ctx)); err != nil {
+		return err
+	}
+	s.cacheRoles.Delete(roleID)
+	return nil
+}
+
 func (s *session) GuildMemberRoleAdd(ctx context.Context, guildID, userID, roleID string) error {
 	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Vendor(s.sess.GuildMemberRoleAdd))
 	defer span.End()
@@ -201,10 +260,27 @@ func (s *session) GuildMemberRoleAdd(ctx context.Context, guildID, userID, roleI
 	if err != nil {
 		return err
 	}
-	s.cacheMembers.Delete(userID)
+	s.cacheMembers.Delete(memberCacheKey(guildID, userID))
+	return nil
+}
+
+func (s *session) GuildMemberRoleRemove(ctx context.Context, guildID, userID, roleID string) error {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Vendor(s.sess.GuildMemberRoleRemove))
+	defer span.End()
+
+	err := s.sess.GuildMemberRoleRemove(guildID, userID, roleID, discordgo.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	s.cacheMembers.Delete(memberCacheKey(guildID, userID))
 	return nil
 }
 
+// memberCacheKey keys members by guild as well as user, since a user's roles differ in each guild.
+func memberCacheKey(guildID, userID string) string {
+	return guildID + "/" + userID
+}
+
 // guild implements IDomainGuild
 type guild struct {
 	id string
@@ -229,9 +305,26 @@ func NewDomainMember(mem *discordgo.Member, roles []IDomainRole) IDomainMember {
 func (m *member) Guild() IDomainGuild  { return NewDomainGuild(m.mem.GuildID) }
 func (m *member) UserID() string       { return m.mem.User.ID }
 func (m *member) Nick() string         { return m.mem.Nick }
-func (m *member) Username() string     { return m.mem.User.Username + "#" + m.mem.User.Discriminator }
 func (m *member) Roles() []IDomainRole { return m.roles }
-func (m *member) CacheKey() string     { return m.UserID() }
+func (m *member) CacheKey() string     { return memberCacheKey(m.mem.GuildID, m.UserID()) }
+
+// Username is the member's unique username. Users who haven't left the legacy discriminator system
+// keep their discriminator, like name#1234; everyone else has a discriminator of 0, which is dropped.
+func (m *member) Username() string {
+	user := m.mem.User
+	if user.Discriminator == "" || user.Discriminator == "0" {
+		return user.Username
+	}
+	return user.Username + "#" + user.Discriminator
+}
+
+// DisplayName is the name the member chose to show across Discord, or else their username.
+func (m *member) DisplayName() string {
+	if m.mem.User.GlobalName != "" {
+		return m.mem.User.GlobalName
+	}
+	return m.Username()
+}
 
 // colourRole implements IDomainRole.
 type colourRole struct {
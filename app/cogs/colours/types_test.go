@@ -0,0 +1,52 @@
+package colours
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fiffu/arisa3/testfixtures/discordtest"
+
+	dgo "github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_session_GuildRoleReorder(t *testing.T) {
+	const guildID = "1"
+	srv := discordtest.NewServer(t)
+	a := srv.AddRole(guildID, &dgo.Role{Name: "a"})
+	b := srv.AddRole(guildID, &dgo.Role{Name: "b"})
+	c := srv.AddRole(guildID, &dgo.Role{Name: "c"})
+	everyone := srv.Roles(guildID)[0]
+
+	// Roles are placed by their index in the list, not by the positions they had
+	s := NewDomainSession(srv.NewSession())
+	err := s.GuildRoleReorder(context.Background(), guildID, []IDomainRole{
+		NewDomainRole(everyone.ID, everyone.Name, 0),
+		NewDomainRole(c.ID, c.Name, 0),
+		NewDomainRole(a.ID, a.Name, 0),
+		NewDomainRole(b.ID, b.Name, 0),
+	})
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, role := range srv.Roles(guildID) {
+		names = append(names, role.Name)
+	}
+	assert.Equal(t, []string{"@everyone", "c", "a", "b"}, names)
+}
+
+func Test_member_fromPayload(t *testing.T) {
+	// A member as the API sends it, so that names are read from the fields discordgo decodes
+	payload := `{
+		"user": {"id": "2", "username": "some.one", "discriminator": "0", "global_name": "Someone"},
+		"nick": "Sam"
+	}`
+	var mem dgo.Member
+	assert.NoError(t, json.Unmarshal([]byte(payload), &mem))
+
+	m := NewDomainMember(&mem, nil)
+	assert.Equal(t, "some.one", m.Username())
+	assert.Equal(t, "Someone", m.DisplayName())
+	assert.Equal(t, "Sam", m.Nick())
+}
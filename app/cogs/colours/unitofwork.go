@@ -0,0 +1,53 @@
+package colours
+
+// unitofwork.go sequences changes to a member's colour across Discord and the database.
+
+import (
+	"context"
+
+	"github.com/fiffu/arisa3/app/log"
+)
+
+// compensation undoes a step of a unit of work that has already taken effect.
+type compensation struct {
+	name string
+	undo func(context.Context) error
+}
+
+// unitOfWork makes a colour change all-or-nothing. Discord API calls are made first, so that a
+// failed call doesn't consume the member's cooldown, and each call that succeeds registers a
+// compensation to undo it. The member's state is committed last, along with its audit log in a
+// single transaction; if that fails, the compensations put Discord back the way it was.
+type unitOfWork struct {
+	compensations []compensation
+}
+
+// onAbort registers a compensation for a step that just succeeded.
+func (u *unitOfWork) onAbort(name string, undo func(context.Context) error) {
+	u.compensations = append(u.compensations, compensation{name, undo})
+}
+
+// abort runs the compensations, newest first, and returns the error that caused the abort.
+// Compensations that fail are logged, since there is nothing more that can be done about them.
+func (u *unitOfWork) abort(ctx context.Context, cause error) error {
+	// The cause may be a cancelled context, but the compensations should run regardless
+	ctx = context.WithoutCancel(ctx)
+	for i := len(u.compensations) - 1; i >= 0; i-- {
+		c := u.compensations[i]
+		log.Infof(ctx, "Compensating with %s, due to: %v", c.name, cause)
+		if err := c.undo(ctx); err != nil {
+			log.Errorf(ctx, err, "Failed to compensate with %s", c.name)
+		}
+	}
+	u.compensations = nil
+	return cause
+}
+
+// commit saves the member's state, aborting if that fails.
+func (u *unitOfWork) commit(ctx context.Context, save func(context.Context) error) error {
+	if err := save(ctx); err != nil {
+		return u.abort(ctx, err)
+	}
+	u.compensations = nil
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/fiffu/arisa3/app/engine"
+	"github.com/fiffu/arisa3/app/instrumentation"
 	"github.com/fiffu/arisa3/app/types"
 	"github.com/fiffu/arisa3/lib"
 
@@ -18,10 +19,12 @@ type Cog struct {
 }
 
 func NewCog(a types.IApp) types.ICog {
-	return &Cog{
+	c := &Cog{
 		commands:    engine.NewCommandRegistry(),
-		pokiesCache: lib.NewCache[*cachedEmojis, string](1 * time.Hour),
+		pokiesCache: lib.NewCache[*cachedEmojis, string](1*time.Hour, lib.WithSweepInterval(1*time.Hour)),
 	}
+	instrumentation.ObserveCache("rng.pokies", c.pokiesCache.Stats)
+	return c
 }
 
 func (c *Cog) Name() string                                             { return "rng" }
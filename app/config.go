@@ -2,6 +2,8 @@ package app
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/fiffu/arisa3/app/log"
 	"github.com/fiffu/arisa3/lib/envconfig"
@@ -14,6 +16,39 @@ type Config struct {
 	DatabaseDSN string                 `mapstructure:"database_dsn" envvar:"DATABASE_URL"`
 	EnableDebug bool                   `mapstructure:"enable_debug" envvar:"ENABLE_DEBUG"`
 	Cogs        map[string]interface{} `mapstructure:"cogs"`
+
+	// CacheStoreURL is a redis:// URL of a store that replicas share their caches in. If unset,
+	// each replica caches in its own memory.
+	CacheStoreURL string `mapstructure:"cache_store_url" envvar:"CACHE_STORE_URL"`
+
+	// AcceptMigrationDrift is a comma-separated list of applied migrations, as <cog>/<version>,
+	// whose edited files should be accepted instead of failing startup.
+	AcceptMigrationDrift string `mapstructure:"accept_migration_drift" envvar:"ACCEPT_MIGRATION_DRIFT"`
+
+	// MigrationLockWaitSecs is how long to wait for another instance to finish its migrations.
+	MigrationLockWaitSecs int `mapstructure:"migration_lock_wait_secs" envvar:"MIGRATION_LOCK_WAIT_SECS"`
+}
+
+// Used if migration_lock_wait_secs is not set.
+const defaultMigrationLockWait = 2 * time.Minute
+
+// MigrationLockWait parses MigrationLockWaitSecs.
+func (c *Config) MigrationLockWait() time.Duration {
+	if c.MigrationLockWaitSecs <= 0 {
+		return defaultMigrationLockWait
+	}
+	return time.Duration(c.MigrationLockWaitSecs) * time.Second
+}
+
+// MigrationDriftOverrides parses AcceptMigrationDrift.
+func (c *Config) MigrationDriftOverrides() []string {
+	overrides := make([]string, 0)
+	for _, key := range strings.Split(c.AcceptMigrationDrift, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			overrides = append(overrides, key)
+		}
+	}
+	return overrides
 }
 
 func Configure(path string) (*Config, error) {
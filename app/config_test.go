@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/fiffu/arisa3/lib"
 	"github.com/stretchr/testify/assert"
@@ -49,3 +50,14 @@ func Test_Configure(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, cfg.BotSecret, "sample")
 }
+
+func Test_MigrationDriftOverrides(t *testing.T) {
+	cfg := &Config{AcceptMigrationDrift: " colours/1650549317, ,cardboard/1651148145"}
+	assert.Equal(t, []string{"colours/1650549317", "cardboard/1651148145"}, cfg.MigrationDriftOverrides())
+	assert.Empty(t, (&Config{}).MigrationDriftOverrides())
+}
+
+func Test_MigrationLockWait(t *testing.T) {
+	assert.Equal(t, defaultMigrationLockWait, (&Config{}).MigrationLockWait())
+	assert.Equal(t, 30*time.Second, (&Config{MigrationLockWaitSecs: 30}).MigrationLockWait())
+}
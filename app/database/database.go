@@ -4,8 +4,10 @@ package database
 import (
 	"context"
 	"errors"
+	"io/fs"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var (
@@ -20,6 +22,9 @@ type IDatabase interface {
 	// Close closes the database client.
 	Close(ctx context.Context) error
 
+	// Dialect names the SQL dialect of the database, which is one of Dialects.
+	Dialect() string
+
 	// Query queries the database, usually a SELECT.
 	Query(ctx context.Context, query string, args ...interface{}) (IRows, error)
 
@@ -32,10 +37,36 @@ type IDatabase interface {
 	// Migrate executes a schema for database migration.
 	Migrate(ctx context.Context, schema ISchema) (executed bool, err error)
 
-	// ParseMigration is a helper function for reading migrations.
-	ParseMigration(ctx context.Context, filepath string) (ISchema, error)
+	// ParseMigration is a helper function for reading migrations from a filesystem. Versions are
+	// unique within a namespace, which is usually the name of the cog that owns the migration.
+	ParseMigration(ctx context.Context, namespace string, fsys fs.FS, filepath string) (ISchema, error)
+
+	// AcceptChecksum records the schema's current checksum for an applied migration, so that
+	// changes to the migration's file are no longer reported as drift.
+	AcceptChecksum(ctx context.Context, schema ISchema) error
+
+	// Rollback reverts applied migrations newer than the target version, newest first. The
+	// schemas should include every known migration, so that each applied one can be reverted.
+	Rollback(ctx context.Context, target string, schemas []ISchema) (rolledBack []string, err error)
+
+	// Migrations lists the migrations recorded as applied, in order of version.
+	Migrations(ctx context.Context) ([]*MigrationRecord, error)
+
+	// Lock takes a named lock shared by every client of the database, waiting up to the given
+	// duration for other holders to release it. A zero wait makes exactly one attempt.
+	Lock(ctx context.Context, name string, wait time.Duration) (unlock UnlockFunc, acquired bool, err error)
+
+	// Notify sends a payload to every listener of the channel, including those of other clients.
+	Notify(ctx context.Context, channel, payload string) error
+
+	// Listen calls the handler with each notification on the channel, in the background, until
+	// ctx is done. Notifications sent before Listen returns may not be delivered.
+	Listen(ctx context.Context, channel string, handler func(Notification)) error
 }
 
+// UnlockFunc releases a lock obtained from IDatabase.Lock.
+type UnlockFunc func(ctx context.Context) error
+
 // ITransaction describes an interface of a database transaction.
 type ITransaction interface {
 	// Query queries the database, usually a SELECT.
@@ -57,17 +88,30 @@ type IResult interface {
 	RowsAffected() (int64, error)
 }
 
-// IRows represents an iterable cursor over items returned by a database query.
+// IRows represents an iterable cursor over items returned by a database query. Prefer QueryAll and
+// QueryOne, which close the rows and check for errors after iterating.
 type IRows interface {
 	Next() bool
 	Scan(dest ...interface{}) error
+	// Columns returns the names of the columns.
+	Columns() ([]string, error)
+	// Err returns the error, if any, that ended iteration early.
+	Err() error
+	// Close releases the connection. Rows are closed by iterating to the end, but must be closed
+	// explicitly if iteration stops early.
+	Close() error
 }
 
 // ISchema represents a schema used in database migrations.
 type ISchema interface {
+	Namespace() string
 	Version() string
 	Source() string
+	// Checksum is a digest of the queries, used to detect changes after the schema was applied.
+	Checksum() string
 	Queries() []string
+	// DownQueries revert the schema. This is empty for migrations that cannot be rolled back.
+	DownQueries() []string
 }
 
 var whitespace = regexp.MustCompile(`\s+`)
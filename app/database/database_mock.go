@@ -6,7 +6,9 @@ package database
 
 import (
 	context "context"
+	fs "io/fs"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,6 +36,20 @@ func (m *MockIDatabase) EXPECT() *MockIDatabaseMockRecorder {
 	return m.recorder
 }
 
+// AcceptChecksum mocks base method.
+func (m *MockIDatabase) AcceptChecksum(ctx context.Context, schema ISchema) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcceptChecksum", ctx, schema)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AcceptChecksum indicates an expected call of AcceptChecksum.
+func (mr *MockIDatabaseMockRecorder) AcceptChecksum(ctx, schema interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptChecksum", reflect.TypeOf((*MockIDatabase)(nil).AcceptChecksum), ctx, schema)
+}
+
 // Begin mocks base method.
 func (m *MockIDatabase) Begin(ctx context.Context) (context.Context, ITransaction, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +80,20 @@ func (mr *MockIDatabaseMockRecorder) Close(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockIDatabase)(nil).Close), ctx)
 }
 
+// Dialect mocks base method.
+func (m *MockIDatabase) Dialect() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dialect")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Dialect indicates an expected call of Dialect.
+func (mr *MockIDatabaseMockRecorder) Dialect() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dialect", reflect.TypeOf((*MockIDatabase)(nil).Dialect))
+}
+
 // Exec mocks base method.
 func (m *MockIDatabase) Exec(ctx context.Context, query string, args ...interface{}) (IResult, error) {
 	m.ctrl.T.Helper()
@@ -84,6 +114,36 @@ func (mr *MockIDatabaseMockRecorder) Exec(ctx, query interface{}, args ...interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockIDatabase)(nil).Exec), varargs...)
 }
 
+// Listen mocks base method.
+func (m *MockIDatabase) Listen(ctx context.Context, channel string, handler func(Notification)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Listen", ctx, channel, handler)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Listen indicates an expected call of Listen.
+func (mr *MockIDatabaseMockRecorder) Listen(ctx, channel, handler interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Listen", reflect.TypeOf((*MockIDatabase)(nil).Listen), ctx, channel, handler)
+}
+
+// Lock mocks base method.
+func (m *MockIDatabase) Lock(ctx context.Context, name string, wait time.Duration) (UnlockFunc, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lock", ctx, name, wait)
+	ret0, _ := ret[0].(UnlockFunc)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockIDatabaseMockRecorder) Lock(ctx, name, wait interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockIDatabase)(nil).Lock), ctx, name, wait)
+}
+
 // Migrate mocks base method.
 func (m *MockIDatabase) Migrate(ctx context.Context, schema ISchema) (bool, error) {
 	m.ctrl.T.Helper()
@@ -99,19 +159,48 @@ func (mr *MockIDatabaseMockRecorder) Migrate(ctx, schema interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrate", reflect.TypeOf((*MockIDatabase)(nil).Migrate), ctx, schema)
 }
 
+// Migrations mocks base method.
+func (m *MockIDatabase) Migrations(ctx context.Context) ([]*MigrationRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Migrations", ctx)
+	ret0, _ := ret[0].([]*MigrationRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Migrations indicates an expected call of Migrations.
+func (mr *MockIDatabaseMockRecorder) Migrations(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrations", reflect.TypeOf((*MockIDatabase)(nil).Migrations), ctx)
+}
+
+// Notify mocks base method.
+func (m *MockIDatabase) Notify(ctx context.Context, channel, payload string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Notify", ctx, channel, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Notify indicates an expected call of Notify.
+func (mr *MockIDatabaseMockRecorder) Notify(ctx, channel, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockIDatabase)(nil).Notify), ctx, channel, payload)
+}
+
 // ParseMigration mocks base method.
-func (m *MockIDatabase) ParseMigration(ctx context.Context, filepath string) (ISchema, error) {
+func (m *MockIDatabase) ParseMigration(ctx context.Context, namespace string, fsys fs.FS, filepath string) (ISchema, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ParseMigration", ctx, filepath)
+	ret := m.ctrl.Call(m, "ParseMigration", ctx, namespace, fsys, filepath)
 	ret0, _ := ret[0].(ISchema)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ParseMigration indicates an expected call of ParseMigration.
-func (mr *MockIDatabaseMockRecorder) ParseMigration(ctx, filepath interface{}) *gomock.Call {
+func (mr *MockIDatabaseMockRecorder) ParseMigration(ctx, namespace, fsys, filepath interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseMigration", reflect.TypeOf((*MockIDatabase)(nil).ParseMigration), ctx, filepath)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseMigration", reflect.TypeOf((*MockIDatabase)(nil).ParseMigration), ctx, namespace, fsys, filepath)
 }
 
 // Query mocks base method.
@@ -134,6 +223,21 @@ func (mr *MockIDatabaseMockRecorder) Query(ctx, query interface{}, args ...inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockIDatabase)(nil).Query), varargs...)
 }
 
+// Rollback mocks base method.
+func (m *MockIDatabase) Rollback(ctx context.Context, target string, schemas []ISchema) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rollback", ctx, target, schemas)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rollback indicates an expected call of Rollback.
+func (mr *MockIDatabaseMockRecorder) Rollback(ctx, target, schemas interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rollback", reflect.TypeOf((*MockIDatabase)(nil).Rollback), ctx, target, schemas)
+}
+
 // MockITransaction is a mock of ITransaction interface.
 type MockITransaction struct {
 	ctrl     *gomock.Controller
@@ -301,6 +405,49 @@ func (m *MockIRows) EXPECT() *MockIRowsMockRecorder {
 	return m.recorder
 }
 
+// Close mocks base method.
+func (m *MockIRows) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockIRowsMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockIRows)(nil).Close))
+}
+
+// Columns mocks base method.
+func (m *MockIRows) Columns() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Columns")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Columns indicates an expected call of Columns.
+func (mr *MockIRowsMockRecorder) Columns() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Columns", reflect.TypeOf((*MockIRows)(nil).Columns))
+}
+
+// Err mocks base method.
+func (m *MockIRows) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Err indicates an expected call of Err.
+func (mr *MockIRowsMockRecorder) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockIRows)(nil).Err))
+}
+
 // Next mocks base method.
 func (m *MockIRows) Next() bool {
 	m.ctrl.T.Helper()
@@ -356,6 +503,48 @@ func (m *MockISchema) EXPECT() *MockISchemaMockRecorder {
 	return m.recorder
 }
 
+// Checksum mocks base method.
+func (m *MockISchema) Checksum() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checksum")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Checksum indicates an expected call of Checksum.
+func (mr *MockISchemaMockRecorder) Checksum() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checksum", reflect.TypeOf((*MockISchema)(nil).Checksum))
+}
+
+// DownQueries mocks base method.
+func (m *MockISchema) DownQueries() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DownQueries")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// DownQueries indicates an expected call of DownQueries.
+func (mr *MockISchemaMockRecorder) DownQueries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownQueries", reflect.TypeOf((*MockISchema)(nil).DownQueries))
+}
+
+// Namespace mocks base method.
+func (m *MockISchema) Namespace() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Namespace")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Namespace indicates an expected call of Namespace.
+func (mr *MockISchemaMockRecorder) Namespace() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Namespace", reflect.TypeOf((*MockISchema)(nil).Namespace))
+}
+
 // Queries mocks base method.
 func (m *MockISchema) Queries() []string {
 	m.ctrl.T.Helper()
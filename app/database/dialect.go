@@ -0,0 +1,72 @@
+package database
+
+// dialect.go describes the SQL dialects that sqlclient supports.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Names of supported dialects, as returned by IDatabase.Dialect().
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+var (
+	// Dialects lists the supported dialects, which migration files may be specific to.
+	Dialects = []string{DialectPostgres, DialectSQLite}
+
+	ErrUnsupportedDSN = errors.New("unsupported database DSN")
+)
+
+// dialect is what differs between the databases that sqlclient supports. Queries throughout the
+// app are written with Postgres-style placeholders ($1, $2, ...), which the dialect rebinds for
+// its driver.
+type dialect interface {
+	Name() string
+
+	// Open opens a connection pool for the driver's DSN.
+	Open(dsn string) (*sql.DB, error)
+
+	// Rebind rewrites the placeholders in a query for the driver.
+	Rebind(query string) string
+
+	// Rows wraps the results of a query, for drivers that return some values in a form that
+	// database/sql can't scan into the types the app expects.
+	Rows(rows *sql.Rows) IRows
+
+	// SeedStatements create the _schema_migrations table, or bring an existing one up to date.
+	SeedStatements() []string
+
+	// Lock implements IDatabase.Lock.
+	Lock(ctx context.Context, pool *sql.DB, name string, wait time.Duration) (UnlockFunc, bool, error)
+
+	// Notify implements IDatabase.Notify.
+	Notify(ctx context.Context, pool *sql.DB, channel, payload string) error
+
+	// Listen implements IDatabase.Listen.
+	Listen(ctx context.Context, channel string, handler func(Notification)) error
+}
+
+// dialectFor picks the dialect for a DSN, and returns the DSN to give its driver. DSNs without a
+// scheme are taken to be Postgres connection strings, such as "host=localhost dbname=arisa3".
+func dialectFor(dsn string) (dialect, string, error) {
+	scheme, rest, found := strings.Cut(dsn, ":")
+	if !found || strings.ContainsAny(scheme, " =") {
+		return postgres{dsn}, dsn, nil
+	}
+	switch scheme {
+	case "postgres", "postgresql":
+		return postgres{dsn}, dsn, nil
+	case "sqlite":
+		path := strings.TrimPrefix(rest, "//")
+		return newSQLite(path), path, nil
+	default:
+		return nil, "", fmt.Errorf("%w: unknown scheme '%s'", ErrUnsupportedDSN, scheme)
+	}
+}
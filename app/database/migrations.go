@@ -0,0 +1,555 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/fiffu/arisa3/lib"
+)
+
+// migrations.go implements migrations for sqlclient
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+
+	// MigrationLockName names the lock held while migrations run, so that instances starting at
+	// the same time, such as during a rolling deploy, do not apply the same migration twice.
+	MigrationLockName = "migrations"
+)
+
+var (
+	filenamePattern          = regexp.MustCompile(`\d+_.+\.sql`)
+	ErrParseMigration        = errors.New("failed to parse migration")
+	ErrIrreversibleMigration = errors.New("migration has no down migration")
+	ErrMissingMigration      = errors.New("applied migration not found in migration files")
+	ErrMigrationDrift        = errors.New("applied migration has changed since it was applied")
+	ErrMigrationLockTimeout  = errors.New("timed out waiting for the migration lock")
+)
+
+// sqlSchema implements ISchema for .sql files.
+type sqlSchema struct {
+	namespace   string
+	source      string
+	version     string
+	queries     []string
+	downQueries []string
+}
+
+func (s sqlSchema) Namespace() string     { return s.namespace }
+func (s sqlSchema) Source() string        { return s.source }
+func (s sqlSchema) Version() string       { return s.version }
+func (s sqlSchema) Queries() []string     { return s.queries }
+func (s sqlSchema) DownQueries() []string { return s.downQueries }
+func (s sqlSchema) Checksum() string      { return Checksum(s.queries) }
+
+// Checksum computes the checksum of a migration's queries.
+func Checksum(queries []string) string {
+	h := sha256.New()
+	for _, q := range queries {
+		h.Write([]byte(q))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MigrationKey identifies a migration across namespaces.
+func MigrationKey(namespace, version string) string {
+	return namespace + "/" + version
+}
+
+type MigrationRecord struct {
+	Namespace string
+	Version   string
+	Source    string
+	// Checksum is empty for migrations applied before checksums were recorded.
+	Checksum  string
+	AppliedAt time.Time
+	Duration  time.Duration
+}
+
+func (r *MigrationRecord) Key() string {
+	return MigrationKey(r.Namespace, r.Version)
+}
+
+// migrationRow models table '_schema_migrations'. Columns added after the table was created may be NULL.
+type migrationRow struct {
+	Cog        string         `db:"cog"`
+	Version    string         `db:"version"`
+	AppliedAt  sql.NullTime   `db:"applied_at"`
+	Source     sql.NullString `db:"source"`
+	Checksum   sql.NullString `db:"checksum"`
+	DurationMs sql.NullInt64  `db:"duration_ms"`
+}
+
+func (r migrationRow) record() *MigrationRecord {
+	return &MigrationRecord{
+		Namespace: r.Cog,
+		Version:   r.Version,
+		Source:    r.Source.String,
+		Checksum:  r.Checksum.String,
+		AppliedAt: r.AppliedAt.Time,
+		Duration:  time.Duration(r.DurationMs.Int64) * time.Millisecond,
+	}
+}
+
+// MigrationDrift describes an applied migration whose file no longer matches what was applied.
+type MigrationDrift struct {
+	Namespace       string
+	Version         string
+	Source          string
+	AppliedAt       time.Time
+	AppliedChecksum string
+	CurrentChecksum string
+}
+
+func (d MigrationDrift) String() string {
+	return fmt.Sprintf(
+		"%s (%s, applied %s): checksum was %s, file is now %s",
+		MigrationKey(d.Namespace, d.Version), filepath.Base(d.Source), d.AppliedAt.Format(time.RFC3339),
+		shortChecksum(d.AppliedChecksum), shortChecksum(d.CurrentChecksum),
+	)
+}
+
+func shortChecksum(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
+
+// DriftError reports every drifted migration, so they can be fixed or accepted in one go.
+type DriftError struct {
+	Drifts []MigrationDrift
+}
+
+func (e *DriftError) Error() string {
+	lines := make([]string, 0, len(e.Drifts)+1)
+	lines = append(lines, fmt.Sprintf("%s (%d migrations):", ErrMigrationDrift, len(e.Drifts)))
+	for _, d := range e.Drifts {
+		lines = append(lines, "  "+d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *DriftError) Unwrap() error {
+	return ErrMigrationDrift
+}
+
+// DetectDrift compares applied migrations with the schemas parsed from their files. Records
+// without a checksum are skipped, as there is nothing to compare against.
+func DetectDrift(records []*MigrationRecord, schemas []ISchema) []MigrationDrift {
+	applied := make(map[string]*MigrationRecord)
+	for _, rec := range records {
+		applied[rec.Key()] = rec
+	}
+
+	drifts := make([]MigrationDrift, 0)
+	for _, schema := range schemas {
+		rec, ok := applied[MigrationKey(schema.Namespace(), schema.Version())]
+		if !ok || rec.Checksum == "" || rec.Checksum == schema.Checksum() {
+			continue
+		}
+		drifts = append(drifts, MigrationDrift{
+			Namespace:       schema.Namespace(),
+			Version:         schema.Version(),
+			Source:          schema.Source(),
+			AppliedAt:       rec.AppliedAt,
+			AppliedChecksum: rec.Checksum,
+			CurrentChecksum: schema.Checksum(),
+		})
+	}
+	return drifts
+}
+
+// seedMigration creates the migrations table if it doesn't exist, or brings it up to date. It
+// holds the migration lock, so that instances starting together don't alter the table at once,
+// nor while another instance is migrating.
+func (c *sqlclient) seedMigration(ctx context.Context, lockWait time.Duration) error {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Database("seedMigration"))
+	defer span.End()
+
+	unlock, acquired, err := c.Lock(ctx, MigrationLockName, lockWait)
+	if err == nil && !acquired {
+		err = fmt.Errorf("%w after %v", ErrMigrationLockTimeout, lockWait)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := unlock(context.Background()); err != nil {
+			log.Errorf(ctx, err, "Failed to release migration lock")
+		}
+	}()
+
+	log.Infof(ctx, "Creating schema migrations table")
+	for _, stmt := range c.dialect.SeedStatements() {
+		if _, err := c.Exec(ctx, stmt); err != nil {
+			log.Errorf(ctx, err, "Failed to creating seed migrations table")
+			return err
+		}
+	}
+	records, err := c.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+	log.Infof(ctx, "Loaded schema migrations (noted %d migration records)", len(records))
+	return nil
+}
+
+// Migrations implements IDatabase.
+func (c *sqlclient) Migrations(ctx context.Context) ([]*MigrationRecord, error) {
+	rows, err := QueryAll[migrationRow](ctx, c,
+		"SELECT cog, version, applied_at, source, checksum, duration_ms FROM _schema_migrations;",
+	)
+	if err != nil {
+		log.Errorf(ctx, err, "Failed reading migration records")
+		return nil, err
+	}
+
+	records := make([]*MigrationRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, row.record())
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return CompareVersions(records[i].Version, records[j].Version) < 0
+	})
+	return records, nil
+}
+
+// Migrate executes a migration and records it in the migrations table.
+func (c *sqlclient) Migrate(ctx context.Context, schema ISchema) (bool, error) {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Database("Migrate"))
+	span.SetAttributes(instrumentation.KV.DBOperation(schema.Source()))
+	defer span.End()
+
+	key := MigrationKey(schema.Namespace(), schema.Version())
+	rec, legacy, err := c.appliedMigration(ctx, schema)
+	if err != nil {
+		return false, err
+	}
+	if rec != nil {
+		switch rec.Checksum {
+		case schema.Checksum():
+			return false, nil
+		case "":
+			log.Infof(ctx, "Recording checksum of migration %s", key)
+			return false, c.AcceptChecksum(ctx, schema)
+		default:
+			drifts := DetectDrift([]*MigrationRecord{rec}, []ISchema{schema})
+			return false, &DriftError{drifts}
+		}
+	}
+	if legacy != nil {
+		if claimed, err := c.claimLegacyMigration(ctx, schema, legacy); claimed || err != nil {
+			return false, err
+		}
+	}
+	log.Infof(ctx, "Executing migration %s (%s)", key, schema.Source())
+
+	start := time.Now()
+	source := filepath.Base(schema.Source())
+	err = c.execMigration(ctx, schema.Queries(), func(txn *sql.Tx) error {
+		query := `INSERT INTO _schema_migrations (cog, version, applied_at, source, checksum, duration_ms)
+			VALUES ($1, $2, CURRENT_TIMESTAMP, $3, $4, $5);`
+		elapsed := time.Since(start).Milliseconds()
+		_, err := txn.ExecContext(ctx, c.dialect.Rebind(query), schema.Namespace(), schema.Version(), source, schema.Checksum(), elapsed)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// appliedMigration reads the schema's record, and any record of the same version from before
+// namespacing. The records are read afresh for each migration, rather than once at startup, as
+// another instance may have applied migrations since.
+func (c *sqlclient) appliedMigration(ctx context.Context, schema ISchema) (rec, legacy *MigrationRecord, err error) {
+	rows, err := QueryAll[migrationRow](ctx, c,
+		`SELECT cog, version, applied_at, source, checksum, duration_ms FROM _schema_migrations
+		WHERE cog IN ($1, '') AND version = $2;`,
+		schema.Namespace(), schema.Version(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range rows {
+		if row.Cog == schema.Namespace() {
+			rec = row.record()
+		} else {
+			legacy = row.record()
+		}
+	}
+	return rec, legacy, nil
+}
+
+// claimLegacyMigration assigns a migration applied before namespacing to the schema's namespace.
+// The row is only claimed if its recorded source, if any, matches the schema's file, as another
+// cog may have a migration with the same version.
+func (c *sqlclient) claimLegacyMigration(ctx context.Context, schema ISchema, rec *MigrationRecord) (bool, error) {
+	source := filepath.Base(schema.Source())
+	if rec.Source != "" && rec.Source != source {
+		return false, nil
+	}
+
+	log.Infof(ctx, "Claiming migration %s for %s", schema.Version(), schema.Namespace())
+	query := `UPDATE _schema_migrations SET cog = $1, source = $2, checksum = $3
+		WHERE cog = '' AND version = $4;`
+	if _, err := c.Exec(ctx, query, schema.Namespace(), source, schema.Checksum(), schema.Version()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AcceptChecksum implements IDatabase.
+func (c *sqlclient) AcceptChecksum(ctx context.Context, schema ISchema) error {
+	key := MigrationKey(schema.Namespace(), schema.Version())
+	query := "UPDATE _schema_migrations SET checksum = $1 WHERE cog = $2 AND version = $3;"
+	res, err := c.Exec(ctx, query, schema.Checksum(), schema.Namespace(), schema.Version())
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%w: %s", ErrMissingMigration, key)
+	}
+	return nil
+}
+
+// Rollback implements IDatabase.
+func (c *sqlclient) Rollback(ctx context.Context, target string, schemas []ISchema) ([]string, error) {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Database("Rollback"))
+	defer span.End()
+
+	records, err := c.Migrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planRollback(records, target, schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	rolledBack := make([]string, 0, len(plan))
+	for _, schema := range plan {
+		log.Infof(ctx, "Rolling back migration %s (%s)", schema.Version(), schema.Source())
+		err := c.execMigration(ctx, schema.DownQueries(), func(txn *sql.Tx) error {
+			query := "DELETE FROM _schema_migrations WHERE cog = $1 AND version = $2;"
+			_, err := txn.ExecContext(ctx, c.dialect.Rebind(query), schema.Namespace(), schema.Version())
+			return err
+		})
+		if err != nil {
+			return rolledBack, err
+		}
+		rolledBack = append(rolledBack, MigrationKey(schema.Namespace(), schema.Version()))
+	}
+	return rolledBack, nil
+}
+
+// planRollback finds the schemas to revert to reach the target version, newest first. It fails if
+// any of them cannot be reverted, so that a rollback never stops halfway on a known problem.
+// Records from before namespacing are skipped: Migrate claims those that belong to a cog's
+// migrations, so any that are left belong to none, and have nothing to revert them with.
+func planRollback(records []*MigrationRecord, target string, schemas []ISchema) ([]ISchema, error) {
+	byKey := make(map[string]ISchema)
+	for _, schema := range schemas {
+		byKey[MigrationKey(schema.Namespace(), schema.Version())] = schema
+	}
+
+	plan := make([]ISchema, 0)
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		if CompareVersions(rec.Version, target) <= 0 {
+			break
+		}
+		if rec.Namespace == "" {
+			continue
+		}
+		schema, ok := byKey[rec.Key()]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s (%s)", ErrMissingMigration, rec.Key(), rec.Source)
+		}
+		if len(schema.DownQueries()) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrIrreversibleMigration, schema.Source())
+		}
+		plan = append(plan, schema)
+	}
+	return plan, nil
+}
+
+// execMigration executes queries and updates the migrations table in a single transaction.
+func (c *sqlclient) execMigration(ctx context.Context, queries []string, record func(*sql.Tx) error) error {
+	txn, err := c.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, q := range queries {
+		if _, err := txn.ExecContext(ctx, q); err != nil {
+			if err := txn.Rollback(); err != nil {
+				return err
+			}
+			return err
+		}
+	}
+	if err := record(txn); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return err
+		}
+		return err
+	}
+	return txn.Commit()
+}
+
+// ParseMigration implements parsing of files into sqlSchema.
+// For a .up.sql file, the .down.sql file beside it is read as the down migration, if it exists.
+func (c *sqlclient) ParseMigration(ctx context.Context, namespace string, fsys fs.FS, theFile string) (ISchema, error) {
+	name, err := validateFileName(theFile)
+	if err != nil {
+		return nil, err
+	}
+	if IsDownMigration(name) {
+		return nil, fmt.Errorf("%w: down migrations are parsed with their up migration, got '%s'", ErrParseMigration, name)
+	}
+
+	bytes, err := fs.ReadFile(fsys, theFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(bytes)
+	version, _ := lib.SplitOnce(name, "_")
+	schema := sqlSchema{
+		namespace: namespace,
+		source:    theFile,
+		version:   version,
+		queries:   []string{s},
+	}
+
+	if strings.HasSuffix(name, upSuffix) {
+		downFile := strings.TrimSuffix(theFile, upSuffix) + downSuffix
+		bytes, err := fs.ReadFile(fsys, downFile)
+		switch {
+		case err == nil:
+			schema.downQueries = []string{string(bytes)}
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+// IsDownMigration returns whether the file is the down half of an up/down migration pair.
+func IsDownMigration(theFile string) bool {
+	return strings.HasSuffix(theFile, downSuffix)
+}
+
+// LoadMigrations parses every migration at the root of fsys into the namespace, ordered by version.
+// A migration may have variants for specific dialects, named like <version>_<name>.sqlite.sql,
+// which replace the generic file on that dialect. Variants for other dialects are skipped.
+func LoadMigrations(ctx context.Context, db IDatabase, namespace string, fsys fs.FS) ([]ISchema, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for _, file := range files {
+		names[file.Name()] = true
+	}
+
+	// Pick one file per migration, keyed by the file name without its dialect and suffix
+	chosen := make(map[string]string)
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			continue
+		}
+		if IsDownMigration(name) {
+			if up := strings.TrimSuffix(name, downSuffix) + upSuffix; !names[up] {
+				return nil, fmt.Errorf("%w: '%s' has no matching '%s'", ErrParseMigration, name, up)
+			}
+			continue
+		}
+		key, fileDialect := splitDialect(name)
+		switch fileDialect {
+		case "":
+			if _, ok := chosen[key]; !ok {
+				chosen[key] = name
+			}
+		case db.Dialect():
+			chosen[key] = name
+		}
+	}
+
+	keys := make([]string, 0, len(chosen))
+	for key := range chosen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	schemas := make([]ISchema, 0)
+	for _, key := range keys {
+		schema, err := db.ParseMigration(ctx, namespace, fsys, chosen[key])
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	sort.SliceStable(schemas, func(i, j int) bool {
+		return CompareVersions(schemas[i].Version(), schemas[j].Version()) < 0
+	})
+	return schemas, nil
+}
+
+// splitDialect separates the dialect from a migration's file name, if it is specific to one.
+func splitDialect(name string) (stem, dialect string) {
+	stem = strings.TrimSuffix(strings.TrimSuffix(name, upSuffix), ".sql")
+	for _, d := range Dialects {
+		if withoutDialect, ok := strings.CutSuffix(stem, "."+d); ok {
+			return withoutDialect, d
+		}
+	}
+	return stem, ""
+}
+
+// CompareVersions orders migration versions numerically, falling back to comparing them as
+// strings if either is not a number.
+func CompareVersions(a, b string) int {
+	numA, errA := strconv.ParseInt(a, 10, 64)
+	numB, errB := strconv.ParseInt(b, 10, 64)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case numA < numB:
+		return -1
+	case numA > numB:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func validateFileName(theFile string) (string, error) {
+	name := filepath.Base(theFile)
+	if !filenamePattern.MatchString(name) {
+		return "", fmt.Errorf(
+			"%w: file should have pattern %s, got '%s'",
+			ErrParseMigration,
+			filenamePattern.String(), name,
+		)
+	}
+	return name, nil
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateFileName(t *testing.T) {
+	type testCase struct {
+		input string
+		err   error
+	}
+	for _, tc := range []testCase{
+		{"foo", assert.AnError},
+		{"foo.sql", assert.AnError},
+		{".sql", assert.AnError},
+		{"", assert.AnError},
+		{"foo.sql", assert.AnError},
+		{"1234_foo.sql", nil},
+	} {
+		_, actual := validateFileName(tc.input)
+		if tc.err != nil {
+			assert.Error(t, actual)
+		} else {
+			assert.NoError(t, actual)
+		}
+	}
+}
+
+func migrationsFS(files map[string]string) fstest.MapFS {
+	fsys := make(fstest.MapFS)
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+func newSqlmockClient(t *testing.T) (*sqlclient, sqlmock.Sqlmock) {
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sqlclient{pool: db, dialect: postgres{}}, dbMock
+}
+
+var recordColumns = []string{"cog", "version", "applied_at", "source", "checksum", "duration_ms"}
+
+// expectApplied expects the lookup of a migration's records, which returns the given rows.
+func expectApplied(dbMock sqlmock.Sqlmock, namespace, version string, rows ...[]driver.Value) {
+	result := sqlmock.NewRows(recordColumns)
+	for _, row := range rows {
+		result.AddRow(row...)
+	}
+	dbMock.ExpectQuery("SELECT cog, version, applied_at, source, checksum, duration_ms FROM _schema_migrations WHERE").
+		WithArgs(namespace, version).
+		WillReturnRows(result)
+}
+
+func Test_LoadMigrations(t *testing.T) {
+	fsys := migrationsFS(map[string]string{
+		"20_second.up.sql":   "CREATE TABLE b;",
+		"20_second.down.sql": "DROP TABLE b;",
+		"3_first.sql":        "CREATE TABLE a;",
+	})
+	c, _ := newSqlmockClient(t)
+
+	schemas, err := LoadMigrations(context.Background(), c, "cog", fsys)
+	assert.NoError(t, err)
+	assert.Len(t, schemas, 2)
+
+	assert.Equal(t, "cog", schemas[0].Namespace())
+	assert.Equal(t, "3", schemas[0].Version())
+	assert.Equal(t, "3_first.sql", schemas[0].Source())
+	assert.Equal(t, []string{"CREATE TABLE a;"}, schemas[0].Queries())
+	assert.Empty(t, schemas[0].DownQueries())
+
+	assert.Equal(t, "20", schemas[1].Version())
+	assert.Equal(t, []string{"CREATE TABLE b;"}, schemas[1].Queries())
+	assert.Equal(t, []string{"DROP TABLE b;"}, schemas[1].DownQueries())
+}
+
+func Test_LoadMigrations_dialectVariants(t *testing.T) {
+	fsys := migrationsFS(map[string]string{
+		"1_first.sql":             "CREATE TABLE a (id SERIAL);",
+		"1_first.sqlite.sql":      "CREATE TABLE a (id INTEGER PRIMARY KEY);",
+		"2_second.postgres.sql":   "CREATE EXTENSION pg_trgm;",
+		"3_third.sqlite.up.sql":   "CREATE INDEX a_id ON a (id);",
+		"3_third.sqlite.down.sql": "DROP INDEX a_id;",
+	})
+	c, _ := newSqlmockClient(t)
+
+	schemas, err := LoadMigrations(context.Background(), c, "cog", fsys)
+	assert.NoError(t, err)
+	assert.Len(t, schemas, 2)
+	assert.Equal(t, "1_first.sql", schemas[0].Source())
+	assert.Equal(t, "2_second.postgres.sql", schemas[1].Source())
+}
+
+func Test_LoadMigrations_orphanedDown(t *testing.T) {
+	fsys := migrationsFS(map[string]string{
+		"1_first.down.sql": "DROP TABLE a;",
+	})
+	c, _ := newSqlmockClient(t)
+
+	_, err := LoadMigrations(context.Background(), c, "cog", fsys)
+	assert.ErrorIs(t, err, ErrParseMigration)
+}
+
+func Test_CompareVersions(t *testing.T) {
+	assert.Equal(t, -1, CompareVersions("3", "20"))
+	assert.Equal(t, 1, CompareVersions("1650549317", "1650549316"))
+	assert.Equal(t, 0, CompareVersions("0005", "5"))
+	assert.Equal(t, -1, CompareVersions("a", "b"))
+}
+
+func Test_Migrate(t *testing.T) {
+	c, dbMock := newSqlmockClient(t)
+	schema := sqlSchema{namespace: "cog", source: "/some/dir/1_first.sql", version: "1", queries: []string{"CREATE TABLE a;"}}
+
+	expectApplied(dbMock, "cog", "1")
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("CREATE TABLE a;").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("INSERT INTO _schema_migrations").
+		WithArgs("cog", "1", "1_first.sql", schema.Checksum(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+
+	executed, err := c.Migrate(context.Background(), schema)
+	assert.NoError(t, err)
+	assert.True(t, executed)
+
+	// Already applied, possibly by another instance
+	expectApplied(dbMock, "cog", "1", []driver.Value{"cog", "1", time.Now(), "1_first.sql", schema.Checksum(), 1})
+	executed, err = c.Migrate(context.Background(), schema)
+	assert.NoError(t, err)
+	assert.False(t, executed)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_Rollback(t *testing.T) {
+	c, dbMock := newSqlmockClient(t)
+	schemas := []ISchema{
+		sqlSchema{namespace: "foo", version: "1", queries: []string{"CREATE TABLE a;"}},
+		sqlSchema{namespace: "foo", version: "2", queries: []string{"CREATE TABLE b;"}, downQueries: []string{"DROP TABLE b;"}},
+		sqlSchema{namespace: "bar", version: "3", queries: []string{"CREATE TABLE c;"}, downQueries: []string{"DROP TABLE c;"}},
+	}
+
+	dbMock.ExpectQuery("SELECT cog, version, applied_at, source, checksum, duration_ms FROM _schema_migrations").
+		WillReturnRows(sqlmock.NewRows(recordColumns).
+			AddRow("bar", "3", time.Now(), "3_c.up.sql", "abc", 1).
+			AddRow("foo", "1", nil, nil, nil, nil).
+			AddRow("foo", "2", time.Now(), "2_b.up.sql", "def", 1))
+	for _, args := range [][]driver.Value{{"bar", "3"}, {"foo", "2"}} {
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec("DROP TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec("DELETE FROM _schema_migrations").WithArgs(args...).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+	}
+
+	rolledBack, err := c.Rollback(context.Background(), "1", schemas)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar/3", "foo/2"}, rolledBack)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_planRollback(t *testing.T) {
+	records := []*MigrationRecord{{Version: "1"}, {Namespace: "foo", Version: "2", Source: "2_b.sql"}}
+
+	t.Run("irreversible", func(t *testing.T) {
+		schemas := []ISchema{sqlSchema{version: "1"}, sqlSchema{namespace: "foo", version: "2"}}
+		_, err := planRollback(records, "1", schemas)
+		assert.ErrorIs(t, err, ErrIrreversibleMigration)
+	})
+	t.Run("missing", func(t *testing.T) {
+		schemas := []ISchema{sqlSchema{version: "1"}, sqlSchema{namespace: "bar", version: "2"}}
+		_, err := planRollback(records, "1", schemas)
+		assert.ErrorIs(t, err, ErrMissingMigration)
+	})
+	t.Run("nothing to do", func(t *testing.T) {
+		plan, err := planRollback(records, "2", nil)
+		assert.NoError(t, err)
+		assert.Empty(t, plan)
+	})
+	t.Run("unclaimed legacy records", func(t *testing.T) {
+		records := []*MigrationRecord{
+			{Version: "1"},
+			{Namespace: "foo", Version: "2", Source: "2_b.up.sql"},
+			{Version: "3", Source: "3_other_cog.sql"},
+		}
+		schema := sqlSchema{namespace: "foo", version: "2", downQueries: []string{"DROP TABLE b;"}}
+		plan, err := planRollback(records, "0", []ISchema{schema})
+		assert.NoError(t, err)
+		assert.Equal(t, []ISchema{schema}, plan)
+	})
+}
+
+func Test_Migrate_drift(t *testing.T) {
+	c, dbMock := newSqlmockClient(t)
+	appliedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	edited := sqlSchema{namespace: "cog", source: "/dir/1_first.sql", version: "1", queries: []string{"CREATE TABLE b;"}}
+	expectApplied(dbMock, "cog", "1",
+		[]driver.Value{"cog", "1", appliedAt, "1_first.sql", Checksum([]string{"CREATE TABLE a;"}), 1},
+	)
+
+	executed, err := c.Migrate(context.Background(), edited)
+	assert.False(t, executed)
+	assert.ErrorIs(t, err, ErrMigrationDrift)
+	assert.Contains(t, err.Error(), "cog/1 (1_first.sql, applied 2024-01-01T00:00:00Z)")
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_Migrate_claimLegacy(t *testing.T) {
+	c, dbMock := newSqlmockClient(t)
+	schema := sqlSchema{namespace: "cog", source: "/dir/1_first.sql", version: "1", queries: []string{"CREATE TABLE a;"}}
+
+	expectApplied(dbMock, "cog", "1", []driver.Value{"", "1", nil, nil, nil, nil})
+	dbMock.ExpectExec("UPDATE _schema_migrations SET cog").
+		WithArgs("cog", "1_first.sql", schema.Checksum(), "1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	executed, err := c.Migrate(context.Background(), schema)
+	assert.NoError(t, err)
+	assert.False(t, executed)
+
+	// Same version from another file is not claimed, and is applied under its own namespace
+	other := sqlSchema{namespace: "cog", source: "/dir/2_second.sql", version: "2", queries: []string{"CREATE TABLE b;"}}
+	expectApplied(dbMock, "cog", "2", []driver.Value{"", "2", nil, "2_other_cog.sql", nil, nil})
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("CREATE TABLE b;").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("INSERT INTO _schema_migrations").WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+
+	executed, err = c.Migrate(context.Background(), other)
+	assert.NoError(t, err)
+	assert.True(t, executed)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_DetectDrift(t *testing.T) {
+	schemas := []ISchema{
+		sqlSchema{namespace: "cog", version: "1", queries: []string{"unchanged"}},
+		sqlSchema{namespace: "cog", version: "2", queries: []string{"changed"}},
+		sqlSchema{namespace: "cog", version: "3", queries: []string{"no checksum"}},
+		sqlSchema{namespace: "cog", version: "4", queries: []string{"pending"}},
+	}
+	records := []*MigrationRecord{
+		{Namespace: "cog", Version: "1", Checksum: Checksum([]string{"unchanged"})},
+		{Namespace: "cog", Version: "2", Checksum: Checksum([]string{"original"})},
+		{Namespace: "cog", Version: "3"},
+	}
+
+	drifts := DetectDrift(records, schemas)
+	assert.Len(t, drifts, 1)
+	assert.Equal(t, "2", drifts[0].Version)
+	assert.Equal(t, Checksum([]string{"original"}), drifts[0].AppliedChecksum)
+	assert.Equal(t, Checksum([]string{"changed"}), drifts[0].CurrentChecksum)
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io/fs"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/fiffu/arisa3/app/instrumentation"
@@ -12,11 +14,18 @@ import (
 
 func NewMockDBClient(t *testing.T) (IDatabase, sqlmock.Sqlmock, error) {
 	db, dbMock, err := sqlmock.New()
-	return &mockClient{db}, dbMock, err
+	return &mockClient{db, newLocalNotifier()}, dbMock, err
 }
 
+// mockClient delivers notifications between its own listeners only, so that they need no
+// expectations on the sqlmock.
 type mockClient struct {
-	db *sql.DB
+	db       *sql.DB
+	notifier *localNotifier
+}
+
+func (c *mockClient) Dialect() string {
+	return DialectPostgres
 }
 
 func (c *mockClient) Close(ctx context.Context) error {
@@ -36,18 +45,44 @@ func (c *mockClient) Exec(ctx context.Context, query string, args ...interface{}
 }
 
 func (c *mockClient) Begin(ctx context.Context) (context.Context, ITransaction, error) {
-	t, err := c.db.Begin()
+	t, err := c.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Database("Transaction"))
-	return ctx, sqlTxnWrap{t, span}, nil
+	return ctx, sqlTxnWrap{t, postgres{}, span}, nil
 }
 
 func (c *mockClient) Migrate(ctx context.Context, schema ISchema) (executed bool, err error) {
 	panic("not implemented")
 }
 
-func (c *mockClient) ParseMigration(ctx context.Context, filepath string) (ISchema, error) {
+func (c *mockClient) ParseMigration(ctx context.Context, namespace string, fsys fs.FS, filepath string) (ISchema, error) {
+	panic("not implemented")
+}
+
+func (c *mockClient) AcceptChecksum(ctx context.Context, schema ISchema) error {
 	panic("not implemented")
 }
+
+func (c *mockClient) Rollback(ctx context.Context, target string, schemas []ISchema) ([]string, error) {
+	panic("not implemented")
+}
+
+func (c *mockClient) Migrations(ctx context.Context) ([]*MigrationRecord, error) {
+	panic("not implemented")
+}
+
+func (c *mockClient) Lock(ctx context.Context, name string, wait time.Duration) (UnlockFunc, bool, error) {
+	return advisoryLock(ctx, c.db, name, wait)
+}
+
+func (c *mockClient) Notify(ctx context.Context, channel, payload string) error {
+	c.notifier.notify(channel, payload)
+	return nil
+}
+
+func (c *mockClient) Listen(ctx context.Context, channel string, handler func(Notification)) error {
+	c.notifier.listen(ctx, channel, handler)
+	return nil
+}
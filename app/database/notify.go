@@ -0,0 +1,90 @@
+package database
+
+// notify.go implements notifications between clients of a database, and cache invalidation on
+// top of them.
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fiffu/arisa3/lib"
+)
+
+// Notification is delivered by IDatabase.Listen for each Notify on the channel.
+type Notification struct {
+	Payload string
+
+	// Missed is set, without a payload, when the listener had to reconnect to the database, and
+	// may have missed notifications in the meantime.
+	Missed bool
+}
+
+// localNotifier delivers notifications to listeners in this process, by channel.
+type localNotifier struct {
+	mutex     sync.Mutex
+	listeners map[string]map[int]func(Notification)
+	nextID    int
+}
+
+func newLocalNotifier() *localNotifier {
+	return &localNotifier{listeners: make(map[string]map[int]func(Notification))}
+}
+
+// notify calls each listener of the channel before returning.
+func (n *localNotifier) notify(channel, payload string) {
+	n.mutex.Lock()
+	handlers := make([]func(Notification), 0, len(n.listeners[channel]))
+	for _, handler := range n.listeners[channel] {
+		handlers = append(handlers, handler)
+	}
+	n.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(Notification{Payload: payload})
+	}
+}
+
+// listen registers the handler for the channel until ctx is done.
+func (n *localNotifier) listen(ctx context.Context, channel string, handler func(Notification)) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	id := n.nextID
+	n.nextID++
+	if n.listeners[channel] == nil {
+		n.listeners[channel] = make(map[int]func(Notification))
+	}
+	n.listeners[channel][id] = handler
+
+	go func() {
+		<-ctx.Done()
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		delete(n.listeners[channel], id)
+	}()
+}
+
+// invalidationChannel implements lib.IInvalidationChannel with the database's notifications.
+type invalidationChannel struct {
+	db      IDatabase
+	channel string
+}
+
+// NewInvalidationChannel carries cache invalidations between replicas over a notification channel
+// of the database. If notifications may have been missed, subscribers are told to evict everything.
+func NewInvalidationChannel(db IDatabase, channel string) lib.IInvalidationChannel {
+	return &invalidationChannel{db, channel}
+}
+
+func (c *invalidationChannel) Publish(ctx context.Context, key string) error {
+	return c.db.Notify(ctx, c.channel, key)
+}
+
+func (c *invalidationChannel) Subscribe(ctx context.Context, evict func(key string)) error {
+	return c.db.Listen(ctx, c.channel, func(n Notification) {
+		if n.Missed {
+			evict(lib.InvalidateAll)
+			return
+		}
+		evict(n.Payload)
+	})
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fiffu/arisa3/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_localNotifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := newLocalNotifier()
+
+	received := make(chan Notification, 1)
+	n.listen(ctx, "test-channel", func(notif Notification) { received <- notif })
+
+	n.notify("test-channel", "hello")
+	assert.Equal(t, Notification{Payload: "hello"}, <-received)
+
+	// Listeners stop receiving once their context is done
+	cancel()
+	assert.Eventually(t, func() bool {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		return len(n.listeners["test-channel"]) == 0
+	}, time.Second, 10*time.Millisecond)
+	n.notify("test-channel", "goodbye")
+	assert.Empty(t, received)
+}
+
+func Test_invalidationChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db, _, err := NewMockDBClient(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two replicas sharing the database
+	first := NewInvalidationChannel(db, "test-channel")
+	second := NewInvalidationChannel(db, "test-channel")
+
+	evicted := make([]string, 0)
+	assert.NoError(t, second.Subscribe(ctx, func(key string) { evicted = append(evicted, key) }))
+	assert.NoError(t, first.Publish(ctx, "some-key"))
+	assert.Equal(t, []string{"some-key"}, evicted)
+
+	// Missed notifications evict everything
+	notifier := db.(*mockClient).notifier
+	for _, handler := range notifier.listeners["test-channel"] {
+		handler(Notification{Missed: true})
+	}
+	assert.Equal(t, []string{"some-key", lib.InvalidateAll}, evicted)
+}
@@ -0,0 +1,78 @@
+package database
+
+// pglock.go implements named locks using Postgres session-level advisory locks.
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"time"
+
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+)
+
+// How long to wait between attempts while another client holds the lock.
+const lockPollInterval = 500 * time.Millisecond
+
+// lockKey derives the bigint key that Postgres advisory locks are identified by.
+func lockKey(name string) int64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(name))
+	return int64(hasher.Sum64())
+}
+
+// advisoryLock holds a dedicated connection out of the pool for as long as the lock is held,
+// because advisory locks belong to the session that acquired them.
+func advisoryLock(ctx context.Context, pool *sql.DB, name string, wait time.Duration) (UnlockFunc, bool, error) {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Database("Lock"))
+	span.SetAttributes(instrumentation.KV.DBOperation(name))
+	defer span.End()
+
+	key := lockKey(name)
+	start := time.Now()
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	deadline := time.Now().Add(wait)
+	for attempt := 1; ; attempt++ {
+		var acquired bool
+		row := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key)
+		if err := row.Scan(&acquired); err != nil {
+			conn.Close()
+			return nil, false, err
+		}
+		if acquired {
+			if attempt > 1 {
+				log.Infof(ctx, "Lock %s acquired after waiting %v", name, time.Since(start))
+			}
+			break
+		}
+		if !time.Now().Before(deadline) {
+			log.Infof(ctx, "Lock %s is held elsewhere, gave up after %d attempts", name, attempt)
+			span.SetAttributes(instrumentation.KV.LockAcquired(false), instrumentation.KV.LockWait(time.Since(start)))
+			conn.Close()
+			return nil, false, nil
+		}
+		if attempt == 1 {
+			log.Infof(ctx, "Lock %s is held elsewhere, waiting up to %v", name, wait)
+		}
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return nil, false, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	span.SetAttributes(instrumentation.KV.LockAcquired(true), instrumentation.KV.LockWait(time.Since(start)))
+
+	unlock := func(ctx context.Context) error {
+		defer conn.Close()
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key)
+		return err
+	}
+	return unlock, true, nil
+}
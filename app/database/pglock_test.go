@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Lock(t *testing.T) {
+	db, dbMock, err := NewMockDBClient(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := lockKey("test-lock")
+
+	dbMock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+	dbMock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(key).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ctx := context.Background()
+	unlock, acquired, err := db.Lock(ctx, "test-lock", 0)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	assert.NoError(t, unlock(ctx))
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_Lock_heldElsewhere(t *testing.T) {
+	db, dbMock, err := NewMockDBClient(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbMock.ExpectQuery("SELECT pg_try_advisory_lock").
+		WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(false))
+
+	unlock, acquired, err := db.Lock(context.Background(), "test-lock", 0)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Nil(t, unlock)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_lockKey(t *testing.T) {
+	assert.Equal(t, lockKey("foo"), lockKey("foo"))
+	assert.NotEqual(t, lockKey("foo"), lockKey("bar"))
+}
@@ -0,0 +1,69 @@
+package database
+
+// pgnotify.go implements notifications using Postgres LISTEN/NOTIFY.
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/lib/pq"
+)
+
+const (
+	// Bounds on the delay between attempts to reconnect a listener.
+	listenerMinReconnect = 10 * time.Second
+	listenerMaxReconnect = 1 * time.Minute
+
+	// How often to check a listener's connection while no notifications arrive, so that a broken
+	// connection is noticed and reconnected.
+	listenerPingInterval = 90 * time.Second
+)
+
+func pgNotify(ctx context.Context, pool *sql.DB, channel, payload string) error {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Database("Notify"))
+	span.SetAttributes(instrumentation.KV.DBOperation(channel))
+	defer span.End()
+
+	_, err := pool.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
+
+// pgListen opens a connection of its own for the listener, because LISTEN belongs to the session
+// that issued it. The connection is reconnected if it breaks, after which the handler is sent a
+// Missed notification.
+func pgListen(ctx context.Context, dsn, channel string, handler func(Notification)) error {
+	reportProblem := func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Errorf(ctx, err, "Listener on %s failed (event: %d)", channel, event)
+		}
+	}
+	listener := pq.NewListener(dsn, listenerMinReconnect, listenerMaxReconnect, reportProblem)
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return err
+	}
+	log.Infof(ctx, "Listening on %s", channel)
+
+	go func() {
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				// pq sends nil after reconnecting
+				if n == nil {
+					handler(Notification{Missed: true})
+					continue
+				}
+				handler(Notification{Payload: n.Extra})
+			case <-time.After(listenerPingInterval):
+				go listener.Ping()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,62 @@
+package database
+
+// postgres.go implements the Postgres dialect, using lib/pq.
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const (
+	createSchemaMigrations = `CREATE TABLE IF NOT EXISTS "_schema_migrations" (version TEXT PRIMARY KEY);`
+
+	// Metadata columns were added after the table was first created, so rows for migrations
+	// applied before then have NULLs here.
+	alterSchemaMigrations = `ALTER TABLE "_schema_migrations"
+		ADD COLUMN IF NOT EXISTS applied_at  TIMESTAMPTZ,
+		ADD COLUMN IF NOT EXISTS source      TEXT,
+		ADD COLUMN IF NOT EXISTS duration_ms BIGINT,
+		ADD COLUMN IF NOT EXISTS cog         TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS checksum    TEXT;`
+
+	// Versions are only unique within a cog. Rows applied before namespacing have an empty cog,
+	// until they are claimed by the cog that owns the migration file.
+	namespaceSchemaMigrations = `ALTER TABLE "_schema_migrations" DROP CONSTRAINT IF EXISTS "_schema_migrations_pkey";`
+	indexSchemaMigrations     = `CREATE UNIQUE INDEX IF NOT EXISTS "_schema_migrations_cog_version" ON "_schema_migrations" (cog, version);`
+)
+
+// postgres implements dialect for Postgres. The DSN is kept for listeners, which need connections
+// of their own outside the pool.
+type postgres struct {
+	dsn string
+}
+
+func (postgres) Name() string { return DialectPostgres }
+
+func (postgres) Open(dsn string) (*sql.DB, error) {
+	return sql.Open("postgres", dsn)
+}
+
+// Rebind is a no-op, as queries are already written for Postgres.
+func (postgres) Rebind(query string) string { return query }
+
+func (postgres) Rows(rows *sql.Rows) IRows { return rows }
+
+func (postgres) SeedStatements() []string {
+	return []string{createSchemaMigrations, alterSchemaMigrations, namespaceSchemaMigrations, indexSchemaMigrations}
+}
+
+func (postgres) Lock(ctx context.Context, pool *sql.DB, name string, wait time.Duration) (UnlockFunc, bool, error) {
+	return advisoryLock(ctx, pool, name, wait)
+}
+
+func (postgres) Notify(ctx context.Context, pool *sql.DB, channel, payload string) error {
+	return pgNotify(ctx, pool, channel, payload)
+}
+
+func (d postgres) Listen(ctx context.Context, channel string, handler func(Notification)) error {
+	return pgListen(ctx, d.dsn, channel, handler)
+}
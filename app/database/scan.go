@@ -0,0 +1,198 @@
+package database
+
+// scan.go maps query results onto Go values, so that repositories don't hand-write scan loops.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	ErrScanTarget = errors.New("unsupported scan target")
+	ErrScanColumn = errors.New("column has no matching field")
+)
+
+// IQuerier is implemented by both IDatabase and ITransaction, so the query helpers work either
+// inside or outside of a transaction.
+type IQuerier interface {
+	Query(ctx context.Context, query string, args ...interface{}) (IRows, error)
+	Exec(ctx context.Context, query string, args ...interface{}) (IResult, error)
+}
+
+// QueryAll runs a query and scans every row into a T. See ScanAll for how rows are mapped.
+func QueryAll[T any](ctx context.Context, q IQuerier, query string, args ...interface{}) ([]T, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ScanAll[T](rows)
+}
+
+// QueryOne runs a query and scans its first row into a T. If there are no rows, it returns
+// ErrNoRecords.
+func QueryOne[T any](ctx context.Context, q IQuerier, query string, args ...interface{}) (T, error) {
+	var zero T
+	results, err := QueryAll[T](ctx, q, query, args...)
+	if err != nil {
+		return zero, err
+	}
+	if len(results) == 0 {
+		return zero, ErrNoRecords
+	}
+	return results[0], nil
+}
+
+// ExecReturning runs an INSERT, UPDATE or DELETE with a RETURNING clause, and scans the rows that
+// it returns into a T.
+func ExecReturning[T any](ctx context.Context, q IQuerier, query string, args ...interface{}) ([]T, error) {
+	return QueryAll[T](ctx, q, query, args...)
+}
+
+// ScanAll scans every row into a T, then closes the rows.
+//
+// If T is a struct, or a pointer to one, each column is scanned into the field whose `db` tag
+// names it, and every column must have such a field. Otherwise, each row must have exactly one
+// column, which is scanned into the T itself, like a string or time.Time.
+//
+// NULL leaves a field at its zero value, unless the field has a type that handles NULL, like a
+// pointer or sql.NullString.
+func ScanAll[T any](rows IRows) (results []T, err error) {
+	defer func() {
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	scan, err := scannerFor(reflect.TypeOf((*T)(nil)).Elem(), rows)
+	if err != nil {
+		return nil, err
+	}
+
+	results = make([]T, 0)
+	for rows.Next() {
+		var result T
+		if err := scan(rows, reflect.ValueOf(&result).Elem()); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// scanFunc scans the current row into dest, which is settable.
+type scanFunc func(rows IRows, dest reflect.Value) error
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+
+	// fieldsByType caches the fields of each struct type by column name.
+	fieldsByType sync.Map
+)
+
+// isScalar reports whether a type is scanned from a single column, rather than mapped by field.
+func isScalar(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return true
+	}
+	return typ == timeType || reflect.PointerTo(typ).Implements(scannerType)
+}
+
+func scannerFor(typ reflect.Type, rows IRows) (scanFunc, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if typ.Kind() == reflect.Pointer && !isScalar(typ.Elem()) {
+		scanStruct, err := structScanner(typ.Elem(), columns)
+		if err != nil {
+			return nil, err
+		}
+		return func(rows IRows, dest reflect.Value) error {
+			dest.Set(reflect.New(typ.Elem()))
+			return scanStruct(rows, dest.Elem())
+		}, nil
+	}
+	if !isScalar(typ) {
+		return structScanner(typ, columns)
+	}
+
+	if len(columns) != 1 {
+		return nil, fmt.Errorf("%w: %v is scanned from one column, got %d", ErrScanTarget, typ, len(columns))
+	}
+	return func(rows IRows, dest reflect.Value) error {
+		target, assign := nullable(dest)
+		if err := rows.Scan(target); err != nil {
+			return err
+		}
+		assign()
+		return nil
+	}, nil
+}
+
+// structScanner maps each column onto a field of the struct type, by the field's `db` tag.
+func structScanner(typ reflect.Type, columns []string) (scanFunc, error) {
+	fields := structFields(typ)
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		index, ok := fields[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %v has no field tagged `db:\"%s\"`", ErrScanColumn, typ, column)
+		}
+		indexes[i] = index
+	}
+
+	return func(rows IRows, dest reflect.Value) error {
+		targets := make([]any, len(indexes))
+		assigns := make([]func(), len(indexes))
+		for i, index := range indexes {
+			targets[i], assigns[i] = nullable(dest.Field(index))
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		for _, assign := range assigns {
+			assign()
+		}
+		return nil
+	}, nil
+}
+
+func structFields(typ reflect.Type) map[string]int {
+	if cached, ok := fieldsByType.Load(typ); ok {
+		return cached.(map[string]int)
+	}
+	fields := make(map[string]int)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if tag := field.Tag.Get("db"); tag != "" && tag != "-" && field.IsExported() {
+			fields[tag] = i
+		}
+	}
+	fieldsByType.Store(typ, fields)
+	return fields
+}
+
+// nullable returns a target to scan into, and a func that assigns the scanned value to dest. Types
+// that don't handle NULL are scanned through a pointer, which database/sql sets to nil for NULL.
+func nullable(dest reflect.Value) (target any, assign func()) {
+	typ := dest.Type()
+	if typ.Kind() == reflect.Pointer || reflect.PointerTo(typ).Implements(scannerType) {
+		return dest.Addr().Interface(), func() {}
+	}
+
+	ptr := reflect.New(reflect.PointerTo(typ))
+	return ptr.Interface(), func() {
+		if value := ptr.Elem(); !value.IsNil() {
+			dest.Set(value.Elem())
+		} else {
+			dest.Set(reflect.Zero(typ))
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type scanRecord struct {
+	Name     string         `db:"name"`
+	Nick     sql.NullString `db:"nick"`
+	Score    *int64         `db:"score"`
+	TStamp   time.Time      `db:"tstamp"`
+	Internal string
+}
+
+func Test_QueryAll_struct(t *testing.T) {
+	db, dbMock := newSqlmockClient(t)
+	score := int64(3)
+	tstamp := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	dbMock.ExpectQuery("SELECT name, nick, score, tstamp FROM t WHERE a = \\$1").
+		WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "nick", "score", "tstamp"}).
+			AddRow("foo", "f", score, tstamp).
+			AddRow(nil, nil, nil, tstamp)).
+		RowsWillBeClosed()
+
+	records, err := QueryAll[scanRecord](context.Background(), db, "SELECT name, nick, score, tstamp FROM t WHERE a = $1", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []scanRecord{
+		{Name: "foo", Nick: sql.NullString{String: "f", Valid: true}, Score: &score, TStamp: tstamp},
+		{TStamp: tstamp},
+	}, records)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_QueryAll_pointerToStruct(t *testing.T) {
+	db, dbMock := newSqlmockClient(t)
+	dbMock.ExpectQuery("SELECT name FROM t").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo").AddRow("bar"))
+
+	records, err := QueryAll[*scanRecord](context.Background(), db, "SELECT name FROM t")
+	assert.NoError(t, err)
+	assert.Equal(t, []*scanRecord{{Name: "foo"}, {Name: "bar"}}, records)
+}
+
+func Test_QueryAll_scalar(t *testing.T) {
+	type tag string
+	db, dbMock := newSqlmockClient(t)
+	dbMock.ExpectQuery("SELECT tag FROM t").
+		WillReturnRows(sqlmock.NewRows([]string{"tag"}).AddRow("foo").AddRow(nil))
+
+	tags, err := QueryAll[tag](context.Background(), db, "SELECT tag FROM t")
+	assert.NoError(t, err)
+	assert.Equal(t, []tag{"foo", ""}, tags)
+
+	tstamp := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	dbMock.ExpectQuery("SELECT tstamp FROM t").
+		WillReturnRows(sqlmock.NewRows([]string{"tstamp"}).AddRow(tstamp))
+	tstamps, err := QueryAll[time.Time](context.Background(), db, "SELECT tstamp FROM t")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{tstamp}, tstamps)
+}
+
+func Test_QueryAll_errors(t *testing.T) {
+	db, dbMock := newSqlmockClient(t)
+	ctx := context.Background()
+
+	dbMock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "unknown"}).AddRow("foo", 1)).
+		RowsWillBeClosed()
+	_, err := QueryAll[scanRecord](ctx, db, "SELECT name, unknown FROM t")
+	assert.ErrorIs(t, err, ErrScanColumn)
+
+	dbMock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"a", "b"}).AddRow("foo", "bar"))
+	_, err = QueryAll[string](ctx, db, "SELECT a, b FROM t")
+	assert.ErrorIs(t, err, ErrScanTarget)
+
+	// Errors that end iteration early are not swallowed
+	dbMock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo").AddRow("bar").RowError(1, assert.AnError))
+	_, err = QueryAll[string](ctx, db, "SELECT name FROM t")
+	assert.ErrorIs(t, err, assert.AnError)
+
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func Test_QueryOne(t *testing.T) {
+	db, dbMock := newSqlmockClient(t)
+	ctx := context.Background()
+
+	dbMock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo").AddRow("bar"))
+	name, err := QueryOne[string](ctx, db, "SELECT name FROM t")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", name)
+
+	dbMock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	_, err = QueryOne[string](ctx, db, "SELECT name FROM t")
+	assert.ErrorIs(t, err, ErrNoRecords)
+}
+
+func Test_ExecReturning(t *testing.T) {
+	db := newSQLiteClient(t)
+	ctx := context.Background()
+	_, err := db.Exec(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, nick TEXT)")
+	assert.NoError(t, err)
+
+	// Works inside transactions as well
+	ctx, tx, err := db.Begin(ctx)
+	assert.NoError(t, err)
+	records, err := ExecReturning[scanRecord](ctx, tx, "INSERT INTO t (name, nick) VALUES ($1, $2), ($3, NULL) RETURNING name, nick", "foo", "f", "bar")
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit(ctx))
+	assert.Equal(t, []scanRecord{
+		{Name: "foo", Nick: sql.NullString{String: "f", Valid: true}},
+		{Name: "bar"},
+	}, records)
+
+	ids, err := ExecReturning[int64](ctx, db, "DELETE FROM t WHERE name = $1 RETURNING id", "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids)
+}
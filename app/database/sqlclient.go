@@ -1,6 +1,6 @@
 package database
 
-// pgclient.go implements a Postgres client satisfying IDatabase.
+// sqlclient.go implements a database/sql client satisfying IDatabase, for any supported dialect.
 
 import (
 	"context"
@@ -8,27 +8,30 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/fiffu/arisa3/app/instrumentation"
 	"github.com/fiffu/arisa3/app/log"
 	"github.com/fiffu/arisa3/lib"
-	_ "github.com/lib/pq"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// pgclient implements IData for database/sql + lib/pq.
-type pgclient struct {
-	pool               *sql.DB
-	existingMigrations map[string]bool
+// sqlclient implements IDatabase for database/sql, with a dialect for the driver in use.
+type sqlclient struct {
+	pool    *sql.DB
+	dialect dialect
 }
 
-func NewDBClient(ctx context.Context, dsn string) (IDatabase, error) {
+// NewDBClient opens a client for the database at the DSN. The DSN's scheme picks the dialect:
+// "sqlite:" for SQLite, otherwise Postgres. The migrations table is seeded under the migration
+// lock, waiting up to lockWait for another instance to release it.
+func NewDBClient(ctx context.Context, dsn string, lockWait time.Duration) (IDatabase, error) {
 	c, err := open(ctx, dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := c.seedMigration(ctx); err != nil {
+	if err := c.seedMigration(ctx, lockWait); err != nil {
 		log.Errorf(ctx, err, "Seed migrations failed")
 		defer c.Close(ctx)
 		return nil, err
@@ -36,18 +39,22 @@ func NewDBClient(ctx context.Context, dsn string) (IDatabase, error) {
 	return c, err
 }
 
-func open(ctx context.Context, dsn string) (*pgclient, error) {
+func open(ctx context.Context, dsn string) (*sqlclient, error) {
 	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Database("sql.Open"))
 	defer span.End()
 
-	pool, err := sql.Open("postgres", dsn)
-	log.Infof(ctx, "Database connection opened")
+	d, driverDSN, err := dialectFor(dsn)
 	if err != nil {
 		return nil, err
 	}
-	return &pgclient{
-		pool:               pool,
-		existingMigrations: make(map[string]bool),
+	pool, err := d.Open(driverDSN)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof(ctx, "Database connection opened (dialect: %s)", d.Name())
+	return &sqlclient{
+		pool:    pool,
+		dialect: d,
 	}, nil
 }
 
@@ -68,7 +75,11 @@ func firstWord(s string) (word string) {
 	return
 }
 
-func (c *pgclient) Close(ctx context.Context) error {
+func (c *sqlclient) Dialect() string {
+	return c.dialect.Name()
+}
+
+func (c *sqlclient) Close(ctx context.Context) error {
 	if err := c.pool.Close(); err != nil {
 		log.Errorf(ctx, err, "Failed to close database connection")
 		return err
@@ -77,6 +88,18 @@ func (c *pgclient) Close(ctx context.Context) error {
 	return nil
 }
 
+func (c *sqlclient) Lock(ctx context.Context, name string, wait time.Duration) (UnlockFunc, bool, error) {
+	return c.dialect.Lock(ctx, c.pool, name, wait)
+}
+
+func (c *sqlclient) Notify(ctx context.Context, channel, payload string) error {
+	return c.dialect.Notify(ctx, c.pool, channel, payload)
+}
+
+func (c *sqlclient) Listen(ctx context.Context, channel string, handler func(Notification)) error {
+	return c.dialect.Listen(ctx, channel, handler)
+}
+
 type delegate[T any] func(ctx context.Context, query string, args ...any) (T, error)
 
 func newOperation[T any](callable delegate[T], caller string) delegate[T] {
@@ -94,51 +117,59 @@ func newOperation[T any](callable delegate[T], caller string) delegate[T] {
 	}
 }
 
-func (c *pgclient) Query(ctx context.Context, query string, args ...interface{}) (IRows, error) {
+func (c *sqlclient) Query(ctx context.Context, query string, args ...interface{}) (IRows, error) {
 	op := newOperation[*sql.Rows](c.pool.QueryContext, lib.WhoCalledMe())
-	rows, err := op(ctx, query, args...)
+	rows, err := op(ctx, c.dialect.Rebind(query), args...)
 	if err == sql.ErrNoRows {
 		return rows, fmt.Errorf("%w (driver: %v)", ErrNoRecords, err)
 	}
-	return rows, err
+	if err != nil {
+		return rows, err
+	}
+	return c.dialect.Rows(rows), nil
 }
 
-func (c *pgclient) Exec(ctx context.Context, query string, args ...interface{}) (IResult, error) {
+func (c *sqlclient) Exec(ctx context.Context, query string, args ...interface{}) (IResult, error) {
 	op := newOperation[sql.Result](c.pool.ExecContext, lib.WhoCalledMe())
-	affected, err := op(ctx, query, args...)
+	affected, err := op(ctx, c.dialect.Rebind(query), args...)
 	return affected, err
 }
 
-func (c *pgclient) Begin(ctx context.Context) (context.Context, ITransaction, error) {
-	t, err := c.pool.Begin()
+func (c *sqlclient) Begin(ctx context.Context) (context.Context, ITransaction, error) {
+	// The transaction is rolled back if ctx ends before it is committed
+	t, err := c.pool.BeginTx(ctx, nil)
 	if err != nil {
 		return ctx, nil, err
 	}
 
 	caller := lib.WhoCalledMe()
 	ctx, span := newSpan(ctx, caller, "BEGIN")
-	return ctx, sqlTxnWrap{t, span}, nil
+	return ctx, sqlTxnWrap{t, c.dialect, span}, nil
 }
 
 // sqlTxnWrap implements ITransaction for (database/sql).*Tx.
 type sqlTxnWrap struct {
 	*sql.Tx
 
-	span trace.Span
+	dialect dialect
+	span    trace.Span
 }
 
 func (txn sqlTxnWrap) Query(ctx context.Context, query string, args ...interface{}) (IRows, error) {
 	op := newOperation[*sql.Rows](txn.Tx.QueryContext, lib.WhoCalledMe())
-	rows, err := op(ctx, query, args...)
+	rows, err := op(ctx, txn.dialect.Rebind(query), args...)
 	if errors.Is(err, sql.ErrNoRows) {
 		return rows, fmt.Errorf("%w (driver: %v)", ErrNoRecords, err)
 	}
-	return rows, err
+	if err != nil {
+		return rows, err
+	}
+	return txn.dialect.Rows(rows), nil
 }
 
 func (txn sqlTxnWrap) Exec(ctx context.Context, query string, args ...interface{}) (IResult, error) {
 	op := newOperation[sql.Result](txn.Tx.ExecContext, lib.WhoCalledMe())
-	rows, err := op(ctx, query, args...)
+	rows, err := op(ctx, txn.dialect.Rebind(query), args...)
 	return rows, err
 }
 
@@ -0,0 +1,274 @@
+package database
+
+// sqlite.go implements the SQLite dialect, using the cgo-free modernc.org/sqlite driver.
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// SQLite has no legacy rows to upgrade, so the table starts out with every column.
+	createSQLiteSchemaMigrations = `CREATE TABLE IF NOT EXISTS "_schema_migrations" (
+		cog         TEXT NOT NULL DEFAULT '',
+		version     TEXT NOT NULL,
+		applied_at  TIMESTAMP,
+		source      TEXT,
+		duration_ms BIGINT,
+		checksum    TEXT,
+		PRIMARY KEY (cog, version)
+	);`
+)
+
+// sqliteTimeFormats are the formats that the driver writes times in, and parses back from columns
+// declared as timestamps. The driver's default is time.Time.String(), like
+// "2006-01-02 15:04:05.999999999 -0700 MST m=+0.001", which is parsed without the monotonic clock
+// reading and the zone's name, as the offset is enough and unnamed zones are written as "+0800".
+var sqliteTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// Locks and notifications on SQLite databases, shared by every client in this process.
+var (
+	sqliteLocks    = &namedLocks{held: make(map[string]bool)}
+	sqliteNotifier = newLocalNotifier()
+)
+
+// sqlite implements dialect for SQLite. The DSN is the path to the database file, or ":memory:".
+type sqlite struct {
+	dsn string
+}
+
+func newSQLite(dsn string) sqlite {
+	return sqlite{dsn}
+}
+
+func (sqlite) Name() string { return DialectSQLite }
+
+// Open limits the pool to one connection. SQLite allows a single writer at a time, and each
+// connection to ":memory:" would otherwise get a database of its own. Other clients of the same
+// file wait for its write lock instead of failing at once.
+func (sqlite) Open(dsn string) (*sql.DB, error) {
+	if !strings.Contains(dsn, "busy_timeout") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "_pragma=busy_timeout(5000)"
+	}
+	pool, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	pool.SetMaxOpenConns(1)
+	return pool, nil
+}
+
+// Rebind rewrites placeholders like $1 into SQLite's ?1, which binds the same argument. Quoted
+// strings, quoted identifiers and comments are left as they are.
+func (sqlite) Rebind(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			end := strings.IndexByte(query[i+1:], ch)
+			if end < 0 {
+				end = len(query) - i - 2
+			}
+			sb.WriteString(query[i : i+end+2])
+			i += end + 1
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			sb.WriteString(query[i : i+end])
+			i += end - 1
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i:], "*/")
+			if end < 0 {
+				end = len(query) - i - 2
+			}
+			sb.WriteString(query[i : i+end+2])
+			i += end + 1
+		case ch == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			sb.WriteByte('?')
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return sb.String()
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+// Rows parses text into times. The driver only parses columns declared as timestamps, so values
+// computed from them, like MAX(tstamp), come back as text.
+func (sqlite) Rows(rows *sql.Rows) IRows {
+	return sqliteRows{rows}
+}
+
+func (sqlite) SeedStatements() []string {
+	return []string{createSQLiteSchemaMigrations}
+}
+
+// Lock only excludes other holders of the same DSN within this process. SQLite has no equivalent
+// of advisory locks, and a SQLite database is not meant to be shared between replicas anyway.
+func (d sqlite) Lock(ctx context.Context, _ *sql.DB, name string, wait time.Duration) (UnlockFunc, bool, error) {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Database("Lock"))
+	span.SetAttributes(instrumentation.KV.DBOperation(name))
+	defer span.End()
+
+	start := time.Now()
+	deadline := start.Add(wait)
+	key := d.dsn + "#" + name
+	for attempt := 1; !sqliteLocks.tryLock(key); attempt++ {
+		if !time.Now().Before(deadline) {
+			log.Infof(ctx, "Lock %s is held elsewhere, gave up after %d attempts", name, attempt)
+			span.SetAttributes(instrumentation.KV.LockAcquired(false), instrumentation.KV.LockWait(time.Since(start)))
+			return nil, false, nil
+		}
+		if attempt == 1 {
+			log.Infof(ctx, "Lock %s is held elsewhere, waiting up to %v", name, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	span.SetAttributes(instrumentation.KV.LockAcquired(true), instrumentation.KV.LockWait(time.Since(start)))
+
+	unlock := func(ctx context.Context) error {
+		sqliteLocks.unlock(key)
+		return nil
+	}
+	return unlock, true, nil
+}
+
+// Notify only reaches listeners of the same DSN within this process, for the same reason as Lock.
+// Listeners are called before Notify returns.
+func (d sqlite) Notify(ctx context.Context, _ *sql.DB, channel, payload string) error {
+	sqliteNotifier.notify(d.dsn+"#"+channel, payload)
+	return nil
+}
+
+func (d sqlite) Listen(ctx context.Context, channel string, handler func(Notification)) error {
+	sqliteNotifier.listen(ctx, d.dsn+"#"+channel, handler)
+	return nil
+}
+
+// namedLocks is a set of locks held in this process, by name.
+type namedLocks struct {
+	mutex sync.Mutex
+	held  map[string]bool
+}
+
+func (l *namedLocks) tryLock(name string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.held[name] {
+		return false
+	}
+	l.held[name] = true
+	return true
+}
+
+func (l *namedLocks) unlock(name string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.held, name)
+}
+
+// sqliteRows scans text into time.Time destinations, see sqlite.Rows.
+type sqliteRows struct {
+	*sql.Rows
+}
+
+func (r sqliteRows) Scan(dest ...interface{}) error {
+	targets := make([]interface{}, len(dest))
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *time.Time:
+			targets[i] = sqliteTime{d}
+		case **time.Time:
+			targets[i] = sqliteNullTime{d}
+		default:
+			targets[i] = d
+		}
+	}
+	return r.Rows.Scan(targets...)
+}
+
+// sqliteTime implements sql.Scanner for a time.Time that may be read as text.
+type sqliteTime struct {
+	dest *time.Time
+}
+
+func (t sqliteTime) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*t.dest = v
+		return nil
+	case string:
+		return parseSQLiteTime(v, t.dest)
+	case []byte:
+		return parseSQLiteTime(string(v), t.dest)
+	default:
+		return fmt.Errorf("can't scan %T into time.Time", src)
+	}
+}
+
+// sqliteNullTime implements sql.Scanner for a *time.Time, which is nil for NULL.
+type sqliteNullTime struct {
+	dest **time.Time
+}
+
+func (t sqliteNullTime) Scan(src any) error {
+	if src == nil {
+		*t.dest = nil
+		return nil
+	}
+	var value time.Time
+	if err := (sqliteTime{&value}).Scan(src); err != nil {
+		return err
+	}
+	*t.dest = &value
+	return nil
+}
+
+func parseSQLiteTime(s string, dest *time.Time) error {
+	s, _, _ = strings.Cut(s, " m=")
+	if fields := strings.Fields(s); len(fields) == 4 {
+		s = strings.Join(fields[:3], " ")
+	}
+	s = strings.TrimSuffix(s, "Z")
+	for _, format := range sqliteTimeFormats {
+		if parsed, err := time.Parse(format, s); err == nil {
+			*dest = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("can't parse %q as a time", s)
+}
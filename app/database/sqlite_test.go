@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sqlite_Rebind(t *testing.T) {
+	testCases := []struct {
+		query  string
+		expect string
+	}{
+		{"SELECT * FROM t WHERE a = $1 AND b = $2", "SELECT * FROM t WHERE a = ?1 AND b = ?2"},
+		{"UPDATE t SET a=$2 WHERE b=$1 OR c=$1", "UPDATE t SET a=?2 WHERE b=?1 OR c=?1"},
+		{"SELECT '$1', \"$2\" FROM t WHERE a = $10", "SELECT '$1', \"$2\" FROM t WHERE a = ?10"},
+		{"SELECT 'it''s $1' WHERE a = $1", "SELECT 'it''s $1' WHERE a = ?1"},
+		{"SELECT a -- costs $1\nFROM t WHERE a = $1", "SELECT a -- costs $1\nFROM t WHERE a = ?1"},
+		{"SELECT /* $1 */ a FROM t WHERE a = $1", "SELECT /* $1 */ a FROM t WHERE a = ?1"},
+		{"SELECT $ FROM t", "SELECT $ FROM t"},
+		{"SELECT 'unterminated $1", "SELECT 'unterminated $1"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expect, sqlite{}.Rebind(tc.query))
+	}
+}
+
+func newSQLiteClient(t *testing.T) IDatabase {
+	db, err := NewDBClient(context.Background(), "sqlite::memory:", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close(context.Background()) })
+	return db
+}
+
+func Test_sqlite_migrations(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteClient(t)
+	assert.Equal(t, DialectSQLite, db.Dialect())
+
+	fsys := fstest.MapFS{
+		"1_create.sql":                 {Data: []byte(`CREATE TABLE a (id INTEGER, note TEXT);`)},
+		"2_partition.sql":              {Data: []byte(`CREATE TABLE b (id INTEGER) PARTITION BY RANGE (id);`)},
+		"2_partition.sqlite.sql":       {Data: []byte(`CREATE TABLE b (id INTEGER);`)},
+		"3_index.up.sql":               {Data: []byte(`CREATE INDEX a_id ON a (id); CREATE INDEX b_id ON b (id);`)},
+		"3_index.down.sql":             {Data: []byte(`DROP INDEX a_id; DROP INDEX b_id;`)},
+		"4_postgres_only.postgres.sql": {Data: []byte(`CREATE EXTENSION pg_trgm;`)},
+	}
+	schemas, err := LoadMigrations(ctx, db, "cog", fsys)
+	assert.NoError(t, err)
+	if !assert.Len(t, schemas, 3) {
+		return
+	}
+	assert.Equal(t, "2_partition.sqlite.sql", schemas[1].Source())
+
+	for _, schema := range schemas {
+		executed, err := db.Migrate(ctx, schema)
+		assert.NoError(t, err)
+		assert.True(t, executed)
+	}
+
+	records, err := db.Migrations(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "cog/3", records[2].Key())
+	assert.Equal(t, schemas[2].Checksum(), records[2].Checksum)
+	assert.WithinDuration(t, time.Now(), records[2].AppliedAt, time.Minute)
+
+	// Queries are written with Postgres placeholders
+	_, err = db.Exec(ctx, "INSERT INTO a (id, note) VALUES ($1, $2)", 1, "one")
+	assert.NoError(t, err)
+	rows, err := db.Query(ctx, "SELECT note FROM a WHERE id = $1", 1)
+	assert.NoError(t, err)
+	var note string
+	assert.True(t, rows.Next())
+	assert.NoError(t, rows.Scan(&note))
+	assert.Equal(t, "one", note)
+	assert.False(t, rows.Next())
+
+	rolledBack, err := db.Rollback(ctx, "2", schemas)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cog/3"}, rolledBack)
+}
+
+func Test_sqlite_Rows_times(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteClient(t)
+	_, err := db.Exec(ctx, "CREATE TABLE t (k TEXT, tstamp TIMESTAMP)")
+	assert.NoError(t, err)
+
+	earlier := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	later := time.Date(2024, 1, 2, 12, 30, 15, 500, time.FixedZone("", 8*60*60))
+	for _, tstamp := range []time.Time{earlier, later, time.Now()} {
+		_, err = db.Exec(ctx, "INSERT INTO t(k, tstamp) VALUES ($1, $2)", tstamp.Format(time.DateOnly), tstamp)
+		assert.NoError(t, err)
+	}
+
+	// Aggregates over timestamps come back from the driver as text
+	type row struct {
+		K      string    `db:"k"`
+		TStamp time.Time `db:"tstamp"`
+	}
+	rows, err := QueryAll[row](ctx, db,
+		"SELECT k, MAX(tstamp) AS tstamp FROM t WHERE k != $1 GROUP BY k ORDER BY k",
+		time.Now().Format(time.DateOnly),
+	)
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.True(t, earlier.Equal(rows[0].TStamp))
+		assert.True(t, later.Equal(rows[1].TStamp))
+	}
+
+	// Including times with a monotonic clock reading, and NULL
+	latest, err := QueryOne[time.Time](ctx, db, "SELECT MAX(tstamp) FROM t")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), latest, time.Minute)
+
+	none, err := QueryOne[*time.Time](ctx, db, "SELECT MAX(tstamp) FROM t WHERE k = ''")
+	assert.NoError(t, err)
+	assert.Nil(t, none)
+}
+
+func Test_sqlite_Lock(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteClient(t)
+
+	unlock, acquired, err := db.Lock(ctx, "test-lock", 0)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	_, acquired, err = db.Lock(ctx, "test-lock", 0)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	assert.NoError(t, unlock(ctx))
+	unlock, acquired, err = db.Lock(ctx, "test-lock", 0)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	assert.NoError(t, unlock(ctx))
+}
+
+func Test_sqlite_Lock_sharedFile(t *testing.T) {
+	ctx := context.Background()
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "test.db")
+	first, err := NewDBClient(ctx, dsn, time.Minute)
+	assert.NoError(t, err)
+	defer first.Close(ctx)
+	second, err := NewDBClient(ctx, dsn, time.Minute)
+	assert.NoError(t, err)
+	defer second.Close(ctx)
+
+	unlock, acquired, err := first.Lock(ctx, "test-lock", 0)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	// The second client waits for the first to release the lock
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		unlock(ctx)
+	}()
+	unlock, acquired, err = second.Lock(ctx, "test-lock", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	assert.NoError(t, unlock(ctx))
+}
+
+func Test_sqlite_seedMigration_locked(t *testing.T) {
+	ctx := context.Background()
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "test.db")
+	first, err := NewDBClient(ctx, dsn, time.Minute)
+	assert.NoError(t, err)
+	defer first.Close(ctx)
+
+	// Seeding waits for the migration lock, like migrations do
+	unlock, acquired, err := first.Lock(ctx, MigrationLockName, 0)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	_, err = NewDBClient(ctx, dsn, 0)
+	assert.ErrorIs(t, err, ErrMigrationLockTimeout)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		unlock(ctx)
+	}()
+	second, err := NewDBClient(ctx, dsn, time.Second)
+	assert.NoError(t, err)
+	defer second.Close(ctx)
+}
+
+func Test_sqlite_Notify_sharedFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "test.db")
+	first, err := NewDBClient(ctx, dsn, time.Minute)
+	assert.NoError(t, err)
+	defer first.Close(ctx)
+	second, err := NewDBClient(ctx, dsn, time.Minute)
+	assert.NoError(t, err)
+	defer second.Close(ctx)
+
+	received := make([]string, 0)
+	assert.NoError(t, second.Listen(ctx, "test-channel", func(n Notification) {
+		received = append(received, n.Payload)
+	}))
+
+	assert.NoError(t, first.Notify(ctx, "test-channel", "hello"))
+	assert.NoError(t, first.Notify(ctx, "other-channel", "ignored"))
+	assert.Equal(t, []string{"hello"}, received)
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// WithTransaction runs fn in a transaction, which is committed if fn succeeds and rolled back if
+// it returns an error or panics. The context given to fn carries the transaction's span, so that
+// queries made through tx are traced under it.
+func WithTransaction(ctx context.Context, db IDatabase, fn func(ctx context.Context, tx ITransaction) error) (err error) {
+	ctx, tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback(ctx)
+			panic(r)
+		}
+	}()
+
+	if err := fn(ctx, tx); err != nil {
+		// If ctx has ended, the driver has rolled back already
+		if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return fmt.Errorf("got another error while rolling back due to '%w': %v", err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
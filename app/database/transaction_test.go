@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithTransaction(t *testing.T) {
+	db := newSQLiteClient(t)
+	ctx := context.Background()
+	_, err := db.Exec(ctx, "CREATE TABLE t (name TEXT)")
+	assert.NoError(t, err)
+
+	insert := func(name string) func(context.Context, ITransaction) error {
+		return func(ctx context.Context, tx ITransaction) error {
+			_, err := tx.Exec(ctx, "INSERT INTO t (name) VALUES ($1)", name)
+			return err
+		}
+	}
+	names := func() []string {
+		names, err := QueryAll[string](ctx, db, "SELECT name FROM t ORDER BY name")
+		assert.NoError(t, err)
+		return names
+	}
+
+	assert.NoError(t, WithTransaction(ctx, db, insert("committed")))
+	assert.Equal(t, []string{"committed"}, names())
+
+	err = WithTransaction(ctx, db, func(ctx context.Context, tx ITransaction) error {
+		assert.NoError(t, insert("failed")(ctx, tx))
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, []string{"committed"}, names())
+
+	assert.Panics(t, func() {
+		_ = WithTransaction(ctx, db, func(ctx context.Context, tx ITransaction) error {
+			assert.NoError(t, insert("panicked")(ctx, tx))
+			panic("oops")
+		})
+	})
+	assert.Equal(t, []string{"committed"}, names())
+}
+
+func Test_WithTransaction_cancelled(t *testing.T) {
+	// Cancelling may close the connection, which would take an in-memory database with it
+	db, err := NewDBClient(context.Background(), "sqlite://"+filepath.Join(t.TempDir(), "test.db"), time.Minute)
+	assert.NoError(t, err)
+	defer db.Close(context.Background())
+	_, err = db.Exec(context.Background(), "CREATE TABLE t (name TEXT)")
+	assert.NoError(t, err)
+
+	// Ending the context rolls the transaction back, which is not an error of its own
+	ctx, cancel := context.WithCancel(context.Background())
+	err = WithTransaction(ctx, db, func(ctx context.Context, tx ITransaction) error {
+		_, err := tx.Exec(ctx, "INSERT INTO t (name) VALUES ($1)", "cancelled")
+		assert.NoError(t, err)
+		cancel()
+		_, err = tx.Exec(ctx, "INSERT INTO t (name) VALUES ($1)", "too late")
+		return err
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotContains(t, err.Error(), "while rolling back")
+
+	// nor is anything committed once it has ended
+	ctx, cancel = context.WithCancel(context.Background())
+	err = WithTransaction(ctx, db, func(ctx context.Context, tx ITransaction) error {
+		_, err := tx.Exec(ctx, "INSERT INTO t (name) VALUES ($1)", "uncommitted")
+		cancel()
+		return err
+	})
+	assert.Error(t, err)
+
+	names, err := QueryAll[string](context.Background(), db, "SELECT name FROM t")
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+}
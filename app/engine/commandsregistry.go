@@ -65,6 +65,10 @@ func (r *CommandsRegistry) BindCallbacks(s *dgo.Session) {
 // onInteractionCreate logs errors from registryHandler.
 func (r *CommandsRegistry) onInteractionCreate(s *dgo.Session, i *dgo.InteractionCreate) {
 	ctx, err := r.registryHandler(s, i)
+	if err == errNotCommand {
+		// Other interactions, like button clicks, are handled by the cogs that sent the buttons
+		return
+	}
 	if err == errDuplicatedRequest {
 		log.Warnf(ctx, "Ignoring duplicated request")
 		return
@@ -124,16 +128,25 @@ func (r *CommandsRegistry) registryHandler(s *dgo.Session, i *dgo.InteractionCre
 		ctx = log.Put(ctx, log.Guild, i.GuildID)
 	}
 
+	// Subcommands are invoked through their parent, with their options nested in the one naming them
+	name, options := cmd.Name(), i.ApplicationCommandData().Options
+	if len(options) == 1 && options[0].Type == dgo.ApplicationCommandOptionSubCommand {
+		if sub, ok := cmd.FindSubCommand(options[0].Name); ok {
+			name = cmd.Name() + " " + sub.Name()
+			cmd, options = sub, options[0].Options
+		}
+	}
+
 	opts := make(map[string]interface{})
-	for _, o := range i.ApplicationCommandData().Options {
+	for _, o := range options {
 		opts[o.Name] = o.Value
 	}
 	log.Infof(ctx, "Interaction incoming <<< user=%s options=%+v", who, opts)
 
 	// Instrumentation for the command handler
-	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Command(cmd.Name()))
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Command(name))
 	span.SetAttributes(
-		instrumentation.KV.CommandName(cmd.Name()),
+		instrumentation.KV.CommandName(name),
 		instrumentation.KV.TraceID(traceID),
 		instrumentation.KV.User(who.String()),
 		instrumentation.KV.Params(opts),
@@ -145,7 +158,7 @@ func (r *CommandsRegistry) registryHandler(s *dgo.Session, i *dgo.InteractionCre
 	if handler == nil {
 		return ctx, r.fallbackHandler(ctx, s, i, cmd)
 	}
-	args := parseArgs(ctx, cmd, i.ApplicationCommandData().Options)
+	args := parseArgs(ctx, cmd, options)
 	err = mustHandleCommand(ctx, cmd, handler, args, s, i)
 	if err != nil {
 		log.Errorf(ctx, err, "Handler errored")
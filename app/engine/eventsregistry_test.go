@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	dgo "github.com/bwmarrin/discordgo"
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewEventHandler(t *testing.T) {
+	var received *dgo.GuildRoleDelete
+	var traceID string
+	hdlr := NewEventHandler(func(ctx context.Context, s *dgo.Session, evt *dgo.GuildRoleDelete) {
+		received = evt
+		traceID = log.Get(ctx, log.TraceID)
+	})
+
+	evt := &dgo.GuildRoleDelete{GuildID: "guild", RoleID: "role"}
+	span := instrumentation.CaptureInstrumentation(t, func() {
+		hdlr(nil, evt)
+	})
+
+	assert.Equal(t, evt, received)
+	assert.Contains(t, traceID, "*discordgo.GuildRoleDelete-")
+	assert.Equal(t, "*discordgo.GuildRoleDelete", span.Attributes.GetAsString("event_name"))
+	assert.True(t, span.Ended)
+}
+
+func Test_NewEventHandler_panic(t *testing.T) {
+	hdlr := NewEventHandler(func(context.Context, *dgo.Session, *dgo.GuildMemberAdd) { panic("testing 123") })
+
+	msg := log.CaptureLogging(t, func() {
+		hdlr(nil, &dgo.GuildMemberAdd{})
+	})
+	assert.Contains(t, msg, "testing 123")
+}
@@ -0,0 +1,294 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MigrationState describes whether a migration has been applied.
+type MigrationState string
+
+const (
+	MigrationApplied MigrationState = "applied"
+	MigrationPending MigrationState = "pending"
+	// MigrationMissing is a migration recorded as applied, which no cog has a file for.
+	MigrationMissing MigrationState = "missing"
+	// MigrationDrifted is an applied migration whose file has changed since.
+	MigrationDrifted MigrationState = "drifted"
+)
+
+// MigrationLockName names the lock held while migrations run. The database client also holds it
+// while seeding the migrations table.
+const MigrationLockName = database.MigrationLockName
+
+var (
+	ErrUnknownDriftOverride = errors.New("drift override does not match a drifted migration")
+	ErrMigrationLockTimeout = database.ErrMigrationLockTimeout
+)
+
+// MigrationStatus is a row of MigrationReport.
+type MigrationStatus struct {
+	Cog       string
+	Version   string
+	Source    string
+	State     MigrationState
+	AppliedAt time.Time
+	Duration  time.Duration
+}
+
+// MigrationReport lists the state of each migration across cogs, ordered by version.
+type MigrationReport []MigrationStatus
+
+// Count returns the number of migrations in the given state.
+func (r MigrationReport) Count(state MigrationState) int {
+	count := 0
+	for _, row := range r {
+		if row.State == state {
+			count++
+		}
+	}
+	return count
+}
+
+func (r MigrationReport) String() string {
+	buf := new(strings.Builder)
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tCOG\tSTATE\tAPPLIED AT\tDURATION\tSOURCE")
+	for _, row := range r {
+		appliedAt, duration := "-", "-"
+		if !row.AppliedAt.IsZero() {
+			appliedAt = row.AppliedAt.Format(time.RFC3339)
+			duration = row.Duration.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", row.Version, row.Cog, row.State, appliedAt, duration, row.Source)
+	}
+	w.Flush()
+	fmt.Fprintf(buf, "%d applied, %d pending, %d missing, %d drifted\n",
+		r.Count(MigrationApplied), r.Count(MigrationPending), r.Count(MigrationMissing), r.Count(MigrationDrifted))
+	return buf.String()
+}
+
+// MigrateCogs applies every cog's pending migrations while holding the migration lock, waiting up
+// to lockWait for another instance to release it. Drift in the migrations named by driftOverrides
+// is accepted first, under the same lock, against the migrations applied by then.
+func MigrateCogs(ctx context.Context, db database.IDatabase, lockWait time.Duration, driftOverrides []string, cogs ...IRepository) error {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Internal("MigrateCogs"))
+	defer span.End()
+
+	unlock, err := lockMigrations(ctx, db, lockWait, span)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := acceptMigrationDrift(ctx, db, driftOverrides, cogs...); err != nil {
+		log.Errorf(ctx, err, "Failed to accept migration drift")
+		span.RecordError(err)
+		return err
+	}
+
+	for _, cog := range cogs {
+		ctx := log.Put(ctx, log.CogName, cog.Name())
+		if err := runMigrations(ctx, cog, db); err != nil {
+			log.Errorf(ctx, err, "Migrations failed")
+			span.RecordError(err)
+			return fmt.Errorf("%s: %w", cog.Name(), err)
+		}
+	}
+	return nil
+}
+
+// lockMigrations acquires the migration lock, waiting up to lockWait for another instance to
+// release it, and records the wait on span. The returned func releases the lock.
+func lockMigrations(ctx context.Context, db database.IDatabase, lockWait time.Duration, span trace.Span) (func(), error) {
+	log.Infof(ctx, "Acquiring migration lock (waiting up to %v)", lockWait)
+	start := time.Now()
+	unlock, acquired, err := db.Lock(ctx, MigrationLockName, lockWait)
+	waited := time.Since(start)
+	span.SetAttributes(instrumentation.KV.LockAcquired(acquired), instrumentation.KV.LockWait(waited))
+	if err == nil && !acquired {
+		err = fmt.Errorf("%w after %v", ErrMigrationLockTimeout, lockWait)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	log.Infof(ctx, "Acquired migration lock after %v", waited)
+
+	return func() {
+		if err := unlock(context.Background()); err != nil {
+			log.Errorf(ctx, err, "Failed to release migration lock")
+		}
+	}, nil
+}
+
+func runMigrations(ctx context.Context, cog IRepository, db database.IDatabase) error {
+	schemas, err := database.LoadMigrations(ctx, db, cog.Name(), cog.MigrationsFS())
+	log.Infof(ctx, "Migrations found (count: %d) for cog: %s", len(schemas), cog.Name())
+	if err != nil {
+		return err
+	}
+
+	// Check every migration for drift before applying any, so the report is complete
+	records, err := db.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+	if drifts := database.DetectDrift(records, schemas); len(drifts) > 0 {
+		return &database.DriftError{Drifts: drifts}
+	}
+
+	migratedCount := 0
+	for _, schema := range schemas {
+		executed, err := db.Migrate(ctx, schema)
+		if err != nil {
+			return err
+		} else if executed {
+			migratedCount += 1
+		}
+	}
+	log.Infof(ctx, "Migrations complete (total executed: %d)", migratedCount)
+	return nil
+}
+
+// ReportMigrations compares the migrations in each cog's MigrationsFS against those applied.
+func ReportMigrations(ctx context.Context, db database.IDatabase, cogs ...IRepository) (MigrationReport, error) {
+	records, err := db.Migrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[string]*database.MigrationRecord)
+	for _, rec := range records {
+		applied[rec.Key()] = rec
+	}
+
+	report := make(MigrationReport, 0)
+	for _, cog := range cogs {
+		schemas, err := database.LoadMigrations(ctx, db, cog.Name(), cog.MigrationsFS())
+		if err != nil {
+			return nil, err
+		}
+		drifted := make(map[string]bool)
+		for _, drift := range database.DetectDrift(records, schemas) {
+			drifted[drift.Version] = true
+		}
+
+		for _, schema := range schemas {
+			row := MigrationStatus{
+				Cog:     cog.Name(),
+				Version: schema.Version(),
+				Source:  filepath.Base(schema.Source()),
+				State:   MigrationPending,
+			}
+			// Migrations applied before namespacing are recorded without a cog
+			key := database.MigrationKey(schema.Namespace(), schema.Version())
+			legacyKey := database.MigrationKey("", schema.Version())
+			if _, ok := applied[key]; !ok {
+				key = legacyKey
+			}
+			if rec, ok := applied[key]; ok {
+				row.State = MigrationApplied
+				if drifted[schema.Version()] {
+					row.State = MigrationDrifted
+				}
+				row.AppliedAt = rec.AppliedAt
+				row.Duration = rec.Duration
+				delete(applied, key)
+			}
+			report = append(report, row)
+		}
+	}
+	for _, rec := range applied {
+		report = append(report, MigrationStatus{
+			Cog:       rec.Namespace,
+			Version:   rec.Version,
+			Source:    rec.Source,
+			State:     MigrationMissing,
+			AppliedAt: rec.AppliedAt,
+			Duration:  rec.Duration,
+		})
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return database.CompareVersions(report[i].Version, report[j].Version) < 0
+	})
+	return report, nil
+}
+
+// RollbackMigrations reverts migrations newer than the target version, across all cogs. Like
+// MigrateCogs, it holds the migration lock, so that it doesn't race an instance that is starting up.
+func RollbackMigrations(ctx context.Context, db database.IDatabase, lockWait time.Duration, target string, cogs ...IRepository) ([]string, error) {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Internal("RollbackMigrations"))
+	defer span.End()
+
+	unlock, err := lockMigrations(ctx, db, lockWait, span)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	schemas := make([]database.ISchema, 0)
+	for _, cog := range cogs {
+		cogSchemas, err := database.LoadMigrations(ctx, db, cog.Name(), cog.MigrationsFS())
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, cogSchemas...)
+	}
+
+	rolledBack, err := db.Rollback(ctx, target, schemas)
+	log.Infof(ctx, "Rolled back %d migrations to version %s: %v", len(rolledBack), target, rolledBack)
+	return rolledBack, err
+}
+
+// acceptMigrationDrift records the current checksums of drifted migrations, so that startup no
+// longer fails on them. Each override names a migration as "<cog>/<version>", and must match a
+// drifted migration, so that a stale override is not silently kept around. It must be called with
+// the migration lock held, so that the applied migrations it reads are not changing underneath it.
+func acceptMigrationDrift(ctx context.Context, db database.IDatabase, overrides []string, cogs ...IRepository) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+	records, err := db.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	drifted := make(map[string]database.ISchema)
+	for _, cog := range cogs {
+		schemas, err := database.LoadMigrations(ctx, db, cog.Name(), cog.MigrationsFS())
+		if err != nil {
+			return err
+		}
+		byVersion := make(map[string]database.ISchema)
+		for _, schema := range schemas {
+			byVersion[schema.Version()] = schema
+		}
+		for _, drift := range database.DetectDrift(records, schemas) {
+			drifted[database.MigrationKey(drift.Namespace, drift.Version)] = byVersion[drift.Version]
+		}
+	}
+
+	for _, key := range overrides {
+		schema, ok := drifted[key]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownDriftOverride, key)
+		}
+		log.Warnf(ctx, "Accepting drift in migration %s (%s), checksum is now %s", key, schema.Source(), schema.Checksum())
+		if err := db.AcceptChecksum(ctx, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package engine
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRepo struct {
+	name string
+	fsys fs.FS
+}
+
+func (r testRepo) Name() string        { return r.name }
+func (r testRepo) MigrationsFS() fs.FS { return r.fsys }
+
+type testSchema struct {
+	namespace, version, source string
+}
+
+func (s testSchema) Namespace() string     { return s.namespace }
+func (s testSchema) Version() string       { return s.version }
+func (s testSchema) Source() string        { return s.source }
+func (s testSchema) Checksum() string      { return "checksum-" + s.version }
+func (s testSchema) Queries() []string     { return []string{"SELECT 1;"} }
+func (s testSchema) DownQueries() []string { return []string{"SELECT 1;"} }
+
+func newTestRepo(t *testing.T, name string, files ...string) testRepo {
+	fsys := make(fstest.MapFS)
+	for _, file := range files {
+		fsys[file] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	}
+	return testRepo{name, fsys}
+}
+
+func newMigrationsDB(t *testing.T, applied ...*database.MigrationRecord) *database.MockIDatabase {
+	db := database.NewMockIDatabase(gomock.NewController(t))
+	db.EXPECT().ParseMigration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(_ context.Context, namespace string, _ fs.FS, path string) (database.ISchema, error) {
+			version, _, _ := strings.Cut(filepath.Base(path), "_")
+			return testSchema{namespace, version, path}, nil
+		},
+	)
+	db.EXPECT().Migrations(gomock.Any()).AnyTimes().Return(applied, nil)
+	db.EXPECT().Dialect().AnyTimes().Return(database.DialectPostgres)
+	return db
+}
+
+func Test_ReportMigrations(t *testing.T) {
+	appliedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := newMigrationsDB(t,
+		&database.MigrationRecord{Version: "1", AppliedAt: appliedAt, Duration: time.Second},
+		&database.MigrationRecord{Namespace: "foo", Version: "2", Source: "2_deleted.sql"},
+		&database.MigrationRecord{Namespace: "bar", Version: "5", Checksum: "edited", AppliedAt: appliedAt},
+	)
+	foo := newTestRepo(t, "foo", "1_a.sql", "3_c.up.sql", "3_c.down.sql")
+	bar := newTestRepo(t, "bar", "4_d.sql", "5_e.sql")
+
+	report, err := ReportMigrations(context.Background(), db, foo, bar)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrationReport{
+		{Cog: "foo", Version: "1", Source: "1_a.sql", State: MigrationApplied, AppliedAt: appliedAt, Duration: time.Second},
+		{Cog: "foo", Version: "2", Source: "2_deleted.sql", State: MigrationMissing},
+		{Cog: "foo", Version: "3", Source: "3_c.up.sql", State: MigrationPending},
+		{Cog: "bar", Version: "4", Source: "4_d.sql", State: MigrationPending},
+		{Cog: "bar", Version: "5", Source: "5_e.sql", State: MigrationDrifted, AppliedAt: appliedAt},
+	}, report)
+
+	out := report.String()
+	assert.Contains(t, out, "1 applied, 2 pending, 1 missing, 1 drifted")
+	assert.Contains(t, out, "2024-01-01T00:00:00Z")
+}
+
+func Test_RollbackMigrations(t *testing.T) {
+	db := newMigrationsDB(t)
+	foo := newTestRepo(t, "foo", "1_a.sql")
+	bar := newTestRepo(t, "bar", "2_b.up.sql", "2_b.down.sql")
+
+	unlocked := false
+	unlock := func(context.Context) error { unlocked = true; return nil }
+	gomock.InOrder(
+		db.EXPECT().Lock(gomock.Any(), MigrationLockName, time.Minute).Return(unlock, true, nil),
+		db.EXPECT().Rollback(gomock.Any(), "1", gomock.Len(2)).Return([]string{"bar/2"}, nil),
+	)
+
+	rolledBack, err := RollbackMigrations(context.Background(), db, time.Minute, "1", foo, bar)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar/2"}, rolledBack)
+	assert.True(t, unlocked)
+}
+
+func Test_RollbackMigrations_lockTimeout(t *testing.T) {
+	db := newMigrationsDB(t)
+	foo := newTestRepo(t, "foo", "1_a.sql")
+
+	// Nothing is rolled back while another instance migrates
+	db.EXPECT().Lock(gomock.Any(), MigrationLockName, time.Minute).Return(nil, false, nil)
+
+	var err error
+	span := instrumentation.CaptureInstrumentation(t, func() {
+		_, err = RollbackMigrations(context.Background(), db, time.Minute, "1", foo)
+	})
+	assert.ErrorIs(t, err, ErrMigrationLockTimeout)
+	assert.Equal(t, "false", span.Attributes.GetAsString("lock_acquired"))
+}
+
+func Test_runMigrations_drift(t *testing.T) {
+	db := newMigrationsDB(t,
+		&database.MigrationRecord{Namespace: "foo", Version: "1", Checksum: "edited"},
+		&database.MigrationRecord{Namespace: "foo", Version: "2", Checksum: "edited"},
+	)
+	foo := newTestRepo(t, "foo", "1_a.sql", "2_b.sql", "3_c.sql")
+
+	// Fails before applying anything, and reports every drifted migration
+	err := runMigrations(context.Background(), foo, db)
+	var driftErr *database.DriftError
+	assert.ErrorAs(t, err, &driftErr)
+	assert.Len(t, driftErr.Drifts, 2)
+}
+
+func Test_acceptMigrationDrift(t *testing.T) {
+	db := newMigrationsDB(t,
+		&database.MigrationRecord{Namespace: "foo", Version: "1", Checksum: "edited"},
+	)
+	foo := newTestRepo(t, "foo", "1_a.sql", "2_b.sql")
+
+	db.EXPECT().AcceptChecksum(gomock.Any(), testSchema{"foo", "1", "1_a.sql"}).Return(nil)
+	err := acceptMigrationDrift(context.Background(), db, []string{"foo/1"}, foo)
+	assert.NoError(t, err)
+
+	err = acceptMigrationDrift(context.Background(), db, []string{"foo/2"}, foo)
+	assert.ErrorIs(t, err, ErrUnknownDriftOverride)
+}
+
+func Test_MigrateCogs(t *testing.T) {
+	foo := newTestRepo(t, "foo", "1_a.sql")
+	unlocked := false
+	unlock := func(context.Context) error { unlocked = true; return nil }
+
+	db := newMigrationsDB(t)
+	db.EXPECT().Lock(gomock.Any(), MigrationLockName, time.Minute).Return(unlock, true, nil)
+	db.EXPECT().Migrate(gomock.Any(), testSchema{"foo", "1", "1_a.sql"}).Return(true, nil)
+
+	var err error
+	span := instrumentation.CaptureInstrumentation(t, func() {
+		err = MigrateCogs(context.Background(), db, time.Minute, nil, foo)
+	})
+	assert.NoError(t, err)
+	assert.True(t, unlocked)
+	assert.Equal(t, "true", span.Attributes.GetAsString("lock_acquired"))
+	assert.NotEmpty(t, span.Attributes.GetAsString("lock_wait_ms"))
+}
+
+func Test_MigrateCogs_acceptDrift(t *testing.T) {
+	foo := newTestRepo(t, "foo", "1_a.sql", "2_b.sql")
+	drifted := &database.MigrationRecord{Namespace: "foo", Version: "1", Checksum: "edited"}
+	unlocked := false
+	unlock := func(context.Context) error { unlocked = true; return nil }
+
+	// Drift is accepted while the lock is held, before pending migrations are applied
+	db := newMigrationsDB(t, drifted)
+	gomock.InOrder(
+		db.EXPECT().Lock(gomock.Any(), MigrationLockName, time.Minute).Return(unlock, true, nil),
+		db.EXPECT().AcceptChecksum(gomock.Any(), testSchema{"foo", "1", "1_a.sql"}).DoAndReturn(
+			func(_ context.Context, schema database.ISchema) error {
+				drifted.Checksum = schema.Checksum()
+				return nil
+			},
+		),
+		db.EXPECT().Migrate(gomock.Any(), testSchema{"foo", "1", "1_a.sql"}).Return(false, nil),
+		db.EXPECT().Migrate(gomock.Any(), testSchema{"foo", "2", "2_b.sql"}).Return(true, nil),
+	)
+
+	var err error
+	instrumentation.CaptureInstrumentation(t, func() {
+		err = MigrateCogs(context.Background(), db, time.Minute, []string{"foo/1"}, foo)
+	})
+	assert.NoError(t, err)
+	assert.True(t, unlocked)
+
+	// Stale overrides fail without applying anything
+	unlocked = false
+	db.EXPECT().Lock(gomock.Any(), MigrationLockName, time.Minute).Return(unlock, true, nil)
+	instrumentation.CaptureInstrumentation(t, func() {
+		err = MigrateCogs(context.Background(), db, time.Minute, []string{"foo/2"}, foo)
+	})
+	assert.ErrorIs(t, err, ErrUnknownDriftOverride)
+	assert.True(t, unlocked)
+}
+
+func Test_MigrateCogs_lockTimeout(t *testing.T) {
+	foo := newTestRepo(t, "foo", "1_a.sql")
+
+	db := newMigrationsDB(t)
+	db.EXPECT().Lock(gomock.Any(), MigrationLockName, time.Minute).Return(nil, false, nil)
+
+	var err error
+	span := instrumentation.CaptureInstrumentation(t, func() {
+		err = MigrateCogs(context.Background(), db, time.Minute, nil, foo)
+	})
+	assert.ErrorIs(t, err, ErrMigrationLockTimeout)
+	assert.Equal(t, "false", span.Attributes.GetAsString("lock_acquired"))
+}
+
+func Test_MigrateCogs_concurrent(t *testing.T) {
+	ctx := context.Background()
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "test.db")
+	foo := testRepo{"foo", fstest.MapFS{
+		"1_a.sql": {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"2_b.sql": {Data: []byte("CREATE TABLE b (id INTEGER);")},
+	}}
+
+	// Both instances start at once; without the lock, one would fail to create the tables again
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		db, err := database.NewDBClient(ctx, dsn, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close(ctx)
+		go func() { errs <- MigrateCogs(ctx, db, 10*time.Second, nil, foo) }()
+	}
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+}
@@ -0,0 +1,8 @@
+-- The most recent run of each job across replicas, which replicas check so that each scheduled run
+-- happens once. Created with IF NOT EXISTS, as the scheduler used to create it on startup.
+CREATE TABLE IF NOT EXISTS "_scheduled_jobs" (
+    name        TEXT PRIMARY KEY,
+    last_run    TIMESTAMP NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    error       TEXT NOT NULL DEFAULT ''
+);
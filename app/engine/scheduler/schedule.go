@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+// Schedule describes when a job should next run.
+type Schedule interface {
+	// Next returns the next activation time, later than the given time.
+	Next(time.Time) time.Time
+}
+
+// interval implements Schedule for fixed intervals.
+type interval time.Duration
+
+// Every returns a Schedule that activates once per the given interval, which must be positive.
+// Jobs with a schedule that doesn't move forward are refused by Register.
+func Every(d time.Duration) Schedule {
+	return interval(d)
+}
+
+func (i interval) Next(from time.Time) time.Time {
+	return from.Add(time.Duration(i))
+}
+
+// Cron returns a Schedule for a standard 5-field cron expression, such as "0 4 * * *".
+// Descriptors like "@daily" and "@every 1h30m" are also accepted.
+func Cron(expr string) (Schedule, error) {
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	return sched, nil
+}
+
+// MustCron is like Cron, but panics if the expression cannot be parsed.
+func MustCron(expr string) Schedule {
+	sched, err := Cron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return sched
+}
@@ -0,0 +1,341 @@
+// package scheduler runs periodic jobs that cogs register during startup.
+package scheduler
+
+//go:generate mockgen -source=scheduler.go -destination=./scheduler_mock.go -package=scheduler
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+)
+
+const (
+	// Jobs without a timeout are cancelled after this long.
+	defaultTimeout = 5 * time.Minute
+
+	selectLastRun      = `SELECT last_run FROM _scheduled_jobs WHERE name = $1;`
+	upsertScheduledJob = `INSERT INTO _scheduled_jobs (name, last_run, duration_ms, error)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET
+			last_run = EXCLUDED.last_run,
+			duration_ms = EXCLUDED.duration_ms,
+			error = EXCLUDED.error;`
+)
+
+var (
+	//go:embed dbmigrations
+	embeddedMigrations embed.FS
+
+	ErrInvalidJob   = errors.New("invalid job")
+	ErrDuplicateJob = errors.New("duplicate job name")
+	errPanic        = errors.New("panic while executing job")
+)
+
+// JobFunc is the work done by a job on each run.
+type JobFunc func(ctx context.Context) error
+
+// Job is a unit of periodic work.
+type Job struct {
+	// Name identifies the job across replicas, so it should be unique, like "colours/cleanup".
+	Name     string
+	Schedule Schedule
+	// Timeout cancels the run context after this long. Defaults to 5 minutes.
+	Timeout time.Duration
+	// Jitter delays each run by a random duration up to this long.
+	Jitter time.Duration
+	Run    JobFunc
+}
+
+// Status reports the outcome of a job's most recent run on this replica.
+type Status struct {
+	Name         string
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastErr      error
+	// LastSkipped indicates that the last run was skipped, because another replica was running the
+	// job or had already run it for that activation.
+	LastSkipped bool
+	NextRun     time.Time
+}
+
+// IScheduler describes a service that runs jobs on their schedules.
+type IScheduler interface {
+	// Register adds jobs to the scheduler. Jobs registered after Start are started immediately.
+	Register(jobs ...*Job) error
+	// Start begins running registered jobs. The scheduler can be started again after Stop.
+	Start(ctx context.Context) error
+	// Stop stops running jobs, cancelling the context of any runs in progress and waiting for
+	// them to return.
+	Stop()
+	// Status reports on all registered jobs, sorted by name.
+	Status() []Status
+}
+
+// scheduler implements IScheduler.
+type scheduler struct {
+	db    database.IDatabase
+	clock func() time.Time
+
+	mutex   *sync.Mutex
+	jobs    map[string]*Job
+	status  map[string]*Status
+	started bool
+	// stopped is the parent of every run's context, and is cancelled by stop. Both are made anew by
+	// each Start.
+	stopped context.Context
+	stop    context.CancelFunc
+	running *sync.WaitGroup
+}
+
+func New(db database.IDatabase) IScheduler {
+	return newScheduler(db)
+}
+
+func newScheduler(db database.IDatabase) *scheduler {
+	return &scheduler{
+		db:      db,
+		clock:   time.Now,
+		mutex:   &sync.Mutex{},
+		jobs:    make(map[string]*Job),
+		status:  make(map[string]*Status),
+		running: &sync.WaitGroup{},
+	}
+}
+
+// Migrations owns the migration of the table where jobs record their runs, which runs with the
+// cogs' migrations before the scheduler starts.
+type Migrations struct{}
+
+func (Migrations) Name() string { return "scheduler" }
+
+func (Migrations) MigrationsFS() fs.FS {
+	// Sub only fails on invalid paths, and this one is fixed by the embed directive above
+	migrations, _ := fs.Sub(embeddedMigrations, "dbmigrations")
+	return migrations
+}
+
+func (s *scheduler) Register(jobs ...*Job) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, job := range jobs {
+		if job == nil || job.Name == "" || job.Schedule == nil || job.Run == nil {
+			return fmt.Errorf("%w: jobs need a name, schedule and func, got %+v", ErrInvalidJob, job)
+		}
+		// Schedules that don't move forward, like Every(0), would run the job in a busy loop
+		if now := s.clock(); !job.Schedule.Next(now).After(now) {
+			return fmt.Errorf("%w: %s would never wait between runs", ErrInvalidSchedule, job.Name)
+		}
+		if _, ok := s.jobs[job.Name]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateJob, job.Name)
+		}
+		s.jobs[job.Name] = job
+		s.status[job.Name] = &Status{Name: job.Name}
+		if s.started {
+			s.spawn(job)
+		}
+	}
+	return nil
+}
+
+func (s *scheduler) Start(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.started {
+		return nil
+	}
+	s.started = true
+	s.stopped, s.stop = context.WithCancel(context.Background())
+	for _, job := range s.jobs {
+		s.spawn(job)
+	}
+	log.Infof(ctx, "Scheduler started (jobs: %d)", len(s.jobs))
+	return nil
+}
+
+func (s *scheduler) Stop() {
+	s.mutex.Lock()
+	if !s.started {
+		s.mutex.Unlock()
+		return
+	}
+	s.started = false
+	s.stop()
+	s.mutex.Unlock()
+
+	s.running.Wait()
+}
+
+func (s *scheduler) Status() []Status {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	out := make([]Status, 0, len(s.status))
+	for _, status := range s.status {
+		out = append(out, *status)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// spawn runs the job in a loop until the scheduler stops. Caller must hold the mutex.
+func (s *scheduler) spawn(job *Job) {
+	s.running.Add(1)
+	go func(stopped context.Context) {
+		defer s.running.Done()
+		for {
+			slot, next := s.nextRun(job)
+			s.patchStatus(job.Name, func(st *Status) { st.NextRun = next })
+
+			timer := time.NewTimer(next.Sub(s.clock()))
+			select {
+			case <-stopped.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				s.runJob(stopped, job, slot)
+			}
+		}
+	}(s.stopped)
+}
+
+// nextRun returns the job's next activation on its schedule, and when to run it after jitter.
+func (s *scheduler) nextRun(job *Job) (slot, next time.Time) {
+	slot = job.Schedule.Next(s.clock())
+	next = slot
+	if job.Jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(job.Jitter))))
+	}
+	return slot, next
+}
+
+func (s *scheduler) patchStatus(name string, patch func(*Status)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if st, ok := s.status[name]; ok {
+		patch(st)
+	}
+}
+
+// runJob executes the job's run for the activation at slot, unless another replica is running the
+// job or has already run it for that activation. The run is cancelled with ctx, or when the job's
+// timeout passes.
+func (s *scheduler) runJob(ctx context.Context, job *Job, slot time.Time) {
+	startTime := s.clock()
+	traceID := fmt.Sprintf("%s-%d", job.Name, startTime.UTC().UnixMilli())
+	ctx = log.Put(ctx, log.TraceID, traceID)
+	ctx = log.Put(ctx, log.JobName, job.Name)
+
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Job(job.Name))
+	span.SetAttributes(
+		instrumentation.KV.JobName(job.Name),
+		instrumentation.KV.TraceID(traceID),
+	)
+	defer span.End()
+
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	failed := func(msg string, err error) {
+		log.Errorf(ctx, err, msg)
+		span.RecordError(err)
+		s.patchStatus(job.Name, func(st *Status) {
+			st.LastRun, st.LastDuration, st.LastErr, st.LastSkipped = startTime, 0, err, false
+		})
+	}
+	skipped := func(reason string) {
+		log.Infof(ctx, "Job skipped, %s", reason)
+		span.SetAttributes(instrumentation.KV.JobSkipped(true))
+		s.patchStatus(job.Name, func(st *Status) { st.LastSkipped = true })
+	}
+
+	unlock, acquired, err := s.db.Lock(ctx, lockName(job), 0)
+	if err != nil {
+		failed("Failed to acquire lock for job", err)
+		return
+	}
+	if !acquired {
+		skipped("another replica is running it")
+		return
+	}
+	defer func() {
+		if err := unlock(context.Background()); err != nil {
+			log.Errorf(ctx, err, "Failed to release lock for job")
+		}
+	}()
+
+	// Replicas don't share their schedules, so one may take the lock after another has finished the
+	// same activation. A run that started in this activation's period counts for it.
+	lastRun, err := database.QueryOne[time.Time](ctx, s.db, selectLastRun, job.Name)
+	switch {
+	case errors.Is(err, database.ErrNoRecords):
+	case err != nil:
+		failed("Failed to read the last run of job", err)
+		return
+	case job.Schedule.Next(lastRun).After(slot):
+		skipped(fmt.Sprintf("it already ran at %s", lastRun.Format(time.RFC3339)))
+		return
+	}
+
+	log.Infof(ctx, "Job starting")
+	err = mustRunJob(ctx, job)
+	elapsed := s.clock().Sub(startTime)
+	if err != nil {
+		log.Errorf(ctx, err, "Job errored in %d millisecs", elapsed.Milliseconds())
+		span.RecordError(err)
+	} else {
+		log.Infof(ctx, "Job completed in %d millisecs", elapsed.Milliseconds())
+	}
+
+	s.patchStatus(job.Name, func(st *Status) {
+		st.LastRun, st.LastDuration, st.LastErr, st.LastSkipped = startTime, elapsed, err, false
+	})
+	s.persistStatus(ctx, job, startTime, elapsed, err)
+}
+
+// persistStatus records the run in the database, so it can be inspected from any replica.
+func (s *scheduler) persistStatus(ctx context.Context, job *Job, startTime time.Time, elapsed time.Duration, runErr error) {
+	errMsg := ""
+	if runErr != nil {
+		errMsg = runErr.Error()
+	}
+	// The run context may have timed out, but the outcome is still worth recording
+	ctx = context.WithoutCancel(ctx)
+	// TIMESTAMP columns drop the zone, so runs are recorded in UTC to be compared across replicas
+	if _, err := s.db.Exec(ctx, upsertScheduledJob, job.Name, startTime.UTC(), elapsed.Milliseconds(), errMsg); err != nil {
+		log.Errorf(ctx, err, "Failed to record job status")
+	}
+}
+
+// mustRunJob executes a job, trapping and logging any panics.
+func mustRunJob(ctx context.Context, job *Job) (returnErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			instrumentation.EmitErrorf(ctx, "job %s panic: %v", job.Name, r)
+			returnErr = fmt.Errorf("%w: %v", errPanic, r)
+			log.Stack(ctx, returnErr)
+		}
+	}()
+
+	returnErr = job.Run(ctx)
+	return
+}
+
+func lockName(job *Job) string {
+	return "arisa3/job/" + job.Name
+}
@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: scheduler.go
+
+// Package scheduler is a generated GoMock package.
+package scheduler
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIScheduler is a mock of IScheduler interface.
+type MockIScheduler struct {
+	ctrl     *gomock.Controller
+	recorder *MockISchedulerMockRecorder
+}
+
+// MockISchedulerMockRecorder is the mock recorder for MockIScheduler.
+type MockISchedulerMockRecorder struct {
+	mock *MockIScheduler
+}
+
+// NewMockIScheduler creates a new mock instance.
+func NewMockIScheduler(ctrl *gomock.Controller) *MockIScheduler {
+	mock := &MockIScheduler{ctrl: ctrl}
+	mock.recorder = &MockISchedulerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIScheduler) EXPECT() *MockISchedulerMockRecorder {
+	return m.recorder
+}
+
+// Register mocks base method.
+func (m *MockIScheduler) Register(jobs ...*Job) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range jobs {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Register", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockISchedulerMockRecorder) Register(jobs ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockIScheduler)(nil).Register), jobs...)
+}
+
+// Start mocks base method.
+func (m *MockIScheduler) Start(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockISchedulerMockRecorder) Start(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockIScheduler)(nil).Start), ctx)
+}
+
+// Status mocks base method.
+func (m *MockIScheduler) Status() []Status {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status")
+	ret0, _ := ret[0].([]Status)
+	return ret0
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockISchedulerMockRecorder) Status() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockIScheduler)(nil).Status))
+}
+
+// Stop mocks base method.
+func (m *MockIScheduler) Stop() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Stop")
+}
+
+// Stop indicates an expected call of Stop.
+func (mr *MockISchedulerMockRecorder) Stop() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockIScheduler)(nil).Stop))
+}
@@ -0,0 +1,316 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var Any = gomock.Any()
+
+func newTestJob(run JobFunc) *Job {
+	return &Job{
+		Name:     "test/job",
+		Schedule: Every(time.Hour),
+		Run:      run,
+	}
+}
+
+func expectLock(db *database.MockIDatabase, acquired bool) (unlocked *bool) {
+	unlocked = new(bool)
+	unlock := database.UnlockFunc(func(context.Context) error { *unlocked = true; return nil })
+	if !acquired {
+		unlock = nil
+	}
+	db.EXPECT().Lock(Any, "arisa3/job/test/job", time.Duration(0)).Return(unlock, acquired, nil)
+	return unlocked
+}
+
+// expectLastRun expects the job's last run to be read, and returns the given time as the last run,
+// or no rows if it is zero.
+func expectLastRun(t *testing.T, db *database.MockIDatabase, lastRun time.Time) *gomock.Call {
+	t.Helper()
+	sqlDB, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	rows := sqlmock.NewRows([]string{"last_run"})
+	if !lastRun.IsZero() {
+		rows.AddRow(lastRun)
+	}
+	sqlMock.ExpectQuery("").WillReturnRows(rows)
+	return db.EXPECT().Query(Any, selectLastRun, "test/job").DoAndReturn(
+		func(context.Context, string, ...interface{}) (database.IRows, error) {
+			return sqlDB.Query("")
+		},
+	)
+}
+
+func Test_Every(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, now.Add(90*time.Minute), Every(90*time.Minute).Next(now))
+}
+
+func Test_Cron(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	sched, err := Cron("0 4 * * *")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 1, 2, 4, 0, 0, 0, time.UTC), sched.Next(now))
+
+	_, err = Cron("not a cron expression")
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+}
+
+func Test_Register(t *testing.T) {
+	s := newScheduler(nil)
+
+	assert.NoError(t, s.Register(newTestJob(func(context.Context) error { return nil })))
+	assert.ErrorIs(t, s.Register(newTestJob(func(context.Context) error { return nil })), ErrDuplicateJob)
+	assert.ErrorIs(t, s.Register(&Job{Name: "no schedule"}), ErrInvalidJob)
+	for _, d := range []time.Duration{0, -time.Minute} {
+		job := &Job{Name: "busy", Schedule: Every(d), Run: func(context.Context) error { return nil }}
+		assert.ErrorIs(t, s.Register(job), ErrInvalidSchedule)
+	}
+
+	status := s.Status()
+	assert.Len(t, status, 1)
+	assert.Equal(t, "test/job", status[0].Name)
+}
+
+func Test_runJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := database.NewMockIDatabase(ctrl)
+	s := newScheduler(db)
+
+	someErr := errors.New("job failed")
+	job := newTestJob(func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		assert.Equal(t, "test/job", log.Get(ctx, log.JobName))
+		return someErr
+	})
+	assert.NoError(t, s.Register(job))
+
+	unlocked := expectLock(db, true)
+	expectLastRun(t, db, time.Time{})
+	db.EXPECT().Exec(Any, upsertScheduledJob, "test/job", Any, Any, someErr.Error()).Return(nil, nil)
+
+	s.runJob(context.Background(), job, time.Now())
+
+	status := s.Status()[0]
+	assert.ErrorIs(t, status.LastErr, someErr)
+	assert.False(t, status.LastRun.IsZero())
+	assert.False(t, status.LastSkipped)
+	assert.True(t, *unlocked)
+}
+
+func Test_runJob_lockHeldElsewhere(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := database.NewMockIDatabase(ctrl)
+	s := newScheduler(db)
+
+	ran := false
+	job := newTestJob(func(ctx context.Context) error { ran = true; return nil })
+	assert.NoError(t, s.Register(job))
+
+	expectLock(db, false)
+	s.runJob(context.Background(), job, time.Now())
+
+	assert.False(t, ran)
+	assert.True(t, s.Status()[0].LastSkipped)
+}
+
+func Test_runJob_alreadyRan(t *testing.T) {
+	slot := time.Date(2023, 1, 2, 4, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		desc     string
+		lastRun  time.Time
+		wantRun  bool
+		schedule Schedule
+	}{
+		{"never ran", time.Time{}, true, MustCron("0 4 * * *")},
+		{"ran for the previous slot", slot.Add(-24 * time.Hour), true, MustCron("0 4 * * *")},
+		{"ran for this slot elsewhere", slot.Add(3 * time.Second), false, MustCron("0 4 * * *")},
+		{"ran just before this slot", slot.Add(-time.Second), true, MustCron("0 4 * * *")},
+		{"interval ran within the period", slot.Add(-20 * time.Minute), false, Every(time.Hour)},
+		{"interval ran a period ago", slot.Add(-time.Hour), true, Every(time.Hour)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			db := database.NewMockIDatabase(ctrl)
+			s := newScheduler(db)
+
+			ran := false
+			job := newTestJob(func(ctx context.Context) error { ran = true; return nil })
+			job.Schedule = tc.schedule
+			assert.NoError(t, s.Register(job))
+
+			unlocked := expectLock(db, true)
+			expectLastRun(t, db, tc.lastRun)
+			if tc.wantRun {
+				db.EXPECT().Exec(Any, upsertScheduledJob, "test/job", Any, Any, "").Return(nil, nil)
+			}
+
+			s.runJob(context.Background(), job, slot)
+			assert.Equal(t, tc.wantRun, ran)
+			assert.Equal(t, !tc.wantRun, s.Status()[0].LastSkipped)
+			assert.True(t, *unlocked)
+		})
+	}
+}
+
+func Test_runJob_lastRunUnreadable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := database.NewMockIDatabase(ctrl)
+	s := newScheduler(db)
+
+	ran := false
+	job := newTestJob(func(ctx context.Context) error { ran = true; return nil })
+	assert.NoError(t, s.Register(job))
+
+	unlocked := expectLock(db, true)
+	db.EXPECT().Query(Any, selectLastRun, "test/job").Return(nil, assert.AnError)
+
+	s.runJob(context.Background(), job, time.Now())
+	assert.False(t, ran)
+	assert.ErrorIs(t, s.Status()[0].LastErr, assert.AnError)
+	assert.True(t, *unlocked)
+}
+
+func Test_runJob_panic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := database.NewMockIDatabase(ctrl)
+	s := newScheduler(db)
+
+	job := newTestJob(func(ctx context.Context) error { panic("testing 123") })
+	assert.NoError(t, s.Register(job))
+
+	unlocked := expectLock(db, true)
+	expectLastRun(t, db, time.Time{})
+	db.EXPECT().Exec(Any, upsertScheduledJob, "test/job", Any, Any, Any).Return(nil, nil)
+
+	msg := log.CaptureLogging(t, func() {
+		s.runJob(context.Background(), job, time.Now())
+	})
+	assert.Contains(t, msg, "testing 123")
+	assert.ErrorIs(t, s.Status()[0].LastErr, errPanic)
+	assert.True(t, *unlocked)
+}
+
+func Test_StartStop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := database.NewMockIDatabase(ctrl)
+	s := newScheduler(db)
+
+	ran := make(chan struct{}, 1)
+	job := &Job{
+		Name:     "test/job",
+		Schedule: Every(time.Millisecond),
+		Run: func(ctx context.Context) error {
+			select {
+			case ran <- struct{}{}:
+			default:
+			}
+			return nil
+		},
+	}
+	db.EXPECT().Lock(Any, Any, Any).AnyTimes().Return(database.UnlockFunc(func(context.Context) error { return nil }), true, nil)
+	db.EXPECT().Query(Any, selectLastRun, Any).AnyTimes().Return(nil, database.ErrNoRecords)
+	db.EXPECT().Exec(Any, upsertScheduledJob, Any, Any, Any, Any).AnyTimes().Return(nil, nil)
+
+	assert.NoError(t, s.Start(context.Background()))
+	assert.NoError(t, s.Register(job))
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("job did not run")
+	}
+	s.Stop()
+}
+
+func Test_Stop_cancelsRuns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := database.NewMockIDatabase(ctrl)
+	s := newScheduler(db)
+
+	started := make(chan struct{})
+	job := &Job{
+		Name:     "test/job",
+		Schedule: Every(time.Millisecond),
+		Timeout:  time.Hour,
+		Run: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	db.EXPECT().Lock(Any, Any, Any).Return(database.UnlockFunc(func(context.Context) error { return nil }), true, nil)
+	expectLastRun(t, db, time.Time{})
+	db.EXPECT().Exec(Any, upsertScheduledJob, "test/job", Any, Any, context.Canceled.Error()).Return(nil, nil)
+
+	assert.NoError(t, s.Register(job))
+	assert.NoError(t, s.Start(context.Background()))
+	<-started
+
+	// Stop returns once the run in progress has been cancelled, rather than after its timeout
+	stopped := make(chan struct{})
+	go func() { s.Stop(); close(stopped) }()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not cancel the running job")
+	}
+	assert.ErrorIs(t, s.Status()[0].LastErr, context.Canceled)
+}
+
+func Test_Start_afterStop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := database.NewMockIDatabase(ctrl)
+	s := newScheduler(db)
+
+	ran := make(chan struct{}, 1)
+	job := &Job{
+		Name:     "test/job",
+		Schedule: Every(time.Millisecond),
+		Run: func(ctx context.Context) error {
+			select {
+			case ran <- struct{}{}:
+			default:
+			}
+			return ctx.Err()
+		},
+	}
+	db.EXPECT().Lock(Any, Any, Any).AnyTimes().Return(database.UnlockFunc(func(context.Context) error { return nil }), true, nil)
+	db.EXPECT().Query(Any, selectLastRun, Any).AnyTimes().Return(nil, database.ErrNoRecords)
+	db.EXPECT().Exec(Any, upsertScheduledJob, Any, Any, Any, Any).AnyTimes().Return(nil, nil)
+	assert.NoError(t, s.Register(job))
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, s.Start(context.Background()))
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatalf("job did not run after start #%d", i+1)
+		}
+		s.Stop()
+		// Drain a run that squeezed in before Stop
+		select {
+		case <-ran:
+		default:
+		}
+	}
+	// Runs after a restart aren't cancelled by the earlier Stop
+	assert.NoError(t, s.Status()[0].LastErr)
+}
@@ -4,10 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"io/fs"
 
-	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/engine/scheduler"
 	"github.com/fiffu/arisa3/app/instrumentation"
 	"github.com/fiffu/arisa3/app/log"
 	"github.com/fiffu/arisa3/app/types"
@@ -33,9 +32,17 @@ type IBootable interface {
 	ReadyCallback(ctx context.Context, s *dgo.Session, r *dgo.Ready) error
 }
 
+// IRepository describes a cog that owns database migrations, which MigrateCogs() runs for all cogs
+// before any of them boot. The migrations are read from the root of MigrationsFS(), which is
+// usually embedded into the binary so that it runs without the source tree.
 type IRepository interface {
 	Name() string
-	MigrationsDir() string
+	MigrationsFS() fs.FS
+}
+
+// IScheduled describes a cog that runs periodic jobs, which Bootstrap() registers with the app's scheduler.
+type IScheduled interface {
+	Jobs() []*scheduler.Job
 }
 
 // StartupContext creates a runtime context for the app startup sequence.
@@ -84,24 +91,13 @@ func Bootstrap(ctx context.Context, app types.IApp, rawConfig types.CogConfig, c
 		return bootError(err)
 	}
 
-	// Setup repo migrations
-	if rcog, ok := c.(IRepository); ok {
-		db := app.Database()
-		log.Infof(ctx, "Migrations starting")
-		if err := runMigrations(ctx, rcog, db); err != nil {
-			log.Errorf(ctx, err, "Migrations starting")
-			log.Stack(ctx, err)
-			if closeErr := db.Close(ctx); closeErr != nil {
-				return bootError(fmt.Errorf(
-					"failed to close DB connection (%v) during teardown due to "+
-						"migration error (%v)",
-					closeErr, err,
-				))
-			}
+	// Register periodic jobs
+	if scog, ok := c.(IScheduled); ok {
+		jobs := scog.Jobs()
+		if err := app.Scheduler().Register(jobs...); err != nil {
 			return bootError(err)
 		}
-	} else {
-		log.Infof(ctx, "Migrations skipped (no migration interface found)")
+		log.Infof(ctx, "Registered %d scheduled jobs", len(jobs))
 	}
 
 	// Bind ready callback after boot sequence is ready
@@ -114,32 +110,6 @@ func Bootstrap(ctx context.Context, app types.IApp, rawConfig types.CogConfig, c
 	return nil
 }
 
-func runMigrations(ctx context.Context, cog IRepository, db database.IDatabase) error {
-	dir := cog.MigrationsDir()
-	files, err := ioutil.ReadDir(dir)
-	log.Infof(ctx, "Migrations found (count: %d) at: %s", len(files), dir)
-	if err != nil {
-		return err
-	}
-
-	migratedCount := 0
-	for _, file := range files {
-		path := filepath.Join(dir, file.Name())
-		schema, err := db.ParseMigration(ctx, path)
-		if err != nil {
-			return err
-		}
-		executed, err := db.Migrate(ctx, schema)
-		if err != nil {
-			return err
-		} else if executed {
-			migratedCount += 1
-		}
-	}
-	log.Infof(ctx, "Migrations complete (total executed: %d)", migratedCount)
-	return nil
-}
-
 // EnvKeyPrefix derives a prefix for environment keys from an IBootable cog.
 func EnvKeyPrefix(cog IBootable) string {
 	return fmt.Sprintf("ARISA3_%sCOG_", cog.Name())
@@ -2,6 +2,7 @@ package instrumentation
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/fiffu/arisa3/app/log"
 	"go.opentelemetry.io/otel/attribute"
@@ -14,6 +15,8 @@ const (
 	attrUser               = string(log.User)
 	attrCommandName        = "command_name"
 	attrEventName          = "event_name"
+	attrJobName            = "job_name"
+	attrJobSkipped         = "job_skipped"
 	attrError              = "error"
 	attrParams             = "params"
 	attrHTTPHost           = "http_host"
@@ -23,6 +26,12 @@ const (
 	attrHTTPContentLength  = "http_total_content_length"
 	attrDBQuery            = "db_query"
 	attrDBOperation        = "db_operation"
+	attrLockWaitMs         = "lock_wait_ms"
+	attrLockAcquired       = "lock_acquired"
+	attrPartitions         = "partitions"
+	attrPartitionsCreated  = "partitions_created"
+	attrPartitionsArchived = "partitions_archived"
+	attrCache              = "cache"
 )
 
 type attrs struct{}
@@ -41,6 +50,14 @@ func (attrs) EventName(value string) attribute.KeyValue {
 	return attribute.String(attrEventName, value)
 }
 
+func (attrs) JobName(value string) attribute.KeyValue {
+	return attribute.String(attrJobName, value)
+}
+
+func (attrs) JobSkipped(value bool) attribute.KeyValue {
+	return attribute.Bool(attrJobSkipped, value)
+}
+
 func (attrs) Error(err error) attribute.KeyValue {
 	return attribute.String(attrError, err.Error())
 }
@@ -87,3 +104,27 @@ func (attrs) DBQuery(sql string) attribute.KeyValue {
 func (attrs) DBOperation(op string) attribute.KeyValue {
 	return attribute.String(attrDBOperation, op)
 }
+
+func (attrs) LockWait(wait time.Duration) attribute.KeyValue {
+	return attribute.Int64(attrLockWaitMs, wait.Milliseconds())
+}
+
+func (attrs) LockAcquired(value bool) attribute.KeyValue {
+	return attribute.Bool(attrLockAcquired, value)
+}
+
+func (attrs) Partitions(names []string) attribute.KeyValue {
+	return attribute.StringSlice(attrPartitions, names)
+}
+
+func (attrs) PartitionsCreated(count int) attribute.KeyValue {
+	return attribute.Int(attrPartitionsCreated, count)
+}
+
+func (attrs) PartitionsArchived(count int) attribute.KeyValue {
+	return attribute.Int(attrPartitionsArchived, count)
+}
+
+func (attrs) Cache(name string) attribute.KeyValue {
+	return attribute.String(attrCache, name)
+}
@@ -0,0 +1,59 @@
+package instrumentation
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fiffu/arisa3/app/log"
+	"github.com/fiffu/arisa3/lib"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ObserveCache reports the stats of a cache as metrics, labelled with the cache's name. stats is
+// called on each collection, so it may sum the stats of several caches.
+func ObserveCache(name string, stats func() lib.CacheStats) {
+	meter := Meter("arisa3/cache")
+	hits, errHits := meter.Int64ObservableCounter(
+		"cache.hits",
+		metric.WithDescription("Lookups that found a value in the cache"),
+	)
+	misses, errMisses := meter.Int64ObservableCounter(
+		"cache.misses",
+		metric.WithDescription("Lookups that found no value, or an expired one, in the cache"),
+	)
+	evictions, errEvictions := meter.Int64ObservableCounter(
+		"cache.evictions",
+		metric.WithDescription("Values removed to keep the cache within its size bound"),
+	)
+	expirations, errExpirations := meter.Int64ObservableCounter(
+		"cache.expirations",
+		metric.WithDescription("Values removed from the cache after they expired"),
+	)
+	storeErrors, errStoreErrors := meter.Int64ObservableCounter(
+		"cache.errors",
+		metric.WithDescription("Failed calls to the store that the cache keeps its values in"),
+	)
+	size, errSize := meter.Int64ObservableGauge(
+		"cache.size",
+		metric.WithDescription("Number of values in the cache"),
+	)
+	if err := errors.Join(errHits, errMisses, errEvictions, errExpirations, errStoreErrors, errSize); err != nil {
+		log.Errorf(context.Background(), err, "Failed to create metrics for cache %s", name)
+		return
+	}
+
+	attrs := metric.WithAttributes(KV.Cache(name))
+	_, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		s := stats()
+		o.ObserveInt64(hits, s.Hits, attrs)
+		o.ObserveInt64(misses, s.Misses, attrs)
+		o.ObserveInt64(evictions, s.Evictions, attrs)
+		o.ObserveInt64(expirations, s.Expirations, attrs)
+		o.ObserveInt64(storeErrors, s.Errors, attrs)
+		o.ObserveInt64(size, int64(s.Size), attrs)
+		return nil
+	}, hits, misses, evictions, expirations, storeErrors, size)
+	if err != nil {
+		log.Errorf(context.Background(), err, "Failed to register metrics for cache %s", name)
+	}
+}
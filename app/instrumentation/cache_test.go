@@ -0,0 +1,48 @@
+package instrumentation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fiffu/arisa3/lib"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func Test_ObserveCache(t *testing.T) {
+	oldProvider := otel.GetMeterProvider()
+	defer otel.SetMeterProvider(oldProvider)
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	ObserveCache("animals", func() lib.CacheStats {
+		return lib.CacheStats{Hits: 3, Misses: 2, Evictions: 1, Errors: 5, Size: 4}
+	})
+
+	var collected metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &collected))
+
+	values := make(map[string]int64)
+	for _, scope := range collected.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				values[m.Name] = data.DataPoints[0].Value
+				name, _ := data.DataPoints[0].Attributes.Value(attrCache)
+				assert.Equal(t, "animals", name.AsString())
+			case metricdata.Gauge[int64]:
+				values[m.Name] = data.DataPoints[0].Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{
+		"cache.hits":        3,
+		"cache.misses":      2,
+		"cache.evictions":   1,
+		"cache.expirations": 0,
+		"cache.errors":      5,
+		"cache.size":        4,
+	}, values)
+}
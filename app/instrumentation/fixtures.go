@@ -2,6 +2,7 @@ package instrumentation
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	"go.opentelemetry.io/otel"
@@ -57,8 +58,11 @@ func newTestSpan(t *testing.T) *testSpan {
 	}
 }
 
+// testSpan records everything done to it. Spans from concurrent goroutines share one testSpan,
+// so its methods are guarded by a mutex.
 type testSpan struct {
 	*testing.T
+	mutex       sync.Mutex
 	Name        string
 	Ended       bool
 	Code        codes.Code
@@ -83,6 +87,8 @@ func (ad AttrDict) GetAsString(attrKey string) string {
 // is called. Therefore, updates to the Span are not allowed after this
 // method has been called.
 func (ts *testSpan) End(options ...trace.SpanEndOption) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
 	ts.Ended = true
 }
 
@@ -91,12 +97,18 @@ func (ts *testSpan) AddEvent(name string, options ...trace.EventOption) {
 	if !ts.IsRecording() {
 		ts.T.Fatalf("AddEvent() not allowed after Span.End() has been called")
 	}
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
 	ts.Events = append(ts.Events, name)
 }
 
 // IsRecording returns the recording state of the Span. It will return
 // true if the Span is active and events can be recorded.
-func (ts *testSpan) IsRecording() bool { return !ts.Ended }
+func (ts *testSpan) IsRecording() bool {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	return !ts.Ended
+}
 
 // RecordError will record err as an exception span event for this span. An
 // additional call to SetStatus is required if the Status of the Span should
@@ -117,17 +129,25 @@ func (ts *testSpan) SpanContext() trace.SpanContext { return trace.SpanContext{}
 // value before (OK > Error > Unset). The description is only included in a
 // status when the code is for an error.
 func (ts *testSpan) SetStatus(code codes.Code, description string) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
 	ts.Code = code
 	ts.Description = description
 }
 
 // SetName sets the Span name.
-func (ts *testSpan) SetName(name string) { ts.Name = name }
+func (ts *testSpan) SetName(name string) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+	ts.Name = name
+}
 
 // SetAttributes sets kv as attributes of the Span. If a key from kv
 // already exists for an attribute of the Span it will be overwritten with
 // the value contained in kv.
 func (ts *testSpan) SetAttributes(kv ...attribute.KeyValue) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
 	for _, attr := range kv {
 		ts.T.Logf("SetAttributes: %s => %s", attr.Key, attr.Value.Emit())
 		ts.Attributes[string(attr.Key)] = attr
@@ -11,6 +11,7 @@ import (
 	honeycomb "github.com/honeycombio/honeycomb-opentelemetry-go"
 	"github.com/honeycombio/otel-config-go/otelconfig"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -59,6 +60,12 @@ func SpanInContext(ctx context.Context, sn ScopedName) (context.Context, trace.S
 	return ctx, span
 }
 
+// Meter returns a meter for instruments in the given scope, such as "arisa3/colours". The OTel SDK
+// exports their metrics alongside traces.
+func Meter(scope string) metric.Meter {
+	return otel.GetMeterProvider().Meter(scope)
+}
+
 func EmitErrorf(ctx context.Context, msg string, args ...any) {
 	span := trace.SpanFromContext(ctx)
 	span.RecordError(fmt.Errorf(msg, args...), WithStackTrace())
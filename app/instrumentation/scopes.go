@@ -14,6 +14,7 @@ const (
 	internalScope     supportedScope = "arisa3/internal"
 	commandScope      supportedScope = "arisa3/command"
 	eventScope        supportedScope = "arisa3/event"
+	jobScope          supportedScope = "arisa3/job"
 	databaseScope     supportedScope = "database"
 	externalHTTPScope supportedScope = "external-http"
 	vendorScope       supportedScope = "vendor"
@@ -41,6 +42,11 @@ type event string
 func (sn event) scope() supportedScope { return eventScope }
 func (sn event) name() string          { return string(sn) }
 
+type Job string
+
+func (sn Job) scope() supportedScope { return jobScope }
+func (sn Job) name() string          { return fmt.Sprintf("Job: %s", sn) }
+
 type Database string
 
 func (sn Database) scope() supportedScope { return databaseScope }
@@ -30,6 +30,7 @@ const (
 	User       CtxKey = "user"
 	Guild      CtxKey = "guild"
 	CogName    CtxKey = "cog"
+	JobName    CtxKey = "job"
 )
 
 var DoNotLogCtxKeys = []CtxKey{}
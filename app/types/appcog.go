@@ -9,12 +9,18 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/engine/scheduler"
+	"github.com/fiffu/arisa3/lib"
 )
 
 type IApp interface {
 	Configs() map[string]interface{}
 	Database() database.IDatabase
 	BotSession() *discordgo.Session
+	Scheduler() scheduler.IScheduler
+	// CacheStore is the networked store that caches can share across replicas, or nil if there
+	// is none configured.
+	CacheStore() lib.ICacheStore
 	Shutdown(context.Context)
 }
 
@@ -10,6 +10,8 @@ import (
 
 	discordgo "github.com/bwmarrin/discordgo"
 	database "github.com/fiffu/arisa3/app/database"
+	scheduler "github.com/fiffu/arisa3/app/engine/scheduler"
+	lib "github.com/fiffu/arisa3/lib"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -50,6 +52,20 @@ func (mr *MockIAppMockRecorder) BotSession() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BotSession", reflect.TypeOf((*MockIApp)(nil).BotSession))
 }
 
+// CacheStore mocks base method.
+func (m *MockIApp) CacheStore() lib.ICacheStore {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CacheStore")
+	ret0, _ := ret[0].(lib.ICacheStore)
+	return ret0
+}
+
+// CacheStore indicates an expected call of CacheStore.
+func (mr *MockIAppMockRecorder) CacheStore() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CacheStore", reflect.TypeOf((*MockIApp)(nil).CacheStore))
+}
+
 // Configs mocks base method.
 func (m *MockIApp) Configs() map[string]interface{} {
 	m.ctrl.T.Helper()
@@ -78,6 +94,20 @@ func (mr *MockIAppMockRecorder) Database() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Database", reflect.TypeOf((*MockIApp)(nil).Database))
 }
 
+// Scheduler mocks base method.
+func (m *MockIApp) Scheduler() scheduler.IScheduler {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Scheduler")
+	ret0, _ := ret[0].(scheduler.IScheduler)
+	return ret0
+}
+
+// Scheduler indicates an expected call of Scheduler.
+func (mr *MockIAppMockRecorder) Scheduler() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scheduler", reflect.TypeOf((*MockIApp)(nil).Scheduler))
+}
+
 // Shutdown mocks base method.
 func (m *MockIApp) Shutdown(arg0 context.Context) {
 	m.ctrl.T.Helper()
@@ -27,6 +27,7 @@ type ICommand interface {
 	Handler(hdlr CommandHandler) *Command
 	HandlerFunc() CommandHandler
 	FindOption(string) (IOption, bool)
+	FindSubCommand(string) (ICommand, bool)
 }
 
 type CommandHandler func(context.Context, ICommandEvent) error
@@ -35,6 +36,7 @@ type Command struct {
 	name    string
 	data    *dgo.ApplicationCommand
 	opts    map[string]IOption
+	subs    map[string]*Command
 	handler CommandHandler
 }
 
@@ -45,6 +47,7 @@ func NewCommand(name string) *Command {
 		name: name,
 		data: &data,
 		opts: opts,
+		subs: make(map[string]*Command),
 	}
 	cmd.mustValidate()
 	return cmd
@@ -99,3 +102,26 @@ func (c *Command) FindOption(name string) (opt IOption, ok bool) {
 	opt, ok = c.opts[name]
 	return
 }
+
+// SubCommands groups commands under this one, to be invoked like /colours gallery. Each subcommand
+// has its own description, options and handler, and this command is only invoked through them.
+func (c *Command) SubCommands(subs ...*Command) *Command {
+	for _, sub := range subs {
+		c.subs[sub.name] = sub
+		c.data.Options = append(c.data.Options, &dgo.ApplicationCommandOption{
+			Type:        dgo.ApplicationCommandOptionSubCommand,
+			Name:        sub.name,
+			Description: sub.data.Description,
+			Options:     sub.data.Options,
+		})
+	}
+	return c
+}
+
+func (c *Command) FindSubCommand(name string) (sub ICommand, ok bool) {
+	found, ok := c.subs[name]
+	if !ok {
+		return nil, false
+	}
+	return found, true
+}
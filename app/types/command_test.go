@@ -22,6 +22,32 @@ func Test_Options_FindOptions(t *testing.T) {
 	assert.Equal(t, "test-opt", opt.Name())
 }
 
+func Test_SubCommands(t *testing.T) {
+	cmd := NewCommand("parent").ForChat().Desc("Parent").SubCommands(
+		NewCommand("child").Desc("Child").Options(NewOption("child-opt").String()),
+	)
+
+	sub, ok := cmd.FindSubCommand("child")
+	if assert.True(t, ok) {
+		assert.Equal(t, "child", sub.Name())
+		_, ok = sub.FindOption("child-opt")
+		assert.True(t, ok)
+	}
+	_, ok = cmd.FindSubCommand("other")
+	assert.False(t, ok)
+
+	assert.Equal(t, []*dgo.ApplicationCommandOption{{
+		Type:        dgo.ApplicationCommandOptionSubCommand,
+		Name:        "child",
+		Description: "Child",
+		Options: []*dgo.ApplicationCommandOption{{
+			Type:        dgo.ApplicationCommandOptionString,
+			Name:        "child-opt",
+			Description: "(no description)",
+		}},
+	}}, cmd.Data().Options)
+}
+
 func Test_Data(t *testing.T) {
 	testCases := []struct {
 		desc   string
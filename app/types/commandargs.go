@@ -56,7 +56,16 @@ func (a *args) Role(key string) (*dgo.Role, bool) {
 	v, ok := a.fetch(key).(*dgo.Role)
 	return v, ok
 }
+
+// User returns the user given for the option. Interactions give users by ID, so only the ID is
+// set, unless a *dgo.User was given as the option's default.
 func (a *args) User(key string) (*dgo.User, bool) {
-	v, ok := a.fetch(key).(*dgo.User)
-	return v, ok
+	switch v := a.fetch(key).(type) {
+	case *dgo.User:
+		return v, true
+	case string:
+		return &dgo.User{ID: v}, v != ""
+	default:
+		return nil, false
+	}
 }
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"testing"
+
+	dgo "github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_args_User(t *testing.T) {
+	given := NewOption("given").User()
+	fallback := NewOption("fallback").User().Default(&dgo.User{ID: "2"})
+	unset := NewOption("unset").User()
+	cmd := NewCommand("cmd").Options(given, fallback, unset)
+
+	args := NewArgs(cmd, map[IOption]*dgo.ApplicationCommandInteractionDataOption{
+		given: {Name: "given", Type: dgo.ApplicationCommandOptionUser, Value: "1"},
+	})
+
+	user, ok := args.User("given")
+	assert.True(t, ok)
+	assert.Equal(t, &dgo.User{ID: "1"}, user)
+
+	user, ok = args.User("fallback")
+	assert.True(t, ok)
+	assert.Equal(t, &dgo.User{ID: "2"}, user)
+
+	_, ok = args.User("unset")
+	assert.False(t, ok)
+}
@@ -29,6 +29,7 @@ type IOption interface {
 	Attachment() IOption
 	Channel() IOption
 	ChannelType(n []dgo.ChannelType) IOption
+	Choice(k string, v interface{}) IOption
 }
 
 type Option struct {
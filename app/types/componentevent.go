@@ -0,0 +1,90 @@
+package types
+
+//go:generate mockgen -source=componentevent.go -destination=./componentevent_mock.go -package=types
+
+import (
+	"context"
+
+	dgo "github.com/bwmarrin/discordgo"
+	"github.com/fiffu/arisa3/app/instrumentation"
+	"github.com/fiffu/arisa3/app/log"
+)
+
+// IComponentEvent wraps interactions from components on a message, like the buttons that
+// Response.Button adds.
+type IComponentEvent interface {
+	Event() IEvent
+	Interaction() *dgo.InteractionCreate
+	// IsComponent returns whether the interaction came from a component. Other interactions, like
+	// commands, carry no custom ID.
+	IsComponent() bool
+	CustomID() string
+	GuildID() string
+	User() *dgo.User
+	Respond(context.Context, ICommandResponse) error
+}
+
+func NewComponentEvent(sess *dgo.Session, source *dgo.InteractionCreate) IComponentEvent {
+	return &componentEvent{
+		i:     source,
+		event: NewEvent(sess, ComponentEvent),
+	}
+}
+
+type componentEvent struct {
+	i     *dgo.InteractionCreate
+	event IEvent
+}
+
+func (c *componentEvent) Event() IEvent                       { return c.event }
+func (c *componentEvent) Interaction() *dgo.InteractionCreate { return c.i }
+func (c *componentEvent) GuildID() string                     { return c.i.GuildID }
+func (c *componentEvent) IsComponent() bool {
+	return c.i.Type == dgo.InteractionMessageComponent
+}
+func (c *componentEvent) CustomID() string {
+	if !c.IsComponent() {
+		return ""
+	}
+	return c.i.MessageComponentData().CustomID
+}
+func (c *componentEvent) User() *dgo.User {
+	user := c.i.User
+	if user == nil && c.i.Member != nil {
+		user = c.i.Member.User
+	}
+	return user
+}
+func (c *componentEvent) Respond(ctx context.Context, resp ICommandResponse) error {
+	sess := c.event.Session()
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Vendor(sess.InteractionRespond))
+	defer span.End()
+
+	log.Infof(ctx, "Component response >>> resp: \n| %s", resp.String())
+	return sess.InteractionRespond(c.i.Interaction, resp.Data(), dgo.WithContext(ctx))
+}
+
+// EditInteractionResponse replaces the message that the bot first responded to an interaction
+// with, such as to take the buttons off a prompt that nobody answered. Interactions can be edited
+// for 15 minutes after they are sent.
+func EditInteractionResponse(ctx context.Context, sess *dgo.Session, i *dgo.Interaction, resp ICommandResponse) error {
+	ctx, span := instrumentation.SpanInContext(ctx, instrumentation.Vendor(sess.InteractionResponseEdit))
+	defer span.End()
+
+	// Nil fields would be sent as null, so send them empty to clear them
+	data := resp.Data().Data
+	components, embeds := data.Components, data.Embeds
+	if components == nil {
+		components = []dgo.MessageComponent{}
+	}
+	if embeds == nil {
+		embeds = []*dgo.MessageEmbed{}
+	}
+	log.Infof(ctx, "Interaction edit >>> resp: \n| %s", resp.String())
+	_, err := sess.InteractionResponseEdit(i, &dgo.WebhookEdit{
+		Content:    &data.Content,
+		Embeds:     &embeds,
+		Components: &components,
+	}, dgo.WithContext(ctx))
+	return err
+}
@@ -0,0 +1,134 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: componentevent.go
+
+// Package types is a generated GoMock package.
+package types
+
+import (
+	context "context"
+	reflect "reflect"
+
+	discordgo "github.com/bwmarrin/discordgo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIComponentEvent is a mock of IComponentEvent interface.
+type MockIComponentEvent struct {
+	ctrl     *gomock.Controller
+	recorder *MockIComponentEventMockRecorder
+}
+
+// MockIComponentEventMockRecorder is the mock recorder for MockIComponentEvent.
+type MockIComponentEventMockRecorder struct {
+	mock *MockIComponentEvent
+}
+
+// NewMockIComponentEvent creates a new mock instance.
+func NewMockIComponentEvent(ctrl *gomock.Controller) *MockIComponentEvent {
+	mock := &MockIComponentEvent{ctrl: ctrl}
+	mock.recorder = &MockIComponentEventMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIComponentEvent) EXPECT() *MockIComponentEventMockRecorder {
+	return m.recorder
+}
+
+// CustomID mocks base method.
+func (m *MockIComponentEvent) CustomID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CustomID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// CustomID indicates an expected call of CustomID.
+func (mr *MockIComponentEventMockRecorder) CustomID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CustomID", reflect.TypeOf((*MockIComponentEvent)(nil).CustomID))
+}
+
+// Event mocks base method.
+func (m *MockIComponentEvent) Event() IEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Event")
+	ret0, _ := ret[0].(IEvent)
+	return ret0
+}
+
+// Event indicates an expected call of Event.
+func (mr *MockIComponentEventMockRecorder) Event() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Event", reflect.TypeOf((*MockIComponentEvent)(nil).Event))
+}
+
+// GuildID mocks base method.
+func (m *MockIComponentEvent) GuildID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuildID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GuildID indicates an expected call of GuildID.
+func (mr *MockIComponentEventMockRecorder) GuildID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildID", reflect.TypeOf((*MockIComponentEvent)(nil).GuildID))
+}
+
+// Interaction mocks base method.
+func (m *MockIComponentEvent) Interaction() *discordgo.InteractionCreate {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Interaction")
+	ret0, _ := ret[0].(*discordgo.InteractionCreate)
+	return ret0
+}
+
+// Interaction indicates an expected call of Interaction.
+func (mr *MockIComponentEventMockRecorder) Interaction() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Interaction", reflect.TypeOf((*MockIComponentEvent)(nil).Interaction))
+}
+
+// IsComponent mocks base method.
+func (m *MockIComponentEvent) IsComponent() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsComponent")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsComponent indicates an expected call of IsComponent.
+func (mr *MockIComponentEventMockRecorder) IsComponent() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsComponent", reflect.TypeOf((*MockIComponentEvent)(nil).IsComponent))
+}
+
+// Respond mocks base method.
+func (m *MockIComponentEvent) Respond(arg0 context.Context, arg1 ICommandResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Respond", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Respond indicates an expected call of Respond.
+func (mr *MockIComponentEventMockRecorder) Respond(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Respond", reflect.TypeOf((*MockIComponentEvent)(nil).Respond), arg0, arg1)
+}
+
+// User mocks base method.
+func (m *MockIComponentEvent) User() *discordgo.User {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "User")
+	ret0, _ := ret[0].(*discordgo.User)
+	return ret0
+}
+
+// User indicates an expected call of User.
+func (mr *MockIComponentEventMockRecorder) User() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "User", reflect.TypeOf((*MockIComponentEvent)(nil).User))
+}
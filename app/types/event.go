@@ -7,11 +7,30 @@ import (
 )
 
 const (
-	MessageCreateEvent = "MessageCreateEvent"
+	MessageCreateEvent    = "MessageCreateEvent"
+	MessageUpdateEvent    = "MessageUpdateEvent"
+	MessageDeleteEvent    = "MessageDeleteEvent"
+	MemberJoinEvent       = "MemberJoinEvent"
+	MemberLeaveEvent      = "MemberLeaveEvent"
+	GuildCreateEvent      = "GuildCreateEvent"
+	GuildDeleteEvent      = "GuildDeleteEvent"
+	ReactionAddEvent      = "ReactionAddEvent"
+	ReactionRemoveEvent   = "ReactionRemoveEvent"
+	VoiceStateUpdateEvent = "VoiceStateUpdateEvent"
+	RoleUpdateEvent       = "RoleUpdateEvent"
+	RoleDeleteEvent       = "RoleDeleteEvent"
+	ComponentEvent        = "ComponentEvent"
 )
 
 type SupportedEvents interface {
-	*dgo.Ready | *dgo.MessageCreate
+	*dgo.Ready |
+		*dgo.MessageCreate | *dgo.MessageUpdate | *dgo.MessageDelete |
+		*dgo.GuildMemberAdd | *dgo.GuildMemberRemove |
+		*dgo.GuildCreate | *dgo.GuildDelete |
+		*dgo.MessageReactionAdd | *dgo.MessageReactionRemove |
+		*dgo.VoiceStateUpdate |
+		*dgo.GuildRoleUpdate | *dgo.GuildRoleDelete |
+		*dgo.InteractionCreate
 }
 
 type EventHandler[E SupportedEvents] func(context.Context, *dgo.Session, E)
@@ -37,3 +56,12 @@ func (e *event) Name() string {
 func (e *event) Session() *dgo.Session {
 	return e.sess
 }
+
+// isSelf returns whether the given user is the bot user of the event's session.
+func isSelf(evt IEvent, user *dgo.User) bool {
+	sess := evt.Session()
+	if user == nil || sess == nil || sess.State == nil || sess.State.User == nil {
+		return false
+	}
+	return user.ID == sess.State.User.ID
+}
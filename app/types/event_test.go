@@ -0,0 +1,151 @@
+package types
+
+import (
+	"testing"
+
+	dgo "github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSession(botUserID string) *dgo.Session {
+	state := dgo.NewState()
+	state.User = &dgo.User{ID: botUserID}
+	return &dgo.Session{State: state}
+}
+
+func Test_MessageEvent_IsFromSelf(t *testing.T) {
+	sess := newTestSession("bot")
+
+	create := NewMessageEvent(sess, &dgo.MessageCreate{Message: &dgo.Message{Author: &dgo.User{ID: "bot"}}})
+	assert.Equal(t, MessageCreateEvent, create.Event().Name())
+	assert.True(t, create.IsFromSelf())
+	assert.Nil(t, create.Before())
+
+	// Deleted messages have no author, unless the message was cached before deletion
+	deleted := NewMessageDeleteEvent(sess, &dgo.MessageDelete{Message: &dgo.Message{ID: "1"}})
+	assert.Nil(t, deleted.User())
+	assert.False(t, deleted.IsFromSelf())
+
+	cached := NewMessageDeleteEvent(sess, &dgo.MessageDelete{
+		Message:      &dgo.Message{ID: "1"},
+		BeforeDelete: &dgo.Message{ID: "1", Author: &dgo.User{ID: "someone"}},
+	})
+	assert.Equal(t, "someone", cached.User().ID)
+	assert.False(t, cached.IsFromSelf())
+}
+
+func Test_MemberEvent(t *testing.T) {
+	sess := newTestSession("bot")
+	mem := &dgo.Member{GuildID: "guild", User: &dgo.User{ID: "user"}}
+
+	join := NewMemberJoinEvent(sess, &dgo.GuildMemberAdd{Member: mem})
+	assert.Equal(t, MemberJoinEvent, join.Event().Name())
+	assert.Equal(t, "guild", join.GuildID())
+	assert.Equal(t, "user", join.User().ID)
+	assert.False(t, join.IsFromSelf())
+
+	leave := NewMemberLeaveEvent(sess, &dgo.GuildMemberRemove{Member: mem})
+	assert.Equal(t, MemberLeaveEvent, leave.Event().Name())
+}
+
+func Test_VoiceStateEvent(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		before       *dgo.VoiceState
+		after        *dgo.VoiceState
+		expectJoined bool
+		expectLeft   bool
+	}{
+		{
+			desc:         "connect from nothing",
+			before:       nil,
+			after:        &dgo.VoiceState{ChannelID: "a"},
+			expectJoined: true,
+		},
+		{
+			desc:   "move between channels",
+			before: &dgo.VoiceState{ChannelID: "a"},
+			after:  &dgo.VoiceState{ChannelID: "b"},
+		},
+		{
+			desc:       "disconnect",
+			before:     &dgo.VoiceState{ChannelID: "a"},
+			after:      &dgo.VoiceState{ChannelID: ""},
+			expectLeft: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			evt := NewVoiceStateEvent(newTestSession("bot"), &dgo.VoiceStateUpdate{
+				VoiceState:   tc.after,
+				BeforeUpdate: tc.before,
+			})
+			assert.Equal(t, tc.expectJoined, evt.Joined())
+			assert.Equal(t, tc.expectLeft, evt.Left())
+		})
+	}
+}
+
+func Test_RoleEvent(t *testing.T) {
+	sess := newTestSession("bot")
+
+	update := NewRoleUpdateEvent(sess, &dgo.GuildRoleUpdate{
+		GuildRole: &dgo.GuildRole{GuildID: "guild", Role: &dgo.Role{ID: "role"}},
+	})
+	assert.Equal(t, "role", update.RoleID())
+	assert.NotNil(t, update.Role())
+	assert.False(t, update.IsDeleted())
+
+	del := NewRoleDeleteEvent(sess, &dgo.GuildRoleDelete{GuildID: "guild", RoleID: "role"})
+	assert.Equal(t, "role", del.RoleID())
+	assert.Equal(t, "guild", del.GuildID())
+	assert.Nil(t, del.Role())
+	assert.True(t, del.IsDeleted())
+}
+
+func Test_ComponentEvent(t *testing.T) {
+	sess := newTestSession("bot")
+
+	click := NewComponentEvent(sess, &dgo.InteractionCreate{Interaction: &dgo.Interaction{
+		Type:    dgo.InteractionMessageComponent,
+		GuildID: "guild",
+		Member:  &dgo.Member{User: &dgo.User{ID: "user"}},
+		Data:    dgo.MessageComponentInteractionData{CustomID: "button"},
+	}})
+	assert.Equal(t, ComponentEvent, click.Event().Name())
+	assert.True(t, click.IsComponent())
+	assert.Equal(t, "button", click.CustomID())
+	assert.Equal(t, "guild", click.GuildID())
+	assert.Equal(t, "user", click.User().ID)
+
+	command := NewComponentEvent(sess, &dgo.InteractionCreate{Interaction: &dgo.Interaction{
+		Type: dgo.InteractionApplicationCommand,
+		User: &dgo.User{ID: "user"},
+		Data: dgo.ApplicationCommandInteractionData{Name: "command"},
+	}})
+	assert.False(t, command.IsComponent())
+	assert.Empty(t, command.CustomID())
+	assert.Equal(t, "user", command.User().ID)
+}
+
+func Test_Response_Button(t *testing.T) {
+	resp := NewResponse().Content("Sure?").
+		Button("Yes", "yes", dgo.SuccessButton).
+		Button("No", "no", dgo.DangerButton)
+	assert.Equal(t, []dgo.MessageComponent{dgo.ActionsRow{Components: []dgo.MessageComponent{
+		dgo.Button{Label: "Yes", CustomID: "yes", Style: dgo.SuccessButton},
+		dgo.Button{Label: "No", CustomID: "no", Style: dgo.DangerButton},
+	}}}, resp.Data().Data.Components)
+
+	// Updates take the buttons off the message
+	update := NewResponse().Content("Done").Update().Ephemeral()
+	assert.Equal(t, dgo.InteractionResponseUpdateMessage, update.Data().Type)
+	assert.Equal(t, []dgo.MessageComponent{}, update.Data().Data.Components)
+	assert.Equal(t, dgo.MessageFlagsEphemeral, update.Data().Data.Flags)
+}
+
+func Test_Response_Deferred(t *testing.T) {
+	resp := NewResponse().Deferred().Ephemeral()
+	assert.Equal(t, dgo.InteractionResponseDeferredChannelMessageWithSource, resp.Data().Type)
+	assert.Equal(t, dgo.MessageFlagsEphemeral, resp.Data().Data.Flags)
+}
@@ -0,0 +1,45 @@
+package types
+
+//go:generate mockgen -source=guildevent.go -destination=./guildevent_mock.go -package=types
+
+import (
+	dgo "github.com/bwmarrin/discordgo"
+)
+
+// IGuildEvent wraps events for the bot joining, leaving or losing access to a guild.
+type IGuildEvent interface {
+	Event() IEvent
+	Guild() *dgo.Guild
+	// Before returns the guild as it was cached prior to deletion, if available.
+	Before() *dgo.Guild
+	GuildID() string
+	// Unavailable indicates an outage rather than the bot actually joining or leaving.
+	Unavailable() bool
+}
+
+func NewGuildCreateEvent(sess *dgo.Session, source *dgo.GuildCreate) IGuildEvent {
+	return &guildEvent{
+		guild: source.Guild,
+		event: NewEvent(sess, GuildCreateEvent),
+	}
+}
+
+func NewGuildDeleteEvent(sess *dgo.Session, source *dgo.GuildDelete) IGuildEvent {
+	return &guildEvent{
+		guild:  source.Guild,
+		before: source.BeforeDelete,
+		event:  NewEvent(sess, GuildDeleteEvent),
+	}
+}
+
+type guildEvent struct {
+	guild  *dgo.Guild
+	before *dgo.Guild
+	event  IEvent
+}
+
+func (g *guildEvent) Event() IEvent      { return g.event }
+func (g *guildEvent) Guild() *dgo.Guild  { return g.guild }
+func (g *guildEvent) Before() *dgo.Guild { return g.before }
+func (g *guildEvent) GuildID() string    { return g.guild.ID }
+func (g *guildEvent) Unavailable() bool  { return g.guild.Unavailable }
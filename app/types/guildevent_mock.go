@@ -0,0 +1,105 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: guildevent.go
+
+// Package types is a generated GoMock package.
+package types
+
+import (
+	reflect "reflect"
+
+	discordgo "github.com/bwmarrin/discordgo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIGuildEvent is a mock of IGuildEvent interface.
+type MockIGuildEvent struct {
+	ctrl     *gomock.Controller
+	recorder *MockIGuildEventMockRecorder
+}
+
+// MockIGuildEventMockRecorder is the mock recorder for MockIGuildEvent.
+type MockIGuildEventMockRecorder struct {
+	mock *MockIGuildEvent
+}
+
+// NewMockIGuildEvent creates a new mock instance.
+func NewMockIGuildEvent(ctrl *gomock.Controller) *MockIGuildEvent {
+	mock := &MockIGuildEvent{ctrl: ctrl}
+	mock.recorder = &MockIGuildEventMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIGuildEvent) EXPECT() *MockIGuildEventMockRecorder {
+	return m.recorder
+}
+
+// Before mocks base method.
+func (m *MockIGuildEvent) Before() *discordgo.Guild {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Before")
+	ret0, _ := ret[0].(*discordgo.Guild)
+	return ret0
+}
+
+// Before indicates an expected call of Before.
+func (mr *MockIGuildEventMockRecorder) Before() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Before", reflect.TypeOf((*MockIGuildEvent)(nil).Before))
+}
+
+// Event mocks base method.
+func (m *MockIGuildEvent) Event() IEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Event")
+	ret0, _ := ret[0].(IEvent)
+	return ret0
+}
+
+// Event indicates an expected call of Event.
+func (mr *MockIGuildEventMockRecorder) Event() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Event", reflect.TypeOf((*MockIGuildEvent)(nil).Event))
+}
+
+// Guild mocks base method.
+func (m *MockIGuildEvent) Guild() *discordgo.Guild {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Guild")
+	ret0, _ := ret[0].(*discordgo.Guild)
+	return ret0
+}
+
+// Guild indicates an expected call of Guild.
+func (mr *MockIGuildEventMockRecorder) Guild() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Guild", reflect.TypeOf((*MockIGuildEvent)(nil).Guild))
+}
+
+// GuildID mocks base method.
+func (m *MockIGuildEvent) GuildID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuildID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GuildID indicates an expected call of GuildID.
+func (mr *MockIGuildEventMockRecorder) GuildID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildID", reflect.TypeOf((*MockIGuildEvent)(nil).GuildID))
+}
+
+// Unavailable mocks base method.
+func (m *MockIGuildEvent) Unavailable() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unavailable")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Unavailable indicates an expected call of Unavailable.
+func (mr *MockIGuildEventMockRecorder) Unavailable() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unavailable", reflect.TypeOf((*MockIGuildEvent)(nil).Unavailable))
+}
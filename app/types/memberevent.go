@@ -0,0 +1,42 @@
+package types
+
+//go:generate mockgen -source=memberevent.go -destination=./memberevent_mock.go -package=types
+
+import (
+	dgo "github.com/bwmarrin/discordgo"
+)
+
+// IMemberEvent wraps events for members joining or leaving a guild.
+// The gateway only sends these if the session identifies with the privileged IntentGuildMembers.
+type IMemberEvent interface {
+	Event() IEvent
+	Member() *dgo.Member
+	GuildID() string
+	User() *dgo.User
+	IsFromSelf() bool
+}
+
+func NewMemberJoinEvent(sess *dgo.Session, source *dgo.GuildMemberAdd) IMemberEvent {
+	return &memberEvent{
+		member: source.Member,
+		event:  NewEvent(sess, MemberJoinEvent),
+	}
+}
+
+func NewMemberLeaveEvent(sess *dgo.Session, source *dgo.GuildMemberRemove) IMemberEvent {
+	return &memberEvent{
+		member: source.Member,
+		event:  NewEvent(sess, MemberLeaveEvent),
+	}
+}
+
+type memberEvent struct {
+	member *dgo.Member
+	event  IEvent
+}
+
+func (m *memberEvent) Event() IEvent       { return m.event }
+func (m *memberEvent) Member() *dgo.Member { return m.member }
+func (m *memberEvent) GuildID() string     { return m.member.GuildID }
+func (m *memberEvent) User() *dgo.User     { return m.member.User }
+func (m *memberEvent) IsFromSelf() bool    { return isSelf(m.event, m.member.User) }
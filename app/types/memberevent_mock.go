@@ -0,0 +1,105 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: memberevent.go
+
+// Package types is a generated GoMock package.
+package types
+
+import (
+	reflect "reflect"
+
+	discordgo "github.com/bwmarrin/discordgo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIMemberEvent is a mock of IMemberEvent interface.
+type MockIMemberEvent struct {
+	ctrl     *gomock.Controller
+	recorder *MockIMemberEventMockRecorder
+}
+
+// MockIMemberEventMockRecorder is the mock recorder for MockIMemberEvent.
+type MockIMemberEventMockRecorder struct {
+	mock *MockIMemberEvent
+}
+
+// NewMockIMemberEvent creates a new mock instance.
+func NewMockIMemberEvent(ctrl *gomock.Controller) *MockIMemberEvent {
+	mock := &MockIMemberEvent{ctrl: ctrl}
+	mock.recorder = &MockIMemberEventMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIMemberEvent) EXPECT() *MockIMemberEventMockRecorder {
+	return m.recorder
+}
+
+// Event mocks base method.
+func (m *MockIMemberEvent) Event() IEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Event")
+	ret0, _ := ret[0].(IEvent)
+	return ret0
+}
+
+// Event indicates an expected call of Event.
+func (mr *MockIMemberEventMockRecorder) Event() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Event", reflect.TypeOf((*MockIMemberEvent)(nil).Event))
+}
+
+// GuildID mocks base method.
+func (m *MockIMemberEvent) GuildID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuildID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GuildID indicates an expected call of GuildID.
+func (mr *MockIMemberEventMockRecorder) GuildID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildID", reflect.TypeOf((*MockIMemberEvent)(nil).GuildID))
+}
+
+// IsFromSelf mocks base method.
+func (m *MockIMemberEvent) IsFromSelf() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsFromSelf")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsFromSelf indicates an expected call of IsFromSelf.
+func (mr *MockIMemberEventMockRecorder) IsFromSelf() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsFromSelf", reflect.TypeOf((*MockIMemberEvent)(nil).IsFromSelf))
+}
+
+// Member mocks base method.
+func (m *MockIMemberEvent) Member() *discordgo.Member {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Member")
+	ret0, _ := ret[0].(*discordgo.Member)
+	return ret0
+}
+
+// Member indicates an expected call of Member.
+func (mr *MockIMemberEventMockRecorder) Member() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Member", reflect.TypeOf((*MockIMemberEvent)(nil).Member))
+}
+
+// User mocks base method.
+func (m *MockIMemberEvent) User() *discordgo.User {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "User")
+	ret0, _ := ret[0].(*discordgo.User)
+	return ret0
+}
+
+// User indicates an expected call of User.
+func (mr *MockIMemberEventMockRecorder) User() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "User", reflect.TypeOf((*MockIMemberEvent)(nil).User))
+}
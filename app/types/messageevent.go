@@ -9,6 +9,9 @@ import (
 type IMessageEvent interface {
 	Event() IEvent
 	Message() *dgo.Message
+	// Before returns the message prior to an update or delete, if it was cached in the
+	// session state. It is always nil for newly-created messages.
+	Before() *dgo.Message
 	GuildID() string
 	User() *dgo.User
 	IsFromSelf() bool
@@ -16,14 +19,31 @@ type IMessageEvent interface {
 
 func NewMessageEvent(sess *dgo.Session, source *dgo.MessageCreate) IMessageEvent {
 	return &msgEvent{
-		source: source,
-		event:  NewEvent(sess, MessageCreateEvent),
+		message: source.Message,
+		event:   NewEvent(sess, MessageCreateEvent),
+	}
+}
+
+func NewMessageUpdateEvent(sess *dgo.Session, source *dgo.MessageUpdate) IMessageEvent {
+	return &msgEvent{
+		message: source.Message,
+		before:  source.BeforeUpdate,
+		event:   NewEvent(sess, MessageUpdateEvent),
+	}
+}
+
+func NewMessageDeleteEvent(sess *dgo.Session, source *dgo.MessageDelete) IMessageEvent {
+	return &msgEvent{
+		message: source.Message,
+		before:  source.BeforeDelete,
+		event:   NewEvent(sess, MessageDeleteEvent),
 	}
 }
 
 type msgEvent struct {
-	source *dgo.MessageCreate
-	event  IEvent
+	message *dgo.Message
+	before  *dgo.Message
+	event   IEvent
 }
 
 func (m *msgEvent) Event() IEvent {
@@ -31,19 +51,25 @@ func (m *msgEvent) Event() IEvent {
 }
 
 func (m *msgEvent) Message() *dgo.Message {
-	return m.source.Message
+	return m.message
+}
+
+func (m *msgEvent) Before() *dgo.Message {
+	return m.before
 }
 
 func (m *msgEvent) User() *dgo.User {
-	return m.source.Author
+	// Deleted messages only carry IDs, so fallback on the cached copy if there is one
+	if m.message.Author == nil && m.before != nil {
+		return m.before.Author
+	}
+	return m.message.Author
 }
 
 func (m *msgEvent) GuildID() string {
-	return m.source.GuildID
+	return m.message.GuildID
 }
 
 func (m *msgEvent) IsFromSelf() bool {
-	botUserID := m.event.Session().State.User.ID
-	authorUserID := m.source.Author.ID
-	return authorUserID == botUserID
+	return isSelf(m.event, m.User())
 }
@@ -34,6 +34,20 @@ func (m *MockIMessageEvent) EXPECT() *MockIMessageEventMockRecorder {
 	return m.recorder
 }
 
+// Before mocks base method.
+func (m *MockIMessageEvent) Before() *discordgo.Message {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Before")
+	ret0, _ := ret[0].(*discordgo.Message)
+	return ret0
+}
+
+// Before indicates an expected call of Before.
+func (mr *MockIMessageEventMockRecorder) Before() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Before", reflect.TypeOf((*MockIMessageEvent)(nil).Before))
+}
+
 // Event mocks base method.
 func (m *MockIMessageEvent) Event() IEvent {
 	m.ctrl.T.Helper()
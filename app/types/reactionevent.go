@@ -0,0 +1,54 @@
+package types
+
+//go:generate mockgen -source=reactionevent.go -destination=./reactionevent_mock.go -package=types
+
+import (
+	dgo "github.com/bwmarrin/discordgo"
+)
+
+// IReactionEvent wraps events for reactions being added to or removed from a message.
+type IReactionEvent interface {
+	Event() IEvent
+	Reaction() *dgo.MessageReaction
+	// Member is only populated for reactions added in a guild.
+	Member() *dgo.Member
+	GuildID() string
+	ChannelID() string
+	MessageID() string
+	UserID() string
+	Emoji() dgo.Emoji
+	IsFromSelf() bool
+}
+
+func NewReactionAddEvent(sess *dgo.Session, source *dgo.MessageReactionAdd) IReactionEvent {
+	return &reactionEvent{
+		reaction: source.MessageReaction,
+		member:   source.Member,
+		event:    NewEvent(sess, ReactionAddEvent),
+	}
+}
+
+func NewReactionRemoveEvent(sess *dgo.Session, source *dgo.MessageReactionRemove) IReactionEvent {
+	return &reactionEvent{
+		reaction: source.MessageReaction,
+		event:    NewEvent(sess, ReactionRemoveEvent),
+	}
+}
+
+type reactionEvent struct {
+	reaction *dgo.MessageReaction
+	member   *dgo.Member
+	event    IEvent
+}
+
+func (r *reactionEvent) Event() IEvent                  { return r.event }
+func (r *reactionEvent) Reaction() *dgo.MessageReaction { return r.reaction }
+func (r *reactionEvent) Member() *dgo.Member            { return r.member }
+func (r *reactionEvent) GuildID() string                { return r.reaction.GuildID }
+func (r *reactionEvent) ChannelID() string              { return r.reaction.ChannelID }
+func (r *reactionEvent) MessageID() string              { return r.reaction.MessageID }
+func (r *reactionEvent) UserID() string                 { return r.reaction.UserID }
+func (r *reactionEvent) Emoji() dgo.Emoji               { return r.reaction.Emoji }
+func (r *reactionEvent) IsFromSelf() bool {
+	return isSelf(r.event, &dgo.User{ID: r.reaction.UserID})
+}
@@ -0,0 +1,161 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: reactionevent.go
+
+// Package types is a generated GoMock package.
+package types
+
+import (
+	reflect "reflect"
+
+	discordgo "github.com/bwmarrin/discordgo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIReactionEvent is a mock of IReactionEvent interface.
+type MockIReactionEvent struct {
+	ctrl     *gomock.Controller
+	recorder *MockIReactionEventMockRecorder
+}
+
+// MockIReactionEventMockRecorder is the mock recorder for MockIReactionEvent.
+type MockIReactionEventMockRecorder struct {
+	mock *MockIReactionEvent
+}
+
+// NewMockIReactionEvent creates a new mock instance.
+func NewMockIReactionEvent(ctrl *gomock.Controller) *MockIReactionEvent {
+	mock := &MockIReactionEvent{ctrl: ctrl}
+	mock.recorder = &MockIReactionEventMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIReactionEvent) EXPECT() *MockIReactionEventMockRecorder {
+	return m.recorder
+}
+
+// ChannelID mocks base method.
+func (m *MockIReactionEvent) ChannelID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChannelID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ChannelID indicates an expected call of ChannelID.
+func (mr *MockIReactionEventMockRecorder) ChannelID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChannelID", reflect.TypeOf((*MockIReactionEvent)(nil).ChannelID))
+}
+
+// Emoji mocks base method.
+func (m *MockIReactionEvent) Emoji() discordgo.Emoji {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Emoji")
+	ret0, _ := ret[0].(discordgo.Emoji)
+	return ret0
+}
+
+// Emoji indicates an expected call of Emoji.
+func (mr *MockIReactionEventMockRecorder) Emoji() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Emoji", reflect.TypeOf((*MockIReactionEvent)(nil).Emoji))
+}
+
+// Event mocks base method.
+func (m *MockIReactionEvent) Event() IEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Event")
+	ret0, _ := ret[0].(IEvent)
+	return ret0
+}
+
+// Event indicates an expected call of Event.
+func (mr *MockIReactionEventMockRecorder) Event() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Event", reflect.TypeOf((*MockIReactionEvent)(nil).Event))
+}
+
+// GuildID mocks base method.
+func (m *MockIReactionEvent) GuildID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuildID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GuildID indicates an expected call of GuildID.
+func (mr *MockIReactionEventMockRecorder) GuildID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildID", reflect.TypeOf((*MockIReactionEvent)(nil).GuildID))
+}
+
+// IsFromSelf mocks base method.
+func (m *MockIReactionEvent) IsFromSelf() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsFromSelf")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsFromSelf indicates an expected call of IsFromSelf.
+func (mr *MockIReactionEventMockRecorder) IsFromSelf() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsFromSelf", reflect.TypeOf((*MockIReactionEvent)(nil).IsFromSelf))
+}
+
+// Member mocks base method.
+func (m *MockIReactionEvent) Member() *discordgo.Member {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Member")
+	ret0, _ := ret[0].(*discordgo.Member)
+	return ret0
+}
+
+// Member indicates an expected call of Member.
+func (mr *MockIReactionEventMockRecorder) Member() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Member", reflect.TypeOf((*MockIReactionEvent)(nil).Member))
+}
+
+// MessageID mocks base method.
+func (m *MockIReactionEvent) MessageID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MessageID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// MessageID indicates an expected call of MessageID.
+func (mr *MockIReactionEventMockRecorder) MessageID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MessageID", reflect.TypeOf((*MockIReactionEvent)(nil).MessageID))
+}
+
+// Reaction mocks base method.
+func (m *MockIReactionEvent) Reaction() *discordgo.MessageReaction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reaction")
+	ret0, _ := ret[0].(*discordgo.MessageReaction)
+	return ret0
+}
+
+// Reaction indicates an expected call of Reaction.
+func (mr *MockIReactionEventMockRecorder) Reaction() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reaction", reflect.TypeOf((*MockIReactionEvent)(nil).Reaction))
+}
+
+// UserID mocks base method.
+func (m *MockIReactionEvent) UserID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// UserID indicates an expected call of UserID.
+func (mr *MockIReactionEventMockRecorder) UserID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserID", reflect.TypeOf((*MockIReactionEvent)(nil).UserID))
+}
@@ -56,6 +56,44 @@ func (r *Response) File(name, contentType string, reader io.Reader) *Response {
 	return r
 }
 
+// Button adds a button below the message. Clicking it sends a component interaction carrying the
+// custom ID, which IComponentEvent wraps.
+func (r *Response) Button(label, customID string, style dgo.ButtonStyle) *Response {
+	button := dgo.Button{Label: label, CustomID: customID, Style: style}
+	components := r.data.Data.Components
+	if len(components) == 0 {
+		r.data.Data.Components = []dgo.MessageComponent{dgo.ActionsRow{Components: []dgo.MessageComponent{button}}}
+		return r
+	}
+	row := components[len(components)-1].(dgo.ActionsRow)
+	row.Components = append(row.Components, button)
+	components[len(components)-1] = row
+	return r
+}
+
+// Update makes the response replace the message that a component was clicked on, rather than
+// send a new one. Buttons on that message are removed unless the response adds its own.
+func (r *Response) Update() *Response {
+	r.data.Type = dgo.InteractionResponseUpdateMessage
+	if r.data.Data.Components == nil {
+		r.data.Data.Components = []dgo.MessageComponent{}
+	}
+	return r
+}
+
+// Ephemeral makes the response visible only to the user who invoked the interaction.
+func (r *Response) Ephemeral() *Response {
+	r.data.Data.Flags |= dgo.MessageFlagsEphemeral
+	return r
+}
+
+// Deferred acknowledges the interaction with a "thinking" message, for commands that take longer
+// than Discord waits for a response. The result is sent later with EditInteractionResponse.
+func (r *Response) Deferred() *Response {
+	r.data.Type = dgo.InteractionResponseDeferredChannelMessageWithSource
+	return r
+}
+
 func (r *Response) Data() *dgo.InteractionResponse {
 	return r.data
 }
@@ -0,0 +1,49 @@
+package types
+
+//go:generate mockgen -source=roleevent.go -destination=./roleevent_mock.go -package=types
+
+import (
+	dgo "github.com/bwmarrin/discordgo"
+)
+
+// IRoleEvent wraps events for guild roles being updated or deleted.
+type IRoleEvent interface {
+	Event() IEvent
+	// Role returns the updated role. Delete events only carry IDs, so this is nil for those.
+	Role() *dgo.Role
+	RoleID() string
+	GuildID() string
+	IsDeleted() bool
+}
+
+func NewRoleUpdateEvent(sess *dgo.Session, source *dgo.GuildRoleUpdate) IRoleEvent {
+	return &roleEvent{
+		role:    source.Role,
+		roleID:  source.Role.ID,
+		guildID: source.GuildID,
+		event:   NewEvent(sess, RoleUpdateEvent),
+	}
+}
+
+func NewRoleDeleteEvent(sess *dgo.Session, source *dgo.GuildRoleDelete) IRoleEvent {
+	return &roleEvent{
+		roleID:  source.RoleID,
+		guildID: source.GuildID,
+		deleted: true,
+		event:   NewEvent(sess, RoleDeleteEvent),
+	}
+}
+
+type roleEvent struct {
+	role    *dgo.Role
+	roleID  string
+	guildID string
+	deleted bool
+	event   IEvent
+}
+
+func (r *roleEvent) Event() IEvent   { return r.event }
+func (r *roleEvent) Role() *dgo.Role { return r.role }
+func (r *roleEvent) RoleID() string  { return r.roleID }
+func (r *roleEvent) GuildID() string { return r.guildID }
+func (r *roleEvent) IsDeleted() bool { return r.deleted }
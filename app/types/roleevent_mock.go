@@ -0,0 +1,105 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: roleevent.go
+
+// Package types is a generated GoMock package.
+package types
+
+import (
+	reflect "reflect"
+
+	discordgo "github.com/bwmarrin/discordgo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIRoleEvent is a mock of IRoleEvent interface.
+type MockIRoleEvent struct {
+	ctrl     *gomock.Controller
+	recorder *MockIRoleEventMockRecorder
+}
+
+// MockIRoleEventMockRecorder is the mock recorder for MockIRoleEvent.
+type MockIRoleEventMockRecorder struct {
+	mock *MockIRoleEvent
+}
+
+// NewMockIRoleEvent creates a new mock instance.
+func NewMockIRoleEvent(ctrl *gomock.Controller) *MockIRoleEvent {
+	mock := &MockIRoleEvent{ctrl: ctrl}
+	mock.recorder = &MockIRoleEventMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIRoleEvent) EXPECT() *MockIRoleEventMockRecorder {
+	return m.recorder
+}
+
+// Event mocks base method.
+func (m *MockIRoleEvent) Event() IEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Event")
+	ret0, _ := ret[0].(IEvent)
+	return ret0
+}
+
+// Event indicates an expected call of Event.
+func (mr *MockIRoleEventMockRecorder) Event() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Event", reflect.TypeOf((*MockIRoleEvent)(nil).Event))
+}
+
+// GuildID mocks base method.
+func (m *MockIRoleEvent) GuildID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuildID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GuildID indicates an expected call of GuildID.
+func (mr *MockIRoleEventMockRecorder) GuildID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildID", reflect.TypeOf((*MockIRoleEvent)(nil).GuildID))
+}
+
+// IsDeleted mocks base method.
+func (m *MockIRoleEvent) IsDeleted() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsDeleted")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsDeleted indicates an expected call of IsDeleted.
+func (mr *MockIRoleEventMockRecorder) IsDeleted() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDeleted", reflect.TypeOf((*MockIRoleEvent)(nil).IsDeleted))
+}
+
+// Role mocks base method.
+func (m *MockIRoleEvent) Role() *discordgo.Role {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Role")
+	ret0, _ := ret[0].(*discordgo.Role)
+	return ret0
+}
+
+// Role indicates an expected call of Role.
+func (mr *MockIRoleEventMockRecorder) Role() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Role", reflect.TypeOf((*MockIRoleEvent)(nil).Role))
+}
+
+// RoleID mocks base method.
+func (m *MockIRoleEvent) RoleID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RoleID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// RoleID indicates an expected call of RoleID.
+func (mr *MockIRoleEventMockRecorder) RoleID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RoleID", reflect.TypeOf((*MockIRoleEvent)(nil).RoleID))
+}
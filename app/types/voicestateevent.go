@@ -0,0 +1,57 @@
+package types
+
+//go:generate mockgen -source=voicestateevent.go -destination=./voicestateevent_mock.go -package=types
+
+import (
+	dgo "github.com/bwmarrin/discordgo"
+)
+
+// IVoiceStateEvent wraps events for members joining, leaving or moving between voice channels.
+type IVoiceStateEvent interface {
+	Event() IEvent
+	State() *dgo.VoiceState
+	// Before returns the previous voice state, if it was cached in the session state.
+	Before() *dgo.VoiceState
+	GuildID() string
+	ChannelID() string
+	UserID() string
+	// Joined returns whether the user connected to a voice channel from being disconnected.
+	Joined() bool
+	// Left returns whether the user disconnected from voice entirely.
+	Left() bool
+	IsFromSelf() bool
+}
+
+func NewVoiceStateEvent(sess *dgo.Session, source *dgo.VoiceStateUpdate) IVoiceStateEvent {
+	return &voiceStateEvent{
+		state:  source.VoiceState,
+		before: source.BeforeUpdate,
+		event:  NewEvent(sess, VoiceStateUpdateEvent),
+	}
+}
+
+type voiceStateEvent struct {
+	state  *dgo.VoiceState
+	before *dgo.VoiceState
+	event  IEvent
+}
+
+func (v *voiceStateEvent) Event() IEvent           { return v.event }
+func (v *voiceStateEvent) State() *dgo.VoiceState  { return v.state }
+func (v *voiceStateEvent) Before() *dgo.VoiceState { return v.before }
+func (v *voiceStateEvent) GuildID() string         { return v.state.GuildID }
+func (v *voiceStateEvent) ChannelID() string       { return v.state.ChannelID }
+func (v *voiceStateEvent) UserID() string          { return v.state.UserID }
+
+func (v *voiceStateEvent) Joined() bool {
+	wasConnected := v.before != nil && v.before.ChannelID != ""
+	return !wasConnected && v.state.ChannelID != ""
+}
+
+func (v *voiceStateEvent) Left() bool {
+	return v.state.ChannelID == ""
+}
+
+func (v *voiceStateEvent) IsFromSelf() bool {
+	return isSelf(v.event, &dgo.User{ID: v.state.UserID})
+}
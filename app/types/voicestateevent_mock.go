@@ -0,0 +1,161 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: voicestateevent.go
+
+// Package types is a generated GoMock package.
+package types
+
+import (
+	reflect "reflect"
+
+	discordgo "github.com/bwmarrin/discordgo"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIVoiceStateEvent is a mock of IVoiceStateEvent interface.
+type MockIVoiceStateEvent struct {
+	ctrl     *gomock.Controller
+	recorder *MockIVoiceStateEventMockRecorder
+}
+
+// MockIVoiceStateEventMockRecorder is the mock recorder for MockIVoiceStateEvent.
+type MockIVoiceStateEventMockRecorder struct {
+	mock *MockIVoiceStateEvent
+}
+
+// NewMockIVoiceStateEvent creates a new mock instance.
+func NewMockIVoiceStateEvent(ctrl *gomock.Controller) *MockIVoiceStateEvent {
+	mock := &MockIVoiceStateEvent{ctrl: ctrl}
+	mock.recorder = &MockIVoiceStateEventMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIVoiceStateEvent) EXPECT() *MockIVoiceStateEventMockRecorder {
+	return m.recorder
+}
+
+// Before mocks base method.
+func (m *MockIVoiceStateEvent) Before() *discordgo.VoiceState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Before")
+	ret0, _ := ret[0].(*discordgo.VoiceState)
+	return ret0
+}
+
+// Before indicates an expected call of Before.
+func (mr *MockIVoiceStateEventMockRecorder) Before() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Before", reflect.TypeOf((*MockIVoiceStateEvent)(nil).Before))
+}
+
+// ChannelID mocks base method.
+func (m *MockIVoiceStateEvent) ChannelID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChannelID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ChannelID indicates an expected call of ChannelID.
+func (mr *MockIVoiceStateEventMockRecorder) ChannelID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChannelID", reflect.TypeOf((*MockIVoiceStateEvent)(nil).ChannelID))
+}
+
+// Event mocks base method.
+func (m *MockIVoiceStateEvent) Event() IEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Event")
+	ret0, _ := ret[0].(IEvent)
+	return ret0
+}
+
+// Event indicates an expected call of Event.
+func (mr *MockIVoiceStateEventMockRecorder) Event() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Event", reflect.TypeOf((*MockIVoiceStateEvent)(nil).Event))
+}
+
+// GuildID mocks base method.
+func (m *MockIVoiceStateEvent) GuildID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuildID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GuildID indicates an expected call of GuildID.
+func (mr *MockIVoiceStateEventMockRecorder) GuildID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuildID", reflect.TypeOf((*MockIVoiceStateEvent)(nil).GuildID))
+}
+
+// IsFromSelf mocks base method.
+func (m *MockIVoiceStateEvent) IsFromSelf() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsFromSelf")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsFromSelf indicates an expected call of IsFromSelf.
+func (mr *MockIVoiceStateEventMockRecorder) IsFromSelf() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsFromSelf", reflect.TypeOf((*MockIVoiceStateEvent)(nil).IsFromSelf))
+}
+
+// Joined mocks base method.
+func (m *MockIVoiceStateEvent) Joined() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Joined")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Joined indicates an expected call of Joined.
+func (mr *MockIVoiceStateEventMockRecorder) Joined() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Joined", reflect.TypeOf((*MockIVoiceStateEvent)(nil).Joined))
+}
+
+// Left mocks base method.
+func (m *MockIVoiceStateEvent) Left() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Left")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Left indicates an expected call of Left.
+func (mr *MockIVoiceStateEventMockRecorder) Left() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Left", reflect.TypeOf((*MockIVoiceStateEvent)(nil).Left))
+}
+
+// State mocks base method.
+func (m *MockIVoiceStateEvent) State() *discordgo.VoiceState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "State")
+	ret0, _ := ret[0].(*discordgo.VoiceState)
+	return ret0
+}
+
+// State indicates an expected call of State.
+func (mr *MockIVoiceStateEventMockRecorder) State() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockIVoiceStateEvent)(nil).State))
+}
+
+// UserID mocks base method.
+func (m *MockIVoiceStateEvent) UserID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// UserID indicates an expected call of UserID.
+func (mr *MockIVoiceStateEventMockRecorder) UserID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserID", reflect.TypeOf((*MockIVoiceStateEvent)(nil).UserID))
+}
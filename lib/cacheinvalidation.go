@@ -0,0 +1,64 @@
+package lib
+
+import (
+	"context"
+	"sync"
+)
+
+// cacheinvalidation.go describes how replicas tell each other to evict cache entries.
+
+// InvalidateAll is published in place of a key to evict every entry.
+const InvalidateAll = ""
+
+// IInvalidationChannel broadcasts the keys of changed cache entries, so that every replica can
+// evict them from its own caches.
+type IInvalidationChannel interface {
+	// Publish tells every subscriber, including those of this replica, that the key changed.
+	Publish(ctx context.Context, key string) error
+
+	// Subscribe calls evict with each published key until ctx is done.
+	Subscribe(ctx context.Context, evict func(key string)) error
+}
+
+// localInvalidationChannel implements IInvalidationChannel within a single process.
+type localInvalidationChannel struct {
+	mutex       sync.Mutex
+	subscribers map[int]func(string)
+	nextID      int
+}
+
+// NewLocalInvalidationChannel broadcasts invalidations within this process only. It suits a single
+// replica, and stands in for a networked channel in tests.
+func NewLocalInvalidationChannel() IInvalidationChannel {
+	return &localInvalidationChannel{subscribers: make(map[int]func(string))}
+}
+
+func (c *localInvalidationChannel) Publish(ctx context.Context, key string) error {
+	c.mutex.Lock()
+	subscribers := make([]func(string), 0, len(c.subscribers))
+	for _, evict := range c.subscribers {
+		subscribers = append(subscribers, evict)
+	}
+	c.mutex.Unlock()
+
+	for _, evict := range subscribers {
+		evict(key)
+	}
+	return nil
+}
+
+func (c *localInvalidationChannel) Subscribe(ctx context.Context, evict func(key string)) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	id := c.nextID
+	c.nextID++
+	c.subscribers[id] = evict
+
+	go func() {
+		<-ctx.Done()
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		delete(c.subscribers, id)
+	}()
+	return nil
+}
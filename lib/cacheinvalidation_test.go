@@ -0,0 +1,27 @@
+package lib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_localInvalidationChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	channel := NewLocalInvalidationChannel()
+
+	evicted := make(chan string, 1)
+	assert.NoError(t, channel.Subscribe(ctx, func(key string) { evicted <- key }))
+	assert.NoError(t, channel.Publish(ctx, "some-key"))
+	assert.Equal(t, "some-key", <-evicted)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		c := channel.(*localInvalidationChannel)
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return len(c.subscribers) == 0
+	}, time.Second, 10*time.Millisecond)
+}
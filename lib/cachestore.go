@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// cachestore.go implements caches that keep their values in a networked store.
+
+// ICacheStore is a networked key-value store, such as Redis, that caches can keep their values in
+// so that every replica shares them. Values expire after their TTL.
+type ICacheStore interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+
+	// DeletePrefix deletes every key that starts with the prefix.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// ICacheCodec encodes the values of a cache that is kept in an ICacheStore. Cached types usually
+// keep their fields unexported, which encoding/json skips, so each such cache has a codec for its
+// type.
+type ICacheCodec[T any] interface {
+	Encode(T) ([]byte, error)
+	Decode([]byte) (T, error)
+}
+
+// jsonCodec implements ICacheCodec by encoding values as JSON through a wire type W, which has
+// exported fields.
+type jsonCodec[T, W any] struct {
+	toWire   func(T) W
+	fromWire func(W) T
+}
+
+// JSONCodec encodes values as JSON, after converting them with toWire into a type whose fields
+// are exported. fromWire converts them back.
+func JSONCodec[T, W any](toWire func(T) W, fromWire func(W) T) ICacheCodec[T] {
+	return jsonCodec[T, W]{toWire, fromWire}
+}
+
+func (c jsonCodec[T, W]) Encode(value T) ([]byte, error) {
+	return json.Marshal(c.toWire(value))
+}
+
+func (c jsonCodec[T, W]) Decode(data []byte) (value T, err error) {
+	var wire W
+	if err = json.Unmarshal(data, &wire); err != nil {
+		return value, err
+	}
+	return c.fromWire(wire), nil
+}
+
+// storeTimeout bounds each call to an ICacheStore, since ICache has no context to cancel them.
+const storeTimeout = 2 * time.Second
+
+// storeCache implements ICache with an ICacheStore.
+type storeCache[T ICacheable[K], K comparable] struct {
+	store     ICacheStore
+	namespace string
+	expiry    time.Duration
+	codec     ICacheCodec[T]
+
+	mutex sync.Mutex
+	stats CacheStats
+}
+
+// NewStoreCache makes a cache that keeps its values in a networked store, encoded by the codec
+// under keys prefixed by the namespace. Caches on the same store and namespace share their values,
+// even across replicas. The store evicts values by itself, so there are no options for bounding
+// or sweeping the cache. Errors from the store are counted in CacheStats.Errors and treated as
+// misses, so that an unreachable store only costs the cache its hits.
+func NewStoreCache[T ICacheable[K], K comparable](store ICacheStore, namespace string, expiry time.Duration, codec ICacheCodec[T]) ICache[T, K] {
+	return &storeCache[T, K]{store: store, namespace: namespace, expiry: expiry, codec: codec}
+}
+
+func (c *storeCache[T, K]) storeKey(key K) string {
+	return fmt.Sprintf("%s:%v", c.namespace, key)
+}
+
+// count applies a change to the stats.
+func (c *storeCache[T, K]) count(update func(*CacheStats)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	update(&c.stats)
+}
+
+func (c *storeCache[T, K]) Peek(key K) (t T, ok bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	data, ok, err := c.store.Get(ctx, c.storeKey(key))
+	if err == nil && ok {
+		t, err = c.codec.Decode(data)
+	}
+	switch {
+	case err != nil:
+		c.count(func(s *CacheStats) { s.Errors++; s.Misses++ })
+		return t, false
+	case !ok:
+		c.count(func(s *CacheStats) { s.Misses++ })
+		return t, false
+	}
+	c.count(func(s *CacheStats) { s.Hits++ })
+	return t, true
+}
+
+func (c *storeCache[T, K]) Put(data T) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	encoded, err := c.codec.Encode(data)
+	if err == nil {
+		err = c.store.Set(ctx, c.storeKey(data.CacheKey()), encoded, c.expiry)
+	}
+	if err != nil {
+		c.count(func(s *CacheStats) { s.Errors++ })
+	}
+}
+
+func (c *storeCache[T, K]) Delete(key K) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := c.store.Delete(ctx, c.storeKey(key)); err != nil {
+		c.count(func(s *CacheStats) { s.Errors++ })
+	}
+}
+
+func (c *storeCache[T, K]) Drop() {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := c.store.DeletePrefix(ctx, c.namespace+":"); err != nil {
+		c.count(func(s *CacheStats) { s.Errors++ })
+	}
+}
+
+// Stats of a store-backed cache only count this replica's lookups, and leave Size at zero.
+func (c *storeCache[T, K]) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fiffu/arisa3/testfixtures/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pet keeps its fields unexported, like the types that the cogs cache.
+type pet struct {
+	name  string
+	sound animal
+}
+
+func (p *pet) CacheKey() string { return p.name }
+
+type petWire struct {
+	Name  string `json:"name"`
+	Sound string `json:"sound"`
+}
+
+var petCodec = JSONCodec(
+	func(p *pet) petWire { return petWire{p.name, string(p.sound)} },
+	func(w petWire) *pet { return &pet{w.Name, animal(w.Sound)} },
+)
+
+func newTestStoreCache(t *testing.T, server *redistest.Server, namespace string) ICache[*pet, string] {
+	store, err := NewRedisStore(server.URL())
+	require.NoError(t, err)
+	t.Cleanup(func() { store.(*redisStore).Close() })
+	return NewStoreCache[*pet, string](store, namespace, time.Hour, petCodec)
+}
+
+func Test_storeCache(t *testing.T) {
+	server := redistest.NewServer(t)
+	cache := newTestStoreCache(t, server, "pets")
+	rex := &pet{"rex", dog}
+
+	// cache miss
+	_, ok := cache.Peek(rex.name)
+	assert.False(t, ok)
+
+	// cache put round-trips the unexported fields
+	cache.Put(rex)
+	got, ok := cache.Peek(rex.name)
+	assert.True(t, ok)
+	assert.Equal(t, rex, got)
+	assert.Equal(t, []string{"pets:rex"}, server.Keys())
+	assert.InDelta(t, time.Hour, server.TTL("pets:rex"), float64(time.Minute))
+
+	// cache delete
+	cache.Delete(rex.name)
+	_, ok = cache.Peek(rex.name)
+	assert.False(t, ok)
+
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2}, cache.Stats())
+}
+
+func Test_storeCache_shared(t *testing.T) {
+	server := redistest.NewServer(t)
+	replica1 := newTestStoreCache(t, server, "pets")
+	replica2 := newTestStoreCache(t, server, "pets")
+	other := newTestStoreCache(t, server, "strays")
+
+	replica1.Put(&pet{"rex", dog})
+	replica1.Put(&pet{"tom", cat})
+	other.Put(&pet{"rex", cat})
+
+	got, ok := replica2.Peek("rex")
+	assert.True(t, ok)
+	assert.Equal(t, dog, got.sound)
+
+	// Drop only clears the namespace, on every replica.
+	replica2.Drop()
+	_, ok = replica1.Peek("rex")
+	assert.False(t, ok)
+	_, ok = replica1.Peek("tom")
+	assert.False(t, ok)
+	got, ok = other.Peek("rex")
+	assert.True(t, ok)
+	assert.Equal(t, cat, got.sound)
+}
+
+func Test_storeCache_storeDown(t *testing.T) {
+	server := redistest.NewServer(t)
+	cache := newTestStoreCache(t, server, "pets")
+	cache.Put(&pet{"rex", dog})
+	server.Close()
+
+	_, ok := cache.Peek("rex")
+	assert.False(t, ok)
+	cache.Put(&pet{"tom", cat})
+	cache.Delete("rex")
+	cache.Drop()
+
+	assert.Equal(t, CacheStats{Misses: 1, Errors: 4}, cache.Stats())
+}
+
+func Test_storeCache_badValue(t *testing.T) {
+	server := redistest.NewServer(t)
+	store, err := NewRedisStore(server.URL())
+	require.NoError(t, err)
+	cache := NewStoreCache[*pet, string](store, "pets", time.Hour, petCodec)
+
+	require.NoError(t, store.Set(context.Background(), "pets:rex", []byte("not json"), time.Hour))
+	_, ok := cache.Peek("rex")
+	assert.False(t, ok)
+	assert.Equal(t, CacheStats{Misses: 1, Errors: 1}, cache.Stats())
+}
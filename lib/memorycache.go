@@ -1,6 +1,11 @@
 package lib
 
-import "time"
+import (
+	"container/list"
+	"runtime"
+	"sync"
+	"time"
+)
 
 // memorycache.go implements an in-memory cache.
 
@@ -8,59 +13,208 @@ type ICacheable[K comparable] interface {
 	CacheKey() K
 }
 
+// ICache is a cache of values by their keys. Values are kept in process memory by NewCache, or in
+// a networked store by NewStoreCache. Implementations are safe for concurrent use.
 type ICache[T ICacheable[K], K comparable] interface {
 	Peek(key K) (T, bool)
 	Put(data T)
 	Delete(key K)
 	Drop()
+	Stats() CacheStats
+}
+
+// CacheStats counts the lookups and removals of a cache since it was created.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	// Evictions counts values removed to keep the cache within its size bound.
+	Evictions int64
+	// Expirations counts values removed after they expired.
+	Expirations int64
+	// Errors counts failed calls to the cache's store, if it has one.
+	Errors int64
+	// Size is the number of values in the cache, including expired ones not yet swept.
+	Size int
+}
+
+// Add sums the stats of two caches.
+func (s CacheStats) Add(other CacheStats) CacheStats {
+	return CacheStats{
+		Hits:        s.Hits + other.Hits,
+		Misses:      s.Misses + other.Misses,
+		Evictions:   s.Evictions + other.Evictions,
+		Expirations: s.Expirations + other.Expirations,
+		Errors:      s.Errors + other.Errors,
+		Size:        s.Size + other.Size,
+	}
+}
+
+// CacheOption configures a cache made by NewCache.
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	maxEntries    int
+	sweepInterval time.Duration
+}
+
+// WithMaxEntries bounds the cache to n values, evicting the least recently used value when full.
+func WithMaxEntries(n int) CacheOption {
+	return func(o *cacheOptions) { o.maxEntries = n }
+}
+
+// WithSweepInterval removes expired values in the background at the given interval. Without it,
+// expired values are only removed when they are looked up.
+func WithSweepInterval(interval time.Duration) CacheOption {
+	return func(o *cacheOptions) { o.sweepInterval = interval }
+}
+
+type cacheEntry[T any, K comparable] struct {
+	key     K
+	value   T
+	expires time.Time
 }
 
 type memoryCache[T ICacheable[K], K comparable] struct {
-	data    map[K]T
-	dataTTL map[K]time.Time
-	expiry  time.Duration
-	clock   func() time.Time
+	mutex      sync.Mutex
+	entries    map[K]*list.Element // of *cacheEntry, ordered from most to least recently used
+	recency    *list.List
+	expiry     time.Duration
+	maxEntries int
+	stats      CacheStats
+	clock      func() time.Time
+}
+
+// sweptCache is handed out by NewCache for caches that sweep in the background. The sweeper only
+// holds the memoryCache inside, so sweptCache can be garbage collected, which stops the sweeper.
+type sweptCache[T ICacheable[K], K comparable] struct {
+	*memoryCache[T, K]
 }
 
-func NewCache[T ICacheable[K], K comparable](expiry time.Duration) ICache[T, K] {
-	return newMemoryCache[T, K](expiry)
+func NewCache[T ICacheable[K], K comparable](expiry time.Duration, opts ...CacheOption) ICache[T, K] {
+	options := cacheOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	c := newMemoryCache[T, K](expiry)
+	c.maxEntries = options.maxEntries
+	if options.sweepInterval <= 0 {
+		return c
+	}
+
+	stop := make(chan struct{})
+	go c.sweepEvery(options.sweepInterval, stop)
+	swept := &sweptCache[T, K]{c}
+	runtime.SetFinalizer(swept, func(*sweptCache[T, K]) { close(stop) })
+	return swept
 }
 
 func newMemoryCache[T ICacheable[K], K comparable](expiry time.Duration) *memoryCache[T, K] {
 	return &memoryCache[T, K]{
-		data:    make(map[K]T),
-		dataTTL: make(map[K]time.Time),
+		entries: make(map[K]*list.Element),
+		recency: list.New(),
 		expiry:  expiry,
 		clock:   time.Now,
 	}
 }
 
 func (c *memoryCache[T, K]) Peek(key K) (t T, ok bool) {
-	expiryTime, ok := c.dataTTL[key]
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.entries[key]
 	if !ok {
+		c.stats.Misses++
 		return
 	}
-	if c.clock().After(expiryTime) {
-		ok = false
-		return
+	entry := elem.Value.(*cacheEntry[T, K])
+	if c.clock().After(entry.expires) {
+		c.remove(elem)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return t, false
 	}
 
-	t, ok = c.data[key]
-	return
+	c.recency.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.value, true
 }
 
 func (c *memoryCache[T, K]) Put(data T) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	key := data.CacheKey()
-	c.dataTTL[key] = time.Now().Add(c.expiry)
-	c.data[key] = data
+	entry := &cacheEntry[T, K]{key, data, c.clock().Add(c.expiry)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.recency.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.recency.PushFront(entry)
+
+	if c.maxEntries > 0 && c.recency.Len() > c.maxEntries {
+		c.remove(c.recency.Back())
+		c.stats.Evictions++
+	}
 }
 
 func (c *memoryCache[T, K]) Delete(key K) {
-	delete(c.dataTTL, key)
-	delete(c.data, key)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
 }
 
 func (c *memoryCache[T, K]) Drop() {
-	c.data = make(map[K]T)
-	c.dataTTL = make(map[K]time.Time)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[K]*list.Element)
+	c.recency.Init()
+}
+
+func (c *memoryCache[T, K]) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := c.stats
+	stats.Size = c.recency.Len()
+	return stats
+}
+
+// remove takes an entry out of the cache. The caller must hold the mutex.
+func (c *memoryCache[T, K]) remove(elem *list.Element) {
+	c.recency.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry[T, K]).key)
+}
+
+// sweep removes every expired entry.
+func (c *memoryCache[T, K]) sweep() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.clock()
+	for elem := c.recency.Front(); elem != nil; {
+		next := elem.Next()
+		if now.After(elem.Value.(*cacheEntry[T, K]).expires) {
+			c.remove(elem)
+			c.stats.Expirations++
+		}
+		elem = next
+	}
+}
+
+func (c *memoryCache[T, K]) sweepEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.sweep()
+		case <-stop:
+			return
+		}
+	}
 }
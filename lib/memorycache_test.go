@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -50,3 +52,64 @@ func Test_memoryCache(t *testing.T) {
 	assert.False(t, ok)
 
 }
+
+func Test_memoryCache_maxEntries(t *testing.T) {
+	cache := NewCache[animal, string](time.Hour, WithMaxEntries(2))
+	cache.Put(dog)
+	cache.Put(cat)
+
+	// Looking up dog makes cat the least recently used
+	_, ok := cache.Peek(dog.CacheKey())
+	assert.True(t, ok)
+	cache.Put(animal("moo"))
+
+	_, ok = cache.Peek(cat.CacheKey())
+	assert.False(t, ok)
+	_, ok = cache.Peek(dog.CacheKey())
+	assert.True(t, ok)
+
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Evictions: 1, Size: 2}, cache.Stats())
+}
+
+func Test_memoryCache_sweep(t *testing.T) {
+	clock := FrozenNow(t)
+	cache := newMemoryCache[animal, string](time.Minute)
+	cache.clock = clock.Now
+	cache.Put(dog)
+	clock.Add(30 * time.Second)
+	cache.Put(cat)
+
+	clock.Add(45 * time.Second)
+	cache.sweep()
+	assert.Equal(t, CacheStats{Expirations: 1, Size: 1}, cache.Stats())
+	_, ok := cache.Peek(cat.CacheKey())
+	assert.True(t, ok)
+}
+
+func Test_NewCache_sweepsInBackground(t *testing.T) {
+	cache := NewCache[animal, string](time.Millisecond, WithSweepInterval(time.Millisecond))
+	cache.Put(dog)
+	assert.Eventually(t, func() bool {
+		return cache.Stats().Size == 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), cache.Stats().Expirations)
+}
+
+func Test_memoryCache_concurrent(t *testing.T) {
+	cache := NewCache[animal, string](time.Hour, WithMaxEntries(10))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				a := animal(fmt.Sprint(i*100 + j))
+				cache.Put(a)
+				cache.Peek(a.CacheKey())
+				cache.Delete(a.CacheKey())
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int64(800), cache.Stats().Hits)
+}
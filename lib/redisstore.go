@@ -0,0 +1,300 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisstore.go implements ICacheStore for Redis, and servers that speak its protocol (RESP) such
+// as Valkey or KeyDB, without a client library.
+
+var (
+	ErrRedisURL   = errors.New("invalid redis URL")
+	ErrRedisReply = errors.New("unexpected reply from redis")
+)
+
+const (
+	// redisMaxIdle is how many connections are kept open between calls.
+	redisMaxIdle = 4
+	// redisScanCount hints how many keys each SCAN looks at, while deleting by prefix.
+	redisScanCount = 100
+)
+
+// redisStore implements ICacheStore with a small pool of connections to a Redis server.
+type redisStore struct {
+	addr     string
+	username string
+	password string
+	db       int
+	dialer   net.Dialer
+
+	mutex  sync.Mutex
+	idle   []*redisConn
+	closed bool
+}
+
+// NewRedisStore connects caches to the Redis server at a URL like "redis://:password@host:6379/0".
+// Connections are made as they are needed, so the server doesn't need to be up yet.
+func NewRedisStore(rawURL string) (ICacheStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRedisURL, err)
+	}
+	if u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("%w: expected redis://host:port, got %s", ErrRedisURL, u.Redacted())
+	}
+	store := &redisStore{addr: u.Host}
+	if u.Port() == "" {
+		store.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		store.username = u.User.Username()
+		store.password, _ = u.User.Password()
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		if store.db, err = strconv.Atoi(path); err != nil {
+			return nil, fmt.Errorf("%w: database should be a number, got %s", ErrRedisURL, path)
+		}
+	}
+	return store, nil
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	switch value := reply.(type) {
+	case nil:
+		return nil, false, nil
+	case []byte:
+		return value, true, nil
+	default:
+		return nil, false, fmt.Errorf("%w: GET returned %T", ErrRedisReply, reply)
+	}
+}
+
+func (r *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+func (r *redisStore) Delete(ctx context.Context, key string) error {
+	_, err := r.do(ctx, "DEL", key)
+	return err
+}
+
+// DeletePrefix scans for the keys with the prefix and deletes each batch, since Redis has no
+// command to delete by pattern. Keys written during the scan may be left behind.
+func (r *redisStore) DeletePrefix(ctx context.Context, prefix string) error {
+	pattern := redisGlobEscape(prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := r.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", strconv.Itoa(redisScanCount))
+		if err != nil {
+			return err
+		}
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 2 {
+			return fmt.Errorf("%w: SCAN returned %v", ErrRedisReply, reply)
+		}
+		next, ok := parts[0].([]byte)
+		keys, ok2 := parts[1].([]any)
+		if !ok || !ok2 {
+			return fmt.Errorf("%w: SCAN returned %v", ErrRedisReply, reply)
+		}
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, key := range keys {
+				if key, ok := key.([]byte); ok {
+					args = append(args, string(key))
+				}
+			}
+			if _, err := r.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+		if cursor = string(next); cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// Close closes the idle connections, and those in use as they are returned.
+func (r *redisStore) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.closed = true
+	for _, conn := range r.idle {
+		conn.Close()
+	}
+	r.idle = nil
+	return nil
+}
+
+// do sends a command and reads its reply. Connections that fail are closed rather than reused, as
+// they may be left partway through a reply.
+func (r *redisStore) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(ctx, args...)
+	var serverErr redisError
+	if err != nil && !errors.As(err, &serverErr) {
+		conn.Close()
+		return nil, err
+	}
+	r.release(conn)
+	return reply, err
+}
+
+// conn takes an idle connection, or opens and sets up a new one.
+func (r *redisStore) conn(ctx context.Context) (*redisConn, error) {
+	r.mutex.Lock()
+	if n := len(r.idle); n > 0 {
+		conn := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mutex.Unlock()
+		return conn, nil
+	}
+	r.mutex.Unlock()
+
+	netConn, err := r.dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	if r.password != "" {
+		args := []string{"AUTH", r.password}
+		if r.username != "" {
+			args = []string{"AUTH", r.username, r.password}
+		}
+		if _, err := conn.do(ctx, args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if r.db != 0 {
+		if _, err := conn.do(ctx, "SELECT", strconv.Itoa(r.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// release returns a connection to the pool, or closes it if the pool is full or closed.
+func (r *redisStore) release(conn *redisConn) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed || len(r.idle) >= redisMaxIdle {
+		conn.Close()
+		return
+	}
+	r.idle = append(r.idle, conn)
+}
+
+// redisError is an error reply from the server, after which the connection is still usable.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisConn is a connection that speaks RESP.
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// do writes a command as an array of bulk strings, and reads the reply.
+func (c *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c, sb.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.reader)
+}
+
+// readRedisReply reads a reply as nil, a string for simple strings, []byte for bulk strings, an
+// int64 for integers, or []any for arrays. Error replies are returned as a redisError.
+func readRedisReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("%w: empty line", ErrRedisReply)
+	}
+
+	kind, rest := line[0], line[1:]
+	switch kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, redisError(rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(rest)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(rest)
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = readRedisReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrRedisReply, line)
+	}
+}
+
+// redisGlobEscape escapes the characters that SCAN MATCH patterns treat as special.
+func redisGlobEscape(s string) string {
+	var sb strings.Builder
+	for _, ch := range s {
+		if strings.ContainsRune(`*?[]\`, ch) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
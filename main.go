@@ -15,6 +15,12 @@ var (
 	ConfigFilePath = flag.String("config-file", "", "Config file path")
 )
 
+// Maintenance commands, which exit instead of starting the bot
+var (
+	MigrationStatus = flag.Bool("migration-status", false, "Print the status of database migrations")
+	RollbackTo      = flag.String("rollback-to", "", "Roll back database migrations newer than this version")
+)
+
 func assertFlags() {
 	if *ConfigFilePath == "" {
 		flag.Usage()
@@ -37,6 +43,12 @@ func main() {
 		flag.Parse()
 		assertFlags()
 
+		switch {
+		case *MigrationStatus:
+			return app.MigrationStatus(app.DefaultInjector{}, *ConfigFilePath)
+		case *RollbackTo != "":
+			return app.RollbackMigrations(app.DefaultInjector{}, *ConfigFilePath, *RollbackTo)
+		}
 		return app.Main(app.DefaultInjector{}, *ConfigFilePath)
 	}
 	err, errPanic := panicWatch(run)
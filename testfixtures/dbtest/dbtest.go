@@ -0,0 +1,103 @@
+// Package dbtest runs integration tests against every database backend that IDatabase supports.
+//
+// SQLite runs in memory and is always tested. Postgres is tested if TEST_POSTGRES_DSN points at a
+// server; each test gets a schema of its own there, which is dropped when the test completes.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fiffu/arisa3/app/database"
+	"github.com/fiffu/arisa3/app/engine"
+)
+
+// Set this env var to the DSN of a Postgres server to also run integration tests against it.
+const EnvPostgresDSN = "TEST_POSTGRES_DSN"
+
+// ForEachBackend runs the test once per backend as a subtest, on a fresh database that has the
+// migrations of the given repositories applied.
+func ForEachBackend(t *testing.T, test func(t *testing.T, db database.IDatabase), repos ...engine.IRepository) {
+	t.Run(database.DialectSQLite, func(t *testing.T) {
+		db := open(t, "sqlite::memory:")
+		migrate(t, db, repos)
+		test(t, db)
+	})
+	t.Run(database.DialectPostgres, func(t *testing.T) {
+		dsn := os.Getenv(EnvPostgresDSN)
+		if dsn == "" {
+			t.Skipf("set %s to run against Postgres", EnvPostgresDSN)
+		}
+		db := open(t, withSchema(t, dsn))
+		migrate(t, db, repos)
+		test(t, db)
+	})
+}
+
+func open(t *testing.T, dsn string) database.IDatabase {
+	t.Helper()
+	db, err := database.NewDBClient(context.Background(), dsn, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(context.Background()) })
+	return db
+}
+
+func migrate(t *testing.T, db database.IDatabase, repos []engine.IRepository) {
+	t.Helper()
+	ctx := context.Background()
+	for _, repo := range repos {
+		schemas, err := database.LoadMigrations(ctx, db, repo.Name(), repo.MigrationsFS())
+		if err != nil {
+			t.Fatalf("failed to load migrations for %s: %v", repo.Name(), err)
+		}
+		for _, schema := range schemas {
+			if _, err := db.Migrate(ctx, schema); err != nil {
+				t.Fatalf("failed to apply migration %s: %v", schema.Source(), err)
+			}
+		}
+	}
+}
+
+// withSchema creates a schema for the test, and returns the DSN with it as the search path.
+func withSchema(t *testing.T, dsn string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	schema := "dbtest_" + hex.EncodeToString(suffix)
+
+	admin := open(t, dsn)
+	if _, err := admin.Exec(ctx, fmt.Sprintf(`CREATE SCHEMA "%s"`, schema)); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(ctx, fmt.Sprintf(`DROP SCHEMA "%s" CASCADE`, schema)); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+	})
+
+	// lib/pq passes unknown parameters on as run-time settings
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", EnvPostgresDSN, err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,260 @@
+package discordtest
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+
+	dgo "github.com/bwmarrin/discordgo"
+)
+
+// route handles a REST request. Path parameters are passed in the order they appear in the path.
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+	handler func(s *Server, call Call, params []string) (status int, body any)
+}
+
+var routes = []route{
+	{"GET", regexp.MustCompile(`^/gateway$`), (*Server).getGateway},
+	{"POST", regexp.MustCompile(`^/applications/(\d+)/commands$`), (*Server).createCommand},
+	{"POST", regexp.MustCompile(`^/interactions/(\d+)/([^/]+)/callback$`), (*Server).interactionCallback},
+	{"PATCH", regexp.MustCompile(`^/webhooks/(\d+)/([^/]+)/messages/@original$`), (*Server).editInteractionResponse},
+	{"GET", regexp.MustCompile(`^/guilds/(\d+)/roles$`), (*Server).getRoles},
+	{"POST", regexp.MustCompile(`^/guilds/(\d+)/roles$`), (*Server).createRole},
+	{"PATCH", regexp.MustCompile(`^/guilds/(\d+)/roles$`), (*Server).reorderRoles},
+	{"PATCH", regexp.MustCompile(`^/guilds/(\d+)/roles/(\d+)$`), (*Server).editRole},
+	{"DELETE", regexp.MustCompile(`^/guilds/(\d+)/roles/(\d+)$`), (*Server).deleteRole},
+	{"GET", regexp.MustCompile(`^/guilds/(\d+)/members$`), (*Server).listMembers},
+	{"GET", regexp.MustCompile(`^/guilds/(\d+)/members/(\d+)$`), (*Server).getMember},
+	{"PUT", regexp.MustCompile(`^/guilds/(\d+)/members/(\d+)/roles/(\d+)$`), (*Server).addMemberRole},
+	{"DELETE", regexp.MustCompile(`^/guilds/(\d+)/members/(\d+)/roles/(\d+)$`), (*Server).removeMemberRole},
+}
+
+// serveAPI records the request and dispatches it to the matching route.
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	call := Call{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Body: readBody(r), ContentType: r.Header.Get("Content-Type")}
+	defer s.record(call)
+
+	for _, rt := range routes {
+		if rt.method != r.Method {
+			continue
+		}
+		match := rt.pattern.FindStringSubmatch(r.URL.Path)
+		if match == nil {
+			continue
+		}
+		status, body := rt.handler(s, call, match[1:])
+		writeJSON(w, status, body)
+		return
+	}
+
+	s.t.Errorf("discordtest: unhandled request %s", call)
+	writeJSON(w, http.StatusNotFound, apiError(10000, "Unknown endpoint"))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func apiError(code int, message string) map[string]any {
+	return map[string]any{"code": code, "message": message}
+}
+
+func (s *Server) getGateway(Call, []string) (int, any) {
+	return http.StatusOK, map[string]string{"url": s.gw.url()}
+}
+
+func (s *Server) createCommand(call Call, _ []string) (int, any) {
+	cmd := &dgo.ApplicationCommand{}
+	if err := call.JSON(cmd); err != nil {
+		return http.StatusBadRequest, apiError(50035, err.Error())
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	cmd.ID = s.nextID()
+	cmd.ApplicationID = AppID
+	s.commands = append(s.commands, cmd)
+	return http.StatusCreated, cmd
+}
+
+func (s *Server) interactionCallback(Call, []string) (int, any) {
+	return http.StatusNoContent, nil
+}
+
+func (s *Server) editInteractionResponse(call Call, _ []string) (int, any) {
+	var body dgo.WebhookEdit
+	if err := call.JSON(&body); err != nil {
+		return http.StatusBadRequest, apiError(50035, err.Error())
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	msg := &dgo.Message{ID: s.nextID()}
+	if body.Content != nil {
+		msg.Content = *body.Content
+	}
+	return http.StatusOK, msg
+}
+
+func (s *Server) getRoles(_ Call, params []string) (int, any) {
+	return http.StatusOK, s.Roles(params[0])
+}
+
+func (s *Server) createRole(call Call, params []string) (int, any) {
+	var body dgo.RoleParams
+	if err := call.JSON(&body); err != nil {
+		return http.StatusBadRequest, apiError(50035, err.Error())
+	}
+	role := &dgo.Role{Name: body.Name}
+	if body.Color != nil {
+		role.Color = *body.Color
+	}
+	return http.StatusOK, s.AddRole(params[0], role)
+}
+
+func (s *Server) reorderRoles(call Call, params []string) (int, any) {
+	var positions []struct {
+		ID       string `json:"id"`
+		Position int    `json:"position"`
+	}
+	if err := call.JSON(&positions); err != nil {
+		return http.StatusBadRequest, apiError(50035, err.Error())
+	}
+
+	s.mutex.Lock()
+	g := s.ensureGuild(params[0])
+	for _, pos := range positions {
+		role := findRole(g, pos.ID)
+		if role == nil {
+			s.mutex.Unlock()
+			return http.StatusNotFound, apiError(10011, "Unknown Role")
+		}
+		role.Position = pos.Position
+	}
+	s.mutex.Unlock()
+	return http.StatusOK, s.Roles(params[0])
+}
+
+func (s *Server) editRole(call Call, params []string) (int, any) {
+	var body dgo.RoleParams
+	if err := call.JSON(&body); err != nil {
+		return http.StatusBadRequest, apiError(50035, err.Error())
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	role := findRole(s.ensureGuild(params[0]), params[1])
+	if role == nil {
+		return http.StatusNotFound, apiError(10011, "Unknown Role")
+	}
+	if body.Name != "" {
+		role.Name = body.Name
+	}
+	if body.Color != nil {
+		role.Color = *body.Color
+	}
+	copied := *role
+	return http.StatusOK, &copied
+}
+
+func (s *Server) deleteRole(_ Call, params []string) (int, any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	g := s.ensureGuild(params[0])
+	roleID := params[1]
+	if findRole(g, roleID) == nil {
+		return http.StatusNotFound, apiError(10011, "Unknown Role")
+	}
+	g.roles = slices.DeleteFunc(g.roles, func(r *dgo.Role) bool { return r.ID == roleID })
+	for _, mem := range g.members {
+		mem.Roles = slices.DeleteFunc(mem.Roles, func(id string) bool { return id == roleID })
+	}
+	return http.StatusNoContent, nil
+}
+
+func (s *Server) getMember(_ Call, params []string) (int, any) {
+	mem := s.Member(params[0], params[1])
+	if mem == nil {
+		return http.StatusNotFound, apiError(10007, "Unknown Member")
+	}
+	return http.StatusOK, mem
+}
+
+// listMembers pages through members in order of user ID, like Discord does.
+func (s *Server) listMembers(call Call, params []string) (int, any) {
+	limit := 1
+	if l := call.Query.Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil {
+			return http.StatusBadRequest, apiError(50035, err.Error())
+		}
+	}
+	after, _ := strconv.ParseInt(call.Query.Get("after"), 10, 64)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	members := make([]*dgo.Member, 0)
+	for _, mem := range s.ensureGuild(params[0]).members {
+		if id, _ := strconv.ParseInt(mem.User.ID, 10, 64); id > after {
+			copied := *mem
+			copied.Roles = append([]string{}, mem.Roles...)
+			members = append(members, &copied)
+		}
+	}
+	slices.SortFunc(members, func(a, b *dgo.Member) int {
+		x, _ := strconv.ParseInt(a.User.ID, 10, 64)
+		y, _ := strconv.ParseInt(b.User.ID, 10, 64)
+		return cmp.Compare(x, y)
+	})
+	if len(members) > limit {
+		members = members[:limit]
+	}
+	return http.StatusOK, members
+}
+
+func (s *Server) addMemberRole(_ Call, params []string) (int, any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	g := s.ensureGuild(params[0])
+	mem, ok := g.members[params[1]]
+	if !ok {
+		return http.StatusNotFound, apiError(10007, "Unknown Member")
+	}
+	if findRole(g, params[2]) == nil {
+		return http.StatusNotFound, apiError(10011, "Unknown Role")
+	}
+	if !slices.Contains(mem.Roles, params[2]) {
+		mem.Roles = append(mem.Roles, params[2])
+	}
+	return http.StatusNoContent, nil
+}
+
+func (s *Server) removeMemberRole(_ Call, params []string) (int, any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	mem, ok := s.ensureGuild(params[0]).members[params[1]]
+	if !ok {
+		return http.StatusNotFound, apiError(10007, "Unknown Member")
+	}
+	mem.Roles = slices.DeleteFunc(mem.Roles, func(id string) bool { return id == params[2] })
+	return http.StatusNoContent, nil
+}
+
+func findRole(g *guild, roleID string) *dgo.Role {
+	for _, role := range g.roles {
+		if role.ID == roleID {
+			return role
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package discordtest
+
+import (
+	"encoding/json"
+
+	dgo "github.com/bwmarrin/discordgo"
+)
+
+// Dispatch sends a gateway event to all connected sessions. The event type is the name used by
+// the gateway, e.g. "GUILD_ROLE_DELETE", and data is marshalled as the event payload.
+func (s *Server) Dispatch(eventType string, data any) {
+	s.t.Helper()
+	s.gw.dispatch(eventType, data)
+}
+
+// SendInteraction dispatches an INTERACTION_CREATE event. The ID and token are generated if unset,
+// so the response can be awaited with WaitForCallback.
+func (s *Server) SendInteraction(i *dgo.Interaction) *dgo.Interaction {
+	s.t.Helper()
+
+	s.mutex.Lock()
+	if i.ID == "" {
+		i.ID = s.nextID()
+	}
+	if i.Token == "" {
+		i.Token = "token-" + i.ID
+	}
+	i.AppID = AppID
+	s.mutex.Unlock()
+
+	s.Dispatch("INTERACTION_CREATE", i)
+	return i
+}
+
+// SendMessage dispatches a MESSAGE_CREATE event. The ID is generated if unset.
+func (s *Server) SendMessage(m *dgo.Message) *dgo.Message {
+	s.t.Helper()
+
+	s.mutex.Lock()
+	if m.ID == "" {
+		m.ID = s.nextID()
+	}
+	s.mutex.Unlock()
+
+	s.Dispatch("MESSAGE_CREATE", m)
+	return m
+}
+
+// WaitForCallback waits for the bot to respond to the interaction, and returns the response.
+func (s *Server) WaitForCallback(i *dgo.Interaction) *dgo.InteractionResponse {
+	s.t.Helper()
+
+	call := s.WaitForCall("POST", "/interactions/"+i.ID+"/"+i.Token+"/callback")
+	resp, err := decodeInteractionResponse(call)
+	if err != nil {
+		s.t.Fatalf("discordtest: failed to decode interaction response: %v", err)
+	}
+	return resp
+}
+
+// decodeInteractionResponse decodes a response along with its components, which discordgo can
+// only decode within messages.
+func decodeInteractionResponse(call Call) (*dgo.InteractionResponse, error) {
+	var raw struct {
+		Type dgo.InteractionResponseType `json:"type"`
+		Data *struct {
+			dgo.InteractionResponseData
+			Components []json.RawMessage `json:"components"`
+		} `json:"data"`
+	}
+	if err := call.JSON(&raw); err != nil {
+		return nil, err
+	}
+	resp := &dgo.InteractionResponse{Type: raw.Type}
+	if raw.Data == nil {
+		return resp, nil
+	}
+	data := raw.Data.InteractionResponseData
+	for _, rawComponent := range raw.Data.Components {
+		component, err := dgo.MessageComponentFromJSON(rawComponent)
+		if err != nil {
+			return nil, err
+		}
+		data.Components = append(data.Components, component)
+	}
+	resp.Data = &data
+	return resp, nil
+}
+
+// SlashCommand builds an interaction for a chat command invoked by a guild member.
+func SlashCommand(member *dgo.Member, name string, options ...*dgo.ApplicationCommandInteractionDataOption) *dgo.Interaction {
+	return &dgo.Interaction{
+		Type:    dgo.InteractionApplicationCommand,
+		GuildID: member.GuildID,
+		Member:  member,
+		Data: dgo.ApplicationCommandInteractionData{
+			Name:    name,
+			Options: options,
+		},
+	}
+}
+
+// ButtonClick builds an interaction for a guild member clicking a button with the given custom ID.
+func ButtonClick(member *dgo.Member, customID string) *dgo.Interaction {
+	return &dgo.Interaction{
+		Type:    dgo.InteractionMessageComponent,
+		GuildID: member.GuildID,
+		Member:  member,
+		Data: dgo.MessageComponentInteractionData{
+			CustomID:      customID,
+			ComponentType: dgo.ButtonComponent,
+		},
+	}
+}
+
+// StringOption builds a string argument for SlashCommand.
+func StringOption(name, value string) *dgo.ApplicationCommandInteractionDataOption {
+	return &dgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  dgo.ApplicationCommandOptionString,
+		Value: value,
+	}
+}
+
+// BoolOption builds a boolean argument for SlashCommand.
+func BoolOption(name string, value bool) *dgo.ApplicationCommandInteractionDataOption {
+	return &dgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  dgo.ApplicationCommandOptionBoolean,
+		Value: value,
+	}
+}
+
+// UserOption builds a user argument for SlashCommand, which interactions give by ID.
+func UserOption(name, userID string) *dgo.ApplicationCommandInteractionDataOption {
+	return &dgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  dgo.ApplicationCommandOptionUser,
+		Value: userID,
+	}
+}
+
+// IntOption builds an integer argument for SlashCommand.
+func IntOption(name string, value int) *dgo.ApplicationCommandInteractionDataOption {
+	return &dgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  dgo.ApplicationCommandOptionInteger,
+		Value: float64(value), // as decoded from JSON
+	}
+}
+
+// SubCommand builds a subcommand for SlashCommand, with its own arguments.
+func SubCommand(name string, options ...*dgo.ApplicationCommandInteractionDataOption) *dgo.ApplicationCommandInteractionDataOption {
+	return &dgo.ApplicationCommandInteractionDataOption{
+		Name:    name,
+		Type:    dgo.ApplicationCommandOptionSubCommand,
+		Options: options,
+	}
+}
+
+// GuildMessage builds a message sent by a guild member.
+func GuildMessage(member *dgo.Member, channelID, content string) *dgo.Message {
+	return &dgo.Message{
+		ChannelID: channelID,
+		GuildID:   member.GuildID,
+		Author:    member.User,
+		Member:    member,
+		Content:   content,
+	}
+}
@@ -0,0 +1,167 @@
+package discordtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	dgo "github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	opDispatch     = 0
+	opHeartbeat    = 1
+	opIdentify     = 2
+	opHello        = 10
+	opHeartbeatAck = 11
+
+	gatewayPath = "/ws/"
+
+	// Long enough that the session never needs to heartbeat during a test.
+	heartbeatIntervalMsec = 60_000
+)
+
+// payload is a gateway message.
+type payload struct {
+	Op       int             `json:"op"`
+	Data     json.RawMessage `json:"d,omitempty"`
+	Sequence int64           `json:"s,omitempty"`
+	Type     string          `json:"t,omitempty"`
+}
+
+// gateway accepts websocket connections from sessions and dispatches events to them.
+type gateway struct {
+	t        *testing.T
+	server   *Server
+	upgrader websocket.Upgrader
+
+	mutex    *sync.Mutex
+	conns    []*gatewayConn
+	sequence int64
+}
+
+type gatewayConn struct {
+	mutex *sync.Mutex
+	ws    *websocket.Conn
+}
+
+func (c *gatewayConn) write(p payload) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ws.WriteJSON(p)
+}
+
+func newGateway(t *testing.T, s *Server) *gateway {
+	return &gateway{
+		t:      t,
+		server: s,
+		mutex:  &sync.Mutex{},
+	}
+}
+
+// url returns the websocket URL that the fake gateway listens on.
+func (g *gateway) url() string {
+	return "ws" + strings.TrimPrefix(g.server.URL(), "http") + gatewayPath
+}
+
+// serveWebsocket performs the Hello/Identify/Ready handshake, then answers heartbeats until the
+// session disconnects.
+func (g *gateway) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		g.t.Errorf("discordtest: failed to upgrade gateway connection: %v", err)
+		return
+	}
+	conn := &gatewayConn{mutex: &sync.Mutex{}, ws: ws}
+	defer ws.Close()
+
+	hello, _ := json.Marshal(map[string]int{"heartbeat_interval": heartbeatIntervalMsec})
+	if err := conn.write(payload{Op: opHello, Data: hello}); err != nil {
+		return
+	}
+
+	var identify payload
+	if err := ws.ReadJSON(&identify); err != nil || identify.Op != opIdentify {
+		g.t.Errorf("discordtest: expected identify from session, got op=%d err=%v", identify.Op, err)
+		return
+	}
+
+	// Register the connection before READY, as the session may return from Open() and have events
+	// dispatched to it before this goroutine resumes.
+	g.mutex.Lock()
+	g.conns = append(g.conns, conn)
+	g.mutex.Unlock()
+
+	if err := g.dispatchTo(conn, "READY", g.server.readyEvent()); err != nil {
+		return
+	}
+
+	for {
+		var msg payload
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.Op == opHeartbeat {
+			conn.write(payload{Op: opHeartbeatAck})
+		}
+	}
+}
+
+// dispatch sends an event to every connected session.
+func (g *gateway) dispatch(eventType string, data any) {
+	g.mutex.Lock()
+	conns := append([]*gatewayConn{}, g.conns...)
+	g.mutex.Unlock()
+
+	if len(conns) == 0 {
+		g.t.Fatalf("discordtest: no sessions connected to dispatch %s to", eventType)
+	}
+	for _, conn := range conns {
+		if err := g.dispatchTo(conn, eventType, data); err != nil {
+			g.t.Fatalf("discordtest: failed to dispatch %s: %v", eventType, err)
+		}
+	}
+}
+
+func (g *gateway) dispatchTo(conn *gatewayConn, eventType string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	g.mutex.Lock()
+	g.sequence++
+	seq := g.sequence
+	g.mutex.Unlock()
+
+	return conn.write(payload{Op: opDispatch, Type: eventType, Sequence: seq, Data: raw})
+}
+
+func (g *gateway) close() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for _, conn := range g.conns {
+		conn.ws.Close()
+	}
+	g.conns = nil
+}
+
+// readyEvent is sent to sessions after they identify.
+func (s *Server) readyEvent() *dgo.Ready {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	guilds := make([]*dgo.Guild, 0, len(s.guilds))
+	for id := range s.guilds {
+		guilds = append(guilds, &dgo.Guild{ID: id, Unavailable: true})
+	}
+	return &dgo.Ready{
+		Version:   9,
+		SessionID: "test-session",
+		User:      &dgo.User{ID: BotUserID, Username: "arisa", Discriminator: "0", Bot: true},
+		Guilds:    guilds,
+	}
+}
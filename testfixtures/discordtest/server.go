@@ -0,0 +1,328 @@
+// package discordtest runs an in-process stand-in for the Discord REST API and gateway, so that
+// tests can drive a real discordgo.Session through the bot's handlers without network access.
+package discordtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dgo "github.com/bwmarrin/discordgo"
+)
+
+const (
+	BotUserID = "100000000000000001"
+	AppID     = BotUserID
+
+	// How long WaitForCall waits before failing the test.
+	defaultWaitTimeout = 5 * time.Second
+)
+
+// Call is a REST request received by the Server.
+type Call struct {
+	Method string
+	// Path is relative to the API root, e.g. "/guilds/123/roles".
+	Path  string
+	Query url.Values
+	Body  []byte
+	// ContentType is multipart/form-data when the request attaches files.
+	ContentType string
+}
+
+// JSON decodes the request body, or its payload_json part if it attaches files.
+func (c Call) JSON(out any) error {
+	parts, err := c.parts()
+	if err != nil {
+		return err
+	}
+	if parts == nil {
+		return json.Unmarshal(c.Body, out)
+	}
+	return json.Unmarshal(parts["payload_json"], out)
+}
+
+// Files returns the files attached to the request, by filename.
+func (c Call) Files() (map[string][]byte, error) {
+	parts, err := c.parts()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for name, content := range parts {
+		if strings.HasPrefix(name, "file:") {
+			files[strings.TrimPrefix(name, "file:")] = content
+		}
+	}
+	return files, nil
+}
+
+// parts reads a multipart body, keyed by form field name or by "file:" and the filename. It is
+// nil for other bodies.
+func (c Call) parts() (map[string][]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(c.ContentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, nil
+	}
+	parts := make(map[string][]byte)
+	reader := multipart.NewReader(bytes.NewReader(c.Body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() != "" {
+			parts["file:"+part.FileName()] = content
+		} else {
+			parts[part.FormName()] = content
+		}
+	}
+}
+
+func (c Call) String() string {
+	return c.Method + " " + c.Path
+}
+
+// Server fakes the parts of the Discord API used by the bot: application commands, interaction
+// callbacks, guild roles and guild members. State is kept in memory and mutated by requests.
+type Server struct {
+	t    *testing.T
+	http *httptest.Server
+	gw   *gateway
+
+	mutex    *sync.Mutex
+	calls    []Call
+	notify   chan struct{}
+	lastID   int64
+	guilds   map[string]*guild
+	commands []*dgo.ApplicationCommand
+}
+
+type guild struct {
+	roles   []*dgo.Role
+	members map[string]*dgo.Member
+}
+
+// NewServer starts a Server that is closed when the test completes.
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+	s := &Server{
+		t:      t,
+		mutex:  &sync.Mutex{},
+		notify: make(chan struct{}),
+		lastID: 200000000000000000,
+		guilds: make(map[string]*guild),
+	}
+	s.gw = newGateway(t, s)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(gatewayPath, s.gw.serveWebsocket)
+	mux.HandleFunc("/", s.serveAPI)
+	s.http = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.gw.close()
+	s.http.Close()
+}
+
+// URL is the base URL of the server.
+func (s *Server) URL() string {
+	return s.http.URL
+}
+
+// NewSession creates a session whose REST and gateway traffic is routed to the server. As in
+// production, event handlers run in their own goroutines, so use WaitForCall to observe their effects.
+func (s *Server) NewSession() *dgo.Session {
+	sess, err := dgo.New("Bot test-token")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	sess.ShouldReconnectOnError = false
+	sess.Client = &http.Client{Transport: &rewriteTransport{target: s.http.URL}}
+	return sess
+}
+
+// Open opens the session's gateway connection, closing it when the test completes.
+func (s *Server) Open(sess *dgo.Session) {
+	s.t.Helper()
+	if err := sess.Open(); err != nil {
+		s.t.Fatalf("failed to open session: %v", err)
+	}
+	s.t.Cleanup(func() { sess.Close() })
+}
+
+// nextID generates a snowflake-like ID.
+func (s *Server) nextID() string {
+	s.lastID++
+	return fmt.Sprint(s.lastID)
+}
+
+func (s *Server) ensureGuild(guildID string) *guild {
+	g, ok := s.guilds[guildID]
+	if !ok {
+		g = &guild{
+			roles:   []*dgo.Role{{ID: guildID, Name: "@everyone"}},
+			members: make(map[string]*dgo.Member),
+		}
+		s.guilds[guildID] = g
+	}
+	return g
+}
+
+// AddRole adds a role to the guild, after any existing roles.
+func (s *Server) AddRole(guildID string, role *dgo.Role) *dgo.Role {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	g := s.ensureGuild(guildID)
+	if role.ID == "" {
+		role.ID = s.nextID()
+	}
+	role.Position = len(g.roles)
+	g.roles = append(g.roles, role)
+	return role
+}
+
+// AddMember adds a member to the guild.
+func (s *Server) AddMember(guildID string, member *dgo.Member) *dgo.Member {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	member.GuildID = guildID
+	s.ensureGuild(guildID).members[member.User.ID] = member
+	return member
+}
+
+// Roles returns a copy of the guild's roles, ordered by position.
+func (s *Server) Roles(guildID string) []*dgo.Role {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	roles := s.sortedRoles(s.ensureGuild(guildID))
+	for i, role := range roles {
+		copied := *role
+		roles[i] = &copied
+	}
+	return roles
+}
+
+// Member returns a copy of a guild member, or nil if not found.
+func (s *Server) Member(guildID, userID string) *dgo.Member {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	mem, ok := s.ensureGuild(guildID).members[userID]
+	if !ok {
+		return nil
+	}
+	copied := *mem
+	copied.Roles = append([]string{}, mem.Roles...)
+	return &copied
+}
+
+// Commands returns the application commands that were created.
+func (s *Server) Commands() []*dgo.ApplicationCommand {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*dgo.ApplicationCommand{}, s.commands...)
+}
+
+// Calls returns the REST requests received so far.
+func (s *Server) Calls() []Call {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Call{}, s.calls...)
+}
+
+// FindCalls returns the REST requests with the given method, whose path matches the pattern.
+func (s *Server) FindCalls(method, pathPattern string) []Call {
+	pattern := regexp.MustCompile("^" + pathPattern + "$")
+	found := make([]Call, 0)
+	for _, call := range s.Calls() {
+		if call.Method == method && pattern.MatchString(call.Path) {
+			found = append(found, call)
+		}
+	}
+	return found
+}
+
+// WaitForCall waits until a matching REST request is received, failing the test on timeout.
+func (s *Server) WaitForCall(method, pathPattern string) Call {
+	s.t.Helper()
+	deadline := time.After(defaultWaitTimeout)
+	for {
+		s.mutex.Lock()
+		notify := s.notify
+		s.mutex.Unlock()
+
+		if found := s.FindCalls(method, pathPattern); len(found) > 0 {
+			return found[0]
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			s.t.Fatalf("timed out waiting for %s %s, got calls: %v", method, pathPattern, s.Calls())
+			return Call{}
+		}
+	}
+}
+
+func (s *Server) record(call Call) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls = append(s.calls, call)
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+func (s *Server) sortedRoles(g *guild) []*dgo.Role {
+	roles := append([]*dgo.Role{}, g.roles...)
+	sort.SliceStable(roles, func(i, j int) bool { return roles[i].Position < roles[j].Position })
+	return roles
+}
+
+// rewriteTransport redirects requests for the Discord API to the fake server.
+type rewriteTransport struct {
+	target string
+}
+
+func (rt *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := strings.TrimPrefix(req.URL.String(), dgo.EndpointAPI)
+	if path == req.URL.String() {
+		return nil, fmt.Errorf("discordtest: unexpected request outside the Discord API: %s", path)
+	}
+	url := rt.target + "/" + path
+	proxied, err := http.NewRequestWithContext(req.Context(), req.Method, url, req.Body)
+	if err != nil {
+		return nil, err
+	}
+	proxied.Header = req.Header
+	return http.DefaultTransport.RoundTrip(proxied)
+}
+
+func readBody(r *http.Request) []byte {
+	body, _ := io.ReadAll(r.Body)
+	return body
+}
@@ -0,0 +1,173 @@
+package discordtest
+
+import (
+	"strings"
+	"testing"
+
+	dgo "github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Server_Open(t *testing.T) {
+	srv := NewServer(t)
+	sess := srv.NewSession()
+
+	ready := make(chan *dgo.Ready, 1)
+	sess.AddHandler(func(s *dgo.Session, r *dgo.Ready) { ready <- r })
+	srv.Open(sess)
+
+	assert.Equal(t, BotUserID, (<-ready).User.ID)
+	assert.Equal(t, BotUserID, sess.State.User.ID)
+}
+
+func Test_Server_SendInteraction(t *testing.T) {
+	srv := NewServer(t)
+	member := srv.AddMember("1", &dgo.Member{User: &dgo.User{ID: "2", Username: "someone"}})
+	srv.AddRole("1", &dgo.Role{ID: "3", Name: "existing"})
+
+	sess := srv.NewSession()
+	sess.AddHandler(func(s *dgo.Session, i *dgo.InteractionCreate) {
+		opt := i.ApplicationCommandData().Options[0].StringValue()
+		role, err := s.GuildRoleCreate(i.GuildID, &dgo.RoleParams{Name: opt})
+		assert.NoError(t, err)
+		assert.NoError(t, s.GuildMemberRoleAdd(i.GuildID, i.Member.User.ID, role.ID))
+		assert.NoError(t, s.InteractionRespond(i.Interaction, &dgo.InteractionResponse{
+			Type: dgo.InteractionResponseChannelMessageWithSource,
+			Data: &dgo.InteractionResponseData{Content: "made " + role.Name},
+		}))
+	})
+	srv.Open(sess)
+
+	itr := srv.SendInteraction(SlashCommand(member, "role", StringOption("name", "fancy")))
+	resp := srv.WaitForCallback(itr)
+	assert.Equal(t, "made fancy", resp.Data.Content)
+
+	roles := srv.Roles("1")
+	assert.Len(t, roles, 3)
+	assert.Equal(t, "fancy", roles[2].Name)
+	assert.Equal(t, []string{roles[2].ID}, srv.Member("1", "2").Roles)
+	assert.Len(t, srv.FindCalls("POST", `/guilds/1/roles`), 1)
+}
+
+func Test_Server_SendInteraction_files(t *testing.T) {
+	srv := NewServer(t)
+	member := srv.AddMember("1", &dgo.Member{User: &dgo.User{ID: "2", Username: "someone"}})
+
+	sess := srv.NewSession()
+	sess.AddHandler(func(s *dgo.Session, i *dgo.InteractionCreate) {
+		assert.NoError(t, s.InteractionRespond(i.Interaction, &dgo.InteractionResponse{
+			Type: dgo.InteractionResponseChannelMessageWithSource,
+			Data: &dgo.InteractionResponseData{
+				Content: "attached",
+				Files:   []*dgo.File{{Name: "hello.txt", ContentType: "text/plain", Reader: strings.NewReader("hello")}},
+			},
+		}))
+	})
+	srv.Open(sess)
+
+	itr := srv.SendInteraction(SlashCommand(member, "attach"))
+	resp := srv.WaitForCallback(itr)
+	assert.Equal(t, "attached", resp.Data.Content)
+
+	files, err := srv.WaitForCall("POST", "/interactions/"+itr.ID+"/"+itr.Token+"/callback").Files()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"hello.txt": []byte("hello")}, files)
+}
+
+func Test_Server_SendInteraction_buttons(t *testing.T) {
+	srv := NewServer(t)
+	member := srv.AddMember("1", &dgo.Member{User: &dgo.User{ID: "2", Username: "someone"}})
+
+	sess := srv.NewSession()
+	sess.AddHandler(func(s *dgo.Session, i *dgo.InteractionCreate) {
+		if i.Type == dgo.InteractionMessageComponent {
+			assert.NoError(t, s.InteractionRespond(i.Interaction, &dgo.InteractionResponse{
+				Type: dgo.InteractionResponseUpdateMessage,
+				Data: &dgo.InteractionResponseData{Content: "clicked " + i.MessageComponentData().CustomID},
+			}))
+			return
+		}
+		assert.NoError(t, s.InteractionRespond(i.Interaction, &dgo.InteractionResponse{
+			Type: dgo.InteractionResponseChannelMessageWithSource,
+			Data: &dgo.InteractionResponseData{
+				Content: "click it",
+				Components: []dgo.MessageComponent{dgo.ActionsRow{Components: []dgo.MessageComponent{
+					dgo.Button{Label: "It", CustomID: "it", Style: dgo.PrimaryButton},
+				}}},
+			},
+		}))
+		content := "too late"
+		_, err := s.InteractionResponseEdit(i.Interaction, &dgo.WebhookEdit{Content: &content})
+		assert.NoError(t, err)
+	})
+	srv.Open(sess)
+
+	itr := srv.SendInteraction(SlashCommand(member, "prompt"))
+	resp := srv.WaitForCallback(itr)
+	if assert.Len(t, resp.Data.Components, 1) {
+		row := resp.Data.Components[0].(*dgo.ActionsRow)
+		assert.Equal(t, "it", row.Components[0].(*dgo.Button).CustomID)
+	}
+	edit := srv.WaitForCall("PATCH", "/webhooks/"+AppID+"/"+itr.Token+"/messages/@original")
+	assert.Contains(t, string(edit.Body), "too late")
+
+	click := srv.SendInteraction(ButtonClick(member, "it"))
+	resp = srv.WaitForCallback(click)
+	assert.Equal(t, dgo.InteractionResponseUpdateMessage, resp.Type)
+	assert.Equal(t, "clicked it", resp.Data.Content)
+}
+
+func Test_Server_SendMessage(t *testing.T) {
+	srv := NewServer(t)
+	member := srv.AddMember("1", &dgo.Member{User: &dgo.User{ID: "2"}})
+
+	sess := srv.NewSession()
+	received := make(chan string, 1)
+	sess.AddHandler(func(s *dgo.Session, m *dgo.MessageCreate) {
+		received <- m.Content
+	})
+	srv.Open(sess)
+
+	srv.SendMessage(GuildMessage(member, "4", "hello"))
+	assert.Equal(t, "hello", <-received)
+}
+
+func Test_Server_reorderRoles(t *testing.T) {
+	srv := NewServer(t)
+	a := srv.AddRole("1", &dgo.Role{Name: "a"})
+	b := srv.AddRole("1", &dgo.Role{Name: "b"})
+
+	sess := srv.NewSession()
+	a.Position, b.Position = 2, 1
+	_, err := sess.GuildRoleReorder("1", []*dgo.Role{a, b})
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, role := range srv.Roles("1") {
+		names = append(names, role.Name)
+	}
+	assert.Equal(t, []string{"@everyone", "b", "a"}, names)
+}
+
+func Test_Server_listMembers(t *testing.T) {
+	srv := NewServer(t)
+	for _, id := range []string{"30", "4", "200"} {
+		srv.AddMember("1", &dgo.Member{User: &dgo.User{ID: id}})
+	}
+
+	sess := srv.NewSession()
+	ids := func(members []*dgo.Member) []string {
+		out := []string{}
+		for _, mem := range members {
+			out = append(out, mem.User.ID)
+		}
+		return out
+	}
+	page, err := sess.GuildMembers("1", "", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4", "30"}, ids(page))
+
+	page, err = sess.GuildMembers("1", "30", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"200"}, ids(page))
+}
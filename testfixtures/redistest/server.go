@@ -0,0 +1,402 @@
+// package redistest runs an in-process stand-in for a Redis server, which speaks enough of its
+// protocol (RESP) for the commands that lib.NewRedisStore sends.
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scanPageSize is how many keys SCAN returns at a time, kept small so that callers page through.
+const scanPageSize = 2
+
+type entry struct {
+	value   string
+	expires time.Time
+}
+
+// Server is a fake Redis server on a local port. It keeps one database, ignoring SELECT.
+type Server struct {
+	t        *testing.T
+	listener net.Listener
+	password string
+
+	mutex    sync.Mutex
+	data     map[string]entry
+	commands []string
+	cursors  []string
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a server that is closed when the test ends.
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+	return newServer(t, "")
+}
+
+// NewServerWithPassword starts a server that requires clients to AUTH with the password.
+func NewServerWithPassword(t *testing.T, password string) *Server {
+	t.Helper()
+	return newServer(t, password)
+}
+
+func newServer(t *testing.T, password string) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("redistest: failed to listen: %v", err)
+	}
+	s := &Server{
+		t:        t,
+		listener: listener,
+		password: password,
+		data:     make(map[string]entry),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// URL returns the address of the server as a redis:// URL, with the password if there is one.
+func (s *Server) URL() string {
+	if s.password != "" {
+		return fmt.Sprintf("redis://:%s@%s", s.password, s.listener.Addr())
+	}
+	return "redis://" + s.listener.Addr().String()
+}
+
+// Close stops the server and drops its connections, so that clients see errors.
+func (s *Server) Close() {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return
+	}
+	s.closed = true
+	s.listener.Close()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+}
+
+// Keys returns the keys that have not expired, in order.
+func (s *Server) Keys() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	keys := s.liveKeys()
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns a value the way a GET would.
+func (s *Server) Get(key string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, ok := s.lookup(key)
+	return e.value, ok
+}
+
+// TTL returns how long a key has left to live, or zero if it doesn't expire.
+func (s *Server) TTL(key string) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, ok := s.lookup(key)
+	if !ok || e.expires.IsZero() {
+		return 0
+	}
+	return time.Until(e.expires)
+}
+
+// Commands returns the names of the commands received so far, e.g. "GET".
+func (s *Server) Commands() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mutex.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+// serve reads commands from a connection and writes their replies until it is closed.
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			if err != io.EOF && !s.isClosed() {
+				s.t.Errorf("redistest: bad command: %v", err)
+			}
+			return
+		}
+		name := strings.ToUpper(args[0])
+		var reply string
+		switch {
+		case name == "AUTH":
+			authed = args[len(args)-1] == s.password
+			reply = s.authReply(authed)
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		default:
+			reply = s.exec(name, args[1:])
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closed
+}
+
+func (s *Server) authReply(ok bool) string {
+	if ok {
+		return "+OK\r\n"
+	}
+	return "-WRONGPASS invalid username-password pair\r\n"
+}
+
+// exec runs a command and returns its encoded reply.
+func (s *Server) exec(name string, args []string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.commands = append(s.commands, name)
+
+	switch name {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		e, ok := s.lookup(args[0])
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(e.value)
+	case "SET":
+		return s.set(args)
+	case "DEL":
+		deleted := 0
+		for _, key := range args {
+			if _, ok := s.lookup(key); ok {
+				deleted++
+			}
+			delete(s.data, key)
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "SCAN":
+		return s.scan(args)
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", name)
+	}
+}
+
+func (s *Server) set(args []string) string {
+	if len(args) != 2 && len(args) != 4 {
+		return wrongArgs("SET")
+	}
+	e := entry{value: args[1]}
+	if len(args) == 4 {
+		n, err := strconv.ParseInt(args[3], 10, 64)
+		if err != nil || n <= 0 {
+			return "-ERR invalid expire time in 'set' command\r\n"
+		}
+		switch strings.ToUpper(args[2]) {
+		case "PX":
+			e.expires = time.Now().Add(time.Duration(n) * time.Millisecond)
+		case "EX":
+			e.expires = time.Now().Add(time.Duration(n) * time.Second)
+		default:
+			return "-ERR syntax error\r\n"
+		}
+	}
+	s.data[args[0]] = e
+	return "+OK\r\n"
+}
+
+// scan pages through the sorted keys. Each cursor after 0 resumes after the last key of the page
+// before it, so that keys deleted between pages don't cause others to be skipped.
+func (s *Server) scan(args []string) string {
+	if len(args) == 0 {
+		return wrongArgs("SCAN")
+	}
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil || cursor < 0 || cursor > len(s.cursors) {
+		return "-ERR invalid cursor\r\n"
+	}
+	match := regexp.MustCompile(".*")
+	for i := 1; i+1 < len(args); i += 2 {
+		if strings.ToUpper(args[i]) == "MATCH" {
+			match = globPattern(args[i+1])
+		}
+	}
+
+	keys := s.liveKeys()
+	sort.Strings(keys)
+	start := 0
+	if cursor > 0 {
+		after := s.cursors[cursor-1]
+		start = sort.Search(len(keys), func(i int) bool { return keys[i] > after })
+	}
+	end := min(start+scanPageSize, len(keys))
+	next := 0
+	if end < len(keys) {
+		s.cursors = append(s.cursors, keys[end-1])
+		next = len(s.cursors)
+	}
+
+	var page []string
+	for _, key := range keys[start:end] {
+		if match.MatchString(key) {
+			page = append(page, key)
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*2\r\n%s*%d\r\n", bulk(strconv.Itoa(next)), len(page))
+	for _, key := range page {
+		sb.WriteString(bulk(key))
+	}
+	return sb.String()
+}
+
+// lookup returns an entry that has not expired, removing it if it has.
+func (s *Server) lookup(key string) (entry, bool) {
+	e, ok := s.data[key]
+	if ok && !e.expires.IsZero() && !time.Now().Before(e.expires) {
+		delete(s.data, key)
+		return entry{}, false
+	}
+	return e, ok
+}
+
+func (s *Server) liveKeys() []string {
+	var keys []string
+	for key := range s.data {
+		if _, ok := s.lookup(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func wrongArgs(name string) string {
+	return fmt.Sprintf("-ERR wrong number of arguments for '%s' command\r\n", strings.ToLower(name))
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("expected an array, got %q", line)
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("bad array length %q", line)
+	}
+	args := make([]string, count)
+	for i := range args {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected a bulk string, got %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("bad bulk string length %q", line)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// globPattern converts a MATCH pattern into a regexp. It handles *, ? and backslash escapes,
+// which is all that callers send.
+func globPattern(glob string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	escaped := false
+	for _, ch := range glob {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == '*':
+			sb.WriteString(".*")
+		case ch == '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}